@@ -1,26 +1,30 @@
 package surfnerd
 
 import (
+	"context"
 	"encoding/json"
 	"encoding/xml"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"math"
-	"strconv"
 	"strings"
 	"time"
 )
 
 const (
-	baseDataURL          = "http://www.ndbc.noaa.gov/data/realtime2/%s%s"
-	baseSpectraPlotURL   = "http://www.ndbc.noaa.gov/spec_plot.php?station=%s"
-	baseLatestReadingURL = "http://www.ndbc.noaa.gov/data/latest_obs/%s.txt"
-	baseAlphaSpectraURL  = "http://www.ndbc.noaa.gov/data/realtime2/%s.swdir"
-	baseEnergyURL        = "http://www.ndbc.noaa.gov/data/realtime2/%s.data_spec"
+	baseDataURL          = "%s/data/realtime2/%s%s"
+	baseSpectraPlotURL   = "%s/spec_plot.php?station=%s"
+	baseLatestReadingURL = "%s/data/latest_obs/%s.txt"
+	baseAlphaSpectraURL  = "%s/data/realtime2/%s.swdir"
+	baseEnergyURL        = "%s/data/realtime2/%s.data_spec"
 	// Old URL for latest was "http://www.ndbc.noaa.gov/get_observation_as_xml.php?station=%s"
 	standardDataPostfix     = ".txt"
 	detailedWaveDataPostfix = ".spec"
+	alphaTwoSpectraPostfix  = ".swdir2"
+	rOneSpectraPostfix      = ".swr1"
+	rTwoSpectraPostfix      = ".swr2"
 	latestDateLayout        = "1504 MST 01/02/06"
 	standardDateLayout      = "1504 MST 01/02/2006"
 )
@@ -40,16 +44,59 @@ type Buoy struct {
 	WaterQuality string   `xml:"waterquality,attr"`
 	Dart         string   `xml:"dart,attr"`
 	BuoyData     []BuoyDataItem
+
+	// The data of the other realtime files, filled in by their Fetch functions
+	OceanData          []BuoyOceanItem          `xml:"-" json:",omitempty"`
+	ContinuousWindData []BuoyContinuousWindItem `xml:"-" json:",omitempty"`
+	SupplementalData   []BuoySupplementalItem   `xml:"-" json:",omitempty"`
+	SolarRadiationData []BuoySolarRadiationItem `xml:"-" json:",omitempty"`
+	CurrentData        []BuoyCurrentProfileItem `xml:"-" json:",omitempty"`
+	DartData           []BuoyDartItem           `xml:"-" json:",omitempty"`
+	HousekeepingData   []BuoyHousekeepingItem   `xml:"-" json:",omitempty"`
+
+	// The platform and sensor placement of the station, filled in by FetchStationMetadata
+	Metadata *StationMetadata `xml:"-" json:",omitempty"`
+
+	// How the Parse functions treat unreadable values, and the values skipped by the last lenient parse
+	ParseMode     ParseMode     `xml:"-" json:"-"`
+	ParseWarnings []*ParseError `xml:"-" json:",omitempty"`
 }
 
-// Finds a buoy for a given identification string
+// Finds a buoy for a given identification string. The station list is cached by the
+// DefaultStationCatalog, so looking up many buoys only downloads it once.
 func GetBuoyByID(stationID string) *Buoy {
-	buoy := BuoyStations{}
-	fetchError := buoy.GetAllActiveBuoyStations()
-	if fetchError != nil {
-		return nil
+	buoy, _ := GetBuoyByIDContext(context.Background(), nil, stationID)
+	return buoy
+}
+
+// Finds a buoy for a given identification string using the given context and Fetcher. A nil
+// Fetcher uses the DefaultFetcher. If the station list is fetched but the buoy is not in it, the
+// returned buoy and error are both nil. The returned buoy is a copy and may be modified freely.
+func GetBuoyByIDContext(ctx context.Context, fetcher Fetcher, stationID string) (*Buoy, error) {
+	return DefaultStationCatalog.FindBuoyByIDContext(ctx, fetcher, stationID)
+}
+
+// Copy the station details of the buoy, leaving out any data that was fetched for it
+func (b *Buoy) copyStation() *Buoy {
+	station := *b
+	if b.Location != nil {
+		location := *b.Location
+		station.Location = &location
 	}
-	return buoy.FindBuoyByID(stationID)
+	if b.Metadata != nil {
+		metadata := *b.Metadata
+		station.Metadata = &metadata
+	}
+	station.BuoyData = nil
+	station.OceanData = nil
+	station.ContinuousWindData = nil
+	station.SupplementalData = nil
+	station.SolarRadiationData = nil
+	station.CurrentData = nil
+	station.DartData = nil
+	station.HousekeepingData = nil
+	station.ParseWarnings = nil
+	return &station
 }
 
 // Returns if the buoy is active. This is functionally a check if the buoy
@@ -95,37 +142,55 @@ func (b Buoy) DoesBuoyHaveDartData() bool {
 
 // Creates and returns the url of the latest buoy buoy reading xml
 func (b Buoy) CreateLatestReadingURL() string {
-	return fmt.Sprintf(baseLatestReadingURL, b.StationID)
+	return fmt.Sprintf(baseLatestReadingURL, NDBCBaseURL, b.StationID)
 }
 
 // Creates and returns the url for fetching the buoys standard meterology report.
 // The url returns tab delimited ascii data.
 func (b Buoy) CreateStandardDataURL() string {
-	return fmt.Sprintf(baseDataURL, b.StationID, standardDataPostfix)
+	return fmt.Sprintf(baseDataURL, NDBCBaseURL, b.StationID, standardDataPostfix)
 }
 
 // Creates and returns the url for fetching the buoys detailed wave data.
 // The url returns tab delimited ascii data.
 func (b Buoy) CreateDetailedWaveDataURL() string {
-	return fmt.Sprintf(baseDataURL, b.StationID, detailedWaveDataPostfix)
+	return fmt.Sprintf(baseDataURL, NDBCBaseURL, b.StationID, detailedWaveDataPostfix)
 }
 
 // Creates and returns the url for fetching the raw directional wave spectra. This is the
 // primary wave direction component and is usually used with the raw energy wave spectra
 func (b Buoy) CreateDirectionalSpectraDataURL() string {
-	return fmt.Sprintf(baseAlphaSpectraURL, b.StationID)
+	return fmt.Sprintf(baseAlphaSpectraURL, NDBCBaseURL, b.StationID)
 }
 
 // Creates and returns the url for fetching the raw wave energy spectra. This is the
 // primary wave energy component and is usually used with the raw directional wave spectra
 func (b Buoy) CreateEnergySpectraDataURL() string {
-	return fmt.Sprintf(baseEnergyURL, b.StationID)
+	return fmt.Sprintf(baseEnergyURL, NDBCBaseURL, b.StationID)
+}
+
+// Creates and returns the url for fetching the raw alpha2 directional wave spectra, the second
+// principal wave direction of each frequency
+func (b Buoy) CreateSecondaryDirectionalSpectraDataURL() string {
+	return fmt.Sprintf(baseDataURL, NDBCBaseURL, b.StationID, alphaTwoSpectraPostfix)
+}
+
+// Creates and returns the url for fetching the raw r1 directional wave spectra, the first
+// normalized polar coordinate of the Fourier coefficients of each frequency
+func (b Buoy) CreateR1SpectraDataURL() string {
+	return fmt.Sprintf(baseDataURL, NDBCBaseURL, b.StationID, rOneSpectraPostfix)
+}
+
+// Creates and returns the url for fetching the raw r2 directional wave spectra, the second
+// normalized polar coordinate of the Fourier coefficients of each frequency
+func (b Buoy) CreateR2SpectraDataURL() string {
+	return fmt.Sprintf(baseDataURL, NDBCBaseURL, b.StationID, rTwoSpectraPostfix)
 }
 
 // Creates and returns the url of the Buoys latest Spectral Density plot.
 // The url returns a jpeg image.
 func (b Buoy) CreateSpectraPlotURL() string {
-	return fmt.Sprintf(baseSpectraPlotURL, b.StationID)
+	return fmt.Sprintf(baseSpectraPlotURL, NDBCBaseURL, b.StationID)
 }
 
 func (b *Buoy) ParseRawLatestBuoyData(rawBuoyData string) error {
@@ -134,15 +199,14 @@ func (b *Buoy) ParseRawLatestBuoyData(rawBuoyData string) error {
 		return errors.New("Could not parse latest buoy data")
 	}
 
-	// Clear out old data if its hanging around
-	b.BuoyData = make([]BuoyDataItem, 1, 1)
+	parser := newValueParser(b.CreateLatestReadingURL(), b.ParseMode)
 
 	// Make a new buoy data item
 	buoyDataItem := BuoyDataItem{}
 
 	// Get the date
 	rawTime := rawBuoyLineData[4]
-	buoyDataItem.Date, _ = time.Parse(latestDateLayout, rawTime)
+	buoyDataItem.Date = parser.date(latestDateLayout, rawTime, 5, 1, "date")
 
 	buoyDataItem.Units = English
 	buoyDataItem.WaveSummary.ChangeUnits(English)
@@ -158,38 +222,43 @@ func (b *Buoy) ParseRawLatestBuoyData(rawBuoyData string) error {
 
 		variable := comps[0]
 		rawValue := strings.Split(strings.TrimSpace(comps[1]), " ")[0]
+		lineNumber := i + 1
 
 		switch variable {
 		case "Wind":
 			windComponents := strings.Split(comps[1], ",")
+			if len(windComponents) < 2 {
+				parser.fail(lineNumber, 2, variable, comps[1], ErrTruncatedRow)
+				break
+			}
 			buoyDataItem.WindDirection = DirectionToDegree(strings.Split(strings.TrimSpace(windComponents[0]), " ")[0])
-			buoyDataItem.WindSpeed, _ = strconv.ParseFloat(strings.Split(strings.TrimSpace(windComponents[1]), " ")[0], 64)
+			buoyDataItem.WindSpeed = parser.float(strings.Split(strings.TrimSpace(windComponents[1]), " ")[0], lineNumber, 4, variable)
 			buoyDataItem.WindSpeed = KnotsToMilesPerHour(buoyDataItem.WindSpeed)
 		case "Gust":
-			buoyDataItem.WindGust, _ = strconv.ParseFloat(rawValue, 64)
+			buoyDataItem.WindGust = parser.float(rawValue, lineNumber, 2, variable)
 			buoyDataItem.WindGust = KnotsToMilesPerHour(buoyDataItem.WindGust)
 		case "Seas":
-			buoyDataItem.WaveSummary.WaveHeight, _ = strconv.ParseFloat(rawValue, 64)
+			buoyDataItem.WaveSummary.WaveHeight = parser.float(rawValue, lineNumber, 2, variable)
 		case "Peak Period":
-			buoyDataItem.WaveSummary.Period, _ = strconv.ParseFloat(rawValue, 64)
+			buoyDataItem.WaveSummary.Period = parser.float(rawValue, lineNumber, 3, variable)
 		case "Pres":
-			buoyDataItem.Pressure, _ = strconv.ParseFloat(rawValue, 64)
+			buoyDataItem.Pressure = parser.float(rawValue, lineNumber, 2, variable)
 		case "Air Temp":
-			buoyDataItem.AirTemperature, _ = strconv.ParseFloat(rawValue, 64)
+			buoyDataItem.AirTemperature = parser.float(rawValue, lineNumber, 3, variable)
 		case "Water Temp":
-			buoyDataItem.WaterTemperature, _ = strconv.ParseFloat(rawValue, 64)
+			buoyDataItem.WaterTemperature = parser.float(rawValue, lineNumber, 3, variable)
 		case "Dew Point":
-			buoyDataItem.DewpointTemperature, _ = strconv.ParseFloat(rawValue, 64)
+			buoyDataItem.DewpointTemperature = parser.float(rawValue, lineNumber, 3, variable)
 		case "Swell":
-			swellWaveComponent.WaveHeight, _ = strconv.ParseFloat(rawValue, 64)
+			swellWaveComponent.WaveHeight = parser.float(rawValue, lineNumber, 2, variable)
 		case "Wind Wave":
-			windWaveComponent.WaveHeight, _ = strconv.ParseFloat(rawValue, 64)
+			windWaveComponent.WaveHeight = parser.float(rawValue, lineNumber, 3, variable)
 		case "Period":
 			if !swellPeriodRead {
-				swellWaveComponent.Period, _ = strconv.ParseFloat(rawValue, 64)
+				swellWaveComponent.Period = parser.float(rawValue, lineNumber, 2, variable)
 				swellPeriodRead = true
 			} else {
-				windWaveComponent.Period, _ = strconv.ParseFloat(rawValue, 64)
+				windWaveComponent.Period = parser.float(rawValue, lineNumber, 2, variable)
 			}
 		case "Direction":
 			if !swellDirectionRead {
@@ -201,118 +270,180 @@ func (b *Buoy) ParseRawLatestBuoyData(rawBuoyData string) error {
 		default:
 			// Do Nothing
 		}
+
+		if failErr := parser.failed(); failErr != nil {
+			return failErr
+		}
+	}
+
+	if failErr := parser.failed(); failErr != nil {
+		return failErr
 	}
 
 	buoyDataItem.SwellComponents = []Swell{swellWaveComponent, windWaveComponent}
 	buoyDataItem.InterpolateDominantWaveDirection()
 
-	b.BuoyData[0] = buoyDataItem
+	// Clear out old data if its hanging around
+	b.BuoyData = []BuoyDataItem{buoyDataItem}
+	b.ParseWarnings = parser.warnings
 
 	return nil
 }
 
+// Parses the realtime standard meteorological data. The columns are found by name from the header
+// line, so files with extra, missing or reordered columns are read correctly. The data should be
+// given as lines. Giving the whitespace separated tokens of the whole file, as returned by
+// strings.Fields, is deprecated, and fails with ErrTruncatedRow when a row has a missing or extra
+// value. Input a negative integer or zero to parse all available data points.
 func (b *Buoy) ParseRawStandardData(rawData []string, dataCountLimit int) error {
-	const dataLineLength = 19
-	const headerLines = 2
-	dataLineCount := (len(rawData) / dataLineLength) - headerLines
-	if dataCountLimit < dataLineCount && dataCountLimit >= 0 {
-		dataLineCount = dataCountLimit
+	lines, linesErr := ndbcLines(rawData, b.CreateStandardDataURL())
+	if linesErr != nil {
+		return linesErr
 	}
+	return b.ParseStandardData(strings.NewReader(strings.Join(lines, "\n")), dataCountLimit)
+}
 
-	b.BuoyData = make([]BuoyDataItem, dataLineCount)
+// Parses realtime or archived standard meteorological data from a stream, such as an open file or
+// response body. Gzipped archives are decompressed as they are read. Input a negative integer or
+// zero to parse all available data points.
+func (b *Buoy) ParseStandardData(r io.Reader, dataCountLimit int) error {
+	buoyData := []BuoyDataItem{}
+	warnings, parseErr := b.readStandardData(r, dataCountLimit, func(item BuoyDataItem) error {
+		buoyData = append(buoyData, item)
+		return nil
+	})
+	if parseErr != nil {
+		return parseErr
+	}
 
-	itemIndex := 0
-	for line := headerLines; line < dataLineCount+headerLines; line++ {
-		lineBeginIndex := line * dataLineLength
-		if lineBeginIndex > len(rawData) {
-			break
-		}
-		newBuoyData := BuoyDataItem{}
-
-		// Units are metric by default
-		newBuoyData.Units = Metric
-		newBuoyData.Units = Metric
-		newBuoyData.WaveSummary.Units = Metric
-
-		rawDate := fmt.Sprintf("%s%s GMT %s/%s/%s", rawData[lineBeginIndex+3], rawData[lineBeginIndex+4], rawData[lineBeginIndex+1], rawData[lineBeginIndex+2], rawData[lineBeginIndex+0])
-		newBuoyData.Date, _ = time.Parse(standardDateLayout, rawDate)
-		newBuoyData.WindDirection, _ = strconv.ParseFloat(rawData[lineBeginIndex+5], 64)
-		newBuoyData.WindSpeed, _ = strconv.ParseFloat(rawData[lineBeginIndex+6], 64)
-		newBuoyData.WindGust, _ = strconv.ParseFloat(rawData[lineBeginIndex+7], 64)
-		newBuoyData.WaveSummary.WaveHeight, _ = strconv.ParseFloat(rawData[lineBeginIndex+8], 64)
-		newBuoyData.WaveSummary.Period, _ = strconv.ParseFloat(rawData[lineBeginIndex+9], 64)
-		newBuoyData.AveragePeriod, _ = strconv.ParseFloat(rawData[lineBeginIndex+10], 64)
-		newBuoyData.WaveSummary.Direction, _ = strconv.ParseFloat(rawData[lineBeginIndex+11], 64)
-		newBuoyData.WaveSummary.CompassDirection = DegreeToDirection(newBuoyData.WaveSummary.Direction)
-		newBuoyData.Pressure, _ = strconv.ParseFloat(rawData[lineBeginIndex+12], 64)
-		newBuoyData.AirTemperature, _ = strconv.ParseFloat(rawData[lineBeginIndex+13], 64)
-		newBuoyData.WaterTemperature, _ = strconv.ParseFloat(rawData[lineBeginIndex+14], 64)
-		newBuoyData.DewpointTemperature, _ = strconv.ParseFloat(rawData[lineBeginIndex+15], 64)
-		newBuoyData.Visibility, _ = strconv.ParseFloat(rawData[lineBeginIndex+16], 64)
-		newBuoyData.PressureTendency, _ = strconv.ParseFloat(rawData[lineBeginIndex+17], 64)
-		newBuoyData.WaterLevel, _ = strconv.ParseFloat(rawData[lineBeginIndex+18], 64)
-		newBuoyData.WaterLevel = FeetToMeters(newBuoyData.WaterLevel)
-
-		b.BuoyData[itemIndex] = newBuoyData
+	b.BuoyData = buoyData
+	b.ParseWarnings = warnings
+	return nil
+}
 
-		itemIndex++
+// Streams standard meteorological data like ParseStandardData, passing each item to fn as it is read
+// instead of collecting them in BuoyData, so archives of any size can be read with bounded memory.
+// An error returned by fn stops the parse and is returned.
+func (b *Buoy) ForEachStandardDataItem(r io.Reader, fn func(BuoyDataItem) error) error {
+	warnings, parseErr := b.readStandardData(r, 0, fn)
+	if parseErr != nil {
+		return parseErr
 	}
 
+	b.ParseWarnings = warnings
 	return nil
 }
 
-func (b *Buoy) ParseRawDetailedWaveData(rawData []string, dataCountLimit int) error {
-	const dataLineLength = 15
-	const headerLines = 2
-	dataLineCount := (len(rawData) / dataLineLength) - headerLines
-	if dataCountLimit < dataLineCount && dataCountLimit >= 0 {
-		dataLineCount = dataCountLimit
+func (b *Buoy) readStandardData(r io.Reader, dataCountLimit int, fn func(BuoyDataItem) error) ([]*ParseError, error) {
+	data, gzipErr := gunzipReaderIfCompressed(r)
+	if gzipErr != nil {
+		return nil, gzipErr
 	}
 
-	b.BuoyData = make([]BuoyDataItem, dataLineCount)
-
-	itemIndex := 0
-	for line := headerLines; line < dataLineCount+headerLines; line++ {
-		lineBeginIndex := line * dataLineLength
-		if lineBeginIndex > len(rawData) {
-			break
+	return readNDBCRows(data, b.CreateStandardDataURL(), b.ParseMode, dataCountLimit, func(table *ndbcTable, row ndbcRow, parser *valueParser) error {
+		item := standardDataItemFromRow(table, row, parser)
+		if parser.failed() != nil {
+			return nil
 		}
+		return fn(item)
+	})
+}
 
-		newBuoyData := BuoyDataItem{}
-		windWaveComponent := Swell{Units: Metric}
-		swellWaveComponent := Swell{Units: Metric}
-		newBuoyData.WaveSummary.Units = Metric
-		rawDate := fmt.Sprintf("%s%s GMT %s/%s/%s", rawData[lineBeginIndex+3], rawData[lineBeginIndex+4], rawData[lineBeginIndex+1], rawData[lineBeginIndex+2], rawData[lineBeginIndex+0])
-		newBuoyData.Date, _ = time.Parse(standardDateLayout, rawDate)
-		newBuoyData.WaveSummary.WaveHeight, _ = strconv.ParseFloat(rawData[lineBeginIndex+5], 64)
-		swellWaveComponent.WaveHeight, _ = strconv.ParseFloat(rawData[lineBeginIndex+6], 64)
-		swellWaveComponent.Period, _ = strconv.ParseFloat(rawData[lineBeginIndex+7], 64)
-		windWaveComponent.WaveHeight, _ = strconv.ParseFloat(rawData[lineBeginIndex+8], 64)
-		windWaveComponent.Period, _ = strconv.ParseFloat(rawData[lineBeginIndex+9], 64)
-		swellWaveComponent.CompassDirection = rawData[lineBeginIndex+10]
-		windWaveComponent.CompassDirection = rawData[lineBeginIndex+11]
-		newBuoyData.Steepness = rawData[lineBeginIndex+12]
-		newBuoyData.AveragePeriod, _ = strconv.ParseFloat(rawData[lineBeginIndex+13], 64)
-		newBuoyData.WaveSummary.Direction, _ = strconv.ParseFloat(rawData[lineBeginIndex+14], 64)
-		newBuoyData.WaveSummary.CompassDirection = DegreeToDirection(newBuoyData.WaveSummary.Direction)
-
-		newBuoyData.SwellComponents = []Swell{swellWaveComponent, windWaveComponent}
-		newBuoyData.InterpolateDominantPeriod()
-		newBuoyData.InterpolateDominantWaveDirection()
-
-		b.BuoyData[itemIndex] = newBuoyData
+// Parses the realtime detailed wave summary data. Like ParseRawStandardData the columns are found by
+// name, and the data should be given as lines rather than the deprecated tokens. Input a negative
+// integer or zero to parse all available data points.
+func (b *Buoy) ParseRawDetailedWaveData(rawData []string, dataCountLimit int) error {
+	lines, linesErr := ndbcLines(rawData, b.CreateDetailedWaveDataURL())
+	if linesErr != nil {
+		return linesErr
+	}
+	return b.ParseDetailedWaveData(strings.NewReader(strings.Join(lines, "\n")), dataCountLimit)
+}
 
-		itemIndex++
+// Parses the realtime detailed wave summary data from a stream, such as an open file or response
+// body. Input a negative integer or zero to parse all available data points.
+func (b *Buoy) ParseDetailedWaveData(r io.Reader, dataCountLimit int) error {
+	buoyData := []BuoyDataItem{}
+	warnings, parseErr := b.readDetailedWaveData(r, dataCountLimit, func(item BuoyDataItem) error {
+		buoyData = append(buoyData, item)
+		return nil
+	})
+	if parseErr != nil {
+		return parseErr
+	}
+
+	b.BuoyData = buoyData
+	b.ParseWarnings = warnings
+	return nil
+}
+
+// Streams detailed wave summary data like ParseDetailedWaveData, passing each item to fn as it is read
+// instead of collecting them in BuoyData. An error returned by fn stops the parse and is returned.
+func (b *Buoy) ForEachDetailedWaveDataItem(r io.Reader, fn func(BuoyDataItem) error) error {
+	warnings, parseErr := b.readDetailedWaveData(r, 0, fn)
+	if parseErr != nil {
+		return parseErr
 	}
 
+	b.ParseWarnings = warnings
 	return nil
 }
 
+func (b *Buoy) readDetailedWaveData(r io.Reader, dataCountLimit int, fn func(BuoyDataItem) error) ([]*ParseError, error) {
+	data, gzipErr := gunzipReaderIfCompressed(r)
+	if gzipErr != nil {
+		return nil, gzipErr
+	}
+
+	return readNDBCRows(data, b.CreateDetailedWaveDataURL(), b.ParseMode, dataCountLimit, func(table *ndbcTable, row ndbcRow, parser *valueParser) error {
+		item := detailedWaveDataItemFromRow(table, row, parser)
+		if parser.failed() != nil {
+			return nil
+		}
+		return fn(item)
+	})
+}
+
+// Create a BuoyDataItem for a detailed wave summary row of an NDBC table
+func detailedWaveDataItemFromRow(table *ndbcTable, row ndbcRow, parser *valueParser) BuoyDataItem {
+	newBuoyData := BuoyDataItem{}
+	windWaveComponent := Swell{Units: Metric}
+	swellWaveComponent := Swell{Units: Metric}
+	newBuoyData.WaveSummary.Units = Metric
+
+	newBuoyData.Date = table.date(row, parser)
+	newBuoyData.WaveSummary.WaveHeight = table.value(row, parser, "WVHT")
+	swellWaveComponent.WaveHeight = table.value(row, parser, "SwH")
+	swellWaveComponent.Period = table.value(row, parser, "SwP")
+	windWaveComponent.WaveHeight = table.value(row, parser, "WWH")
+	windWaveComponent.Period = table.value(row, parser, "WWP")
+	swellWaveComponent.CompassDirection, swellWaveComponent.Direction = parseCompassDirection(table.text(row, "SwD"))
+	windWaveComponent.CompassDirection, windWaveComponent.Direction = parseCompassDirection(table.text(row, "WWD"))
+	newBuoyData.Steepness = table.text(row, "STEEPNESS")
+	newBuoyData.AveragePeriod = table.value(row, parser, "APD")
+	newBuoyData.WaveSummary.Direction = table.value(row, parser, "MWD")
+	newBuoyData.WaveSummary.CompassDirection = DegreeToDirection(newBuoyData.WaveSummary.Direction)
+
+	newBuoyData.SwellComponents = []Swell{swellWaveComponent, windWaveComponent}
+	newBuoyData.InterpolateDominantPeriod()
+	newBuoyData.InterpolateDominantWaveDirection()
+
+	return newBuoyData
+}
+
 func (b *Buoy) ParseRawWaveSpectraData(rawAlphaData, rawEnergyData []string, dataCountLimit int) error {
 	const headerLines = 1
 	const firstAlphaDataIndex = 5
 	const seperationFrequencyIndex = 5
 
+	// Ignore the trailing newlines at the end of the files
+	for len(rawAlphaData) > 0 && strings.TrimSpace(rawAlphaData[len(rawAlphaData)-1]) == "" {
+		rawAlphaData = rawAlphaData[:len(rawAlphaData)-1]
+	}
+	for len(rawEnergyData) > 0 && strings.TrimSpace(rawEnergyData[len(rawEnergyData)-1]) == "" {
+		rawEnergyData = rawEnergyData[:len(rawEnergyData)-1]
+	}
+
 	// Parse the raw alpha data then the raw energy data
 	if len(rawAlphaData) != len(rawEnergyData) {
 		return errors.New("Swell direction and energy spectra data does not match, could not parse")
@@ -328,69 +459,240 @@ func (b *Buoy) ParseRawWaveSpectraData(rawAlphaData, rawEnergyData []string, dat
 		dataLineCount = dataCountLimit
 	}
 
-	b.BuoyData = make([]BuoyDataItem, dataLineCount)
+	alphaParser := newValueParser(b.CreateDirectionalSpectraDataURL(), b.ParseMode)
+	energyParser := newValueParser(b.CreateEnergySpectraDataURL(), b.ParseMode)
+	buoyData := make([]BuoyDataItem, dataLineCount)
 
 	// Run through all of the data, creating a new BuoySpectraItem for each
+	itemIndex := 0
 	for i := headerLines; i < dataLineCount+headerLines; i += 1 {
 		// Split the line by spaces
-		rawAlphaData[i] = strings.TrimSpace(rawAlphaData[i])
-		rawEnergyData[i] = strings.TrimSpace(rawEnergyData[i])
 		trimmedAlphaData := strings.Replace(rawAlphaData[i], "(", "", -1)
 		trimmedAlphaData = strings.Replace(trimmedAlphaData, ")", "", -1)
 		trimmedEnergyData := strings.Replace(rawEnergyData[i], "(", "", -1)
 		trimmedEnergyData = strings.Replace(trimmedEnergyData, ")", "", -1)
-		rawAlphaLine := strings.Split(trimmedAlphaData, " ")
-		rawEnergyLine := strings.Split(trimmedEnergyData, " ")
+		rawAlphaLine := strings.Fields(trimmedAlphaData)
+		rawEnergyLine := strings.Fields(trimmedEnergyData)
+		lineNumber := i + 1
+
+		// Each alpha row is the date followed by angle and frequency pairs, and each energy
+		// row adds the seperation frequency before its energy and frequency pairs
+		if len(rawAlphaLine) < firstAlphaDataIndex+2 {
+			alphaParser.fail(lineNumber, len(rawAlphaLine)+1, "alpha1", rawAlphaData[i], ErrTruncatedRow)
+		} else if len(rawEnergyLine) < len(rawAlphaLine)+1 {
+			energyParser.fail(lineNumber, len(rawEnergyLine)+1, "energy", rawEnergyData[i], ErrTruncatedRow)
+		}
+		if failErr := alphaParser.failed(); failErr != nil {
+			return failErr
+		} else if failErr := energyParser.failed(); failErr != nil {
+			return failErr
+		} else if len(rawAlphaLine) < firstAlphaDataIndex+2 || len(rawEnergyLine) < len(rawAlphaLine)+1 {
+			continue
+		}
 
-		freqCount := (len(rawAlphaLine) - 5) / 2
+		freqCount := (len(rawAlphaLine) - firstAlphaDataIndex) / 2
 
 		// Create the new item
-		buoyItem := BuoyDataItem{}
 		item := BuoySpectraItem{}
 
 		// Start with the date
 		rawDate := fmt.Sprintf("%s%s GMT %s/%s/%s", rawAlphaLine[3], rawAlphaLine[4], rawAlphaLine[1], rawAlphaLine[2], rawAlphaLine[0])
-		buoyItem.Date, _ = time.Parse(standardDateLayout, rawDate)
+		date := alphaParser.date(standardDateLayout, rawDate, lineNumber, 1, "date")
+		if date.IsZero() {
+			// The unreadable date is kept as a warning, but the row can not be placed in the series
+			if failErr := alphaParser.failed(); failErr != nil {
+				return failErr
+			}
+			continue
+		}
 
 		// Fill the frequency, direction, nad energy data
 		item.Frequencies = make([]float64, freqCount)
 		item.Angles = make([]float64, freqCount)
 		item.Energies = make([]float64, freqCount)
-		freqIndex := 0
-		for j := firstAlphaDataIndex; j < len(rawAlphaLine); j += 2 {
+		for freqIndex := 0; freqIndex < freqCount; freqIndex++ {
+			j := firstAlphaDataIndex + 2*freqIndex
+
 			// Get the frequency
-			item.Frequencies[freqIndex], _ = strconv.ParseFloat(rawAlphaLine[j+1], 64)
+			item.Frequencies[freqIndex] = alphaParser.float(rawAlphaLine[j+1], lineNumber, j+2, "frequency")
 
 			// Get the angle
-			item.Angles[freqIndex], _ = strconv.ParseFloat(rawAlphaLine[j], 64)
+			item.Angles[freqIndex] = alphaParser.float(rawAlphaLine[j], lineNumber, j+1, "alpha1")
 
 			// Get the energy
-			item.Energies[freqIndex], _ = strconv.ParseFloat(rawEnergyLine[j+1], 64)
-
-			// Increment the index
-			freqIndex += 1
+			item.Energies[freqIndex] = energyParser.float(rawEnergyLine[j+1], lineNumber, j+2, "energy")
 		}
 
 		// Get the seperation frequency
-		item.SeperationFrequency, _ = strconv.ParseFloat(rawEnergyLine[seperationFrequencyIndex], 64)
+		item.SeperationFrequency = energyParser.float(rawEnergyLine[seperationFrequencyIndex], lineNumber, seperationFrequencyIndex+1, "Sep_Freq")
+
+		if failErr := alphaParser.failed(); failErr != nil {
+			return failErr
+		} else if failErr := energyParser.failed(); failErr != nil {
+			return failErr
+		}
 
 		// Add the item!
-		buoyItem.WaveSpectra = item
-		buoyItem.WaveSummary = item.WaveSummary()
-		buoyItem.SwellComponents = item.FindSwellComponents()
-		buoyItem.Steepness = SolveSteepness(buoyItem.WaveSummary.WaveHeight, buoyItem.WaveSummary.Period)
-		buoyItem.AveragePeriod = item.AveragePeriod()
+		buoyData[itemIndex] = newSpectraBuoyDataItem(date, item)
 
-		b.BuoyData[i-headerLines] = buoyItem
+		itemIndex++
 	}
 
+	b.BuoyData = buoyData[:itemIndex]
+	b.ParseWarnings = append(alphaParser.warnings, energyParser.warnings...)
+
 	return nil
 }
 
+// Parses the raw alpha2, r1 and r2 spectra files and adds their coefficients to the wave spectra
+// already parsed by ParseRawWaveSpectraData, matching the observations by date.
+func (b *Buoy) ParseRawDirectionalCoefficientData(rawAlphaTwoData, rawROneData, rawRTwoData []string) error {
+	alphaTwoTable, alphaTwoErr := parseRealtimeSpectralTable(rawAlphaTwoData, b.CreateSecondaryDirectionalSpectraDataURL(), "alpha2", b.ParseMode)
+	if alphaTwoErr != nil {
+		return alphaTwoErr
+	}
+
+	rOneTable, rOneErr := parseRealtimeSpectralTable(rawROneData, b.CreateR1SpectraDataURL(), "r1", b.ParseMode)
+	if rOneErr != nil {
+		return rOneErr
+	}
+
+	rTwoTable, rTwoErr := parseRealtimeSpectralTable(rawRTwoData, b.CreateR2SpectraDataURL(), "r2", b.ParseMode)
+	if rTwoErr != nil {
+		return rTwoErr
+	}
+
+	return b.AddDirectionalCoefficients(alphaTwoTable, rOneTable, rTwoTable)
+}
+
+// Adds the alpha2, r1 and r2 coefficients to the wave spectra of each BuoyDataItem, matching the
+// observations by date. Coefficients without a matching observation are ignored, and observations
+// without matching coefficients get missing values.
+func (b *Buoy) AddDirectionalCoefficients(alphaTwoTable, rOneTable, rTwoTable *SpectralTable) error {
+	if len(b.BuoyData) == 0 {
+		return errors.New("No wave spectra to add the directional coefficients to")
+	}
+
+	tables := []*SpectralTable{alphaTwoTable, rOneTable, rTwoTable}
+	rows := make([]map[time.Time][]float64, len(tables))
+	for i, table := range tables {
+		if table == nil {
+			return errors.New("Missing directional coefficient data")
+		}
+
+		// Dates are keyed in UTC since parsed times may carry different locations for the same instant
+		rows[i] = map[time.Time][]float64{}
+		for j, date := range table.Dates {
+			rows[i][date.UTC()] = table.Values[j]
+		}
+		b.ParseWarnings = append(b.ParseWarnings, table.ParseWarnings...)
+	}
+
+	coefficientRow := func(index int, date time.Time, count int) []float64 {
+		if values, ok := rows[index][date.UTC()]; ok && len(values) == count {
+			return values
+		}
+
+		values := make([]float64, count)
+		for i := range values {
+			values[i] = MissingValue()
+		}
+		return values
+	}
+
+	for i := range b.BuoyData {
+		spectra := &b.BuoyData[i].WaveSpectra
+		count := len(spectra.Frequencies)
+		date := b.BuoyData[i].Date
+
+		spectra.SecondaryAngles = coefficientRow(0, date, count)
+		spectra.R1 = coefficientRow(1, date, count)
+		spectra.R2 = coefficientRow(2, date, count)
+	}
+
+	return nil
+}
+
+// Parses a realtime spectra file, where each row is the date followed by value and (frequency) pairs.
+// The field names the coefficient in the file and selects its missing value sentinel.
+func parseRealtimeSpectralTable(rawData []string, source, field string, mode ParseMode) (*SpectralTable, error) {
+	const firstDataIndex = 5
+
+	parser := newValueParser(source, mode)
+	spectra := &SpectralTable{}
+
+	for lineIndex, line := range rawData {
+		tokens := strings.Fields(strings.NewReplacer("(", "", ")", "").Replace(line))
+		lineNumber := lineIndex + 1
+		if len(tokens) == 0 || strings.HasPrefix(tokens[0], "#") {
+			continue
+		}
+
+		if len(tokens) < firstDataIndex+2 || (spectra.Frequencies != nil && len(tokens) < firstDataIndex+2*len(spectra.Frequencies)) {
+			parser.fail(lineNumber, len(tokens)+1, field, line, ErrTruncatedRow)
+			if failErr := parser.failed(); failErr != nil {
+				return nil, failErr
+			}
+			continue
+		}
+
+		rawDate := fmt.Sprintf("%s%s GMT %s/%s/%s", tokens[3], tokens[4], tokens[1], tokens[2], tokens[0])
+		date := parser.date(standardDateLayout, rawDate, lineNumber, 1, "date")
+		if date.IsZero() {
+			if failErr := parser.failed(); failErr != nil {
+				return nil, failErr
+			}
+			continue
+		}
+
+		freqCount := (len(tokens) - firstDataIndex) / 2
+		if spectra.Frequencies != nil {
+			freqCount = len(spectra.Frequencies)
+		}
+
+		frequencies := make([]float64, freqCount)
+		values := make([]float64, freqCount)
+		for i := range values {
+			j := firstDataIndex + 2*i
+			frequencies[i] = parser.float(tokens[j+1], lineNumber, j+2, "frequency")
+			values[i] = parser.float(tokens[j], lineNumber, j+1, field)
+		}
+
+		if failErr := parser.failed(); failErr != nil {
+			return nil, failErr
+		}
+
+		if spectra.Frequencies == nil {
+			spectra.Frequencies = frequencies
+		}
+		spectra.Dates = append(spectra.Dates, date)
+		spectra.Values = append(spectra.Values, values)
+	}
+
+	spectra.ParseWarnings = parser.warnings
+	return spectra, nil
+}
+
+// Read a compass direction column such as SSW, returning the direction and its degree value.
+// Missing directions are returned as an empty direction and a missing degree value.
+func parseCompassDirection(token string) (string, float64) {
+	degree := DirectionToDegree(token)
+	if degree < 0 {
+		return "", MissingValue()
+	}
+	return token, degree
+}
+
 // Fetches the latest buoy reading data from the buoy and fills the
 // BuoyData member with the latest value
 func (b *Buoy) FetchLatestBuoyReading() error {
-	rawData, error := fetchRawDataFromURL(b.CreateLatestReadingURL())
+	return b.FetchLatestBuoyReadingContext(context.Background(), nil)
+}
+
+// Fetches the latest buoy reading data using the given context and Fetcher. A nil
+// Fetcher uses the DefaultFetcher.
+func (b *Buoy) FetchLatestBuoyReadingContext(ctx context.Context, fetcher Fetcher) error {
+	rawData, error := fetchRawDataFromURL(ctx, fetcher, b.CreateLatestReadingURL())
 	if error != nil {
 		return error
 	}
@@ -407,39 +709,55 @@ func (b *Buoy) FetchLatestBuoyReading() error {
 // wave heights, periods, water temps, and wind. Input a negative integer or zero to download all
 // available data points.
 func (b *Buoy) FetchStandardData(dataCountLimit int) error {
-	rawData, fetchError := fetchSpaceDelimitedString(b.CreateStandardDataURL())
+	return b.FetchStandardDataContext(context.Background(), nil, dataCountLimit)
+}
+
+// Grabs the latest standard data using the given context and Fetcher. A nil Fetcher uses
+// the DefaultFetcher.
+func (b *Buoy) FetchStandardDataContext(ctx context.Context, fetcher Fetcher, dataCountLimit int) error {
+	body, fetchError := fetchStreamFromURL(ctx, fetcher, b.CreateStandardDataURL())
 	if fetchError != nil {
 		return fetchError
-	} else if rawData == nil {
-		return errors.New("No data received from NOAA Buoy")
 	}
+	defer body.Close()
 
-	return b.ParseRawStandardData(rawData, dataCountLimit)
+	return b.ParseStandardData(body, dataCountLimit)
 }
 
 // Grabs the latest spectral wave data as a time series of BuoyDataItem objects. This data contains things
 // like the primary and secondary swell components, and significant wave height. Input a negative integer
 // or zero to download all available data points
 func (b *Buoy) FetchDetailedWaveData(dataCountLimit int) error {
-	rawData, fetchError := fetchSpaceDelimitedString(b.CreateDetailedWaveDataURL())
+	return b.FetchDetailedWaveDataContext(context.Background(), nil, dataCountLimit)
+}
+
+// Grabs the latest spectral wave data using the given context and Fetcher. A nil Fetcher uses
+// the DefaultFetcher.
+func (b *Buoy) FetchDetailedWaveDataContext(ctx context.Context, fetcher Fetcher, dataCountLimit int) error {
+	body, fetchError := fetchStreamFromURL(ctx, fetcher, b.CreateDetailedWaveDataURL())
 	if fetchError != nil {
 		return fetchError
-	} else if rawData == nil {
-		return errors.New("No data received from NOAA Buoy")
 	}
+	defer body.Close()
 
-	return b.ParseRawDetailedWaveData(rawData, dataCountLimit)
+	return b.ParseDetailedWaveData(body, dataCountLimit)
 }
 
 func (b *Buoy) FetchRawWaveSpectraData(dataCountLimit int) error {
-	rawAlphaData, rawAlphaError := fetchLineDelimitedString(b.CreateDirectionalSpectraDataURL())
+	return b.FetchRawWaveSpectraDataContext(context.Background(), nil, dataCountLimit)
+}
+
+// Grabs the raw directional and energy wave spectra using the given context and Fetcher. A nil
+// Fetcher uses the DefaultFetcher.
+func (b *Buoy) FetchRawWaveSpectraDataContext(ctx context.Context, fetcher Fetcher, dataCountLimit int) error {
+	rawAlphaData, rawAlphaError := fetchLineDelimitedString(ctx, fetcher, b.CreateDirectionalSpectraDataURL())
 	if rawAlphaError != nil {
 		return rawAlphaError
 	} else if rawAlphaData == nil {
 		return errors.New("No directional data recieved for this buoy")
 	}
 
-	rawEnergyData, rawEnergyError := fetchLineDelimitedString(b.CreateEnergySpectraDataURL())
+	rawEnergyData, rawEnergyError := fetchLineDelimitedString(ctx, fetcher, b.CreateEnergySpectraDataURL())
 	if rawEnergyError != nil {
 		return rawEnergyError
 	} else if rawEnergyData == nil {
@@ -449,6 +767,37 @@ func (b *Buoy) FetchRawWaveSpectraData(dataCountLimit int) error {
 	return b.ParseRawWaveSpectraData(rawAlphaData, rawEnergyData, dataCountLimit)
 }
 
+// Grabs the raw wave spectra along with the alpha2, r1 and r2 directional coefficients, so the full
+// directional spectrum of each observation can be reconstructed with DirectionalSpectrum.
+func (b *Buoy) FetchDirectionalWaveSpectraData(dataCountLimit int) error {
+	return b.FetchDirectionalWaveSpectraDataContext(context.Background(), nil, dataCountLimit)
+}
+
+// Grabs the raw wave spectra and its directional coefficients using the given context and Fetcher.
+// A nil Fetcher uses the DefaultFetcher.
+func (b *Buoy) FetchDirectionalWaveSpectraDataContext(ctx context.Context, fetcher Fetcher, dataCountLimit int) error {
+	if spectraErr := b.FetchRawWaveSpectraDataContext(ctx, fetcher, dataCountLimit); spectraErr != nil {
+		return spectraErr
+	}
+
+	rawAlphaTwoData, rawAlphaTwoError := fetchLineDelimitedString(ctx, fetcher, b.CreateSecondaryDirectionalSpectraDataURL())
+	if rawAlphaTwoError != nil {
+		return rawAlphaTwoError
+	}
+
+	rawROneData, rawROneError := fetchLineDelimitedString(ctx, fetcher, b.CreateR1SpectraDataURL())
+	if rawROneError != nil {
+		return rawROneError
+	}
+
+	rawRTwoData, rawRTwoError := fetchLineDelimitedString(ctx, fetcher, b.CreateR2SpectraDataURL())
+	if rawRTwoError != nil {
+		return rawRTwoError
+	}
+
+	return b.ParseRawDirectionalCoefficientData(rawAlphaTwoData, rawROneData, rawRTwoData)
+}
+
 // Finds the closest BuoyDataItem to a given time and returns the data at that data point.
 // If it fails, the duration returned is -1.
 func (b *Buoy) FindConditionsForDateAndTime(date time.Time) (BuoyDataItem, time.Duration) {
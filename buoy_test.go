@@ -1,11 +1,27 @@
 package surfnerd
 
 import (
+	"bytes"
+	"compress/gzip"
+	"errors"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 )
 
+// A standard data file with the columns reordered, an unknown column added and TIDE removed
+const testReorderedStandardData = `#YY  MM DD hh mm WSPD WDIR GST  WVHT   DPD   APD MWD   PRES  ATMP  WTMP  DEWP  VIS PTDY  NEW
+#yr  mo dy hr mn m/s  degT m/s     m   sec   sec degT   hPa  degC  degC  degC  nmi  hPa    -
+2016 10 18 14 50  6.0 210  8.0   1.2     9   6.1 180 1015.2  15.1  16.8  12.3   MM -0.9  4.2
+`
+
+const testDetailedWaveData = `#YY  MM DD hh mm WVHT  SwH  SwP  WWH  WWP SwD WWD  STEEPNESS  APD MWD
+#yr  mo dy hr mn    m    m  sec    m  sec  -  degT     -      sec degT
+2016 10 18 14 00  1.4  1.1 10.0  0.8  5.0 SSE  SW    AVERAGE  6.1 160
+2016 10 18 13 00  1.5  1.2 10.8  0.8  5.3   S  SW      SWELL  6.2 170
+`
+
 func TestLatestBuoyReadingFetch(t *testing.T) {
 	buoy := GetBuoyByID("44097")
 	if buoy == nil {
@@ -81,3 +97,104 @@ func TestClosestBuoyDataFinder(t *testing.T) {
 		t.FailNow()
 	}
 }
+
+func TestStandardDataColumnsByName(t *testing.T) {
+	buoy := Buoy{StationID: "44017"}
+	if parseErr := buoy.ParseRawStandardData(strings.Split(testReorderedStandardData, "\n"), -1); parseErr != nil {
+		t.Fatal(parseErr)
+	}
+
+	if len(buoy.BuoyData) != 1 {
+		t.Fatalf("Expected 1 data item, got %d", len(buoy.BuoyData))
+	}
+	item := buoy.BuoyData[0]
+	if item.WindSpeed != 6.0 || item.WindDirection != 210 || item.PressureTendency != -0.9 {
+		t.Errorf("Columns were not mapped by name: %+v", item)
+	}
+	if !IsMissing(item.WaterLevel) {
+		t.Errorf("Expected the missing TIDE column to be missing, got %v", item.WaterLevel)
+	}
+}
+
+func TestDetailedWaveDataLinesAndTokens(t *testing.T) {
+	fromLines := Buoy{StationID: "44017"}
+	if parseErr := fromLines.ParseRawDetailedWaveData(strings.Split(testDetailedWaveData, "\n"), 1); parseErr != nil {
+		t.Fatal(parseErr)
+	}
+	fromTokens := Buoy{StationID: "44017"}
+	if parseErr := fromTokens.ParseRawDetailedWaveData(strings.Fields(testDetailedWaveData), -1); parseErr != nil {
+		t.Fatal(parseErr)
+	}
+
+	if len(fromLines.BuoyData) != 1 || len(fromTokens.BuoyData) != 2 {
+		t.Fatalf("Expected 1 and 2 data items, got %d and %d", len(fromLines.BuoyData), len(fromTokens.BuoyData))
+	}
+
+	item := fromTokens.BuoyData[1]
+	if item.SwellComponents[0].WaveHeight != 1.2 || item.SwellComponents[0].CompassDirection != "S" || item.Steepness != "SWELL" {
+		t.Errorf("Unexpected detailed wave item %+v", item)
+	}
+	if !fromLines.BuoyData[0].Date.Equal(fromTokens.BuoyData[0].Date) || fromLines.BuoyData[0].WaveSummary.WaveHeight != 1.4 {
+		t.Error("Lines and tokens were not parsed the same")
+	}
+}
+
+func TestStandardDataTokensWithMissingValue(t *testing.T) {
+	// Dropping the wind speed of the first row shifts every later token into the wrong column
+	tokens := strings.Fields(testStandardData)
+	for i, token := range tokens {
+		if token == "6.0" {
+			tokens = append(tokens[:i], tokens[i+1:]...)
+			break
+		}
+	}
+
+	buoy := Buoy{StationID: "44017"}
+	parseErr := buoy.ParseRawStandardData(tokens, -1)
+	if !errors.Is(parseErr, ErrTruncatedRow) {
+		t.Fatalf("Expected the shifted tokens to fail with ErrTruncatedRow, got %v", parseErr)
+	}
+	if len(buoy.BuoyData) != 0 {
+		t.Errorf("Expected no data from the shifted tokens, got %d items", len(buoy.BuoyData))
+	}
+}
+
+func TestForEachStandardDataItemStreamsGzippedData(t *testing.T) {
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	writer.Write([]byte(testHistoricalStandardData))
+	writer.Close()
+
+	buoy := Buoy{StationID: "44017"}
+	dates := []time.Time{}
+	streamErr := buoy.ForEachStandardDataItem(&compressed, func(item BuoyDataItem) error {
+		dates = append(dates, item.Date)
+		return nil
+	})
+	if streamErr != nil {
+		t.Fatal(streamErr)
+	}
+	if len(dates) == 0 || len(buoy.BuoyData) != 0 {
+		t.Fatalf("Expected items to be streamed without being stored, got %d items and %d stored", len(dates), len(buoy.BuoyData))
+	}
+
+	if parseErr := buoy.ParseStandardData(strings.NewReader(testHistoricalStandardData), -1); parseErr != nil {
+		t.Fatal(parseErr)
+	}
+	if len(buoy.BuoyData) != len(dates) || !buoy.BuoyData[0].Date.Equal(dates[0]) {
+		t.Error("Expected the streamed items to match the parsed items")
+	}
+}
+
+func TestForEachDetailedWaveDataItemStops(t *testing.T) {
+	errStop := errors.New("stop")
+	count := 0
+	buoy := Buoy{StationID: "44097"}
+	streamErr := buoy.ForEachDetailedWaveDataItem(strings.NewReader(testDetailedWaveData), func(item BuoyDataItem) error {
+		count++
+		return errStop
+	})
+	if streamErr != errStop || count != 1 {
+		t.Errorf("Expected the callback error to stop the parse after 1 item, got %v after %d", streamErr, count)
+	}
+}
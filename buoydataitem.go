@@ -1,13 +1,14 @@
 package surfnerd
 
 import (
+	"encoding/json"
 	"math"
 	"time"
 )
 
 // Holds all of the data that a buoy could report in either the Standard Meteorological Data
 // or the Detailed Wave Data reports. Refer to http://www.ndbc.noaa.gov/data/realtime2/ for
-// detailed descriptions. All
+// detailed descriptions. Measurements the buoy did not report are missing, see IsMissing.
 type BuoyDataItem struct {
 	Date time.Time
 
@@ -16,6 +17,10 @@ type BuoyDataItem struct {
 	WindSpeed     float64 `json:",omitempty"`
 	WindGust      float64 `json:",omitempty"`
 
+	// Set once the wind speed and gust have been reduced to 10 meter neutral winds by
+	// Buoy.AdjustWindToTenMeters
+	TenMeterWinds bool `json:",omitempty"`
+
 	// Waves
 	WaveSummary     Swell           `json:",omitempty"`
 	SwellComponents []Swell         `json:",omitempty"`
@@ -36,6 +41,75 @@ type BuoyDataItem struct {
 	Units UnitSystem
 }
 
+// Convert a BuoyDataItem to json, writing missing measurements as null
+func (b BuoyDataItem) MarshalJSON() ([]byte, error) {
+	type plainItem BuoyDataItem
+	return json.Marshal(struct {
+		plainItem
+		WindDirection       nullableFloat `json:",omitempty"`
+		WindSpeed           nullableFloat `json:",omitempty"`
+		WindGust            nullableFloat `json:",omitempty"`
+		AveragePeriod       nullableFloat `json:",omitempty"`
+		Pressure            nullableFloat `json:",omitempty"`
+		AirTemperature      nullableFloat `json:",omitempty"`
+		WaterTemperature    nullableFloat `json:",omitempty"`
+		DewpointTemperature nullableFloat `json:",omitempty"`
+		Visibility          nullableFloat `json:",omitempty"`
+		PressureTendency    nullableFloat `json:",omitempty"`
+		WaterLevel          nullableFloat `json:",omitempty"`
+	}{
+		plainItem(b),
+		nullableFloat(b.WindDirection),
+		nullableFloat(b.WindSpeed),
+		nullableFloat(b.WindGust),
+		nullableFloat(b.AveragePeriod),
+		nullableFloat(b.Pressure),
+		nullableFloat(b.AirTemperature),
+		nullableFloat(b.WaterTemperature),
+		nullableFloat(b.DewpointTemperature),
+		nullableFloat(b.Visibility),
+		nullableFloat(b.PressureTendency),
+		nullableFloat(b.WaterLevel),
+	})
+}
+
+// Read a BuoyDataItem from json, reading null measurements as missing
+func (b *BuoyDataItem) UnmarshalJSON(data []byte) error {
+	type plainItem BuoyDataItem
+	shadow := struct {
+		*plainItem
+		WindDirection       nullableFloat
+		WindSpeed           nullableFloat
+		WindGust            nullableFloat
+		AveragePeriod       nullableFloat
+		Pressure            nullableFloat
+		AirTemperature      nullableFloat
+		WaterTemperature    nullableFloat
+		DewpointTemperature nullableFloat
+		Visibility          nullableFloat
+		PressureTendency    nullableFloat
+		WaterLevel          nullableFloat
+	}{plainItem: (*plainItem)(b)}
+
+	if err := json.Unmarshal(data, &shadow); err != nil {
+		return err
+	}
+
+	b.WindDirection = float64(shadow.WindDirection)
+	b.WindSpeed = float64(shadow.WindSpeed)
+	b.WindGust = float64(shadow.WindGust)
+	b.AveragePeriod = float64(shadow.AveragePeriod)
+	b.Pressure = float64(shadow.Pressure)
+	b.AirTemperature = float64(shadow.AirTemperature)
+	b.WaterTemperature = float64(shadow.WaterTemperature)
+	b.DewpointTemperature = float64(shadow.DewpointTemperature)
+	b.Visibility = float64(shadow.Visibility)
+	b.PressureTendency = float64(shadow.PressureTendency)
+	b.WaterLevel = float64(shadow.WaterLevel)
+	return nil
+}
+
+// Converts all of the measurements to the given unit system. Missing measurements stay missing.
 func (b *BuoyDataItem) ChangeUnits(newUnits UnitSystem) {
 	if newUnits == b.Units {
 		return
@@ -58,8 +132,8 @@ func (b *BuoyDataItem) ChangeUnits(newUnits UnitSystem) {
 		b.WaterTemperature = CelsiusToFahrenheit(b.WaterTemperature)
 		b.DewpointTemperature = CelsiusToFahrenheit(b.DewpointTemperature)
 		b.Pressure = HectoPascalToInchMercury(b.Pressure)
-		b.PressureTendency = HectoPascalToInchMercury(b.Pressure)
-		b.WaterLevel = FeetToMeters(b.WaterLevel)
+		b.PressureTendency = HectoPascalToInchMercury(b.PressureTendency)
+		b.WaterLevel = MetersToFeet(b.WaterLevel)
 	}
 
 	b.WaveSummary.ChangeUnits(newUnits)
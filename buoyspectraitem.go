@@ -1,6 +1,7 @@
 package surfnerd
 
 import (
+	"encoding/json"
 	"math"
 	"sort"
 
@@ -12,38 +13,32 @@ import (
 // To the data for a given frequency. The seperation frequency is what NDBC defines as the difference
 // between a Swell wave and a Wind wave.
 //
+// Angles holds the mean wave direction (alpha1) of each frequency. When the buoy reports its full
+// directional spectra, SecondaryAngles (alpha2), R1 and R2 hold the rest of the directional Fourier
+// coefficients, which are used to reconstruct the 2D directional spectrum.
+//
 // All of the math for this struct can be found here -> http://www.ndbc.noaa.gov/algor.shtml
 type BuoySpectraItem struct {
 	Frequencies []float64
 	Energies    []float64
 	Angles      []float64
 
+	SecondaryAngles []float64 `json:",omitempty"`
+	R1              []float64 `json:",omitempty"`
+	R2              []float64 `json:",omitempty"`
+
 	SeperationFrequency float64
 }
 
+// Get the average (zero crossing) period of the spectra, or -1 if there is no spectra
 func (b BuoySpectraItem) AveragePeriod() float64 {
-	zeroMoment := 0.0
-	secondMoment := 0.0
-
 	if b.Frequencies == nil {
 		return -1.0
 	} else if b.Energies == nil {
 		return -1.0
 	}
 
-	for index, _ := range b.Frequencies {
-		bandwidth := 0.01
-		if index > 0 {
-			bandwidth = math.Abs(b.Frequencies[index] - b.Frequencies[index-1])
-		} else if len(b.Frequencies) > 1 {
-			bandwidth = math.Abs(b.Frequencies[index+1] - b.Frequencies[index])
-		}
-
-		zeroMoment += SolveZeroSpectralMoment(b.Energies[index], bandwidth)
-		secondMoment += SolveSecondSpectralMoment(b.Energies[index], bandwidth, b.Frequencies[index])
-	}
-
-	return math.Sqrt(zeroMoment / secondMoment)
+	return b.ZeroCrossingPeriod()
 }
 
 func (b BuoySpectraItem) WaveSummary() Swell {
@@ -64,15 +59,13 @@ func (b BuoySpectraItem) WaveSummary() Swell {
 	maxEnergyIndex := -1
 	maxEnergy := -1.0
 	zeroMoment := 0.0
+	bandwidths := b.Bandwidths()
 	for index, _ := range b.Frequencies {
-		bandwidth := 0.01
-		if index > 0 {
-			bandwidth = math.Abs(b.Frequencies[index] - b.Frequencies[index-1])
-		} else if len(b.Frequencies) > 1 {
-			bandwidth = math.Abs(b.Frequencies[index+1] - b.Frequencies[index])
+		if IsMissing(b.Energies[index]) {
+			continue
 		}
 
-		zeroMoment += SolveZeroSpectralMoment(b.Energies[index], bandwidth)
+		zeroMoment += SolveZeroSpectralMoment(b.Energies[index], bandwidths[index])
 
 		if b.Energies[index] > maxEnergy {
 			maxEnergy = b.Energies[index]
@@ -80,6 +73,10 @@ func (b BuoySpectraItem) WaveSummary() Swell {
 		}
 	}
 
+	if maxEnergyIndex < 0 {
+		return Swell{}
+	}
+
 	primarySwell := Swell{Units: Metric}
 	primarySwell.WaveHeight = 4.0 * math.Sqrt(zeroMoment)
 	primarySwell.Period = 1.0 / b.Frequencies[maxEnergyIndex]
@@ -103,12 +100,13 @@ func (b BuoySpectraItem) FindSwellComponents() []Swell {
 	}
 
 	// Find the peaks from the energy data
-	minIndexes, _, maxIndexes, maxEnergies := peakdetect.PeakDetect(b.Energies, 0.01)
+	minIndexes, _, maxIndexes, maxEnergies := peakdetect.PeakDetect(b.knownEnergies(), 0.01)
 
 	// Allocate the list of components to the size of the local max peaks found
 	components := make([]Swell, len(maxIndexes), len(maxIndexes))
 
 	// Loop through and find all of the swell components
+	bandwidths := b.Bandwidths()
 	prevIndex := 0
 	for maxIndex, maxEnergy := range maxEnergies {
 		minIndex := prevIndex
@@ -120,14 +118,11 @@ func (b BuoySpectraItem) FindSwellComponents() []Swell {
 
 		zeroMoment := 0.0
 		for i := prevIndex; i < minIndex; i++ {
-			bandwidth := 0.01
-			if i > 0 {
-				bandwidth = math.Abs(b.Frequencies[i] - b.Frequencies[i-1])
-			} else if len(b.Frequencies) > 1 {
-				bandwidth = math.Abs(b.Frequencies[i+1] - b.Frequencies[i])
+			if IsMissing(b.Energies[i]) {
+				continue
 			}
 
-			zeroMoment += SolveZeroSpectralMoment(b.Energies[i], bandwidth)
+			zeroMoment += SolveZeroSpectralMoment(b.Energies[i], bandwidths[i])
 		}
 
 		// Add the component we found!!
@@ -147,3 +142,65 @@ func (b BuoySpectraItem) FindSwellComponents() []Swell {
 
 	return components
 }
+
+// Get the energies with the missing values replaced by zero, for the calculations that need
+// every frequency band to have a value
+func (b BuoySpectraItem) knownEnergies() []float64 {
+	energies := make([]float64, len(b.Energies))
+	for i, energy := range b.Energies {
+		if !IsMissing(energy) {
+			energies[i] = energy
+		}
+	}
+	return energies
+}
+
+func (b BuoySpectraItem) MarshalJSON() ([]byte, error) {
+	type plainItem BuoySpectraItem
+	return json.Marshal(struct {
+		plainItem
+		Frequencies         []nullableFloat
+		Energies            []nullableFloat
+		Angles              []nullableFloat
+		SecondaryAngles     []nullableFloat `json:",omitempty"`
+		R1                  []nullableFloat `json:",omitempty"`
+		R2                  []nullableFloat `json:",omitempty"`
+		SeperationFrequency nullableFloat
+	}{
+		plainItem(b),
+		nullableFloats(b.Frequencies),
+		nullableFloats(b.Energies),
+		nullableFloats(b.Angles),
+		nullableFloats(b.SecondaryAngles),
+		nullableFloats(b.R1),
+		nullableFloats(b.R2),
+		nullableFloat(b.SeperationFrequency),
+	})
+}
+
+func (b *BuoySpectraItem) UnmarshalJSON(data []byte) error {
+	type plainItem BuoySpectraItem
+	shadow := struct {
+		*plainItem
+		Frequencies         []nullableFloat
+		Energies            []nullableFloat
+		Angles              []nullableFloat
+		SecondaryAngles     []nullableFloat
+		R1                  []nullableFloat
+		R2                  []nullableFloat
+		SeperationFrequency nullableFloat
+	}{plainItem: (*plainItem)(b)}
+
+	if err := json.Unmarshal(data, &shadow); err != nil {
+		return err
+	}
+
+	b.Frequencies = floatsFromNullable(shadow.Frequencies)
+	b.Energies = floatsFromNullable(shadow.Energies)
+	b.Angles = floatsFromNullable(shadow.Angles)
+	b.SecondaryAngles = floatsFromNullable(shadow.SecondaryAngles)
+	b.R1 = floatsFromNullable(shadow.R1)
+	b.R2 = floatsFromNullable(shadow.R2)
+	b.SeperationFrequency = float64(shadow.SeperationFrequency)
+	return nil
+}
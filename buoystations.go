@@ -1,33 +1,60 @@
 package surfnerd
 
 import (
+	"context"
 	"encoding/json"
 	"encoding/xml"
 	"strings"
+	"sync"
 )
 
-const (
-	ActiveBuoysURL = "http://www.ndbc.noaa.gov/activestations.xml"
-)
+// The url of the NDBC active station list. Like NDBCBaseURL it may be overridden to point
+// at a mirror or a test server.
+var ActiveBuoysURL = "http://www.ndbc.noaa.gov/activestations.xml"
 
 // Container to hold all of the buoy locations that are reported by NOAA in their
-// active stations xml file. Works as an in
+// active stations xml file. Works as an in memory database of the stations, with a spatial index for
+// finding the stations near a location or inside a box.
 type BuoyStations struct {
 	XMLName      xml.Name `xml:"stations"`
 	CreationDate string   `xml:"created,attr"`
 	StationCount int      `xml:"count,attr"`
 	Stations     []*Buoy  `xml:"station"`
+
+	// The spatial index of the stations, built when they are loaded. It is held behind a pointer so
+	// BuoyStations values can be copied without copying its lock. Copies share the index, which is
+	// rebuilt for whichever copy searches with a different Stations slice than it was built from.
+	index *stationIndexState
+}
+
+// The spatial index of a BuoyStations and the lock guarding it
+type stationIndexState struct {
+	lock  sync.Mutex
+	index *stationIndex
 }
 
+// Guards the creation of the index state of BuoyStations values that have not built an index yet
+var stationIndexStateLock sync.Mutex
+
 // Fetch all of the buoy stations in xml format from the NOAA endpoint and parse them into buoy objects.
 // Returns true if the buoys were successfully parsed into the Stations variable
 func (b *BuoyStations) GetAllActiveBuoyStations() error {
-	rawStations, dlErr := fetchRawDataFromURL(ActiveBuoysURL)
+	return b.GetAllActiveBuoyStationsContext(context.Background(), nil)
+}
+
+// Fetch all of the buoy stations using the given context and Fetcher. A nil Fetcher uses
+// the DefaultFetcher.
+func (b *BuoyStations) GetAllActiveBuoyStationsContext(ctx context.Context, fetcher Fetcher) error {
+	rawStations, dlErr := fetchRawDataFromURL(ctx, fetcher, ActiveBuoysURL)
 	if dlErr != nil {
 		return dlErr
 	}
 
-	xml.Unmarshal(rawStations, b)
+	if xmlErr := xml.Unmarshal(rawStations, b); xmlErr != nil {
+		return xmlErr
+	}
+
+	b.BuildIndex()
 	return nil
 }
 
@@ -42,86 +69,93 @@ func (b *BuoyStations) FindBuoyByID(stationID string) *Buoy {
 }
 
 // Finds and returns the closest buoy to a given location
-// Lat and long should be in relative, not absolute (41.0, -71) not (41.5, 289)
+// The longitude may be in either the -180 to 180 or the 0 to 360 convention
 func (b *BuoyStations) FindClosestActiveBuoy(loc Location) *Buoy {
-	if len(b.Stations) < 1 {
+	closest := b.FindNearest(loc, 1, ActiveStationFilter)
+	if len(closest) < 1 {
 		return nil
 	}
-
-	var closestBuoy *Buoy = nil
-	closestDistance := 9999999999.9
-
-	for _, buoy := range b.Stations {
-		if !buoy.IsBuoyActive() {
-			continue
-		}
-
-		dist := loc.DistanceTo(*buoy.Location)
-		if dist < closestDistance {
-			closestBuoy = buoy
-			closestDistance = dist
-		}
-	}
-
-	return closestBuoy
+	return closest[0]
 }
 
 // Finds and returns the closest buoy with wave data to a given location
-// Lat and long should be in relative, not absolute (41.0, -71) not (41.5, 289)
+// The longitude may be in either the -180 to 180 or the 0 to 360 convention
 func (b *BuoyStations) FindClosestActiveWaveBuoy(loc Location) *Buoy {
+	closest := b.FindNearest(loc, 1, ActiveStationFilter.And(StationTypeFilter("buoy")))
+	if len(closest) < 1 {
+		return nil
+	}
+	return closest[0]
+}
+
+// Finds and returns the 3 closest buoys with wave data to a given location, closest first
+// The longitude may be in either the -180 to 180 or the 0 to 360 convention
+func (b *BuoyStations) FindClosestActiveWaveBuoys(loc Location) []*Buoy {
 	if len(b.Stations) < 1 {
 		return nil
 	}
 
-	var closestBuoy *Buoy = nil
-	closestDistance := 9999999999.9
+	return b.FindNearest(loc, 3, ActiveStationFilter.And(StationTypeFilter("buoy")))
+}
 
-	for _, buoy := range b.Stations {
-		if !buoy.IsBuoyActive() {
-			continue
-		} else if buoy.Type != "buoy" {
-			continue
-		}
+// Finds the k closest stations passing the filter to a given location, closest first. A nil filter
+// includes every station.
+func (b *BuoyStations) FindNearest(loc Location, k int, filter StationFilter) []*Buoy {
+	return stationMatchBuoys(b.spatialIndex().nearest(loc, k, filter))
+}
 
-		dist := loc.DistanceTo(*buoy.Location)
-		if dist < closestDistance {
-			closestBuoy = buoy
-			closestDistance = dist
-		}
-	}
+// Finds every station passing the filter within the given great circle distance in kilometers of a
+// location, closest first. A nil filter includes every station.
+func (b *BuoyStations) FindWithinRadius(loc Location, radius float64, filter StationFilter) []*Buoy {
+	return stationMatchBuoys(b.spatialIndex().withinChord(loc, chordForDistance(radius), filter))
+}
 
-	return closestBuoy
+// Finds every station passing the filter inside the box with the given bottom left and top right
+// corners. Boxes may cross the longitude seam, such as from 170 to -170. A nil filter includes every station.
+func (b *BuoyStations) FindInBoundingBox(bottomLeft, topRight Location, filter StationFilter) []*Buoy {
+	return b.spatialIndex().inBox(newGeoBox(bottomLeft, topRight), filter)
 }
 
-// Finds and returns the 3 closest buoys with wave data to a given location
-// Lat and long should be in relative, not absolute (41.0, -71) not (41.5, 289)
-func (b *BuoyStations) FindClosestActiveWaveBuoys(loc Location) []*Buoy {
-	if len(b.Stations) < 1 {
-		return nil
-	}
+// Rebuild the spatial index used by the Find functions. The index is built when the stations are
+// fetched and whenever the Stations slice is replaced or changes length, so this only needs to be called
+// after stations are moved or replaced in place.
+func (b *BuoyStations) BuildIndex() {
+	state := b.indexState()
+	state.lock.Lock()
+	defer state.lock.Unlock()
 
-	closestBuoys := make([]*Buoy, 3, 3)
-	closestDistances := [...]float64{9999999.999, 9999999.999, 9999999.999}
+	state.index = newStationIndex(b.Stations)
+}
 
-	for _, buoy := range b.Stations {
-		if !buoy.IsBuoyActive() {
-			continue
-		} else if buoy.Type != "buoy" {
-			continue
-		}
+// Get the spatial index, building it if the stations have changed
+func (b *BuoyStations) spatialIndex() *stationIndex {
+	state := b.indexState()
+	state.lock.Lock()
+	defer state.lock.Unlock()
+
+	if state.index == nil || !state.index.indexes(b.Stations) {
+		state.index = newStationIndex(b.Stations)
+	}
+	return state.index
+}
 
-		dist := loc.DistanceTo(*buoy.Location)
+// Get the index state, creating it the first time the index is used
+func (b *BuoyStations) indexState() *stationIndexState {
+	stationIndexStateLock.Lock()
+	defer stationIndexStateLock.Unlock()
 
-		for i := 0; i < 3; i++ {
-			if dist < closestDistances[i] {
-				closestBuoys[i] = buoy
-				closestDistances[i] = dist
-				break
-			}
-		}
+	if b.index == nil {
+		b.index = &stationIndexState{}
 	}
+	return b.index
+}
 
-	return closestBuoys
+func stationMatchBuoys(matches []stationMatch) []*Buoy {
+	buoys := make([]*Buoy, len(matches))
+	for i, match := range matches {
+		buoys[i] = match.buoy
+	}
+	return buoys
 }
 
 // Convert a Buoy object to a json formatted string
@@ -0,0 +1,407 @@
+package surfnerd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// The type of a variable in a DAP2 dataset
+type DAPType string
+
+const (
+	DAPByte      DAPType = "Byte"
+	DAPInt16     DAPType = "Int16"
+	DAPUInt16    DAPType = "UInt16"
+	DAPInt32     DAPType = "Int32"
+	DAPUInt32    DAPType = "UInt32"
+	DAPFloat32   DAPType = "Float32"
+	DAPFloat64   DAPType = "Float64"
+	DAPString    DAPType = "String"
+	DAPURL       DAPType = "Url"
+	DAPGrid      DAPType = "Grid"
+	DAPStructure DAPType = "Structure"
+	DAPSequence  DAPType = "Sequence"
+)
+
+// Get the DAPType for a type name of a DDS or DAS document, or an empty type if it is unknown.
+// Attribute containers may also use Alias, which is treated as a string.
+func dapTypeForName(name string) DAPType {
+	for _, dapType := range []DAPType{DAPByte, DAPInt16, DAPUInt16, DAPInt32, DAPUInt32, DAPFloat32, DAPFloat64, DAPString, DAPURL, DAPGrid, DAPStructure, DAPSequence} {
+		if strings.EqualFold(name, string(dapType)) {
+			return dapType
+		}
+	}
+	if strings.EqualFold(name, "Alias") {
+		return DAPString
+	}
+	return ""
+}
+
+// A dimension of a DAP array, such as [lat = 331]. Anonymous dimensions have an empty name.
+type DAPDimension struct {
+	Name string
+	Size int
+}
+
+// A variable declared in the DDS of a dataset. Grids hold their data array and a map array with the
+// coordinates of each of its dimensions, structures and sequences hold their members.
+type DAPVariable struct {
+	Name       string
+	Type       DAPType
+	Dimensions []DAPDimension
+	Array      *DAPVariable
+	Maps       []*DAPVariable
+	Members    []*DAPVariable
+}
+
+// The structure of a DAP dataset as described by its DDS
+type DAPDataset struct {
+	Name      string
+	Variables []*DAPVariable
+}
+
+// Find a top level variable of the dataset by name, or nil if it is not present
+func (d *DAPDataset) Variable(name string) *DAPVariable {
+	for _, variable := range d.Variables {
+		if variable.Name == name {
+			return variable
+		}
+	}
+	return nil
+}
+
+// An attribute from the DAS of a dataset. The values are kept as written, with strings unquoted.
+type DAPAttribute struct {
+	Type   DAPType
+	Values []string
+}
+
+// The attributes of a single variable, keyed by name
+type DAPAttributes map[string]DAPAttribute
+
+// The attributes of every variable of a dataset, keyed by variable name. NC_GLOBAL holds the
+// attributes of the dataset itself.
+type DAPAttributeTable map[string]DAPAttributes
+
+// Get the first value of a numeric attribute
+func (a DAPAttributes) Float(name string) (float64, bool) {
+	attribute, ok := a[name]
+	if !ok || len(attribute.Values) == 0 {
+		return 0, false
+	}
+
+	value, parseErr := strconv.ParseFloat(attribute.Values[0], 64)
+	if parseErr != nil {
+		return 0, false
+	}
+	return value, true
+}
+
+// Get the first value of an attribute as a string, or an empty string if it is not present
+func (a DAPAttributes) String(name string) string {
+	attribute, ok := a[name]
+	if !ok || len(attribute.Values) == 0 {
+		return ""
+	}
+	return attribute.Values[0]
+}
+
+// A typed multi dimensional array read from a DAP data response. Numeric values are stored as
+// float64 in row major order, string values in Strings. Arrays read from grids hold the coordinate
+// arrays of their dimensions in Maps.
+type DAPArray struct {
+	Name       string
+	Type       DAPType
+	Dimensions []DAPDimension
+	Values     []float64
+	Strings    []string
+	Attributes DAPAttributes
+	Maps       []*DAPArray
+}
+
+// Get the size of each dimension of the array
+func (a *DAPArray) Shape() []int {
+	shape := make([]int, len(a.Dimensions))
+	for i, dimension := range a.Dimensions {
+		shape[i] = dimension.Size
+	}
+	return shape
+}
+
+// Get the position in Values of the element at the given index in each dimension, or -1 if the
+// index is outside of the array
+func (a *DAPArray) Offset(indices ...int) int {
+	if len(indices) != len(a.Dimensions) {
+		return -1
+	}
+
+	offset := 0
+	for i, index := range indices {
+		if index < 0 || index >= a.Dimensions[i].Size {
+			return -1
+		}
+		offset = offset*a.Dimensions[i].Size + index
+	}
+	return offset
+}
+
+// Get the value at the given index in each dimension. Indices outside of the array are missing.
+func (a *DAPArray) At(indices ...int) float64 {
+	offset := a.Offset(indices...)
+	if offset < 0 || offset >= len(a.Values) {
+		return MissingValue()
+	}
+	return a.Values[offset]
+}
+
+// Find the coordinate array of a dimension of a grid by name, or nil if it is not present
+func (a *DAPArray) Map(name string) *DAPArray {
+	for _, dimensionMap := range a.Maps {
+		if dimensionMap.Name == name {
+			return dimensionMap
+		}
+	}
+	return nil
+}
+
+// Get the value used to mark cells without data, from the _FillValue or missing_value attribute
+func (a *DAPArray) FillValue() (float64, bool) {
+	if fill, ok := a.Attributes.Float("_FillValue"); ok {
+		return fill, true
+	}
+	return a.Attributes.Float("missing_value")
+}
+
+// Check if a value of the array is its fill value. The fill value of a Float32 array is compared at
+// single precision, as the attribute is usually written with more digits than the data holds.
+func (a *DAPArray) IsFill(value float64) bool {
+	fill, ok := a.FillValue()
+	if !ok {
+		return false
+	}
+	if a.Type == DAPFloat32 {
+		return float32(value) == float32(fill)
+	}
+	return value == fill || math.Abs(value-fill) <= math.Abs(fill)*1e-7
+}
+
+// Get the units of the array, or an empty string if they are not given
+func (a *DAPArray) Units() string {
+	return a.Attributes.String("units")
+}
+
+// A hyperslab selecting every Stride-th index from Start to Stop of a dimension, both inclusive
+type DAPSlice struct {
+	Start  int
+	Stride int
+	Stop   int
+}
+
+// Create a slice selecting every index from start to stop, both inclusive
+func NewDAPSlice(start, stop int) DAPSlice {
+	return DAPSlice{Start: start, Stride: 1, Stop: stop}
+}
+
+// Get the number of indices the slice selects
+func (s DAPSlice) Count() int {
+	stride := s.Stride
+	if stride < 1 {
+		stride = 1
+	}
+	if s.Stop < s.Start {
+		return 0
+	}
+	return (s.Stop-s.Start)/stride + 1
+}
+
+// A projection of a variable with a hyperslab for each of its dimensions, such as
+// htsgwsfc[0:1:60][246:1:246][171:1:171]. Requesting a grid by its name returns its maps as well,
+// while requesting grid.array returns only the data array.
+type DAPConstraint struct {
+	Name   string
+	Slices []DAPSlice
+}
+
+// Format the constraint as it is written in a DAP query
+func (c DAPConstraint) String() string {
+	var b strings.Builder
+	b.WriteString(c.Name)
+	for _, slice := range c.Slices {
+		stride := slice.Stride
+		if stride < 1 {
+			stride = 1
+		}
+		fmt.Fprintf(&b, "[%d:%d:%d]", slice.Start, stride, slice.Stop)
+	}
+	return b.String()
+}
+
+// Format a list of constraints as the query of a DAP request
+func dapQuery(constraints []DAPConstraint) string {
+	if len(constraints) == 0 {
+		return ""
+	}
+
+	projections := make([]string, len(constraints))
+	for i, constraint := range constraints {
+		projections[i] = constraint.String()
+	}
+	return "?" + strings.Join(projections, ",")
+}
+
+// A client for DAP2 (OPeNDAP) servers such as the NOMADS GrADS data server. Data is requested as
+// binary .dods responses, described by the .dds and .das documents of the dataset. The attributes
+// of each dataset are fetched once and cached.
+type DAPClient struct {
+	// The Fetcher used for requests. A nil Fetcher uses the DefaultFetcher.
+	Fetcher Fetcher
+
+	lock       sync.Mutex
+	attributes map[string]DAPAttributeTable
+}
+
+// Create a new DAPClient using the given Fetcher. A nil Fetcher uses the DefaultFetcher.
+func NewDAPClient(fetcher Fetcher) *DAPClient {
+	return &DAPClient{Fetcher: fetcher}
+}
+
+// Fetch the DDS describing the variables of a dataset, such as
+// http://nomads.ncep.noaa.gov:9090/dods/wave/mww3/20161018/multi_1.at_10m20161018_06z. Constraints
+// limit the description to the variables and hyperslabs they select.
+func (c *DAPClient) FetchDDS(ctx context.Context, datasetURL string, constraints ...DAPConstraint) (*DAPDataset, error) {
+	rawDDS, fetchErr := fetchRawDataFromURL(ctx, c.Fetcher, datasetURL+".dds"+dapQuery(constraints))
+	if fetchErr != nil {
+		return nil, fetchErr
+	}
+
+	text := string(rawDDS)
+	if serverErr := dapServerError(text); serverErr != nil {
+		return nil, serverErr
+	}
+	return parseDDS(text)
+}
+
+// Fetch the DAS holding the attributes of every variable of a dataset
+func (c *DAPClient) FetchDAS(ctx context.Context, datasetURL string) (DAPAttributeTable, error) {
+	c.lock.Lock()
+	cached, ok := c.attributes[datasetURL]
+	c.lock.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	rawDAS, fetchErr := fetchRawDataFromURL(ctx, c.Fetcher, datasetURL+".das")
+	if fetchErr != nil {
+		return nil, fetchErr
+	}
+
+	text := string(rawDAS)
+	if serverErr := dapServerError(text); serverErr != nil {
+		return nil, serverErr
+	}
+	table, parseErr := parseDAS(text)
+	if parseErr != nil {
+		return nil, parseErr
+	}
+
+	c.lock.Lock()
+	if c.attributes == nil {
+		c.attributes = map[string]DAPAttributeTable{}
+	}
+	c.attributes[datasetURL] = table
+	c.lock.Unlock()
+
+	return table, nil
+}
+
+// Fetch the variables selected by the constraints from a dataset as typed arrays, in the order the
+// server returns them. Each array and grid map is given its attributes from the DAS of the dataset.
+func (c *DAPClient) FetchArrays(ctx context.Context, datasetURL string, constraints ...DAPConstraint) ([]*DAPArray, error) {
+	attributes, dasErr := c.FetchDAS(ctx, datasetURL)
+	if dasErr != nil {
+		return nil, dasErr
+	}
+
+	body, fetchErr := fetchStreamFromURL(ctx, c.Fetcher, datasetURL+".dods"+dapQuery(constraints))
+	if fetchErr != nil {
+		return nil, fetchErr
+	}
+	defer body.Close()
+
+	arrays, readErr := ReadDAPData(body)
+	if readErr != nil {
+		return nil, readErr
+	}
+
+	for _, array := range arrays {
+		array.Attributes = attributes[array.Name]
+		for _, dimensionMap := range array.Maps {
+			dimensionMap.Attributes = attributes[dimensionMap.Name]
+		}
+	}
+	return arrays, nil
+}
+
+// Read a binary .dods response, such as one saved to disk, into typed arrays. The response starts
+// with the DDS describing the data, followed by a Data: line and the XDR encoded values.
+func ReadDAPData(r io.Reader) ([]*DAPArray, error) {
+	reader := bufio.NewReader(r)
+
+	var dds strings.Builder
+	for {
+		line, readErr := reader.ReadString('\n')
+		if strings.TrimRight(line, "\r\n") == "Data:" {
+			break
+		}
+		dds.WriteString(line)
+
+		if readErr == io.EOF {
+			if serverErr := dapServerError(dds.String()); serverErr != nil {
+				return nil, serverErr
+			}
+			return nil, ErrMalformedDAPResponse
+		} else if readErr != nil {
+			return nil, readErr
+		}
+	}
+
+	dataset, ddsErr := parseDDS(dds.String())
+	if ddsErr != nil {
+		return nil, ddsErr
+	}
+
+	arrays := make([]*DAPArray, 0, len(dataset.Variables))
+	for _, variable := range dataset.Variables {
+		array, decodeErr := readDAPVariable(reader, variable)
+		if decodeErr != nil {
+			return nil, decodeErr
+		}
+		arrays = append(arrays, array)
+	}
+	return arrays, nil
+}
+
+// The message of the error a DAP server reports in place of a response
+var dapErrorMessagePattern = regexp.MustCompile(`message\s*=\s*("(?:[^"\\]|\\.)*")`)
+
+// Get the error reported by a DAP server in place of a response, such as
+// Error { code = 0; message = "some problem"; };
+func dapServerError(text string) error {
+	trimmed := strings.TrimSpace(text)
+	if !strings.HasPrefix(trimmed, "Error") {
+		return nil
+	}
+
+	message := trimmed
+	if match := dapErrorMessagePattern.FindStringSubmatch(trimmed); match != nil {
+		message = unquoteDAPValue(match[1])
+	}
+	return fmt.Errorf("DAP request failed: %s", message)
+}
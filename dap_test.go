@@ -0,0 +1,175 @@
+package surfnerd
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+const testDDS = `Dataset {
+    Float64 time[time = 2];
+    Grid {
+     ARRAY:
+        Float32 htsgwsfc[time = 2][lat = 1][lon = 2];
+     MAPS:
+        Float64 time[time = 2];
+        Float64 lat[lat = 1];
+        Float64 lon[lon = 2];
+    } htsgwsfc;
+} multi_1.at_10m20161018_06z;
+`
+
+const testDAS = `Attributes {
+    htsgwsfc {
+        Float32 _FillValue 9.999E20;
+        Float32 missing_value 9.999E20;
+        String long_name "** surface sig height of wind waves and swell [m] ";
+        String units "m";
+    }
+    NC_GLOBAL {
+        String title "WW3 \"multi_1\" at 06z";
+        String history "created", "updated";
+    }
+}
+`
+
+func writeTestXDR(b *bytes.Buffer, values interface{}, count int) {
+	binary.Write(b, binary.BigEndian, uint32(count))
+	binary.Write(b, binary.BigEndian, uint32(count))
+	binary.Write(b, binary.BigEndian, values)
+}
+
+func testDODS() []byte {
+	var b bytes.Buffer
+	b.WriteString(testDDS + "Data:\n")
+	writeTestXDR(&b, []float64{736621.25, 736621.375}, 2)
+	writeTestXDR(&b, []float32{1.5, 9.999e20, 2.5, 3}, 4)
+	writeTestXDR(&b, []float64{736621.25, 736621.375}, 2)
+	writeTestXDR(&b, []float64{40.5}, 1)
+	writeTestXDR(&b, []float64{288.5, 288.666}, 2)
+	return b.Bytes()
+}
+
+func TestParseDDS(t *testing.T) {
+	dataset, parseErr := parseDDS(testDDS)
+	if parseErr != nil {
+		t.Fatal(parseErr)
+	}
+
+	if dataset.Name != "multi_1.at_10m20161018_06z" || len(dataset.Variables) != 2 {
+		t.Fatalf("Unexpected dataset %s with %d variables", dataset.Name, len(dataset.Variables))
+	}
+
+	grid := dataset.Variable("htsgwsfc")
+	if grid == nil || grid.Type != DAPGrid {
+		t.Fatal("Expected htsgwsfc to be a grid")
+	}
+	if grid.Array.Type != DAPFloat32 || len(grid.Maps) != 3 {
+		t.Errorf("Unexpected grid array %s with %d maps", grid.Array.Type, len(grid.Maps))
+	}
+	if len(grid.Dimensions) != 3 || grid.Dimensions[2].Name != "lon" || grid.Dimensions[2].Size != 2 {
+		t.Errorf("Unexpected grid dimensions %v", grid.Dimensions)
+	}
+
+	if _, badErr := parseDDS("Dataset { Float32 x[time = ]; } bad;"); !errors.Is(badErr, ErrMalformedDAPResponse) {
+		t.Errorf("Expected a malformed dimension to fail with ErrMalformedDAPResponse, got %v", badErr)
+	}
+	if _, typeErr := parseDDS("Dataset { Float128 x[time = 2]; } bad;"); !errors.Is(typeErr, ErrMalformedDAPResponse) {
+		t.Errorf("Expected an unknown type to fail with ErrMalformedDAPResponse, got %v", typeErr)
+	}
+}
+
+func TestParseDAS(t *testing.T) {
+	table, parseErr := parseDAS(testDAS)
+	if parseErr != nil {
+		t.Fatal(parseErr)
+	}
+
+	if fill, ok := table["htsgwsfc"].Float("_FillValue"); !ok || fill != 9.999e20 {
+		t.Errorf("Expected a fill value of 9.999e20, got %v", fill)
+	}
+	if units := table["htsgwsfc"].String("units"); units != "m" {
+		t.Errorf("Expected units of m, got %q", units)
+	}
+	if title := table["NC_GLOBAL"].String("title"); title != `WW3 "multi_1" at 06z` {
+		t.Errorf("Expected the title to be unquoted, got %q", title)
+	}
+	if history := table["NC_GLOBAL"]["history"]; len(history.Values) != 2 {
+		t.Errorf("Expected two history values, got %v", history.Values)
+	}
+}
+
+func TestReadDAPData(t *testing.T) {
+	arrays, readErr := ReadDAPData(bytes.NewReader(testDODS()))
+	if readErr != nil {
+		t.Fatal(readErr)
+	}
+	if len(arrays) != 2 {
+		t.Fatalf("Expected 2 arrays, got %d", len(arrays))
+	}
+
+	heights := arrays[1]
+	if shape := heights.Shape(); len(shape) != 3 || shape[0] != 2 || shape[2] != 2 {
+		t.Errorf("Unexpected shape %v", shape)
+	}
+	if value := heights.At(1, 0, 1); value != 3 {
+		t.Errorf("Expected 3 at [1][0][1], got %v", value)
+	}
+	if lon := heights.Map("lon"); lon == nil || math.Abs(lon.Values[1]-288.666) > 1e-9 {
+		t.Error("Expected the longitude map to be read")
+	}
+
+	heights.Attributes = DAPAttributes{
+		"_FillValue": DAPAttribute{Type: DAPFloat32, Values: []string{"9.999E20"}},
+		"units":      DAPAttribute{Type: DAPString, Values: []string{"m"}},
+	}
+	if !heights.IsFill(heights.At(0, 0, 1)) || heights.IsFill(heights.At(0, 0, 0)) {
+		t.Error("Expected only the second value to be a fill value")
+	}
+	if heights.Units() != "m" {
+		t.Errorf("Expected units of m, got %q", heights.Units())
+	}
+
+	if _, truncatedErr := ReadDAPData(bytes.NewReader(testDODS()[:len(testDDS)+20])); truncatedErr == nil {
+		t.Error("Expected a truncated response to fail")
+	}
+}
+
+func TestDAPServerError(t *testing.T) {
+	response := "Error {\n    code = 0;\n    message = \"GrADS error: no such variable \\\"foo\\\"; check the name\";\n};"
+	_, readErr := ReadDAPData(strings.NewReader(response))
+	if readErr == nil || readErr.Error() != `DAP request failed: GrADS error: no such variable "foo"; check the name` {
+		t.Errorf("Unexpected error %v", readErr)
+	}
+}
+
+func TestDAPClientFetchArrays(t *testing.T) {
+	datasetURL := "http://nomads.ncep.noaa.gov:9090/dods/wave/mww3/20161018/multi_1.at_10m20161018_06z"
+	constraints := []DAPConstraint{
+		{Name: "time", Slices: []DAPSlice{NewDAPSlice(0, 1)}},
+		{Name: "htsgwsfc", Slices: []DAPSlice{NewDAPSlice(0, 1), NewDAPSlice(246, 246), NewDAPSlice(171, 172)}},
+	}
+	if query := dapQuery(constraints); query != "?time[0:1:1],htsgwsfc[0:1:1][246:1:246][171:1:172]" {
+		t.Errorf("Unexpected query %s", query)
+	}
+
+	fetcher := mapFetcher{
+		datasetURL + ".das":                          testDAS,
+		datasetURL + ".dods" + dapQuery(constraints): string(testDODS()),
+	}
+	arrays, fetchErr := NewDAPClient(fetcher).FetchArrays(context.Background(), datasetURL, constraints...)
+	if fetchErr != nil {
+		t.Fatal(fetchErr)
+	}
+
+	if units := arrays[1].Units(); units != "m" {
+		t.Errorf("Expected the DAS units to be attached, got %q", units)
+	}
+	if fill, ok := arrays[1].FillValue(); !ok || fill != 9.999e20 {
+		t.Errorf("Expected the DAS fill value to be attached, got %v", fill)
+	}
+}
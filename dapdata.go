@@ -0,0 +1,129 @@
+package surfnerd
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Returned when a data response holds a structure or sequence, which the DAPClient does not decode
+var ErrUnsupportedDAPType = errors.New("Structures and sequences are not supported")
+
+// Decode the XDR encoded values of a variable from a .dods response. Grids are read as their data
+// array followed by their maps.
+func readDAPVariable(r io.Reader, variable *DAPVariable) (*DAPArray, error) {
+	switch variable.Type {
+	case DAPGrid:
+		array, arrayErr := readDAPVariable(r, variable.Array)
+		if arrayErr != nil {
+			return nil, arrayErr
+		}
+
+		for _, mapVariable := range variable.Maps {
+			dimensionMap, mapErr := readDAPVariable(r, mapVariable)
+			if mapErr != nil {
+				return nil, mapErr
+			}
+			array.Maps = append(array.Maps, dimensionMap)
+		}
+		return array, nil
+	case DAPStructure, DAPSequence:
+		return nil, ErrUnsupportedDAPType
+	}
+
+	array := &DAPArray{Name: variable.Name, Type: variable.Type, Dimensions: variable.Dimensions}
+	count := 1
+	for _, dimension := range variable.Dimensions {
+		count *= dimension.Size
+	}
+
+	// Arrays start with their length, which is repeated for every type but strings
+	if len(variable.Dimensions) > 0 {
+		length, lengthErr := readXDRUint32(r)
+		if lengthErr != nil {
+			return nil, lengthErr
+		}
+		if variable.Type != DAPString && variable.Type != DAPURL {
+			if _, repeatErr := readXDRUint32(r); repeatErr != nil {
+				return nil, repeatErr
+			}
+		}
+		if int(length) != count {
+			return nil, fmt.Errorf("%w: %s has %d values but its dimensions hold %d", ErrMalformedDAPResponse, variable.Name, length, count)
+		}
+	}
+
+	switch variable.Type {
+	case DAPString, DAPURL:
+		array.Strings = make([]string, count)
+		for i := range array.Strings {
+			value, stringErr := readXDRString(r)
+			if stringErr != nil {
+				return nil, stringErr
+			}
+			array.Strings[i] = value
+		}
+		return array, nil
+	case DAPByte:
+		// Bytes are packed, with the whole array padded to four bytes
+		raw := make([]byte, count+(4-count%4)%4)
+		if _, readErr := io.ReadFull(r, raw); readErr != nil {
+			return nil, readErr
+		}
+		array.Values = make([]float64, count)
+		for i := range array.Values {
+			array.Values[i] = float64(raw[i])
+		}
+		return array, nil
+	}
+
+	size := 4
+	if variable.Type == DAPFloat64 {
+		size = 8
+	}
+	raw := make([]byte, count*size)
+	if _, readErr := io.ReadFull(r, raw); readErr != nil {
+		return nil, readErr
+	}
+
+	array.Values = make([]float64, count)
+	for i := range array.Values {
+		word := raw[i*size : (i+1)*size]
+		switch variable.Type {
+		case DAPFloat64:
+			array.Values[i] = math.Float64frombits(binary.BigEndian.Uint64(word))
+		case DAPFloat32:
+			array.Values[i] = float64(math.Float32frombits(binary.BigEndian.Uint32(word)))
+		case DAPInt16, DAPInt32:
+			array.Values[i] = float64(int32(binary.BigEndian.Uint32(word)))
+		default:
+			array.Values[i] = float64(binary.BigEndian.Uint32(word))
+		}
+	}
+	return array, nil
+}
+
+// Read a four byte big endian unsigned integer
+func readXDRUint32(r io.Reader) (uint32, error) {
+	var word [4]byte
+	if _, readErr := io.ReadFull(r, word[:]); readErr != nil {
+		return 0, readErr
+	}
+	return binary.BigEndian.Uint32(word[:]), nil
+}
+
+// Read a length prefixed string padded to four bytes
+func readXDRString(r io.Reader) (string, error) {
+	length, lengthErr := readXDRUint32(r)
+	if lengthErr != nil {
+		return "", lengthErr
+	}
+
+	raw := make([]byte, int(length)+(4-int(length)%4)%4)
+	if _, readErr := io.ReadFull(r, raw); readErr != nil {
+		return "", readErr
+	}
+	return string(raw[:length]), nil
+}
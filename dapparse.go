@@ -0,0 +1,302 @@
+package surfnerd
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Returned when a DDS or DAS response does not follow the DAP2 grammar
+var ErrMalformedDAPResponse = errors.New("Malformed DAP response")
+
+// Reads the tokens of a DDS or DAS document
+type dapParser struct {
+	tokens []string
+	pos    int
+}
+
+// Split a DDS or DAS document into words, quoted strings and the punctuation of the grammar
+func newDAPParser(text string) (*dapParser, error) {
+	tokens := []string{}
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		c := runes[i]
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case strings.ContainsRune("{}[];=,:", c):
+			tokens = append(tokens, string(c))
+			i++
+		case c == '"':
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				if runes[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(runes) {
+				return nil, ErrMalformedDAPResponse
+			}
+			tokens = append(tokens, string(runes[i:end+1]))
+			i = end + 1
+		default:
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) && !strings.ContainsRune("{}[];=,:\"", runes[end]) {
+				end++
+			}
+			tokens = append(tokens, string(runes[i:end]))
+			i = end
+		}
+	}
+	return &dapParser{tokens: tokens}, nil
+}
+
+// Get the next token without consuming it, or an empty string at the end
+func (p *dapParser) peek() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+	return p.tokens[p.pos]
+}
+
+// Consume the next token
+func (p *dapParser) next() string {
+	token := p.peek()
+	p.pos++
+	return token
+}
+
+// Consume the next token, failing if it is not the expected one. Keywords are not case sensitive.
+func (p *dapParser) expect(expected string) error {
+	token := p.next()
+	if !strings.EqualFold(token, expected) {
+		return fmt.Errorf("%w: expected %q but found %q", ErrMalformedDAPResponse, expected, token)
+	}
+	return nil
+}
+
+// Parse a DDS document describing the variables of a dataset
+func parseDDS(text string) (*DAPDataset, error) {
+	parser, tokenErr := newDAPParser(text)
+	if tokenErr != nil {
+		return nil, tokenErr
+	}
+
+	if expectErr := parser.expect("Dataset"); expectErr != nil {
+		return nil, expectErr
+	}
+	variables, declErr := parser.declarations()
+	if declErr != nil {
+		return nil, declErr
+	}
+
+	dataset := &DAPDataset{Name: parser.next(), Variables: variables}
+	if expectErr := parser.expect(";"); expectErr != nil {
+		return nil, expectErr
+	}
+	return dataset, nil
+}
+
+// Parse a braced list of declarations
+func (p *dapParser) declarations() ([]*DAPVariable, error) {
+	if expectErr := p.expect("{"); expectErr != nil {
+		return nil, expectErr
+	}
+
+	variables := []*DAPVariable{}
+	for p.peek() != "}" {
+		if p.peek() == "" {
+			return nil, ErrMalformedDAPResponse
+		}
+
+		variable, declErr := p.declaration()
+		if declErr != nil {
+			return nil, declErr
+		}
+		variables = append(variables, variable)
+	}
+	p.next()
+
+	return variables, nil
+}
+
+// Parse a single declaration, such as Float32 htsgwsfc[time = 81][lat = 1][lon = 1];
+func (p *dapParser) declaration() (*DAPVariable, error) {
+	typeName := p.next()
+	variable := &DAPVariable{Type: dapTypeForName(typeName)}
+
+	switch variable.Type {
+	case DAPGrid:
+		if expectErr := p.expect("{"); expectErr != nil {
+			return nil, expectErr
+		}
+		if expectErr := p.expect("ARRAY"); expectErr != nil {
+			return nil, expectErr
+		}
+		if expectErr := p.expect(":"); expectErr != nil {
+			return nil, expectErr
+		}
+
+		array, arrayErr := p.declaration()
+		if arrayErr != nil {
+			return nil, arrayErr
+		}
+		variable.Array = array
+		variable.Dimensions = array.Dimensions
+
+		if expectErr := p.expect("MAPS"); expectErr != nil {
+			return nil, expectErr
+		}
+		if expectErr := p.expect(":"); expectErr != nil {
+			return nil, expectErr
+		}
+		for p.peek() != "}" {
+			if p.peek() == "" {
+				return nil, ErrMalformedDAPResponse
+			}
+
+			dimensionMap, mapErr := p.declaration()
+			if mapErr != nil {
+				return nil, mapErr
+			}
+			variable.Maps = append(variable.Maps, dimensionMap)
+		}
+		p.next()
+	case DAPStructure, DAPSequence:
+		members, membersErr := p.declarations()
+		if membersErr != nil {
+			return nil, membersErr
+		}
+		variable.Members = members
+	case "":
+		return nil, fmt.Errorf("%w: unknown type %q", ErrMalformedDAPResponse, typeName)
+	default:
+		variable.Name = p.next()
+		for p.peek() == "[" {
+			dimension, dimensionErr := p.dimension()
+			if dimensionErr != nil {
+				return nil, dimensionErr
+			}
+			variable.Dimensions = append(variable.Dimensions, dimension)
+		}
+		if expectErr := p.expect(";"); expectErr != nil {
+			return nil, expectErr
+		}
+		return variable, nil
+	}
+
+	variable.Name = p.next()
+	if expectErr := p.expect(";"); expectErr != nil {
+		return nil, expectErr
+	}
+	return variable, nil
+}
+
+// Parse an array dimension, either [size] or [name = size]
+func (p *dapParser) dimension() (DAPDimension, error) {
+	p.next()
+
+	dimension := DAPDimension{}
+	sizeToken := p.next()
+	if p.peek() == "=" {
+		p.next()
+		dimension.Name = sizeToken
+		sizeToken = p.next()
+	}
+
+	size, sizeErr := strconv.Atoi(sizeToken)
+	if sizeErr != nil {
+		return dimension, fmt.Errorf("%w: bad dimension size %q", ErrMalformedDAPResponse, sizeToken)
+	}
+	dimension.Size = size
+
+	return dimension, p.expect("]")
+}
+
+// Parse a DAS document holding the attributes of the variables of a dataset
+func parseDAS(text string) (DAPAttributeTable, error) {
+	parser, tokenErr := newDAPParser(text)
+	if tokenErr != nil {
+		return nil, tokenErr
+	}
+
+	if expectErr := parser.expect("Attributes"); expectErr != nil {
+		return nil, expectErr
+	}
+
+	table := DAPAttributeTable{}
+	if containerErr := parser.attributeContainer("", table); containerErr != nil {
+		return nil, containerErr
+	}
+	return table, nil
+}
+
+// Parse a braced attribute container. Nested containers are stored under their dotted path, such as
+// htsgwsfc.grads for a grads container inside the htsgwsfc container.
+func (p *dapParser) attributeContainer(path string, table DAPAttributeTable) error {
+	if expectErr := p.expect("{"); expectErr != nil {
+		return expectErr
+	}
+
+	for p.peek() != "}" {
+		name := p.next()
+		if name == "" {
+			return ErrMalformedDAPResponse
+		}
+
+		if p.peek() == "{" {
+			childPath := name
+			if path != "" {
+				childPath = path + "." + name
+			}
+			if _, exists := table[childPath]; !exists {
+				table[childPath] = DAPAttributes{}
+			}
+			if containerErr := p.attributeContainer(childPath, table); containerErr != nil {
+				return containerErr
+			}
+			continue
+		}
+
+		// An attribute is its type, its name and a comma separated list of values
+		attribute := DAPAttribute{Type: dapTypeForName(name)}
+		attributeName := p.next()
+		for {
+			value := p.next()
+			if value == "" {
+				return ErrMalformedDAPResponse
+			}
+			attribute.Values = append(attribute.Values, unquoteDAPValue(value))
+
+			separator := p.next()
+			if separator == ";" {
+				break
+			} else if separator != "," {
+				return fmt.Errorf("%w: expected , or ; after attribute %s", ErrMalformedDAPResponse, attributeName)
+			}
+		}
+
+		if table[path] == nil {
+			table[path] = DAPAttributes{}
+		}
+		table[path][attributeName] = attribute
+	}
+	p.next()
+
+	return nil
+}
+
+// Remove the quotes and escapes of a quoted string attribute value
+func unquoteDAPValue(value string) string {
+	if len(value) < 2 || value[0] != '"' {
+		return value
+	}
+
+	if unquoted, unquoteErr := strconv.Unquote(value); unquoteErr == nil {
+		return unquoted
+	}
+	return value[1 : len(value)-1]
+}
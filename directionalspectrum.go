@@ -0,0 +1,198 @@
+package surfnerd
+
+import (
+	"errors"
+	"math"
+	"math/cmplx"
+)
+
+// The method used to spread the energy of each frequency band over direction
+type DirectionalSpreadingMethod int
+
+const (
+	// The Maximum Entropy Method of Lygre and Krogstad (1986), which uses all four directional Fourier
+	// coefficients and can resolve two wave trains crossing in the same frequency band. Bands without
+	// a full set of coefficients fall back to the cosine method.
+	MaximumEntropyMethod DirectionalSpreadingMethod = iota
+
+	// The cos-2s spreading function of Longuet-Higgins et al. (1963) centered on the mean direction,
+	// with its width taken from r1. It only needs alpha1 and r1.
+	CosineSpreadingMethod
+)
+
+// The cos-2s spreading parameter used when a band has no r1 coefficient
+const defaultCosineSpreading = 10.0
+
+// The widest and narrowest cos-2s spreading allowed, keeping r1 values of 0 and 1 usable
+const (
+	minimumCosineSpreading = 0.5
+	maximumCosineSpreading = 100.0
+)
+
+// A 2D frequency-direction wave spectrum E(f,θ). Directions are in degrees and are the direction the
+// waves are coming from, like the NDBC mean wave directions. Energies are indexed by frequency and
+// then direction in m^2/Hz/degree, so integrating over direction gives back the 1D spectrum.
+type DirectionalSpectrum struct {
+	Frequencies []float64
+	Directions  []float64
+	Energies    [][]float64
+}
+
+// Check if the spectra has the full set of directional Fourier coefficients needed by the Maximum
+// Entropy Method
+func (b BuoySpectraItem) HasDirectionalCoefficients() bool {
+	count := len(b.Frequencies)
+	return count > 0 && len(b.Angles) == count && len(b.SecondaryAngles) == count && len(b.R1) == count && len(b.R2) == count
+}
+
+// Reconstruct the 2D frequency-direction spectrum with the given number of evenly spaced direction
+// bins. Frequency bands with a missing energy or mean direction have missing energies in every direction.
+func (b BuoySpectraItem) DirectionalSpectrum(directionCount int, method DirectionalSpreadingMethod) (*DirectionalSpectrum, error) {
+	if directionCount < 1 {
+		return nil, errors.New("The directional spectrum needs at least one direction")
+	} else if len(b.Frequencies) == 0 || len(b.Energies) != len(b.Frequencies) {
+		return nil, errors.New("The energy spectra does not match its frequencies")
+	} else if len(b.Angles) != len(b.Frequencies) {
+		return nil, errors.New("The spectra has no mean wave direction for each frequency")
+	}
+
+	resolution := 360.0 / float64(directionCount)
+	spectrum := &DirectionalSpectrum{
+		Frequencies: b.Frequencies,
+		Directions:  make([]float64, directionCount),
+		Energies:    make([][]float64, len(b.Frequencies)),
+	}
+	for i := range spectrum.Directions {
+		spectrum.Directions[i] = float64(i) * resolution
+	}
+
+	useMaximumEntropy := method == MaximumEntropyMethod && b.HasDirectionalCoefficients()
+	for i := range b.Frequencies {
+		spectrum.Energies[i] = make([]float64, directionCount)
+
+		if IsMissing(b.Energies[i]) || IsMissing(b.Angles[i]) {
+			for j := range spectrum.Energies[i] {
+				spectrum.Energies[i][j] = MissingValue()
+			}
+			continue
+		}
+
+		var spreading []float64
+		if useMaximumEntropy {
+			spreading = maximumEntropySpreading(spectrum.Directions, b.Angles[i], b.SecondaryAngles[i], b.R1[i], b.R2[i])
+		}
+		if spreading == nil {
+			r1 := MissingValue()
+			if len(b.R1) == len(b.Frequencies) {
+				r1 = b.R1[i]
+			}
+			spreading = cosineSpreading(spectrum.Directions, b.Angles[i], r1)
+		}
+
+		for j, weight := range spreading {
+			spectrum.Energies[i][j] = b.Energies[i] * weight
+		}
+	}
+
+	return spectrum, nil
+}
+
+// Integrate the energy over direction, giving the 1D spectrum for each frequency
+func (d DirectionalSpectrum) FrequencyEnergies() []float64 {
+	resolution := d.DirectionResolution()
+	energies := make([]float64, len(d.Frequencies))
+	for i := range d.Energies {
+		for _, energy := range d.Energies[i] {
+			energies[i] += energy * resolution
+		}
+	}
+	return energies
+}
+
+// Get the width of each direction bin in degrees
+func (d DirectionalSpectrum) DirectionResolution() float64 {
+	if len(d.Directions) == 0 {
+		return 0
+	}
+	return 360.0 / float64(len(d.Directions))
+}
+
+// The Maximum Entropy estimate of the directional spreading function from the first four Fourier
+// coefficients. Returns nil when the coefficients are missing or do not give a valid distribution.
+func maximumEntropySpreading(directions []float64, alpha1, alpha2, r1, r2 float64) []float64 {
+	if IsMissing(alpha1) || IsMissing(alpha2) || IsMissing(r1) || IsMissing(r2) {
+		return nil
+	}
+
+	c1 := cmplx.Rect(r1, alpha1*math.Pi/180.0)
+	c2 := cmplx.Rect(r2, 2.0*alpha2*math.Pi/180.0)
+	c1Squared := real(c1)*real(c1) + imag(c1)*imag(c1)
+	if c1Squared >= 1.0 {
+		return nil
+	}
+
+	phi1 := (c1 - c2*cmplx.Conj(c1)) / complex(1.0-c1Squared, 0)
+	phi2 := c2 - c1*phi1
+	numerator := real(1.0 - phi1*cmplx.Conj(c1) - phi2*cmplx.Conj(c2))
+
+	spreading := make([]float64, len(directions))
+	for i, direction := range directions {
+		theta := direction * math.Pi / 180.0
+		denominator := 1.0 - phi1*cmplx.Exp(complex(0, -theta)) - phi2*cmplx.Exp(complex(0, -2.0*theta))
+		spreading[i] = numerator / (2.0 * math.Pi * math.Pow(cmplx.Abs(denominator), 2))
+	}
+
+	return normalizeSpreading(spreading)
+}
+
+// The cos-2s estimate of the directional spreading function. The spreading parameter is related to
+// r1 by s = r1 / (1 - r1).
+func cosineSpreading(directions []float64, meanDirection, r1 float64) []float64 {
+	s := defaultCosineSpreading
+	if !IsMissing(r1) && r1 < 1.0 {
+		s = r1 / (1.0 - r1)
+	} else if !IsMissing(r1) {
+		s = maximumCosineSpreading
+	}
+	s = math.Max(minimumCosineSpreading, math.Min(s, maximumCosineSpreading))
+
+	spreading := make([]float64, len(directions))
+	for i, direction := range directions {
+		halfAngle := angleDifference(direction, meanDirection) * math.Pi / 360.0
+		spreading[i] = math.Pow(math.Cos(halfAngle), 2.0*s)
+	}
+
+	return normalizeSpreading(spreading)
+}
+
+// Scale a spreading function so it integrates to one over the direction bins, in units of 1/degree.
+// Returns nil if it has no valid weight.
+func normalizeSpreading(spreading []float64) []float64 {
+	resolution := 360.0 / float64(len(spreading))
+	total := 0.0
+	for _, weight := range spreading {
+		if math.IsNaN(weight) || math.IsInf(weight, 0) || weight < 0 {
+			return nil
+		}
+		total += weight * resolution
+	}
+	if total <= 0 {
+		return nil
+	}
+
+	for i := range spreading {
+		spreading[i] /= total
+	}
+	return spreading
+}
+
+// Get the signed difference between two compass angles in degrees, wrapped to -180 to 180
+func angleDifference(angle, reference float64) float64 {
+	difference := math.Mod(angle-reference, 360.0)
+	if difference > 180.0 {
+		difference -= 360.0
+	} else if difference < -180.0 {
+		difference += 360.0
+	}
+	return difference
+}
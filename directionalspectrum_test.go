@@ -0,0 +1,136 @@
+package surfnerd
+
+import (
+	"math"
+	"testing"
+)
+
+// Compute the directional Fourier coefficients of a spreading function, as a buoy would report them
+func directionalCoefficients(directions, spreading []float64) (alpha1, alpha2, r1, r2 float64) {
+	var a1, b1, a2, b2 float64
+	resolution := 360.0 / float64(len(directions))
+	for i, direction := range directions {
+		theta := direction * math.Pi / 180.0
+		weight := spreading[i] * resolution
+		a1 += weight * math.Cos(theta)
+		b1 += weight * math.Sin(theta)
+		a2 += weight * math.Cos(2*theta)
+		b2 += weight * math.Sin(2*theta)
+	}
+
+	alpha1 = math.Mod(math.Atan2(b1, a1)*180.0/math.Pi+360.0, 360.0)
+	alpha2 = math.Mod(math.Atan2(b2, a2)*90.0/math.Pi+360.0, 360.0)
+	r1 = math.Hypot(a1, b1)
+	r2 = math.Hypot(a2, b2)
+	return
+}
+
+// Find the directions of the local maximums of a spreading function
+func spreadingPeaks(directions, spreading []float64) []float64 {
+	peaks := []float64{}
+	count := len(spreading)
+	for i, weight := range spreading {
+		if weight > spreading[(i+count-1)%count] && weight >= spreading[(i+1)%count] {
+			peaks = append(peaks, directions[i])
+		}
+	}
+	return peaks
+}
+
+func TestCosineDirectionalSpectrum(t *testing.T) {
+	spectra := BuoySpectraItem{
+		Frequencies: []float64{0.08, 0.1},
+		Energies:    []float64{2.0, MissingValue()},
+		Angles:      []float64{200.0, 180.0},
+	}
+
+	spectrum, spectrumErr := spectra.DirectionalSpectrum(72, MaximumEntropyMethod)
+	if spectrumErr != nil {
+		t.Fatal(spectrumErr)
+	}
+
+	energies := spectrum.FrequencyEnergies()
+	if math.Abs(energies[0]-2.0) > 1e-9 {
+		t.Errorf("Expected the directional energy to integrate to 2.0, got %v", energies[0])
+	}
+	if peaks := spreadingPeaks(spectrum.Directions, spectrum.Energies[0]); len(peaks) != 1 || peaks[0] != 200.0 {
+		t.Errorf("Expected a single peak at 200, got %v", peaks)
+	}
+	if !IsMissing(spectrum.Energies[1][0]) {
+		t.Error("Expected the missing energy band to be missing")
+	}
+}
+
+func TestMaximumEntropyCrossingSwells(t *testing.T) {
+	directions := make([]float64, 360)
+	for i := range directions {
+		directions[i] = float64(i)
+	}
+
+	// Two equal swells crossing from the east and the south
+	east := cosineSpreading(directions, 90.0, 0.9)
+	south := cosineSpreading(directions, 180.0, 0.9)
+	combined := make([]float64, len(directions))
+	for i := range combined {
+		combined[i] = 0.5 * (east[i] + south[i])
+	}
+	alpha1, alpha2, r1, r2 := directionalCoefficients(directions, combined)
+
+	spectra := BuoySpectraItem{
+		Frequencies:     []float64{0.1},
+		Energies:        []float64{1.0},
+		Angles:          []float64{alpha1},
+		SecondaryAngles: []float64{alpha2},
+		R1:              []float64{r1},
+		R2:              []float64{r2},
+	}
+
+	spectrum, spectrumErr := spectra.DirectionalSpectrum(360, MaximumEntropyMethod)
+	if spectrumErr != nil {
+		t.Fatal(spectrumErr)
+	}
+
+	peaks := spreadingPeaks(spectrum.Directions, spectrum.Energies[0])
+	if len(peaks) != 2 || math.Abs(peaks[0]-90.0) > 10.0 || math.Abs(peaks[1]-180.0) > 10.0 {
+		t.Errorf("Expected peaks near 90 and 180, got %v", peaks)
+	}
+	if energies := spectrum.FrequencyEnergies(); math.Abs(energies[0]-1.0) > 1e-9 {
+		t.Errorf("Expected the directional energy to integrate to 1.0, got %v", energies[0])
+	}
+
+	// The cosine method can only find the mean direction between the two
+	cosine, _ := spectra.DirectionalSpectrum(360, CosineSpreadingMethod)
+	if peaks := spreadingPeaks(cosine.Directions, cosine.Energies[0]); len(peaks) != 1 || math.Abs(peaks[0]-135.0) > 1.0 {
+		t.Errorf("Expected a single cosine peak near 135, got %v", peaks)
+	}
+}
+
+func TestParseRawDirectionalCoefficientData(t *testing.T) {
+	buoy := Buoy{StationID: "44097"}
+	alpha := []string{
+		"#YY  MM DD hh mm alpha1_1 (freq_1) alpha1_2 (freq_2)",
+		"2016 10 18 14 40 180.0 (0.033) 190.0 (0.038)",
+	}
+	energy := []string{
+		"#YY  MM DD hh mm Sep_Freq  < spec_1 (freq_1) spec_2 (freq_2)",
+		"2016 10 18 14 40 0.150 0.5 (0.033) 1.5 (0.038)",
+	}
+	if parseErr := buoy.ParseRawWaveSpectraData(alpha, energy, 1); parseErr != nil {
+		t.Fatal(parseErr)
+	}
+
+	alphaTwo := []string{"#YY  MM DD hh mm alpha2_1 (freq_1) alpha2_2 (freq_2)", "2016 10 18 14 40 185.0 (0.033) 999.0 (0.038)"}
+	rOne := []string{"#YY  MM DD hh mm r1_1 (freq_1) r1_2 (freq_2)", "2016 10 18 14 40 0.8 (0.033) 0.7 (0.038)"}
+	rTwo := []string{"#YY  MM DD hh mm r2_1 (freq_1) r2_2 (freq_2)", "2016 10 18 14 40 0.6 (0.033) 0.5 (0.038)"}
+	if parseErr := buoy.ParseRawDirectionalCoefficientData(alphaTwo, rOne, rTwo); parseErr != nil {
+		t.Fatal(parseErr)
+	}
+
+	spectra := buoy.BuoyData[0].WaveSpectra
+	if !spectra.HasDirectionalCoefficients() {
+		t.Fatal("Expected the spectra to have directional coefficients")
+	}
+	if spectra.SecondaryAngles[0] != 185.0 || !IsMissing(spectra.SecondaryAngles[1]) || spectra.R1[1] != 0.7 || spectra.R2[0] != 0.6 {
+		t.Errorf("Unexpected coefficients %v %v %v", spectra.SecondaryAngles, spectra.R1, spectra.R2)
+	}
+}
@@ -0,0 +1,173 @@
+package surfnerd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// Retrieves the raw contents of a url. All of the network access in the package goes
+// through a Fetcher so callers can inject timeouts, proxies, caches or test doubles.
+type Fetcher interface {
+	Fetch(ctx context.Context, url string) ([]byte, error)
+}
+
+// Returned by a ConditionalFetcher when the resource has not changed since the validators were issued
+var ErrNotModified = errors.New("The resource has not been modified")
+
+// Wrapped by the errors of an HTTPFetcher when the server responds that a url does not exist. Other
+// Fetchers should wrap it as well so callers can tell a missing resource from a failed request.
+var ErrNotFound = errors.New("The resource was not found")
+
+// The values a server gives to identify a version of a resource, sent back to it as If-None-Match and
+// If-Modified-Since to revalidate a cached copy
+type CacheValidators struct {
+	ETag         string
+	LastModified string
+}
+
+// Checks if there are no validators to revalidate with
+func (c CacheValidators) IsEmpty() bool {
+	return c.ETag == "" && c.LastModified == ""
+}
+
+// A Fetcher that can revalidate a cached copy of a resource. FetchIfModified returns the contents and
+// the new validators when the resource has changed, and ErrNotModified when the cached copy is still
+// current. Caches in the package use it when the Fetcher they are given implements it and fall back to
+// a full Fetch otherwise.
+type ConditionalFetcher interface {
+	Fetcher
+	FetchIfModified(ctx context.Context, url string, validators CacheValidators) ([]byte, CacheValidators, error)
+}
+
+// A Fetcher that can return the body of a url as a stream rather than reading it into memory. The
+// parsers that can read a stream use it when the Fetcher they are given implements it, so large
+// archives are parsed with bounded memory. The caller must close the returned body.
+type StreamingFetcher interface {
+	Fetcher
+	FetchStream(ctx context.Context, url string) (io.ReadCloser, error)
+}
+
+// The default Fetcher implementation, wrapping a standard http client.
+type HTTPFetcher struct {
+	Client *http.Client
+}
+
+// The Fetcher used whenever nil is passed to one of the Context fetch variants, and by
+// all of the fetch functions that do not take a Fetcher.
+var DefaultFetcher Fetcher = NewHTTPFetcher(&http.Client{Timeout: 60 * time.Second})
+
+// Create a new HTTPFetcher using the given client. If the client is nil, http.DefaultClient is used.
+func NewHTTPFetcher(client *http.Client) *HTTPFetcher {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &HTTPFetcher{Client: client}
+}
+
+// Fetch the contents of the given url, respecting the cancellation and deadline of the context.
+// Responses with a non 200 status code are reported as errors, wrapping ErrNotFound for a 404.
+func (h *HTTPFetcher) Fetch(ctx context.Context, url string) ([]byte, error) {
+	body, fetchErr := h.FetchStream(ctx, url)
+	if fetchErr != nil {
+		return nil, fetchErr
+	}
+	defer body.Close()
+
+	return ioutil.ReadAll(body)
+}
+
+// Open the body of the given url as a stream, respecting the cancellation and deadline of the
+// context. Responses with a non 200 status code are reported as errors.
+func (h *HTTPFetcher) FetchStream(ctx context.Context, url string) (io.ReadCloser, error) {
+	request, requestErr := http.NewRequest("GET", url, nil)
+	if requestErr != nil {
+		return nil, requestErr
+	}
+	request = request.WithContext(ctx)
+
+	client := h.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	response, httpErr := client.Do(request)
+	if httpErr != nil {
+		return nil, httpErr
+	}
+
+	if response.StatusCode != http.StatusOK {
+		response.Body.Close()
+		return nil, statusError(url, response)
+	}
+
+	return response.Body, nil
+}
+
+// Fetch the contents of the given url unless it has not changed since the given validators were
+// issued, in which case ErrNotModified is returned.
+func (h *HTTPFetcher) FetchIfModified(ctx context.Context, url string, validators CacheValidators) ([]byte, CacheValidators, error) {
+	request, requestErr := http.NewRequest("GET", url, nil)
+	if requestErr != nil {
+		return nil, CacheValidators{}, requestErr
+	}
+	request = request.WithContext(ctx)
+	if validators.ETag != "" {
+		request.Header.Set("If-None-Match", validators.ETag)
+	}
+	if validators.LastModified != "" {
+		request.Header.Set("If-Modified-Since", validators.LastModified)
+	}
+
+	client := h.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	response, httpErr := client.Do(request)
+	if httpErr != nil {
+		return nil, CacheValidators{}, httpErr
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNotModified {
+		return nil, validators, ErrNotModified
+	} else if response.StatusCode != http.StatusOK {
+		return nil, CacheValidators{}, statusError(url, response)
+	}
+
+	data, readErr := ioutil.ReadAll(response.Body)
+	if readErr != nil {
+		return nil, CacheValidators{}, readErr
+	}
+
+	return data, CacheValidators{ETag: response.Header.Get("ETag"), LastModified: response.Header.Get("Last-Modified")}, nil
+}
+
+// Get the error for a response with a status other than 200 OK
+func statusError(url string, response *http.Response) error {
+	if response.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: request for %s failed with status %s", ErrNotFound, url, response.Status)
+	}
+	return fmt.Errorf("Request for %s failed with status %s", url, response.Status)
+}
+
+// Returns the given fetcher, or the DefaultFetcher if it is nil
+func fetcherOrDefault(fetcher Fetcher) Fetcher {
+	if fetcher == nil {
+		return DefaultFetcher
+	}
+	return fetcher
+}
+
+// Returns the given context, or a background context if it is nil
+func contextOrBackground(ctx context.Context) context.Context {
+	if ctx == nil {
+		return context.Background()
+	}
+	return ctx
+}
@@ -0,0 +1,76 @@
+package surfnerd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const testStandardData = `#YY  MM DD hh mm WDIR WSPD GST  WVHT   DPD   APD MWD   PRES  ATMP  WTMP  DEWP  VIS PTDY  TIDE
+#yr  mo dy hr mn degT m/s  m/s     m   sec   sec degT   hPa  degC  degC  degC  nmi  hPa    ft
+2016 10 18 14 50 210  6.0  8.0   1.2     9   6.1 180 1015.2  15.1  16.8  12.3   MM -0.9    MM
+2016 10 18 13 50 200  5.0  7.0   1.1     8   5.9 170 1015.8  15.0  16.8  12.1   MM -0.7    MM
+`
+
+// A Fetcher that serves canned responses keyed by url
+type mapFetcher map[string]string
+
+func (m mapFetcher) Fetch(ctx context.Context, url string) ([]byte, error) {
+	data, ok := m[url]
+	if !ok {
+		return nil, fmt.Errorf("%w: no canned response for %s", ErrNotFound, url)
+	}
+	return []byte(data), nil
+}
+
+func TestHTTPFetcherHonorsContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, fetchErr := NewHTTPFetcher(nil).Fetch(ctx, server.URL)
+	if fetchErr == nil {
+		t.Fatal("Expected the fetch to be cancelled by the context")
+	}
+}
+
+func TestHTTPFetcherStatusError(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	_, fetchErr := NewHTTPFetcher(server.Client()).Fetch(context.Background(), server.URL)
+	if !errors.Is(fetchErr, ErrNotFound) {
+		t.Fatalf("Expected a 404 response to be reported as ErrNotFound, got %v", fetchErr)
+	}
+}
+
+func TestStandardDataFetchWithFetcher(t *testing.T) {
+	buoy := Buoy{StationID: "44017"}
+	fetcher := mapFetcher{buoy.CreateStandardDataURL(): testStandardData}
+
+	fetchError := buoy.FetchStandardDataContext(context.Background(), fetcher, -1)
+	if fetchError != nil {
+		t.Fatal(fetchError)
+	}
+
+	if len(buoy.BuoyData) != 2 {
+		t.Fatalf("Expected 2 data items, got %d", len(buoy.BuoyData))
+	}
+	if buoy.BuoyData[0].WaveSummary.WaveHeight != 1.2 {
+		t.Fail()
+	}
+	if buoy.BuoyData[0].WindDirection != 210 {
+		t.Fail()
+	}
+}
@@ -0,0 +1,127 @@
+package surfnerd
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// How a FixtureFetcher treats the network and its fixture directory
+type FixtureMode int
+
+const (
+	// Serve every response from the fixture directory and never touch the network
+	FixtureReplay FixtureMode = iota
+
+	// Fetch every response from the upstream Fetcher and save it to the fixture directory
+	FixtureRecord
+
+	// Serve responses from the fixture directory, recording any that are missing
+	FixtureReplayOrRecord
+)
+
+// Returned when a FixtureFetcher is replaying and has no fixture for the requested url
+var ErrFixtureNotFound = errors.New("No fixture recorded for url")
+
+// A Fetcher that records raw NDBC and NOMADS responses to a directory and replays them later
+// without network access. Fixtures are keyed by the full url, so the same request always
+// maps to the same file.
+type FixtureFetcher struct {
+	Directory string
+	Mode      FixtureMode
+	Upstream  Fetcher
+
+	// The model run used by the fetch functions given the fetcher, instead of the newest one
+	// published. The model urls hold their run, so fixtures recorded with a run set here can be
+	// replayed long after newer runs have been published.
+	ModelRun time.Time
+}
+
+// Create a FixtureFetcher that records every response from upstream into the given directory.
+// A nil upstream uses the DefaultFetcher.
+func NewRecordingFetcher(directory string, upstream Fetcher) *FixtureFetcher {
+	return &FixtureFetcher{Directory: directory, Mode: FixtureRecord, Upstream: upstream}
+}
+
+// Create a FixtureFetcher that only serves responses previously recorded into the given directory.
+func NewReplayFetcher(directory string) *FixtureFetcher {
+	return &FixtureFetcher{Directory: directory, Mode: FixtureReplay}
+}
+
+// Fetch the given url from the fixture directory or the upstream Fetcher, depending on the mode.
+func (f *FixtureFetcher) Fetch(ctx context.Context, url string) ([]byte, error) {
+	fixturePath := f.FixturePath(url)
+
+	if f.Mode == FixtureReplay || f.Mode == FixtureReplayOrRecord {
+		data, readErr := ioutil.ReadFile(fixturePath)
+		if readErr == nil {
+			return data, nil
+		} else if !os.IsNotExist(readErr) {
+			return nil, readErr
+		} else if f.Mode == FixtureReplay {
+			return nil, fmt.Errorf("%w: %s", ErrFixtureNotFound, url)
+		}
+	}
+
+	data, fetchErr := fetcherOrDefault(f.Upstream).Fetch(ctx, url)
+	if fetchErr != nil {
+		return nil, fetchErr
+	}
+
+	if mkdirErr := os.MkdirAll(f.Directory, 0755); mkdirErr != nil {
+		return nil, mkdirErr
+	}
+	if writeErr := ioutil.WriteFile(fixturePath, data, 0644); writeErr != nil {
+		return nil, writeErr
+	}
+
+	return data, nil
+}
+
+// Get the model run the fixtures are recorded and replayed with, or the zero time to use the newest
+// run published
+func (f *FixtureFetcher) PinnedModelRun() time.Time {
+	return f.ModelRun
+}
+
+// Get the path of the fixture file used to store the response for the given url
+func (f *FixtureFetcher) FixturePath(url string) string {
+	return filepath.Join(f.Directory, FixtureFileName(url))
+}
+
+// Get the fixture file name for a given url. The name keeps a readable form of the url so the
+// fixture directory can be browsed by hand, followed by a hash of the whole url so long NOMADS
+// queries that only differ in their constraints never collide.
+func FixtureFileName(url string) string {
+	const maxReadableLength = 80
+
+	readable := url
+	if schemeIndex := strings.Index(readable, "://"); schemeIndex >= 0 {
+		readable = readable[schemeIndex+3:]
+	}
+	if queryIndex := strings.IndexAny(readable, "?"); queryIndex >= 0 {
+		readable = readable[:queryIndex]
+	}
+
+	readable = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-':
+			return r
+		default:
+			return '_'
+		}
+	}, readable)
+	if len(readable) > maxReadableLength {
+		readable = readable[len(readable)-maxReadableLength:]
+	}
+
+	hash := sha1.Sum([]byte(url))
+	return readable + "-" + hex.EncodeToString(hash[:6]) + ".fixture"
+}
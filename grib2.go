@@ -0,0 +1,480 @@
+package surfnerd
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// Returned when a GRIB2 message is truncated or does not follow the layout of the GRIB2 sections
+var ErrMalformedGRIB2 = errors.New("Malformed GRIB2 message")
+
+// Returned for GRIB2 grids, products and packings the reader does not decode, such as JPEG2000 packing
+var ErrUnsupportedGRIB2Template = errors.New("Unsupported GRIB2 template")
+
+// A regular latitude longitude grid, GRIB2 grid template 3.0. The values of fields on the grid are
+// reordered to start at the south west corner and run west to east along each row of latitude and
+// south to north across rows, the same layout as the [lat][lon] grids of the GrADS servers.
+type GRIB2Grid struct {
+	Latitudes           int
+	Longitudes          int
+	BottomLeftLocation  Location
+	TopRightLocation    Location
+	LatitudeResolution  float64
+	LongitudeResolution float64
+}
+
+// Get the index of the row and column of the grid cell holding a location, truncating to the south
+// west corner of the cell like NOAAModel.LocationIndices. Returns (-1,-1) if the location is not on the grid.
+func (g GRIB2Grid) LocationIndices(loc Location) (int, int) {
+	// A tolerance of a hundredth of a cell keeps locations on the edges of the grid from being rounded off it
+	const edgeTolerance = 0.01
+
+	latOffset := (loc.Latitude - g.BottomLeftLocation.Latitude) / g.LatitudeResolution
+	lonOffset := (normalizeLongitudeFrom(loc.Longitude, g.BottomLeftLocation.Longitude) - g.BottomLeftLocation.Longitude) / g.LongitudeResolution
+	if g.Latitudes == 1 {
+		latOffset = 0
+	}
+	if g.Longitudes == 1 {
+		lonOffset = 0
+	}
+
+	latIndex := int(latOffset + edgeTolerance)
+	lonIndex := int(lonOffset + edgeTolerance)
+	if latOffset < -edgeTolerance || lonOffset < -edgeTolerance || latIndex >= g.Latitudes || lonIndex >= g.Longitudes {
+		return -1, -1
+	}
+	return latIndex, lonIndex
+}
+
+// Get the location of the grid point at the given row and column
+func (g GRIB2Grid) Location(latIndex, lonIndex int) Location {
+	return NewLocationForLatLong(
+		g.BottomLeftLocation.Latitude+float64(latIndex)*g.LatitudeResolution,
+		g.BottomLeftLocation.Longitude+float64(lonIndex)*g.LongitudeResolution,
+	)
+}
+
+// A single field decoded from a GRIB2 message, such as the significant wave height of one forecast hour
+type GRIB2Field struct {
+	// The discipline, parameter category and parameter number identifying the field
+	Discipline int
+	Category   int
+	Number     int
+
+	// The GrADS style name of the field, such as htsgwsfc, swell_1 or ugrd10m
+	Name string
+
+	// The type and value of the fixed surface the field is on, such as 103 and 10 for 10 meters above ground
+	SurfaceType  int
+	SurfaceValue float64
+
+	// The UTC times of the model run and of the forecast
+	RunTime   time.Time
+	ValidTime time.Time
+
+	Grid GRIB2Grid
+
+	// The values of the grid points, with points left out by the bitmap or marked missing by the packing
+	// stored as MissingValue()
+	Values []float64
+}
+
+// Get the value of the grid point at the given row and column
+func (f *GRIB2Field) ValueAt(latIndex, lonIndex int) float64 {
+	return f.Values[latIndex*f.Grid.Longitudes+lonIndex]
+}
+
+// Get the number of hours between the run and the valid time of the field
+func (f *GRIB2Field) ForecastHour() int {
+	return int(f.ValidTime.Sub(f.RunTime).Hours())
+}
+
+// Read every field of a GRIB2 file, such as one downloaded from a NOMADS filter
+func ReadGRIB2(r io.Reader) ([]*GRIB2Field, error) {
+	fields := []*GRIB2Field{}
+	readErr := ForEachGRIB2Field(r, func(field *GRIB2Field) error {
+		fields = append(fields, field)
+		return nil
+	})
+	if readErr != nil {
+		return nil, readErr
+	}
+	return fields, nil
+}
+
+// Stream the fields of a GRIB2 file, passing each to fn as its message is decoded. Only one message is
+// held in memory at a time. An error returned by fn stops the read and is returned.
+func ForEachGRIB2Field(r io.Reader, fn func(field *GRIB2Field) error) error {
+	reader := bufio.NewReader(r)
+	for {
+		message, readErr := readGRIB2Message(reader)
+		if readErr == io.EOF {
+			return nil
+		} else if readErr != nil {
+			return readErr
+		}
+
+		fields, decodeErr := decodeGRIB2Message(message)
+		if decodeErr != nil {
+			return decodeErr
+		}
+		for _, field := range fields {
+			if fnErr := fn(field); fnErr != nil {
+				return fnErr
+			}
+		}
+	}
+}
+
+// Read the next whole message, starting at its indicator section. Returns io.EOF when there are no more.
+func readGRIB2Message(r io.Reader) ([]byte, error) {
+	indicator := make([]byte, 16)
+	if _, readErr := io.ReadFull(r, indicator); readErr == io.EOF {
+		return nil, io.EOF
+	} else if readErr != nil {
+		return nil, ErrMalformedGRIB2
+	}
+
+	if string(indicator[:4]) != "GRIB" {
+		return nil, fmt.Errorf("%w: missing GRIB indicator", ErrMalformedGRIB2)
+	} else if indicator[7] != 2 {
+		return nil, fmt.Errorf("%w: GRIB edition %d", ErrUnsupportedGRIB2Template, indicator[7])
+	}
+
+	length := binary.BigEndian.Uint64(indicator[8:16])
+	if length < 16+4 || length > math.MaxInt32 {
+		return nil, fmt.Errorf("%w: bad message length %d", ErrMalformedGRIB2, length)
+	}
+
+	message := make([]byte, length)
+	copy(message, indicator)
+	if _, readErr := io.ReadFull(r, message[16:]); readErr != nil {
+		return nil, fmt.Errorf("%w: message is truncated", ErrMalformedGRIB2)
+	}
+	return message, nil
+}
+
+// Decode the fields of a message. Sections 2 to 7 may repeat within a message, each data section
+// completing a field with the latest grid, product, data representation and bitmap sections.
+func decodeGRIB2Message(message []byte) ([]*GRIB2Field, error) {
+	discipline := int(message[6])
+
+	var (
+		runTime        time.Time
+		grid           *GRIB2Grid
+		scanningMode   byte
+		product        *GRIB2Field
+		forecastOffset time.Duration
+		representation *gribDataRepresentation
+		bitmap         []byte
+	)
+
+	fields := []*GRIB2Field{}
+	for pos := 16; ; {
+		if len(message)-pos >= 4 && string(message[pos:pos+4]) == "7777" {
+			return fields, nil
+		}
+		if len(message)-pos < 5 {
+			return nil, fmt.Errorf("%w: missing end section", ErrMalformedGRIB2)
+		}
+
+		length := int(binary.BigEndian.Uint32(message[pos : pos+4]))
+		if length < 5 || pos+length > len(message) {
+			return nil, fmt.Errorf("%w: bad section length %d", ErrMalformedGRIB2, length)
+		}
+		section := message[pos : pos+length]
+		pos += length
+
+		var sectionErr error
+		switch section[4] {
+		case 1:
+			runTime, sectionErr = parseGRIB2ReferenceTime(section)
+		case 3:
+			grid, scanningMode, sectionErr = parseGRIB2Grid(section)
+		case 4:
+			product, forecastOffset, sectionErr = parseGRIB2Product(section, discipline)
+		case 5:
+			representation, sectionErr = parseGRIB2DataRepresentation(section)
+		case 6:
+			bitmap, sectionErr = parseGRIB2Bitmap(section, bitmap)
+		case 7:
+			if grid == nil || product == nil || representation == nil {
+				return nil, fmt.Errorf("%w: data section before its definitions", ErrMalformedGRIB2)
+			}
+
+			values, unpackErr := representation.unpack(section[5:])
+			if unpackErr != nil {
+				return nil, unpackErr
+			}
+			points, bitmapErr := applyGRIB2Bitmap(values, bitmap, grid.Latitudes*grid.Longitudes)
+			if bitmapErr != nil {
+				return nil, bitmapErr
+			}
+
+			field := *product
+			field.Grid = *grid
+			field.RunTime = runTime
+			field.ValidTime = runTime.Add(forecastOffset)
+			field.Values = scanGRIB2Points(points, *grid, scanningMode)
+			fields = append(fields, &field)
+		}
+		if sectionErr != nil {
+			return nil, sectionErr
+		}
+	}
+}
+
+// Read the reference time of the identification section, which is the model run for forecasts
+func parseGRIB2ReferenceTime(section []byte) (time.Time, error) {
+	if len(section) < 19 {
+		return time.Time{}, fmt.Errorf("%w: short identification section", ErrMalformedGRIB2)
+	}
+
+	year := int(binary.BigEndian.Uint16(section[12:14]))
+	return time.Date(year, time.Month(section[14]), int(section[15]), int(section[16]), int(section[17]), int(section[18]), 0, time.UTC), nil
+}
+
+// Read a regular latitude longitude grid definition, returning the grid as its values are reordered to
+// along with the scanning mode they are stored in
+func parseGRIB2Grid(section []byte) (*GRIB2Grid, byte, error) {
+	if len(section) < 14 {
+		return nil, 0, fmt.Errorf("%w: short grid definition section", ErrMalformedGRIB2)
+	}
+	if template := binary.BigEndian.Uint16(section[12:14]); template != 0 {
+		return nil, 0, fmt.Errorf("%w: grid definition template 3.%d", ErrUnsupportedGRIB2Template, template)
+	}
+	if len(section) < 72 {
+		return nil, 0, fmt.Errorf("%w: short grid definition section", ErrMalformedGRIB2)
+	}
+	if section[10] != 0 {
+		return nil, 0, fmt.Errorf("%w: quasi regular grids", ErrUnsupportedGRIB2Template)
+	}
+
+	// Angles are in millionths of a degree unless the grid gives its own basic angle and subdivisions
+	unit := 1e-6
+	basicAngle := binary.BigEndian.Uint32(section[38:42])
+	subdivisions := binary.BigEndian.Uint32(section[42:46])
+	if basicAngle != 0 && basicAngle != math.MaxUint32 && subdivisions != 0 && subdivisions != math.MaxUint32 {
+		unit = float64(basicAngle) / float64(subdivisions)
+	}
+	angle := func(b []byte) float64 {
+		return float64(gribSignedInt(b)) * unit
+	}
+
+	ni := int(binary.BigEndian.Uint32(section[30:34]))
+	nj := int(binary.BigEndian.Uint32(section[34:38]))
+	firstLat, firstLon := angle(section[46:50]), angle(section[50:54])
+	lastLat, lastLon := angle(section[55:59]), angle(section[59:63])
+	scanningMode := section[71]
+	if ni < 1 || nj < 1 {
+		return nil, 0, fmt.Errorf("%w: grid of %d by %d points", ErrMalformedGRIB2, ni, nj)
+	}
+	if scanningMode&0x10 != 0 {
+		return nil, 0, fmt.Errorf("%w: alternating row scanning", ErrUnsupportedGRIB2Template)
+	}
+
+	westLon, eastLon := firstLon, lastLon
+	if scanningMode&0x80 != 0 {
+		westLon, eastLon = lastLon, firstLon
+	}
+	westLon = NormalizeLongitude360(westLon)
+	eastLon = normalizeLongitudeFrom(eastLon, westLon)
+
+	grid := &GRIB2Grid{
+		Latitudes:           nj,
+		Longitudes:          ni,
+		BottomLeftLocation:  NewLocationForLatLong(math.Min(firstLat, lastLat), westLon),
+		TopRightLocation:    NewLocationForLatLong(math.Max(firstLat, lastLat), eastLon),
+		LatitudeResolution:  angle(section[67:71]),
+		LongitudeResolution: angle(section[63:67]),
+	}
+
+	// The increments are optional, so fall back to the spacing of the corners when they are not given
+	if section[54]&0x20 == 0 || grid.LongitudeResolution <= 0 {
+		grid.LongitudeResolution = 0
+		if ni > 1 {
+			grid.LongitudeResolution = (eastLon - westLon) / float64(ni-1)
+		}
+	}
+	if section[54]&0x10 == 0 || grid.LatitudeResolution <= 0 {
+		grid.LatitudeResolution = 0
+		if nj > 1 {
+			grid.LatitudeResolution = (grid.TopRightLocation.Latitude - grid.BottomLeftLocation.Latitude) / float64(nj-1)
+		}
+	}
+	return grid, scanningMode, nil
+}
+
+// Read an analysis or forecast at a point in time, product definition template 4.0, along with the time
+// from the reference time to the forecast
+func parseGRIB2Product(section []byte, discipline int) (*GRIB2Field, time.Duration, error) {
+	if len(section) < 9 {
+		return nil, 0, fmt.Errorf("%w: short product definition section", ErrMalformedGRIB2)
+	}
+	if template := binary.BigEndian.Uint16(section[7:9]); template != 0 {
+		return nil, 0, fmt.Errorf("%w: product definition template 4.%d", ErrUnsupportedGRIB2Template, template)
+	}
+	if len(section) < 34 {
+		return nil, 0, fmt.Errorf("%w: short product definition section", ErrMalformedGRIB2)
+	}
+
+	unit, ok := grib2TimeUnits[section[17]]
+	if !ok {
+		return nil, 0, fmt.Errorf("%w: time unit %d", ErrUnsupportedGRIB2Template, section[17])
+	}
+
+	field := &GRIB2Field{
+		Discipline:  discipline,
+		Category:    int(section[9]),
+		Number:      int(section[10]),
+		SurfaceType: int(section[22]),
+	}
+	if section[23] != 0xff {
+		field.SurfaceValue = float64(gribSignedInt(section[24:28])) / math.Pow(10, float64(gribSignedInt(section[23:24])))
+	}
+	field.Name = grib2FieldName(field.Discipline, field.Category, field.Number, field.SurfaceType, field.SurfaceValue)
+	return field, time.Duration(binary.BigEndian.Uint32(section[18:22])) * unit, nil
+}
+
+// Read the bitmap section. A bitmap may be reused from an earlier field of the same message.
+func parseGRIB2Bitmap(section []byte, previous []byte) ([]byte, error) {
+	if len(section) < 6 {
+		return nil, fmt.Errorf("%w: short bitmap section", ErrMalformedGRIB2)
+	}
+
+	switch section[5] {
+	case 0:
+		return section[6:], nil
+	case 254:
+		if previous == nil {
+			return nil, fmt.Errorf("%w: no previous bitmap to reuse", ErrMalformedGRIB2)
+		}
+		return previous, nil
+	case 255:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("%w: predefined bitmap %d", ErrUnsupportedGRIB2Template, section[5])
+}
+
+// Spread the unpacked values over the points of the grid, filling the points the bitmap leaves out
+// with missing values
+func applyGRIB2Bitmap(values []float64, bitmap []byte, pointCount int) ([]float64, error) {
+	if bitmap == nil {
+		if len(values) != pointCount {
+			return nil, fmt.Errorf("%w: %d values for %d grid points", ErrMalformedGRIB2, len(values), pointCount)
+		}
+		return values, nil
+	}
+	if len(bitmap)*8 < pointCount {
+		return nil, fmt.Errorf("%w: bitmap is shorter than the grid", ErrMalformedGRIB2)
+	}
+
+	points := make([]float64, pointCount)
+	next := 0
+	for i := range points {
+		if bitmap[i/8]&(0x80>>uint(i%8)) == 0 {
+			points[i] = MissingValue()
+			continue
+		}
+		if next >= len(values) {
+			return nil, fmt.Errorf("%w: bitmap has more points than values", ErrMalformedGRIB2)
+		}
+		points[i] = values[next]
+		next++
+	}
+	return points, nil
+}
+
+// Reorder grid points from the scanning mode they are stored in to rows running west to east,
+// starting from the southern row
+func scanGRIB2Points(points []float64, grid GRIB2Grid, scanningMode byte) []float64 {
+	ni, nj := grid.Longitudes, grid.Latitudes
+	if scanningMode == 0x40 {
+		return points
+	}
+
+	ordered := make([]float64, len(points))
+	for k, value := range points {
+		i, j := k%ni, k/ni
+		if scanningMode&0x20 != 0 {
+			i, j = k/nj, k%nj
+		}
+		if scanningMode&0x80 != 0 {
+			i = ni - 1 - i
+		}
+		if scanningMode&0x40 == 0 {
+			j = nj - 1 - j
+		}
+		ordered[j*ni+i] = value
+	}
+	return ordered
+}
+
+// Read a GRIB2 signed integer, which stores its sign in the highest bit rather than as two's complement
+func gribSignedInt(b []byte) int64 {
+	var magnitude int64
+	for i, c := range b {
+		if i == 0 {
+			c &= 0x7f
+		}
+		magnitude = magnitude<<8 | int64(c)
+	}
+	if b[0]&0x80 != 0 {
+		return -magnitude
+	}
+	return magnitude
+}
+
+// The lengths of the units of forecast time, code table 4.4
+var grib2TimeUnits = map[byte]time.Duration{
+	0:  time.Minute,
+	1:  time.Hour,
+	2:  24 * time.Hour,
+	10: 3 * time.Hour,
+	11: 6 * time.Hour,
+	12: 12 * time.Hour,
+	13: time.Second,
+}
+
+// The GrADS names of the parameters of the wave and wind models, keyed by discipline, category and number
+var grib2ParameterNames = map[[3]int]string{
+	{0, 2, 0}:   "wdir",
+	{0, 2, 1}:   "wind",
+	{0, 2, 2}:   "ugrd",
+	{0, 2, 3}:   "vgrd",
+	{0, 2, 22}:  "gust",
+	{10, 0, 3}:  "htsgw",
+	{10, 0, 4}:  "wvdir",
+	{10, 0, 5}:  "wvhgt",
+	{10, 0, 6}:  "wvper",
+	{10, 0, 7}:  "swdir",
+	{10, 0, 8}:  "swell",
+	{10, 0, 9}:  "swper",
+	{10, 0, 10}: "dirpw",
+	{10, 0, 11}: "perpw",
+}
+
+// Name a field the way the GrADS servers name their variables, the parameter followed by its level, so
+// fields read from GRIB2 files fill the same ModelDataMap keys as data fetched from the GrADS servers
+func grib2FieldName(discipline, category, number, surfaceType int, surfaceValue float64) string {
+	name, ok := grib2ParameterNames[[3]int{discipline, category, number}]
+	if !ok {
+		name = fmt.Sprintf("var%d_%d_%d", discipline, category, number)
+	}
+
+	switch surfaceType {
+	case 1:
+		return name + "sfc"
+	case 103:
+		return fmt.Sprintf("%s%gm", name, surfaceValue)
+	case 241:
+		// Ordered sequences, such as the first and second swell partitions
+		return fmt.Sprintf("%s_%g", name, surfaceValue)
+	}
+	return fmt.Sprintf("%slev%d", name, surfaceType)
+}
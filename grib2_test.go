@@ -0,0 +1,423 @@
+package surfnerd
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Writes the big endian bit fields of a GRIB2 data section
+type gribTestBitWriter struct {
+	data []byte
+	bits int
+}
+
+func (w *gribTestBitWriter) write(value uint64, bits int) {
+	for i := bits - 1; i >= 0; i-- {
+		if w.bits%8 == 0 {
+			w.data = append(w.data, 0)
+		}
+		if value&(1<<uint(i)) != 0 {
+			w.data[len(w.data)-1] |= 0x80 >> uint(w.bits%8)
+		}
+		w.bits++
+	}
+}
+
+func (w *gribTestBitWriter) align() {
+	w.bits = len(w.data) * 8
+}
+
+// Encode a GRIB2 signed integer, which stores its sign in the highest bit
+func gribTestSigned(value int64, size int) []byte {
+	b := make([]byte, 8)
+	if value < 0 {
+		binary.BigEndian.PutUint64(b, uint64(-value))
+		b[8-size] |= 0x80
+	} else {
+		binary.BigEndian.PutUint64(b, uint64(value))
+	}
+	return b[8-size:]
+}
+
+func gribTestSection(number byte, body []byte) []byte {
+	section := make([]byte, 5, 5+len(body))
+	binary.BigEndian.PutUint32(section, uint32(5+len(body)))
+	section[4] = number
+	return append(section, body...)
+}
+
+// The grid of a test message, with corners in degrees
+type gribTestGrid struct {
+	ni, nj                    int
+	lat1, lon1, lat2, lon2, d float64
+	scanningMode              byte
+}
+
+// A test field with its data representation and data sections already encoded
+type gribTestField struct {
+	discipline, category, number byte
+	surfaceType                  byte
+	surfaceValue                 int64
+	forecastHour                 uint32
+	representation               []byte
+	bitmap                       []byte
+	data                         []byte
+}
+
+func buildTestGRIB2(run time.Time, grid gribTestGrid, field gribTestField) []byte {
+	identification := make([]byte, 16)
+	binary.BigEndian.PutUint16(identification[0:2], 7)
+	identification[4] = 2
+	binary.BigEndian.PutUint16(identification[7:9], uint16(run.Year()))
+	identification[9] = byte(run.Month())
+	identification[10] = byte(run.Day())
+	identification[11] = byte(run.Hour())
+	identification[15] = 1
+
+	degrees := func(value float64) []byte {
+		return gribTestSigned(int64(math.Round(value*1e6)), 4)
+	}
+	gridDefinition := make([]byte, 67)
+	binary.BigEndian.PutUint32(gridDefinition[1:5], uint32(grid.ni*grid.nj))
+	gridDefinition[9] = 6
+	binary.BigEndian.PutUint32(gridDefinition[25:29], uint32(grid.ni))
+	binary.BigEndian.PutUint32(gridDefinition[29:33], uint32(grid.nj))
+	binary.BigEndian.PutUint32(gridDefinition[37:41], math.MaxUint32)
+	copy(gridDefinition[41:45], degrees(grid.lat1))
+	copy(gridDefinition[45:49], degrees(grid.lon1))
+	gridDefinition[49] = 0x30
+	copy(gridDefinition[50:54], degrees(grid.lat2))
+	copy(gridDefinition[54:58], degrees(grid.lon2))
+	copy(gridDefinition[58:62], degrees(grid.d))
+	copy(gridDefinition[62:66], degrees(grid.d))
+	gridDefinition[66] = grid.scanningMode
+
+	product := make([]byte, 29)
+	product[4] = field.category
+	product[5] = field.number
+	product[6] = 2
+	product[12] = 1
+	binary.BigEndian.PutUint32(product[13:17], field.forecastHour)
+	product[17] = field.surfaceType
+	copy(product[19:23], gribTestSigned(field.surfaceValue, 4))
+	product[23] = 0xff
+
+	bitmap := []byte{255}
+	if field.bitmap != nil {
+		bitmap = append([]byte{0}, field.bitmap...)
+	}
+
+	var message bytes.Buffer
+	message.Write([]byte{'G', 'R', 'I', 'B', 0, 0, field.discipline, 2})
+	message.Write(make([]byte, 8))
+	message.Write(gribTestSection(1, identification))
+	message.Write(gribTestSection(3, gridDefinition))
+	message.Write(gribTestSection(4, product))
+	message.Write(gribTestSection(5, field.representation))
+	message.Write(gribTestSection(6, bitmap))
+	message.Write(gribTestSection(7, field.data))
+	message.WriteString("7777")
+
+	raw := message.Bytes()
+	binary.BigEndian.PutUint64(raw[8:16], uint64(len(raw)))
+	return raw
+}
+
+// Encode a simple packing data representation (5.0) of 8 bit values
+func gribTestSimpleRepresentation(count int, reference float32, decimalScale int64) []byte {
+	representation := make([]byte, 16)
+	binary.BigEndian.PutUint32(representation[0:4], uint32(count))
+	binary.BigEndian.PutUint32(representation[6:10], math.Float32bits(reference))
+	copy(representation[12:14], gribTestSigned(decimalScale, 2))
+	representation[14] = 8
+	return representation
+}
+
+var testGRIB2Run = time.Date(2016, 10, 18, 6, 0, 0, 0, time.UTC)
+
+// A 3 by 2 grid scanned from the north west corner
+var testGRIB2Grid = gribTestGrid{ni: 3, nj: 2, lat1: 41, lon1: 288, lat2: 40, lon2: 289, d: 0.5}
+
+func testGRIB2HeightField(forecastHour uint32, offset byte) []byte {
+	return buildTestGRIB2(testGRIB2Run, testGRIB2Grid, gribTestField{
+		discipline: 10, number: 3, surfaceType: 1, forecastHour: forecastHour,
+		representation: gribTestSimpleRepresentation(6, 100, 2),
+		data:           []byte{10 + offset, 11 + offset, 12 + offset, 20 + offset, 21 + offset, 22 + offset},
+	})
+}
+
+func TestReadGRIB2SimplePacking(t *testing.T) {
+	fields, readErr := ReadGRIB2(bytes.NewReader(testGRIB2HeightField(3, 0)))
+	if readErr != nil {
+		t.Fatal(readErr)
+	}
+	if len(fields) != 1 {
+		t.Fatalf("Expected 1 field, got %d", len(fields))
+	}
+
+	field := fields[0]
+	if field.Name != "htsgwsfc" {
+		t.Errorf("Expected the significant wave height, got %s", field.Name)
+	}
+	if !field.RunTime.Equal(testGRIB2Run) || field.ForecastHour() != 3 {
+		t.Errorf("Unexpected run %v and forecast hour %d", field.RunTime, field.ForecastHour())
+	}
+
+	grid := field.Grid
+	if grid.BottomLeftLocation.Latitude != 40 || grid.TopRightLocation.Longitude != 289 || grid.LatitudeResolution != 0.5 {
+		t.Errorf("Unexpected grid %+v", grid)
+	}
+
+	// The southern row was scanned last, so it is reordered to come first
+	expected := []float64{1.2, 1.21, 1.22, 1.1, 1.11, 1.12}
+	for i, value := range expected {
+		if math.Abs(field.Values[i]-value) > 1e-9 {
+			t.Errorf("Expected %v at %d, got %v", value, i, field.Values[i])
+		}
+	}
+
+	latIndex, lonIndex := grid.LocationIndices(NewLocationForLatLong(40.9, -71.4))
+	if latIndex != 1 || lonIndex != 1 || field.ValueAt(latIndex, lonIndex) != field.Values[4] {
+		t.Errorf("Unexpected indices %d, %d", latIndex, lonIndex)
+	}
+	if latIndex, _ := grid.LocationIndices(NewLocationForLatLong(39.0, -71.4)); latIndex != -1 {
+		t.Error("Expected a location south of the grid to be off it")
+	}
+}
+
+func TestReadGRIB2Bitmap(t *testing.T) {
+	message := buildTestGRIB2(testGRIB2Run, gribTestGrid{ni: 3, nj: 1, lat1: 40, lon1: 288, lat2: 40, lon2: 289, d: 0.5, scanningMode: 0x40}, gribTestField{
+		discipline: 10, number: 8, surfaceType: 241, surfaceValue: 2,
+		representation: gribTestSimpleRepresentation(2, 0, 0),
+		bitmap:         []byte{0xa0},
+		data:           []byte{4, 6},
+	})
+
+	fields, readErr := ReadGRIB2(bytes.NewReader(message))
+	if readErr != nil {
+		t.Fatal(readErr)
+	}
+	if fields[0].Name != "swell_2" {
+		t.Errorf("Expected the second swell partition, got %s", fields[0].Name)
+	}
+	if values := fields[0].Values; values[0] != 4 || !IsMissing(values[1]) || values[2] != 6 {
+		t.Errorf("Expected the masked point to be missing, got %v", values)
+	}
+}
+
+func TestReadGRIB2ComplexPacking(t *testing.T) {
+	// Two groups, the second of constant missing values: 5, missing, 7, missing, missing
+	bits := &gribTestBitWriter{}
+	bits.write(5, 4)
+	bits.write(15, 4)
+	bits.align()
+	bits.write(2, 2)
+	bits.write(0, 2)
+	bits.align()
+	bits.write(0, 1)
+	bits.write(0, 1)
+	bits.align()
+	bits.write(0, 2)
+	bits.write(3, 2)
+	bits.write(2, 2)
+
+	representation := make([]byte, 42)
+	binary.BigEndian.PutUint32(representation[0:4], 5)
+	representation[5] = 2
+	representation[14] = 4
+	representation[17] = 1
+	binary.BigEndian.PutUint32(representation[26:30], 2)
+	representation[31] = 2
+	binary.BigEndian.PutUint32(representation[32:36], 3)
+	representation[36] = 1
+	binary.BigEndian.PutUint32(representation[37:41], 2)
+	representation[41] = 1
+
+	message := buildTestGRIB2(testGRIB2Run, gribTestGrid{ni: 5, nj: 1, lat1: 40, lon1: 288, lat2: 40, lon2: 290, d: 0.5, scanningMode: 0x40}, gribTestField{
+		discipline: 0, category: 2, number: 22, surfaceType: 1,
+		representation: representation,
+		data:           bits.data,
+	})
+
+	fields, readErr := ReadGRIB2(bytes.NewReader(message))
+	if readErr != nil {
+		t.Fatal(readErr)
+	}
+	if fields[0].Name != "gustsfc" {
+		t.Errorf("Expected the surface gusts, got %s", fields[0].Name)
+	}
+	values := fields[0].Values
+	if values[0] != 5 || !IsMissing(values[1]) || values[2] != 7 || !IsMissing(values[3]) || !IsMissing(values[4]) {
+		t.Errorf("Unexpected values %v", values)
+	}
+}
+
+func TestReadGRIB2SpatialDifferencing(t *testing.T) {
+	// Second order differences of 10, 12, 15, 15, 14, 20 are 1, -3, -1, 7 with a minimum of -3
+	bits := &gribTestBitWriter{}
+	bits.write(10, 16)
+	bits.write(12, 16)
+	bits.write(0x8003, 16)
+	bits.align()
+	bits.write(0, 4)
+	bits.write(0, 4)
+	bits.align()
+	bits.write(3, 3)
+	bits.write(4, 3)
+	bits.align()
+	bits.write(0, 2)
+	bits.write(0, 2)
+	bits.align()
+	for _, value := range []uint64{0, 0, 4} {
+		bits.write(value, 3)
+	}
+	for _, value := range []uint64{0, 2, 10} {
+		bits.write(value, 4)
+	}
+
+	representation := make([]byte, 44)
+	binary.BigEndian.PutUint32(representation[0:4], 6)
+	representation[5] = 3
+	copy(representation[12:14], gribTestSigned(1, 2))
+	representation[14] = 4
+	representation[17] = 0
+	binary.BigEndian.PutUint32(representation[26:30], 2)
+	representation[31] = 3
+	binary.BigEndian.PutUint32(representation[32:36], 3)
+	representation[36] = 1
+	binary.BigEndian.PutUint32(representation[37:41], 3)
+	representation[41] = 2
+	representation[42] = 2
+	representation[43] = 2
+
+	message := buildTestGRIB2(testGRIB2Run, gribTestGrid{ni: 3, nj: 2, lat1: 40, lon1: 288, lat2: 40.5, lon2: 289, d: 0.5, scanningMode: 0x40}, gribTestField{
+		discipline: 0, category: 2, number: 2, surfaceType: 103, surfaceValue: 10, forecastHour: 6,
+		representation: representation,
+		data:           bits.data,
+	})
+
+	fields, readErr := ReadGRIB2(bytes.NewReader(message))
+	if readErr != nil {
+		t.Fatal(readErr)
+	}
+	if fields[0].Name != "ugrd10m" {
+		t.Errorf("Expected the 10 meter u wind, got %s", fields[0].Name)
+	}
+
+	expected := []float64{1.0, 1.2, 1.5, 1.5, 1.4, 2.0}
+	for i, value := range expected {
+		if math.Abs(fields[0].Values[i]-value) > 1e-9 {
+			t.Errorf("Expected %v at %d, got %v", value, i, fields[0].Values[i])
+		}
+	}
+}
+
+func TestReadGRIB2UnsupportedPacking(t *testing.T) {
+	representation := gribTestSimpleRepresentation(6, 0, 0)
+	representation[5] = 40
+
+	message := buildTestGRIB2(testGRIB2Run, testGRIB2Grid, gribTestField{
+		discipline: 10, number: 3, surfaceType: 1,
+		representation: representation,
+		data:           []byte{0},
+	})
+	_, readErr := ReadGRIB2(bytes.NewReader(message))
+	if !errors.Is(readErr, ErrUnsupportedGRIB2Template) {
+		t.Errorf("Expected JPEG2000 packing to be unsupported, got %v", readErr)
+	}
+
+	if _, truncatedErr := ReadGRIB2(bytes.NewReader(testGRIB2HeightField(0, 0)[:40])); !errors.Is(truncatedErr, ErrMalformedGRIB2) {
+		t.Errorf("Expected a truncated message to be malformed, got %v", truncatedErr)
+	}
+}
+
+func TestModelDataFromGRIB2(t *testing.T) {
+	var file bytes.Buffer
+	file.Write(testGRIB2HeightField(6, 5))
+	file.Write(testGRIB2HeightField(3, 0))
+	fields, readErr := ReadGRIB2(&file)
+	if readErr != nil {
+		t.Fatal(readErr)
+	}
+
+	loc := NewLocationForLatLong(40.1, -71.4)
+	modelData, dataErr := ModelDataFromGRIB2(loc, NewEastCoastWaveModel().NOAAModel, fields)
+	if dataErr != nil {
+		t.Fatal(dataErr)
+	}
+
+	if !modelData.Model.RunTime.Equal(testGRIB2Run) {
+		t.Errorf("Expected the run of the fields, got %v", modelData.Model.RunTime)
+	}
+	validTimes := modelData.ValidTimes()
+	if len(validTimes) != 2 || !validTimes[0].Equal(testGRIB2Run.Add(3*time.Hour)) || !validTimes[1].Equal(testGRIB2Run.Add(6*time.Hour)) {
+		t.Errorf("Expected the forecast hours in order, got %v", validTimes)
+	}
+
+	heights := modelData.Data["htsgwsfc"]
+	if len(heights) != 2 || math.Abs(heights[0]-1.21) > 1e-9 || math.Abs(heights[1]-1.26) > 1e-9 {
+		t.Errorf("Unexpected heights %v", heights)
+	}
+	if modelData.Data["lat"][0] != 40 || modelData.Data["lon"][0] != 288.5 {
+		t.Errorf("Unexpected cell %v, %v", modelData.Data["lat"], modelData.Data["lon"])
+	}
+
+	otherRun := NewEastCoastWaveModel().NOAAModel
+	otherRun.RunTime = testGRIB2Run.Add(-6 * time.Hour)
+	if _, mismatchErr := ModelDataFromGRIB2(loc, otherRun, fields); mismatchErr != ErrModelRunMismatch {
+		t.Errorf("Expected a run mismatch, got %v", mismatchErr)
+	}
+}
+
+func TestGrADSDays(t *testing.T) {
+	validTime := time.Date(2016, 10, 18, 9, 0, 0, 0, time.UTC)
+	if roundTrip := GrADSTime(GrADSDays(validTime)); !roundTrip.Equal(validTime) {
+		t.Errorf("Expected %v, got %v", validTime, roundTrip)
+	}
+}
+
+func TestCreateGRIBFilterURL(t *testing.T) {
+	waveModel := NewEastCoastWaveModel()
+	waveURL := waveModel.CreateGRIBFilterURL(testGRIB2Run, 3, NewLocationForLatLong(40, -72), NewLocationForLatLong(41, -71), []string{"htsgw"})
+	if !strings.HasPrefix(waveURL, NOMADSFilterBaseURL+"/filter_wave_multi.pl?") {
+		t.Errorf("Unexpected filter %s", waveURL)
+	}
+	for _, part := range []string{"file=multi_1.at_10m.t06z.f003.grib2", "dir=%2Fmulti_1.20161018", "var_HTSGW=on", "leftlon=288", "rightlon=289", "toplat=41"} {
+		if !strings.Contains(waveURL, part) {
+			t.Errorf("Expected %s in %s", part, waveURL)
+		}
+	}
+
+	windURL := NewGFSWindModel().CreateGRIBFilterURL(testGRIB2Run, 12, NewLocationForLatLong(40, -72), NewLocationForLatLong(41, -71), nil)
+	for _, part := range []string{"filter_gfs_0p50.pl", "file=gfs.t06z.pgrb2full.0p50.f012", "dir=%2Fgfs.20161018%2F06%2Fatmos", "lev_10_m_above_ground=on", "var_GUST=on"} {
+		if !strings.Contains(windURL, part) {
+			t.Errorf("Expected %s in %s", part, windURL)
+		}
+	}
+}
+
+func TestFetchWaveModelDataFromGRIB(t *testing.T) {
+	loc := NewLocationForLatLong(40.1, -71.4)
+	model := GetWaveModelForLocation(loc)
+	margin := model.LocationResolution
+	bottomLeft := NewLocationForLatLong(loc.Latitude-margin, loc.Longitude-margin)
+	topRight := NewLocationForLatLong(loc.Latitude+margin, loc.Longitude+margin)
+
+	fetcher := mapFetcher{
+		model.CreateGRIBFilterURL(testGRIB2Run, 3, bottomLeft, topRight, nil): string(testGRIB2HeightField(3, 0)),
+		model.CreateGRIBFilterURL(testGRIB2Run, 6, bottomLeft, topRight, nil): string(testGRIB2HeightField(6, 5)),
+	}
+	modelData, fetchErr := FetchWaveModelDataFromGRIBContext(context.Background(), fetcher, loc, testGRIB2Run, []int{3, 6})
+	if fetchErr != nil {
+		t.Fatal(fetchErr)
+	}
+	if len(modelData.Data["htsgwsfc"]) != 2 {
+		t.Errorf("Expected a value for each forecast hour, got %v", modelData.Data["htsgwsfc"])
+	}
+}
@@ -0,0 +1,299 @@
+package surfnerd
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// The packing of the values of a field, from its data representation section
+type gribDataRepresentation struct {
+	template int
+	count    int
+
+	// Values are (reference + packed * 2^binaryScale) / 10^decimalScale
+	reference    float64
+	binaryScale  int
+	decimalScale int
+	bits         int
+
+	// The groups of complex packing, templates 5.2 and 5.3
+	missingManagement    int
+	groups               int
+	groupWidthReference  int
+	groupWidthBits       int
+	groupLengthReference int
+	groupLengthIncrement int
+	lastGroupLength      int
+	groupLengthBits      int
+
+	// The spatial differencing of template 5.3
+	spatialOrder  int
+	spatialOctets int
+}
+
+// Read the data representation section. Simple packing (5.0), complex packing (5.2) and complex packing
+// with spatial differencing (5.3) are supported, JPEG2000 (5.40) and PNG (5.41) packing are not.
+func parseGRIB2DataRepresentation(section []byte) (*gribDataRepresentation, error) {
+	if len(section) < 11 {
+		return nil, fmt.Errorf("%w: short data representation section", ErrMalformedGRIB2)
+	}
+
+	representation := &gribDataRepresentation{
+		template: int(binary.BigEndian.Uint16(section[9:11])),
+		count:    int(binary.BigEndian.Uint32(section[5:9])),
+	}
+	switch representation.template {
+	case 0, 2, 3:
+	default:
+		return nil, fmt.Errorf("%w: data representation template 5.%d", ErrUnsupportedGRIB2Template, representation.template)
+	}
+	if len(section) < 21 {
+		return nil, fmt.Errorf("%w: short data representation section", ErrMalformedGRIB2)
+	}
+
+	representation.reference = float64(math.Float32frombits(binary.BigEndian.Uint32(section[11:15])))
+	representation.binaryScale = int(gribSignedInt(section[15:17]))
+	representation.decimalScale = int(gribSignedInt(section[17:19]))
+	representation.bits = int(section[19])
+	if representation.template == 0 {
+		return representation, nil
+	}
+
+	minimumLength := 47
+	if representation.template == 3 {
+		minimumLength = 49
+	}
+	if len(section) < minimumLength {
+		return nil, fmt.Errorf("%w: short data representation section", ErrMalformedGRIB2)
+	}
+
+	representation.missingManagement = int(section[22])
+	representation.groups = int(binary.BigEndian.Uint32(section[31:35]))
+	representation.groupWidthReference = int(section[35])
+	representation.groupWidthBits = int(section[36])
+	representation.groupLengthReference = int(binary.BigEndian.Uint32(section[37:41]))
+	representation.groupLengthIncrement = int(section[41])
+	representation.lastGroupLength = int(binary.BigEndian.Uint32(section[42:46]))
+	representation.groupLengthBits = int(section[46])
+	if representation.template == 3 {
+		representation.spatialOrder = int(section[47])
+		representation.spatialOctets = int(section[48])
+		if representation.spatialOrder != 1 && representation.spatialOrder != 2 {
+			return nil, fmt.Errorf("%w: spatial differencing of order %d", ErrUnsupportedGRIB2Template, representation.spatialOrder)
+		}
+	}
+	return representation, nil
+}
+
+// Unpack the values of the data section, with values marked missing by complex packing set to MissingValue()
+func (d *gribDataRepresentation) unpack(data []byte) ([]float64, error) {
+	bits := &gribBitReader{data: data}
+
+	var packed []int64
+	var missing []bool
+	var unpackErr error
+	if d.template == 0 {
+		packed, unpackErr = d.unpackSimple(bits)
+	} else {
+		packed, missing, unpackErr = d.unpackComplex(bits)
+	}
+	if unpackErr != nil {
+		return nil, unpackErr
+	}
+
+	binaryScale := math.Pow(2, float64(d.binaryScale))
+	decimalScale := math.Pow(10, float64(-d.decimalScale))
+	values := make([]float64, d.count)
+	next := 0
+	for i := range values {
+		if missing != nil && missing[i] {
+			values[i] = MissingValue()
+			continue
+		}
+		values[i] = (d.reference + float64(packed[next])*binaryScale) * decimalScale
+		next++
+	}
+	return values, nil
+}
+
+// Read values packed with the same number of bits each
+func (d *gribDataRepresentation) unpackSimple(bits *gribBitReader) ([]int64, error) {
+	packed := make([]int64, d.count)
+	if d.bits == 0 {
+		// Constant fields pack no values at all
+		return packed, nil
+	}
+
+	for i := range packed {
+		value, readErr := bits.read(d.bits)
+		if readErr != nil {
+			return nil, readErr
+		}
+		packed[i] = int64(value)
+	}
+	return packed, nil
+}
+
+// Read values packed in groups, each with its own reference and bit width, and undo the spatial
+// differencing of template 5.3. Returns the values that are present and which of the values are missing.
+func (d *gribDataRepresentation) unpackComplex(bits *gribBitReader) ([]int64, []bool, error) {
+	// The first values and the minimum of the differences lead the data of spatially differenced fields
+	var firstValues []int64
+	var minimumDifference int64
+	if d.template == 3 && d.spatialOctets > 0 {
+		for i := 0; i <= d.spatialOrder; i++ {
+			value, readErr := bits.readSigned(d.spatialOctets * 8)
+			if readErr != nil {
+				return nil, nil, readErr
+			}
+			if i < d.spatialOrder {
+				firstValues = append(firstValues, value)
+			} else {
+				minimumDifference = value
+			}
+		}
+	}
+
+	references, referencesErr := bits.readGroup(d.groups, d.bits)
+	if referencesErr != nil {
+		return nil, nil, referencesErr
+	}
+	widths, widthsErr := bits.readGroup(d.groups, d.groupWidthBits)
+	if widthsErr != nil {
+		return nil, nil, widthsErr
+	}
+	lengths, lengthsErr := bits.readGroup(d.groups, d.groupLengthBits)
+	if lengthsErr != nil {
+		return nil, nil, lengthsErr
+	}
+
+	total := 0
+	for g := range lengths {
+		widths[g] += uint64(d.groupWidthReference)
+		lengths[g] = uint64(d.groupLengthReference) + lengths[g]*uint64(d.groupLengthIncrement)
+		if g == d.groups-1 {
+			lengths[g] = uint64(d.lastGroupLength)
+		}
+		total += int(lengths[g])
+	}
+	if total != d.count {
+		return nil, nil, fmt.Errorf("%w: groups hold %d values but the field has %d", ErrMalformedGRIB2, total, d.count)
+	}
+
+	// Values of all ones mark the primary missing value and all ones but the last bit the secondary one
+	isMissing := func(value uint64, width int) bool {
+		if d.missingManagement == 0 || width == 0 {
+			return false
+		}
+		allOnes := uint64(1)<<uint(width) - 1
+		return value == allOnes || (d.missingManagement == 2 && value == allOnes-1)
+	}
+
+	packed := make([]int64, 0, d.count)
+	missing := make([]bool, 0, d.count)
+	for g := range lengths {
+		width := int(widths[g])
+		for n := 0; n < int(lengths[g]); n++ {
+			// Groups of constant values only store their reference
+			absent := isMissing(references[g], d.bits)
+			value := uint64(0)
+			if width > 0 {
+				var readErr error
+				if value, readErr = bits.read(width); readErr != nil {
+					return nil, nil, readErr
+				}
+				absent = isMissing(value, width)
+			}
+
+			missing = append(missing, absent)
+			if !absent {
+				packed = append(packed, int64(references[g]+value))
+			}
+		}
+	}
+
+	// Spatial differencing applies to the values that are present, in order
+	if len(firstValues) > 0 {
+		for i := range packed {
+			switch {
+			case i < len(firstValues):
+				packed[i] = firstValues[i]
+			case d.spatialOrder == 1:
+				packed[i] += minimumDifference + packed[i-1]
+			default:
+				packed[i] += minimumDifference + 2*packed[i-1] - packed[i-2]
+			}
+		}
+	}
+
+	if d.missingManagement == 0 {
+		missing = nil
+	}
+	return packed, missing, nil
+}
+
+// Reads big endian bit fields from a data section
+type gribBitReader struct {
+	data []byte
+	pos  int
+}
+
+// Read an unsigned value of the given number of bits
+func (b *gribBitReader) read(bits int) (uint64, error) {
+	if b.pos+bits > len(b.data)*8 {
+		return 0, fmt.Errorf("%w: data section is truncated", ErrMalformedGRIB2)
+	}
+
+	var value uint64
+	for bits > 0 {
+		offset := b.pos % 8
+		take := 8 - offset
+		if take > bits {
+			take = bits
+		}
+		chunk := uint64(b.data[b.pos/8]>>uint(8-offset-take)) & (1<<uint(take) - 1)
+		value = value<<uint(take) | chunk
+		b.pos += take
+		bits -= take
+	}
+	return value, nil
+}
+
+// Read a value of the given number of bits whose first bit is its sign
+func (b *gribBitReader) readSigned(bits int) (int64, error) {
+	sign, signErr := b.read(1)
+	if signErr != nil {
+		return 0, signErr
+	}
+	magnitude, readErr := b.read(bits - 1)
+	if readErr != nil {
+		return 0, readErr
+	}
+	if sign == 1 {
+		return -int64(magnitude), nil
+	}
+	return int64(magnitude), nil
+}
+
+// Read a list of values of the same number of bits, which starts and ends on a byte boundary
+func (b *gribBitReader) readGroup(count, bits int) ([]uint64, error) {
+	b.align()
+	values := make([]uint64, count)
+	if bits > 0 {
+		for i := range values {
+			value, readErr := b.read(bits)
+			if readErr != nil {
+				return nil, readErr
+			}
+			values[i] = value
+		}
+	}
+	b.align()
+	return values, nil
+}
+
+// Skip to the start of the next byte
+func (b *gribBitReader) align() {
+	b.pos = (b.pos + 7) / 8 * 8
+}
@@ -0,0 +1,388 @@
+package surfnerd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// An NDBC historical data archive. Each archive is published as a gzipped text file per station
+// and year, and per month for the months of the current year that have not been archived yet.
+// More info is available here http://www.ndbc.noaa.gov/historical_data.shtml
+type HistoricalDataset string
+
+const (
+	HistoricalStandardData       HistoricalDataset = "stdmet"
+	HistoricalSpectralDensity    HistoricalDataset = "swden"
+	HistoricalSpectralDirection  HistoricalDataset = "swdir"
+	HistoricalSpectralDirection2 HistoricalDataset = "swdir2"
+	HistoricalSpectralR1         HistoricalDataset = "swr1"
+	HistoricalSpectralR2         HistoricalDataset = "swr2"
+)
+
+const (
+	baseHistoricalDataURL = "%s/data/historical/%s/%s%s%d.txt.gz"
+	baseMonthlyDataURL    = "%s/data/%s/%s/%s%s%d.txt.gz"
+
+	// The value NDBC writes in place of a missing spectral value
+	missingSpectralValue = 999.0
+
+	// The archived r1 and r2 values are stored in hundredths
+	archivedCoefficientScale = 0.01
+)
+
+// The letter identifying each archive in its file names
+var historicalDatasetFileCodes = map[HistoricalDataset]string{
+	HistoricalStandardData:       "h",
+	HistoricalSpectralDensity:    "w",
+	HistoricalSpectralDirection:  "d",
+	HistoricalSpectralDirection2: "i",
+	HistoricalSpectralR1:         "j",
+	HistoricalSpectralR2:         "k",
+}
+
+// A table of values from one of the NDBC spectral archives, with a row for every observation and
+// a column for every frequency band. Missing values are stored as missing, see IsMissing.
+type SpectralTable struct {
+	Dates       []time.Time
+	Frequencies []float64
+	Values      [][]float64
+
+	// The values skipped while leniently parsing the raw data
+	ParseWarnings []*ParseError `json:",omitempty"`
+}
+
+// Creates and returns the url of a yearly historical archive file for the buoy
+func (b Buoy) CreateHistoricalDataURL(dataset HistoricalDataset, year int) string {
+	return fmt.Sprintf(baseHistoricalDataURL, NDBCBaseURL, dataset, strings.ToLower(b.StationID), historicalDatasetFileCodes[dataset], year)
+}
+
+// Creates and returns the url of a monthly historical archive file for the buoy. Monthly files
+// are only published for the months that have not yet been rolled into a yearly archive.
+func (b Buoy) CreateMonthlyDataURL(dataset HistoricalDataset, year int, month time.Month) string {
+	monthCode := strconv.FormatInt(int64(month), 16)
+	return fmt.Sprintf(baseMonthlyDataURL, NDBCBaseURL, dataset, month.String()[:3], strings.ToLower(b.StationID), monthCode, year)
+}
+
+// Grabs a year of archived standard meteorological data as a time series of BuoyDataItem objects.
+func (b *Buoy) FetchHistoricalStandardData(year int) error {
+	return b.FetchHistoricalStandardDataContext(context.Background(), nil, year)
+}
+
+// Grabs a year of archived standard meteorological data using the given context and Fetcher. A nil
+// Fetcher uses the DefaultFetcher.
+func (b *Buoy) FetchHistoricalStandardDataContext(ctx context.Context, fetcher Fetcher, year int) error {
+	return b.fetchHistoricalStandardData(ctx, fetcher, b.CreateHistoricalDataURL(HistoricalStandardData, year))
+}
+
+// Grabs a month of archived standard meteorological data as a time series of BuoyDataItem objects.
+func (b *Buoy) FetchMonthlyStandardData(year int, month time.Month) error {
+	return b.FetchMonthlyStandardDataContext(context.Background(), nil, year, month)
+}
+
+// Grabs a month of archived standard meteorological data using the given context and Fetcher. A nil
+// Fetcher uses the DefaultFetcher.
+func (b *Buoy) FetchMonthlyStandardDataContext(ctx context.Context, fetcher Fetcher, year int, month time.Month) error {
+	return b.fetchHistoricalStandardData(ctx, fetcher, b.CreateMonthlyDataURL(HistoricalStandardData, year, month))
+}
+
+func (b *Buoy) fetchHistoricalStandardData(ctx context.Context, fetcher Fetcher, url string) error {
+	body, fetchErr := fetchStreamFromURL(ctx, fetcher, url)
+	if fetchErr != nil {
+		return fetchErr
+	}
+	defer body.Close()
+
+	return b.ParseStandardData(body, 0)
+}
+
+// Grabs a year of archived spectral wave data as a time series of BuoyDataItem objects, combining the
+// spectral density and mean wave direction archives.
+func (b *Buoy) FetchHistoricalWaveSpectraData(year int) error {
+	return b.FetchHistoricalWaveSpectraDataContext(context.Background(), nil, year)
+}
+
+// Grabs a year of archived spectral wave data using the given context and Fetcher. A nil Fetcher uses
+// the DefaultFetcher.
+func (b *Buoy) FetchHistoricalWaveSpectraDataContext(ctx context.Context, fetcher Fetcher, year int) error {
+	return b.fetchHistoricalWaveSpectraData(ctx, fetcher, func(dataset HistoricalDataset) string {
+		return b.CreateHistoricalDataURL(dataset, year)
+	})
+}
+
+// Grabs a month of archived spectral wave data as a time series of BuoyDataItem objects.
+func (b *Buoy) FetchMonthlyWaveSpectraData(year int, month time.Month) error {
+	return b.FetchMonthlyWaveSpectraDataContext(context.Background(), nil, year, month)
+}
+
+// Grabs a month of archived spectral wave data using the given context and Fetcher. A nil Fetcher uses
+// the DefaultFetcher.
+func (b *Buoy) FetchMonthlyWaveSpectraDataContext(ctx context.Context, fetcher Fetcher, year int, month time.Month) error {
+	return b.fetchHistoricalWaveSpectraData(ctx, fetcher, func(dataset HistoricalDataset) string {
+		return b.CreateMonthlyDataURL(dataset, year, month)
+	})
+}
+
+func (b *Buoy) fetchHistoricalWaveSpectraData(ctx context.Context, fetcher Fetcher, createURL func(HistoricalDataset) string) error {
+	energyTable, energyErr := b.fetchHistoricalSpectralTable(ctx, fetcher, HistoricalSpectralDensity, createURL(HistoricalSpectralDensity))
+	if energyErr != nil {
+		return energyErr
+	}
+
+	alphaTable, alphaErr := b.fetchHistoricalSpectralTable(ctx, fetcher, HistoricalSpectralDirection, createURL(HistoricalSpectralDirection))
+	if alphaErr != nil {
+		return alphaErr
+	}
+
+	return b.ParseHistoricalWaveSpectraData(energyTable, alphaTable)
+}
+
+// Grabs a year of archived alpha2, r1 and r2 directional coefficients and adds them to the wave spectra
+// fetched by FetchHistoricalWaveSpectraData.
+func (b *Buoy) FetchHistoricalDirectionalCoefficients(year int) error {
+	return b.FetchHistoricalDirectionalCoefficientsContext(context.Background(), nil, year)
+}
+
+// Grabs a year of archived directional coefficients using the given context and Fetcher. A nil Fetcher
+// uses the DefaultFetcher.
+func (b *Buoy) FetchHistoricalDirectionalCoefficientsContext(ctx context.Context, fetcher Fetcher, year int) error {
+	return b.fetchHistoricalDirectionalCoefficients(ctx, fetcher, func(dataset HistoricalDataset) string {
+		return b.CreateHistoricalDataURL(dataset, year)
+	})
+}
+
+// Grabs a month of archived alpha2, r1 and r2 directional coefficients and adds them to the wave spectra
+// fetched by FetchMonthlyWaveSpectraData.
+func (b *Buoy) FetchMonthlyDirectionalCoefficients(year int, month time.Month) error {
+	return b.FetchMonthlyDirectionalCoefficientsContext(context.Background(), nil, year, month)
+}
+
+// Grabs a month of archived directional coefficients using the given context and Fetcher. A nil Fetcher
+// uses the DefaultFetcher.
+func (b *Buoy) FetchMonthlyDirectionalCoefficientsContext(ctx context.Context, fetcher Fetcher, year int, month time.Month) error {
+	return b.fetchHistoricalDirectionalCoefficients(ctx, fetcher, func(dataset HistoricalDataset) string {
+		return b.CreateMonthlyDataURL(dataset, year, month)
+	})
+}
+
+func (b *Buoy) fetchHistoricalDirectionalCoefficients(ctx context.Context, fetcher Fetcher, createURL func(HistoricalDataset) string) error {
+	alphaTwoTable, alphaTwoErr := b.fetchHistoricalSpectralTable(ctx, fetcher, HistoricalSpectralDirection2, createURL(HistoricalSpectralDirection2))
+	if alphaTwoErr != nil {
+		return alphaTwoErr
+	}
+
+	rOneTable, rOneErr := b.fetchHistoricalSpectralTable(ctx, fetcher, HistoricalSpectralR1, createURL(HistoricalSpectralR1))
+	if rOneErr != nil {
+		return rOneErr
+	}
+
+	rTwoTable, rTwoErr := b.fetchHistoricalSpectralTable(ctx, fetcher, HistoricalSpectralR2, createURL(HistoricalSpectralR2))
+	if rTwoErr != nil {
+		return rTwoErr
+	}
+
+	rOneTable.scale(archivedCoefficientScale)
+	rTwoTable.scale(archivedCoefficientScale)
+
+	return b.AddDirectionalCoefficients(alphaTwoTable, rOneTable, rTwoTable)
+}
+
+// Grabs and parses any of the spectral archive files, such as the url from
+// CreateHistoricalDataURL(HistoricalSpectralR1, 2015). A nil Fetcher uses the DefaultFetcher.
+func (b *Buoy) FetchSpectralTableContext(ctx context.Context, fetcher Fetcher, url string) (*SpectralTable, error) {
+	return b.fetchHistoricalSpectralTable(ctx, fetcher, "", url)
+}
+
+// Grabs and parses a spectral archive file, treating the missing value sentinels of its dataset as missing
+func (b *Buoy) fetchHistoricalSpectralTable(ctx context.Context, fetcher Fetcher, dataset HistoricalDataset, url string) (*SpectralTable, error) {
+	body, fetchErr := fetchStreamFromURL(ctx, fetcher, url)
+	if fetchErr != nil {
+		return nil, fetchErr
+	}
+	defer body.Close()
+
+	return readSpectralTable(body, url, dataset, b.ParseMode)
+}
+
+// Parses the lines of an archived standard meteorological data file. Every layout NDBC has used is
+// supported, including the two digit years and missing minute column of the older archives.
+func (b *Buoy) ParseHistoricalStandardData(rawData []string) error {
+	return b.ParseStandardData(strings.NewReader(strings.Join(rawData, "\n")), 0)
+}
+
+// Combines parsed spectral density and mean wave direction tables into a time series of BuoyDataItem
+// objects. Observations missing from the direction table keep missing angles.
+func (b *Buoy) ParseHistoricalWaveSpectraData(energyTable, alphaTable *SpectralTable) error {
+	if energyTable == nil {
+		return errors.New("No spectral density data to parse")
+	}
+
+	alphaRows := map[time.Time][]float64{}
+	if alphaTable != nil {
+		if len(alphaTable.Frequencies) != len(energyTable.Frequencies) {
+			return errors.New("Swell direction and energy spectra data does not match, could not parse")
+		}
+		for i, date := range alphaTable.Dates {
+			alphaRows[date.UTC()] = alphaTable.Values[i]
+		}
+	}
+
+	buoyData := make([]BuoyDataItem, len(energyTable.Dates))
+	for i, date := range energyTable.Dates {
+		item := BuoySpectraItem{
+			Frequencies:         energyTable.Frequencies,
+			Energies:            energyTable.Values[i],
+			SeperationFrequency: MissingValue(),
+		}
+
+		if angles, ok := alphaRows[date.UTC()]; ok {
+			item.Angles = angles
+		} else {
+			item.Angles = make([]float64, len(item.Frequencies))
+			for j := range item.Angles {
+				item.Angles[j] = MissingValue()
+			}
+		}
+
+		buoyData[i] = newSpectraBuoyDataItem(date, item)
+	}
+
+	b.BuoyData = buoyData
+	b.ParseWarnings = energyTable.ParseWarnings
+	if alphaTable != nil {
+		b.ParseWarnings = append(b.ParseWarnings, alphaTable.ParseWarnings...)
+	}
+
+	return nil
+}
+
+// Parses the lines of any NDBC spectral archive file. The frequency bands are read from the header.
+func ParseSpectralTable(rawData []string, mode ParseMode) (*SpectralTable, error) {
+	return ReadSpectralTable(strings.NewReader(strings.Join(rawData, "\n")), mode)
+}
+
+// Reads any NDBC spectral archive file from a stream, decompressing it if it is gzipped
+func ReadSpectralTable(r io.Reader, mode ParseMode) (*SpectralTable, error) {
+	return readSpectralTable(r, "spectral data", "", mode)
+}
+
+func readSpectralTable(r io.Reader, source string, dataset HistoricalDataset, mode ParseMode) (*SpectralTable, error) {
+	data, gzipErr := gunzipReaderIfCompressed(r)
+	if gzipErr != nil {
+		return nil, gzipErr
+	}
+
+	table, tableErr := newNDBCTableReader(data)
+	if tableErr != nil {
+		return nil, tableErr
+	}
+
+	parser := newValueParser(source, mode)
+	dateColumns := table.dateColumnCount()
+
+	spectra := &SpectralTable{}
+	spectra.Frequencies = make([]float64, len(table.Fields)-dateColumns)
+	for i := range spectra.Frequencies {
+		frequency, parseErr := strconv.ParseFloat(table.Fields[dateColumns+i], 64)
+		if parseErr != nil {
+			return nil, &ParseError{Source: source, Line: table.line, Column: dateColumns + i + 1, Field: "frequency", Token: table.Fields[dateColumns+i], Err: parseErr}
+		}
+		spectra.Frequencies[i] = frequency
+	}
+
+	for {
+		row, ok := table.next()
+		if !ok {
+			break
+		}
+
+		if !table.complete(row, parser) {
+			if failErr := parser.failed(); failErr != nil {
+				return nil, failErr
+			}
+			continue
+		}
+
+		date := table.date(row, parser)
+		if date.IsZero() {
+			if failErr := parser.failed(); failErr != nil {
+				return nil, failErr
+			}
+			continue
+		}
+
+		values := make([]float64, len(spectra.Frequencies))
+		for i := range values {
+			values[i] = parser.float(row.Tokens[dateColumns+i], row.Line, dateColumns+i+1, table.Fields[dateColumns+i])
+			if values[i] == missingSpectralValue || isNDBCMissingSentinel(string(dataset), values[i]) {
+				values[i] = MissingValue()
+			}
+		}
+
+		if failErr := parser.failed(); failErr != nil {
+			return nil, failErr
+		}
+
+		spectra.Dates = append(spectra.Dates, date)
+		spectra.Values = append(spectra.Values, values)
+	}
+
+	if readErr := table.err(); readErr != nil {
+		return nil, readErr
+	}
+
+	spectra.ParseWarnings = parser.warnings
+	return spectra, nil
+}
+
+// Multiply every value in the table by the given factor
+func (s *SpectralTable) scale(factor float64) {
+	for _, row := range s.Values {
+		for i := range row {
+			row[i] *= factor
+		}
+	}
+}
+
+// Create a BuoyDataItem for a standard meteorological data row of an NDBC table
+func standardDataItemFromRow(table *ndbcTable, row ndbcRow, parser *valueParser) BuoyDataItem {
+	newBuoyData := BuoyDataItem{}
+
+	// Units are metric by default
+	newBuoyData.Units = Metric
+	newBuoyData.WaveSummary.Units = Metric
+
+	newBuoyData.Date = table.date(row, parser)
+	newBuoyData.WindDirection = table.value(row, parser, "WDIR", "WD")
+	newBuoyData.WindSpeed = table.value(row, parser, "WSPD")
+	newBuoyData.WindGust = table.value(row, parser, "GST")
+	newBuoyData.WaveSummary.WaveHeight = table.value(row, parser, "WVHT")
+	newBuoyData.WaveSummary.Period = table.value(row, parser, "DPD")
+	newBuoyData.AveragePeriod = table.value(row, parser, "APD")
+	newBuoyData.WaveSummary.Direction = table.value(row, parser, "MWD")
+	newBuoyData.WaveSummary.CompassDirection = DegreeToDirection(newBuoyData.WaveSummary.Direction)
+	newBuoyData.Pressure = table.value(row, parser, "PRES", "BAR")
+	newBuoyData.AirTemperature = table.value(row, parser, "ATMP")
+	newBuoyData.WaterTemperature = table.value(row, parser, "WTMP")
+	newBuoyData.DewpointTemperature = table.value(row, parser, "DEWP")
+	newBuoyData.Visibility = table.value(row, parser, "VIS")
+	newBuoyData.PressureTendency = table.value(row, parser, "PTDY")
+	newBuoyData.WaterLevel = FeetToMeters(table.value(row, parser, "TIDE"))
+
+	return newBuoyData
+}
+
+// Create a BuoyDataItem from a wave spectra, solving for the wave summary and swell components
+func newSpectraBuoyDataItem(date time.Time, item BuoySpectraItem) BuoyDataItem {
+	buoyItem := BuoyDataItem{Date: date}
+	buoyItem.WaveSpectra = item
+	buoyItem.WaveSummary = item.WaveSummary()
+	buoyItem.SwellComponents = item.FindSwellComponents()
+	buoyItem.Steepness = SolveSteepness(buoyItem.WaveSummary.WaveHeight, buoyItem.WaveSummary.Period)
+	buoyItem.AveragePeriod = item.AveragePeriod()
+	return buoyItem
+}
@@ -0,0 +1,152 @@
+package surfnerd
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"testing"
+	"time"
+)
+
+const testHistoricalStandardData = `#YY  MM DD hh mm WDIR WSPD GST  WVHT   DPD   APD MWD   PRES  ATMP  WTMP  DEWP  VIS  TIDE
+#yr  mo dy hr mn degT m/s  m/s     m   sec   sec degT   hPa  degC  degC  degC  mi    ft
+2015 01 01 00 50 270  7.2  9.1  1.21  8.33  5.86 251 1021.3   3.2   7.9 -1.2 99.0 99.00
+2015 01 01 01 50 999 99.0 99.0 99.00 99.00 99.00 999 9999.0 999.0   7.8 999.0 99.0 99.00
+`
+
+const testOldHistoricalStandardData = `YY MM DD hh  WD  WSPD GST  WVHT  DPD   APD  MWD  BAR    ATMP  WTMP  DEWP  VIS
+98 01 01 00 200  5.1  6.2  0.80 11.11  6.04 999 1018.9   4.8   8.1 999.0 99.0
+98 01 01 01 210  5.5  6.8  0.90 10.00  6.20 999 1018.5   4.9   8.1 999.0 99.0
+`
+
+const testHistoricalSpectralDensity = `#YY  MM DD hh mm   .0200  .0325  .0375
+2015 01 01 00 40   0.00   0.12   0.55
+2015 01 01 01 40   0.00 999.00   0.61
+`
+
+const testHistoricalSpectralDirection = `YYYY MM DD hh mm   .0200  .0325  .0375
+2015 01 01 00 40   999.0  180.0  190.0
+`
+
+func gzipString(t *testing.T, data string) string {
+	var buffer bytes.Buffer
+	writer := gzip.NewWriter(&buffer)
+	if _, writeErr := writer.Write([]byte(data)); writeErr != nil {
+		t.Fatal(writeErr)
+	}
+	if closeErr := writer.Close(); closeErr != nil {
+		t.Fatal(closeErr)
+	}
+	return buffer.String()
+}
+
+func TestHistoricalDataURLs(t *testing.T) {
+	buoy := Buoy{StationID: "41001"}
+
+	if url := buoy.CreateHistoricalDataURL(HistoricalStandardData, 2015); url != NDBCBaseURL+"/data/historical/stdmet/41001h2015.txt.gz" {
+		t.Errorf("Unexpected yearly url %s", url)
+	}
+	if url := buoy.CreateHistoricalDataURL(HistoricalSpectralR2, 2015); url != NDBCBaseURL+"/data/historical/swr2/41001k2015.txt.gz" {
+		t.Errorf("Unexpected yearly url %s", url)
+	}
+	if url := buoy.CreateMonthlyDataURL(HistoricalSpectralDensity, 2016, time.March); url != NDBCBaseURL+"/data/swden/Mar/4100132016.txt.gz" {
+		t.Errorf("Unexpected monthly url %s", url)
+	}
+	if url := buoy.CreateMonthlyDataURL(HistoricalStandardData, 2016, time.November); url != NDBCBaseURL+"/data/stdmet/Nov/41001b2016.txt.gz" {
+		t.Errorf("Unexpected monthly url %s", url)
+	}
+}
+
+func TestFetchHistoricalStandardData(t *testing.T) {
+	buoy := Buoy{StationID: "41001"}
+	fetcher := mapFetcher{buoy.CreateHistoricalDataURL(HistoricalStandardData, 2015): gzipString(t, testHistoricalStandardData)}
+
+	if fetchErr := buoy.FetchHistoricalStandardDataContext(context.Background(), fetcher, 2015); fetchErr != nil {
+		t.Fatal(fetchErr)
+	}
+
+	if len(buoy.BuoyData) != 2 {
+		t.Fatalf("Expected 2 data items, got %d", len(buoy.BuoyData))
+	}
+
+	item := buoy.BuoyData[0]
+	if !item.Date.Equal(time.Date(2015, 1, 1, 0, 50, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected date %v", item.Date)
+	}
+	if item.WindDirection != 270 || item.WaveSummary.WaveHeight != 1.21 || item.Pressure != 1021.3 {
+		t.Errorf("Unexpected values %+v", item)
+	}
+	if !IsMissing(item.Visibility) || !IsMissing(item.WaterLevel) {
+		t.Error("Expected the sentinel visibility and tide to be missing")
+	}
+	if !IsMissing(buoy.BuoyData[1].Pressure) || buoy.BuoyData[1].WaterTemperature != 7.8 {
+		t.Errorf("Unexpected values %+v", buoy.BuoyData[1])
+	}
+}
+
+func TestParseOldHistoricalStandardData(t *testing.T) {
+	buoy := Buoy{StationID: "41001"}
+	fetcher := mapFetcher{buoy.CreateHistoricalDataURL(HistoricalStandardData, 1998): testOldHistoricalStandardData}
+
+	if fetchErr := buoy.FetchHistoricalStandardDataContext(context.Background(), fetcher, 1998); fetchErr != nil {
+		t.Fatal(fetchErr)
+	}
+
+	if len(buoy.BuoyData) != 2 {
+		t.Fatalf("Expected 2 data items, got %d", len(buoy.BuoyData))
+	}
+
+	item := buoy.BuoyData[1]
+	if !item.Date.Equal(time.Date(1998, 1, 1, 1, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected date %v", item.Date)
+	}
+	if item.WindDirection != 210 || item.Pressure != 1018.5 {
+		t.Errorf("Expected the WD and BAR columns to be read, got %+v", item)
+	}
+	if !IsMissing(item.PressureTendency) {
+		t.Error("Expected the absent PTDY column to be missing")
+	}
+}
+
+func TestFetchMonthlyWaveSpectraData(t *testing.T) {
+	buoy := Buoy{StationID: "41001"}
+	fetcher := mapFetcher{
+		buoy.CreateMonthlyDataURL(HistoricalSpectralDensity, 2016, time.March):   gzipString(t, testHistoricalSpectralDensity),
+		buoy.CreateMonthlyDataURL(HistoricalSpectralDirection, 2016, time.March): gzipString(t, testHistoricalSpectralDirection),
+	}
+
+	if fetchErr := buoy.FetchMonthlyWaveSpectraDataContext(context.Background(), fetcher, 2016, time.March); fetchErr != nil {
+		t.Fatal(fetchErr)
+	}
+
+	if len(buoy.BuoyData) != 2 {
+		t.Fatalf("Expected 2 data items, got %d", len(buoy.BuoyData))
+	}
+
+	spectra := buoy.BuoyData[0].WaveSpectra
+	if len(spectra.Frequencies) != 3 || spectra.Frequencies[1] != 0.0325 {
+		t.Fatalf("Unexpected frequencies %v", spectra.Frequencies)
+	}
+	if !IsMissing(spectra.Angles[0]) || spectra.Angles[2] != 190 {
+		t.Errorf("Unexpected angles %v", spectra.Angles)
+	}
+	if buoy.BuoyData[0].WaveSummary.Direction != 190 {
+		t.Errorf("Expected the peak direction to be 190, got %v", buoy.BuoyData[0].WaveSummary.Direction)
+	}
+
+	// The second observation has no direction row and a missing energy
+	second := buoy.BuoyData[1].WaveSpectra
+	if !IsMissing(second.Energies[1]) || !IsMissing(second.Angles[2]) {
+		t.Errorf("Expected missing values, got %v %v", second.Energies, second.Angles)
+	}
+	if IsMissing(buoy.BuoyData[1].WaveSummary.WaveHeight) {
+		t.Error("Expected the wave height to skip the missing energy")
+	}
+}
+
+func TestParseSpectralTableStrict(t *testing.T) {
+	_, parseErr := ParseSpectralTable([]string{"YYYY MM DD hh mm .0200 .0325", "2015 01 01 00 40 0.00 bad"}, StrictParsing)
+	if parseErr == nil {
+		t.Fatal("Expected a strict parse error")
+	}
+}
@@ -1,9 +1,24 @@
 package surfnerd
 
 import (
+	"errors"
 	"math"
 )
 
+const (
+	// The mean radius of the earth in kilometers, used for the spherical calculations
+	earthRadiusKilometers = 6371.0088
+
+	// The WGS-84 ellipsoid, used for the Vincenty calculations
+	wgs84SemiMajorAxis   = 6378.137
+	wgs84Flattening      = 1 / 298.257223563
+	wgs84SemiMinorAxis   = wgs84SemiMajorAxis * (1 - wgs84Flattening)
+	vincentyMaxIteration = 200
+)
+
+// Returned when the Vincenty distance does not converge, which can happen for nearly antipodal points
+var ErrVincentyNoConvergence = errors.New("Vincenty distance failed to converge")
+
 // Container holding location information.
 type Location struct {
 	Latitude     float64 `xml:"lat,attr"`
@@ -12,35 +27,152 @@ type Location struct {
 	LocationName string  `xml:"name,attr"`
 }
 
-// Get an adjusted longitude that will be + or - 180 degrees
+// Get an adjusted longitude that will be + or - 180 degrees, the convention the buoys use
 func (l Location) AdjustedLongitude() float64 {
-	if l.Longitude > 180 {
-		return l.Longitude - 360.0
-	} else {
-		return l.Longitude
-	}
+	return NormalizeLongitude(l.Longitude)
 }
 
-// Get an adjusted latitude that will be + or - 85
+// Get an adjusted longitude from 0 to 360 degrees east, the convention the NOAA models use
+func (l Location) EastLongitude() float64 {
+	return NormalizeLongitude360(l.Longitude)
+}
+
+// Get an adjusted latitude that will be + or - 90 degrees
 func (l Location) AdjustedLatitude() float64 {
-	if l.Latitude > 85 {
-		return l.Latitude - 360.0
-	} else {
-		return l.Latitude
-	}
+	return math.Max(-90.0, math.Min(l.Latitude, 90.0))
 }
 
-// Get the lat and long components of the distance between two locations
+// Get the lat and long components of the distance between two locations in degrees. The
+// longitude distance is always the short way around, whichever longitude convention is used.
 func (l Location) ComponentDistanceTo(otherLoc Location) (latDist, lonDist float64) {
 	latDist = math.Abs(l.Latitude - otherLoc.Latitude)
-	lonDist = math.Abs(l.Longitude - otherLoc.Longitude)
+	lonDist = math.Abs(NormalizeLongitude(l.Longitude - otherLoc.Longitude))
 	return
 }
 
-// Get the estimated distance vector between two location points
+// Get the great circle distance between two locations in kilometers, using the haversine formula
 func (l Location) DistanceTo(otherLoc Location) float64 {
-	latDist, lonDist := l.ComponentDistanceTo(otherLoc)
-	return math.Sqrt(math.Pow(latDist, 2) + math.Pow(lonDist, 2))
+	lat1, lon1 := l.radians()
+	lat2, lon2 := otherLoc.radians()
+
+	a := math.Pow(math.Sin((lat2-lat1)/2), 2) + math.Cos(lat1)*math.Cos(lat2)*math.Pow(math.Sin((lon2-lon1)/2), 2)
+	return 2 * earthRadiusKilometers * math.Asin(math.Min(1.0, math.Sqrt(a)))
+}
+
+// Get the great circle distance between two locations in nautical miles
+func (l Location) DistanceToInNauticalMiles(otherLoc Location) float64 {
+	return KilometersToNauticalMiles(l.DistanceTo(otherLoc))
+}
+
+// Get the distance between two locations in kilometers on the WGS-84 ellipsoid, using Vincenty's
+// inverse formula. It is accurate to within a millimeter but is slower than DistanceTo, and returns
+// ErrVincentyNoConvergence for nearly antipodal locations.
+func (l Location) VincentyDistanceTo(otherLoc Location) (float64, error) {
+	lat1, _ := l.radians()
+	lat2, _ := otherLoc.radians()
+
+	lonDiff := NormalizeLongitude(otherLoc.Longitude-l.Longitude) * math.Pi / 180
+	u1 := math.Atan((1 - wgs84Flattening) * math.Tan(lat1))
+	u2 := math.Atan((1 - wgs84Flattening) * math.Tan(lat2))
+	sinU1, cosU1 := math.Sin(u1), math.Cos(u1)
+	sinU2, cosU2 := math.Sin(u2), math.Cos(u2)
+
+	lambda := lonDiff
+	var sinSigma, cosSigma, sigma, cosSqAlpha, cos2SigmaM float64
+	for iteration := 0; ; iteration++ {
+		if iteration >= vincentyMaxIteration {
+			return 0, ErrVincentyNoConvergence
+		}
+
+		sinLambda, cosLambda := math.Sin(lambda), math.Cos(lambda)
+		sinSigma = math.Sqrt(math.Pow(cosU2*sinLambda, 2) + math.Pow(cosU1*sinU2-sinU1*cosU2*cosLambda, 2))
+		if sinSigma == 0 {
+			// The locations are the same
+			return 0, nil
+		}
+
+		cosSigma = sinU1*sinU2 + cosU1*cosU2*cosLambda
+		sigma = math.Atan2(sinSigma, cosSigma)
+		sinAlpha := cosU1 * cosU2 * sinLambda / sinSigma
+		cosSqAlpha = 1 - sinAlpha*sinAlpha
+
+		// Both locations are on the equator
+		cos2SigmaM = 0
+		if cosSqAlpha != 0 {
+			cos2SigmaM = cosSigma - 2*sinU1*sinU2/cosSqAlpha
+		}
+
+		c := wgs84Flattening / 16 * cosSqAlpha * (4 + wgs84Flattening*(4-3*cosSqAlpha))
+		previousLambda := lambda
+		lambda = lonDiff + (1-c)*wgs84Flattening*sinAlpha*(sigma+c*sinSigma*(cos2SigmaM+c*cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)))
+		if math.Abs(lambda-previousLambda) < 1e-12 {
+			break
+		}
+	}
+
+	uSq := cosSqAlpha * (wgs84SemiMajorAxis*wgs84SemiMajorAxis - wgs84SemiMinorAxis*wgs84SemiMinorAxis) / (wgs84SemiMinorAxis * wgs84SemiMinorAxis)
+	a := 1 + uSq/16384*(4096+uSq*(-768+uSq*(320-175*uSq)))
+	b := uSq / 1024 * (256 + uSq*(-128+uSq*(74-47*uSq)))
+	deltaSigma := b * sinSigma * (cos2SigmaM + b/4*(cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)-b/6*cos2SigmaM*(-3+4*sinSigma*sinSigma)*(-3+4*cos2SigmaM*cos2SigmaM)))
+
+	return wgs84SemiMinorAxis * a * (sigma - deltaSigma), nil
+}
+
+// Get the initial bearing in degrees from north to follow the great circle to another location
+func (l Location) InitialBearingTo(otherLoc Location) float64 {
+	lat1, lon1 := l.radians()
+	lat2, lon2 := otherLoc.radians()
+
+	y := math.Sin(lon2-lon1) * math.Cos(lat2)
+	x := math.Cos(lat1)*math.Sin(lat2) - math.Sin(lat1)*math.Cos(lat2)*math.Cos(lon2-lon1)
+	return NormalizeLongitude360(math.Atan2(y, x) * 180 / math.Pi)
+}
+
+// Get the final bearing in degrees from north when arriving at another location along the great circle
+func (l Location) FinalBearingTo(otherLoc Location) float64 {
+	return NormalizeLongitude360(otherLoc.InitialBearingTo(l) + 180.0)
+}
+
+// Get the location reached by travelling the given distance in kilometers along the great circle
+// starting at the given bearing. The longitude is returned in the same convention as the starting
+// location.
+func (l Location) DestinationPoint(bearing, distance float64) Location {
+	lat1, lon1 := l.radians()
+	theta := bearing * math.Pi / 180
+	delta := distance / earthRadiusKilometers
+
+	lat2 := math.Asin(math.Sin(lat1)*math.Cos(delta) + math.Cos(lat1)*math.Sin(delta)*math.Cos(theta))
+	lon2 := lon1 + math.Atan2(math.Sin(theta)*math.Sin(delta)*math.Cos(lat1), math.Cos(delta)-math.Sin(lat1)*math.Sin(lat2))
+
+	destination := Location{Latitude: lat2 * 180 / math.Pi, Longitude: NormalizeLongitude(lon2 * 180 / math.Pi)}
+	if l.Longitude > 180 {
+		destination.Longitude = NormalizeLongitude360(destination.Longitude)
+	}
+	return destination
+}
+
+// Get the latitude and longitude in radians
+func (l Location) radians() (lat, lon float64) {
+	return l.Latitude * math.Pi / 180, l.Longitude * math.Pi / 180
+}
+
+// Normalize a longitude to the -180 up to 180 degree convention
+func NormalizeLongitude(lon float64) float64 {
+	return normalizeLongitudeFrom(lon, -180.0)
+}
+
+// Normalize a longitude to the 0 up to 360 degree convention
+func NormalizeLongitude360(lon float64) float64 {
+	return normalizeLongitudeFrom(lon, 0.0)
+}
+
+// Shift a longitude by whole turns so it falls in the 360 degrees starting at the given longitude
+func normalizeLongitudeFrom(lon, start float64) float64 {
+	normalized := math.Mod(lon-start, 360.0)
+	if normalized < 0 {
+		normalized += 360.0
+	}
+	return normalized + start
 }
 
 // Create a new Location object from a given latitude and longitude pair
@@ -0,0 +1,118 @@
+package surfnerd
+
+import (
+	"math"
+	"testing"
+)
+
+func TestVincentyDistance(t *testing.T) {
+	// The worked example from Vincenty's paper, Flinders Peak to Buninyong
+	flindersPeak := NewLocationForLatLong(-37.95103342, 144.42486789)
+	buninyong := NewLocationForLatLong(-37.65282114, 143.92649554)
+
+	distance, distanceErr := flindersPeak.VincentyDistanceTo(buninyong)
+	if distanceErr != nil {
+		t.Fatal(distanceErr)
+	}
+	if math.Abs(distance-54.972271) > 1e-6 {
+		t.Errorf("Expected a Vincenty distance of 54.972271 km, got %v", distance)
+	}
+	if haversine := flindersPeak.DistanceTo(buninyong); math.Abs(haversine-distance)/distance > 0.005 {
+		t.Errorf("Expected the haversine distance to be within half a percent, got %v", haversine)
+	}
+}
+
+func TestDistanceAcrossLongitudeSeam(t *testing.T) {
+	east := NewLocationForLatLong(0, 179.5)
+	west := NewLocationForLatLong(0, -179.5)
+	if distance := east.DistanceTo(west); math.Abs(distance-111.195) > 0.01 {
+		t.Errorf("Expected a degree of longitude at the equator, got %v", distance)
+	}
+
+	// Mixing the buoy and model longitude conventions should not change the distance
+	buoy := NewLocationForLatLong(41.1, -71.4)
+	model := NewLocationForLatLong(41.1, 288.6)
+	if distance := buoy.DistanceTo(model); distance > 1e-6 {
+		t.Errorf("Expected the same location, got %v km apart", distance)
+	}
+	if vincenty, _ := buoy.VincentyDistanceTo(model); vincenty > 1e-6 {
+		t.Errorf("Expected the same location, got %v km apart", vincenty)
+	}
+	if _, lonDist := east.ComponentDistanceTo(west); math.Abs(lonDist-1.0) > 1e-9 {
+		t.Errorf("Expected a longitude distance of 1 degree, got %v", lonDist)
+	}
+}
+
+func TestBearingAndDestination(t *testing.T) {
+	start := NewLocationForLatLong(41.0, -71.0)
+	north := NewLocationForLatLong(42.0, -71.0)
+	if bearing := start.InitialBearingTo(north); math.Abs(bearing) > 1e-9 {
+		t.Errorf("Expected a bearing of 0, got %v", bearing)
+	}
+
+	end := NewLocationForLatLong(50.0, -5.0)
+	initial := start.InitialBearingTo(end)
+	final := start.FinalBearingTo(end)
+	if initial < 40 || initial > 60 || final <= initial {
+		t.Errorf("Unexpected bearings %v and %v", initial, final)
+	}
+
+	destination := start.DestinationPoint(initial, start.DistanceTo(end))
+	if destination.DistanceTo(end) > 1e-6 {
+		t.Errorf("Expected to arrive at %v, got %v", end, destination)
+	}
+
+	// The destination keeps the longitude convention of the start
+	modelStart := NewLocationForLatLong(41.0, 289.0)
+	if destination := modelStart.DestinationPoint(90.0, 10.0); destination.Longitude < 289.0 || destination.Longitude > 290.0 {
+		t.Errorf("Unexpected destination longitude %v", destination.Longitude)
+	}
+}
+
+func TestNormalizeLongitude(t *testing.T) {
+	for lon, expected := range map[float64][2]float64{
+		-71.0: {-71.0, 289.0},
+		289.0: {-71.0, 289.0},
+		180.0: {-180.0, 180.0},
+		720.5: {0.5, 0.5},
+		-360:  {0, 0},
+	} {
+		if normalized := NormalizeLongitude(lon); normalized != expected[0] {
+			t.Errorf("Expected %v to normalize to %v, got %v", lon, expected[0], normalized)
+		}
+		if normalized := NormalizeLongitude360(lon); normalized != expected[1] {
+			t.Errorf("Expected %v to normalize to %v, got %v", lon, expected[1], normalized)
+		}
+	}
+
+	if latitude := NewLocationForLatLong(88.0, 0).AdjustedLatitude(); latitude != 88.0 {
+		t.Errorf("Expected a high latitude to be kept, got %v", latitude)
+	}
+}
+
+func TestFindClosestActiveBuoyAtHighLatitude(t *testing.T) {
+	// Two degrees of longitude in Maine are closer than 1.6 degrees of latitude
+	west := &Buoy{StationID: "west", Active: "y", Location: &Location{Latitude: 44.0, Longitude: -70.0}}
+	south := &Buoy{StationID: "south", Active: "y", Location: &Location{Latitude: 42.4, Longitude: -68.0}}
+	stations := BuoyStations{Stations: []*Buoy{south, west}}
+
+	closest := stations.FindClosestActiveBuoy(NewLocationForLatLong(44.0, 292.0))
+	if closest == nil || closest.StationID != "west" {
+		t.Errorf("Expected the western buoy to be closest, got %v", closest)
+	}
+}
+
+func TestModelContainsEitherLongitudeConvention(t *testing.T) {
+	model := NewEastCoastWaveModel()
+	relative := NewLocationForLatLong(41.323, -71.396)
+	absolute := NewLocationForLatLong(41.323, 360-71.396)
+	if !model.ContainsLocation(relative) || !model.ContainsLocation(absolute) {
+		t.Fatal("Expected the east coast model to contain both longitudes")
+	}
+
+	relativeLat, relativeLon := model.LocationIndices(relative)
+	absoluteLat, absoluteLon := model.LocationIndices(absolute)
+	if relativeLat != absoluteLat || relativeLon != absoluteLon || relativeLon < 0 {
+		t.Errorf("Expected the same indices, got %d %d and %d %d", relativeLat, relativeLon, absoluteLat, absoluteLon)
+	}
+}
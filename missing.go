@@ -0,0 +1,114 @@
+package surfnerd
+
+import (
+	"encoding/json"
+	"math"
+)
+
+// The sentinel values NDBC writes in place of missing measurements, keyed by column name. Any value
+// at or above the sentinel is treated as missing. Realtime files use MM instead, which is always missing.
+var ndbcMissingSentinels = map[string]float64{
+	"WDIR": 999.0,
+	"WD":   999.0,
+	"WSPD": 99.0,
+	"GST":  99.0,
+	"WVHT": 99.0,
+	"DPD":  99.0,
+	"APD":  99.0,
+	"MWD":  999.0,
+	"PRES": 9999.0,
+	"BAR":  9999.0,
+	"ATMP": 999.0,
+	"WTMP": 999.0,
+	"DEWP": 999.0,
+	"VIS":  99.0,
+	"PTDY": 99.0,
+	"TIDE": 99.0,
+	"SwH":  99.0,
+	"SwP":  99.0,
+	"WWH":  99.0,
+	"WWP":  99.0,
+
+	// Continuous winds, supplemental measurements and DART water column heights
+	"GDR":    999.0,
+	"GTIME":  9999.0,
+	"PTIME":  9999.0,
+	"WTIME":  9999.0,
+	"HEIGHT": 9999.0,
+
+	// Directional spectral coefficients, named by coefficient in realtime files and by dataset in the
+	// archives. The archived r1 and r2 sentinels are compared before they are scaled from hundredths.
+	"alpha1": 999.0,
+	"alpha2": 999.0,
+	"r1":     999.0,
+	"r2":     999.0,
+	"swdir":  999.0,
+	"swdir2": 999.0,
+	"swr1":   999.0,
+	"swr2":   999.0,
+}
+
+// Get the value used to represent a missing measurement. Missing values are stored as NaN so they
+// flow through unit conversions untouched and can never be mistaken for a real reading.
+func MissingValue() float64 {
+	return math.NaN()
+}
+
+// Check if a measurement is missing
+func IsMissing(value float64) bool {
+	return math.IsNaN(value)
+}
+
+// Check if a raw value read from the given NDBC column is one of its missing value sentinels
+func isNDBCMissingSentinel(field string, value float64) bool {
+	sentinel, ok := ndbcMissingSentinels[field]
+	return ok && value >= sentinel
+}
+
+// A float that is written to json as null when it is missing, and read back as missing from null
+type nullableFloat float64
+
+func (n nullableFloat) MarshalJSON() ([]byte, error) {
+	if IsMissing(float64(n)) {
+		return []byte("null"), nil
+	}
+	return json.Marshal(float64(n))
+}
+
+func (n *nullableFloat) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*n = nullableFloat(MissingValue())
+		return nil
+	}
+
+	var value float64
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	*n = nullableFloat(value)
+	return nil
+}
+
+func nullableFloats(values []float64) []nullableFloat {
+	if values == nil {
+		return nil
+	}
+
+	nullable := make([]nullableFloat, len(values))
+	for i, value := range values {
+		nullable[i] = nullableFloat(value)
+	}
+	return nullable
+}
+
+func floatsFromNullable(nullable []nullableFloat) []float64 {
+	if nullable == nil {
+		return nil
+	}
+
+	values := make([]float64, len(nullable))
+	for i, value := range nullable {
+		values[i] = float64(value)
+	}
+	return values
+}
@@ -0,0 +1,132 @@
+package surfnerd
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testMissingStandardData = `#YY  MM DD hh mm WDIR WSPD GST  WVHT   DPD   APD MWD   PRES  ATMP  WTMP  DEWP  VIS PTDY  TIDE
+#yr  mo dy hr mn degT m/s  m/s     m   sec   sec degT   hPa  degC  degC  degC  nmi  hPa    ft
+2016 10 18 14 50 999 99.0 99.0 99.00 99.00 99.00 999 1015.2  15.1 999.0  12.3   MM 99.0    MM
+`
+
+func TestMissingStandardDataValues(t *testing.T) {
+	buoy := Buoy{StationID: "44017"}
+	if parseErr := buoy.ParseRawStandardData(strings.Fields(testMissingStandardData), -1); parseErr != nil {
+		t.Fatal(parseErr)
+	}
+	if len(buoy.ParseWarnings) != 0 {
+		t.Fatalf("Missing values should not be reported as warnings: %v", buoy.ParseWarnings)
+	}
+
+	item := buoy.BuoyData[0]
+	for name, value := range map[string]float64{
+		"WindDirection":    item.WindDirection,
+		"WindSpeed":        item.WindSpeed,
+		"WaveHeight":       item.WaveSummary.WaveHeight,
+		"WaterTemperature": item.WaterTemperature,
+		"Visibility":       item.Visibility,
+		"PressureTendency": item.PressureTendency,
+		"WaterLevel":       item.WaterLevel,
+	} {
+		if !IsMissing(value) {
+			t.Errorf("Expected %s to be missing, got %v", name, value)
+		}
+	}
+	if item.Pressure != 1015.2 {
+		t.Errorf("Expected a valid pressure, got %v", item.Pressure)
+	}
+
+	item.ChangeUnits(English)
+	if !IsMissing(item.WindSpeed) || item.WaveSummary.IsValid() {
+		t.Error("Missing values should stay missing when changing units")
+	}
+}
+
+func TestMissingRealtimeSpectralCoefficients(t *testing.T) {
+	buoy := Buoy{StationID: "44097"}
+	alpha := []string{"#YY  MM DD hh mm alpha1_1 (freq_1) alpha1_2 (freq_2)", "2016 10 18 14 40 999.0 (0.033) 190.0 (0.038)"}
+	energy := []string{"#YY  MM DD hh mm Sep_Freq  < spec_1 (freq_1) spec_2 (freq_2)", "2016 10 18 14 40 0.150 0.5 (0.033) 1.5 (0.038)"}
+	if parseErr := buoy.ParseRawWaveSpectraData(alpha, energy, 1); parseErr != nil {
+		t.Fatal(parseErr)
+	}
+
+	alphaTwo := []string{"#YY  MM DD hh mm alpha2_1 (freq_1) alpha2_2 (freq_2)", "2016 10 18 14 40 999 (0.033) 185.0 (0.038)"}
+	rOne := []string{"#YY  MM DD hh mm r1_1 (freq_1) r1_2 (freq_2)", "2016 10 18 14 40 0.8 (0.033) 999 (0.038)"}
+	rTwo := []string{"#YY  MM DD hh mm r2_1 (freq_1) r2_2 (freq_2)", "2016 10 18 14 40 999.0 (0.033) 0.5 (0.038)"}
+	if parseErr := buoy.ParseRawDirectionalCoefficientData(alphaTwo, rOne, rTwo); parseErr != nil {
+		t.Fatal(parseErr)
+	}
+
+	spectra := buoy.BuoyData[0].WaveSpectra
+	if !IsMissing(spectra.Angles[0]) || spectra.Angles[1] != 190.0 {
+		t.Errorf("Expected the alpha1 sentinel to be missing, got %v", spectra.Angles)
+	}
+	if !IsMissing(spectra.SecondaryAngles[0]) || !IsMissing(spectra.R1[1]) || !IsMissing(spectra.R2[0]) {
+		t.Errorf("Expected the coefficient sentinels to be missing, got %v %v %v", spectra.SecondaryAngles, spectra.R1, spectra.R2)
+	}
+	if spectra.SecondaryAngles[1] != 185.0 || spectra.R1[0] != 0.8 || spectra.R2[1] != 0.5 {
+		t.Errorf("Unexpected coefficients %v %v %v", spectra.SecondaryAngles, spectra.R1, spectra.R2)
+	}
+	if len(buoy.ParseWarnings) != 0 {
+		t.Errorf("Missing values should not be reported as warnings: %v", buoy.ParseWarnings)
+	}
+}
+
+func TestMissingHistoricalSpectralCoefficients(t *testing.T) {
+	buoy := Buoy{StationID: "41001"}
+	fetcher := mapFetcher{
+		buoy.CreateMonthlyDataURL(HistoricalSpectralDensity, 2016, time.March):    gzipString(t, testHistoricalSpectralDensity),
+		buoy.CreateMonthlyDataURL(HistoricalSpectralDirection, 2016, time.March):  gzipString(t, testHistoricalSpectralDirection),
+		buoy.CreateMonthlyDataURL(HistoricalSpectralDirection2, 2016, time.March): gzipString(t, "YYYY MM DD hh mm   .0200  .0325  .0375\n2015 01 01 00 40   999.0  185.0  195.0\n"),
+		buoy.CreateMonthlyDataURL(HistoricalSpectralR1, 2016, time.March):         gzipString(t, "YYYY MM DD hh mm   .0200  .0325  .0375\n2015 01 01 00 40  999.00  80.00  70.00\n"),
+		buoy.CreateMonthlyDataURL(HistoricalSpectralR2, 2016, time.March):         gzipString(t, "YYYY MM DD hh mm   .0200  .0325  .0375\n2015 01 01 00 40   60.00 999.00  50.00\n"),
+	}
+
+	ctx := context.Background()
+	if fetchErr := buoy.FetchMonthlyWaveSpectraDataContext(ctx, fetcher, 2016, time.March); fetchErr != nil {
+		t.Fatal(fetchErr)
+	}
+	if fetchErr := buoy.FetchMonthlyDirectionalCoefficientsContext(ctx, fetcher, 2016, time.March); fetchErr != nil {
+		t.Fatal(fetchErr)
+	}
+
+	// The r1 and r2 sentinels must stay missing rather than being scaled to 9.99
+	spectra := buoy.BuoyData[0].WaveSpectra
+	if !IsMissing(spectra.SecondaryAngles[0]) || !IsMissing(spectra.R1[0]) || !IsMissing(spectra.R2[1]) {
+		t.Errorf("Expected the archived sentinels to be missing, got %v %v %v", spectra.SecondaryAngles, spectra.R1, spectra.R2)
+	}
+	if spectra.SecondaryAngles[1] != 185.0 || spectra.R1[1] != 0.8 || spectra.R2[2] != 0.5 {
+		t.Errorf("Unexpected coefficients %v %v %v", spectra.SecondaryAngles, spectra.R1, spectra.R2)
+	}
+}
+
+func TestMissingValuesJSONRoundTrip(t *testing.T) {
+	item := BuoyDataItem{
+		WindSpeed:   MissingValue(),
+		Pressure:    1012.0,
+		WaveSummary: Swell{WaveHeight: MissingValue(), Period: 9},
+	}
+
+	jsonData, jsonErr := json.Marshal(item)
+	if jsonErr != nil {
+		t.Fatal(jsonErr)
+	}
+	if !strings.Contains(string(jsonData), `"WindSpeed":null`) {
+		t.Errorf("Expected missing wind speed to be null: %s", jsonData)
+	}
+
+	decoded := BuoyDataItem{}
+	if jsonErr := json.Unmarshal(jsonData, &decoded); jsonErr != nil {
+		t.Fatal(jsonErr)
+	}
+	if !IsMissing(decoded.WindSpeed) || !IsMissing(decoded.WaveSummary.WaveHeight) {
+		t.Error("Expected missing values to be read back as missing")
+	}
+	if decoded.Pressure != 1012.0 || decoded.WaveSummary.Period != 9 {
+		t.Error("Expected valid values to survive the round trip")
+	}
+}
@@ -0,0 +1,122 @@
+package surfnerd
+
+import (
+	"errors"
+	"math"
+)
+
+// The value the GrADS servers fill model cells without data with, such as the land cells of the wave models
+const ModelFillValue = 9.999e20
+
+// Returned when no grid cell with data is found for a location, such as one far inland of the wave models
+var ErrNoValidModelCell = errors.New("No model cell with data was found near the location")
+
+// Check if a model value is a fill value or missing. Fill values are compared loosely since they are
+// served in single precision.
+func IsModelFillValue(value float64) bool {
+	return IsMissing(value) || math.Abs(value) >= 0.999*ModelFillValue
+}
+
+// How the grid cell used for a location is found when the cell holding it has no data, as happens to
+// coastal spots that fall on a land cell of the wave models. The rings of cells around the cell of the
+// location are searched outward, and the closest cell with data in the first ring holding one is used.
+type ModelCellSearch struct {
+	// The number of rings of cells around the cell of the location to search
+	MaxRings int
+
+	// The width in degrees of the sector the search is limited to, centered on the beach angle so only
+	// cells offshore of the location are used. A zero width searches in every direction.
+	SectorWidth float64
+
+	// The direction the beach faces in degrees, the same beach angle given to NewSurfForecast
+	BeachAngle float64
+}
+
+// The search used by models without their own, three rings of cells in every direction
+var DefaultModelCellSearch = ModelCellSearch{MaxRings: 3}
+
+// Get the search the model finds the cell of a location with
+func (n NOAAModel) cellSearch() ModelCellSearch {
+	if n.CellSearch == nil {
+		return DefaultModelCellSearch
+	}
+	return *n.CellSearch
+}
+
+// Get the corners of the area holding the given number of rings of cells around the cell of a location,
+// limited to the coverage of the model
+func (n NOAAModel) ringArea(loc Location, rings int) (Location, Location) {
+	// Corners are kept half a cell inside the coverage, which LocationIndices does not include the edges of
+	margin := float64(rings) * n.LocationResolution
+	edge := n.LocationResolution / 2
+	clamp := func(value, minimum, maximum float64) float64 {
+		return math.Max(minimum+edge, math.Min(maximum-edge, value))
+	}
+
+	lat, lon := loc.Latitude, n.modelLongitude(loc)
+	bottom, top := n.BottomLeftLocation.Latitude, n.TopRightLocation.Latitude
+	left, right := n.BottomLeftLocation.Longitude, n.TopRightLocation.Longitude
+	return NewLocationForLatLong(clamp(lat-margin, bottom, top), clamp(lon-margin, left, right)),
+		NewLocationForLatLong(clamp(lat+margin, bottom, top), clamp(lon+margin, left, right))
+}
+
+// Check if a grid cell holds data, which it does unless every one of its values is a fill value
+func (g *ModelGrid) IsValidCell(latIndex, lonIndex int) bool {
+	for variable := range g.Data {
+		for _, value := range g.Series(variable, latIndex, lonIndex) {
+			if !IsModelFillValue(value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Find the grid cell to use for a location, searching outward from the cell holding it when that cell has
+// no data. Returns (-1,-1) if no cell with data is found.
+func (g *ModelGrid) NearestValidCell(loc Location, search ModelCellSearch) (int, int) {
+	centerLat, centerLon := g.LocationIndices(loc)
+	if centerLat < 0 {
+		return -1, -1
+	}
+
+	for ring := 0; ring <= search.MaxRings; ring++ {
+		bestLat, bestLon := -1, -1
+		bestDistance := math.Inf(1)
+		for latIndex := centerLat - ring; latIndex <= centerLat+ring; latIndex++ {
+			for lonIndex := centerLon - ring; lonIndex <= centerLon+ring; lonIndex++ {
+				// Only the cells on the edge of the ring are new to it
+				onRing := latIndex == centerLat-ring || latIndex == centerLat+ring || lonIndex == centerLon-ring || lonIndex == centerLon+ring
+				if !onRing || latIndex < 0 || lonIndex < 0 || latIndex >= len(g.Latitudes) || lonIndex >= len(g.Longitudes) {
+					continue
+				}
+				if !g.IsValidCell(latIndex, lonIndex) {
+					continue
+				}
+
+				cell := g.Location(latIndex, lonIndex)
+				if ring > 0 && search.SectorWidth > 0 && math.Abs(angleDifference(loc.InitialBearingTo(cell), search.BeachAngle)) > search.SectorWidth/2 {
+					continue
+				}
+				if distance := loc.DistanceTo(cell); distance < bestDistance {
+					bestLat, bestLon, bestDistance = latIndex, lonIndex, distance
+				}
+			}
+		}
+
+		if bestLat >= 0 {
+			return bestLat, bestLon
+		}
+	}
+	return -1, -1
+}
+
+// Get the time series of every variable at the grid cell found for a location by a search, as a
+// ModelData container located at the cell that was used
+func (g *ModelGrid) NearestModelData(loc Location, search ModelCellSearch) (*ModelData, error) {
+	latIndex, lonIndex := g.NearestValidCell(loc, search)
+	if latIndex < 0 {
+		return nil, ErrNoValidModelCell
+	}
+	return g.modelDataAt(loc, latIndex, lonIndex), nil
+}
@@ -0,0 +1,81 @@
+package surfnerd
+
+import (
+	"testing"
+	"time"
+)
+
+// Build a grid of wave heights around a location, with the cells that land reports for filled
+func testLandGrid(land func(latIndex, lonIndex int) bool) *ModelGrid {
+	model := NewEastCoastWaveModel().NOAAModel
+	latIndex, lonIndex := model.LocationIndices(NewLocationForLatLong(41.0, 360-71.0))
+	grid := &ModelGrid{
+		Model:      model,
+		Latitudes:  make([]float64, 7),
+		Longitudes: make([]float64, 7),
+		Times:      []time.Time{time.Date(2016, 10, 18, 6, 0, 0, 0, time.UTC)},
+		Data:       ModelDataMap{"htsgwsfc": make([]float64, 49)},
+	}
+	for i := range grid.Latitudes {
+		grid.Latitudes[i] = model.BottomLeftLocation.Latitude + float64(latIndex-3+i)*model.LocationResolution
+		grid.Longitudes[i] = model.BottomLeftLocation.Longitude + float64(lonIndex-3+i)*model.LocationResolution
+	}
+	for lat := 0; lat < 7; lat++ {
+		for lon := 0; lon < 7; lon++ {
+			value := float64(lat*10 + lon)
+			if land(lat, lon) {
+				value = MissingValue()
+			}
+			grid.Data["htsgwsfc"][grid.offset(lat, lon)] = value
+		}
+	}
+	return grid
+}
+
+func TestNearestValidCell(t *testing.T) {
+	water := testLandGrid(func(latIndex, lonIndex int) bool { return false })
+	loc := water.Location(3, 3)
+	loc.Latitude += water.Model.LocationResolution / 4
+	loc.Longitude += water.Model.LocationResolution / 4
+
+	if latIndex, lonIndex := water.NearestValidCell(loc, DefaultModelCellSearch); latIndex != 3 || lonIndex != 3 {
+		t.Errorf("Expected the cell holding the location, got (%d,%d)", latIndex, lonIndex)
+	}
+
+	// The land is to the north east of the center, so the closest water is a ring away to the south west
+	coast := testLandGrid(func(latIndex, lonIndex int) bool { return latIndex+lonIndex >= 6 })
+	if latIndex, lonIndex := coast.NearestValidCell(loc, DefaultModelCellSearch); latIndex+lonIndex != 5 || latIndex < 2 || lonIndex < 2 {
+		t.Errorf("Expected a water cell in the first ring, got (%d,%d)", latIndex, lonIndex)
+	}
+
+	// Facing north east, only the land offshore is in the sector
+	offshore := ModelCellSearch{MaxRings: 3, SectorWidth: 90, BeachAngle: 45}
+	if latIndex, _ := coast.NearestValidCell(loc, offshore); latIndex >= 0 {
+		t.Errorf("Expected no offshore water cell, got latitude index %d", latIndex)
+	}
+
+	modelData, dataErr := coast.NearestModelData(loc, DefaultModelCellSearch)
+	if dataErr != nil {
+		t.Fatal(dataErr)
+	}
+	if modelData.Location.Latitude+modelData.Location.Longitude >= loc.Latitude+loc.Longitude {
+		t.Errorf("Expected the data to be located at the water cell, got %v", modelData.Location)
+	}
+	if IsMissing(modelData.Data["htsgwsfc"][0]) {
+		t.Error("Expected a wave height at the water cell")
+	}
+
+	inland := testLandGrid(func(latIndex, lonIndex int) bool { return true })
+	if _, inlandErr := inland.NearestModelData(loc, DefaultModelCellSearch); inlandErr != ErrNoValidModelCell {
+		t.Errorf("Expected ErrNoValidModelCell, got %v", inlandErr)
+	}
+}
+
+func TestIsModelFillValue(t *testing.T) {
+	if !IsModelFillValue(float64(float32(ModelFillValue))) || !IsModelFillValue(MissingValue()) {
+		t.Error("Expected the single precision fill value and NaN to be fill values")
+	}
+	if IsModelFillValue(1.5) {
+		t.Error("Expected a wave height not to be a fill value")
+	}
+}
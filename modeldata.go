@@ -1,10 +1,31 @@
 package surfnerd
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/json"
+	"errors"
+	"io"
 	"io/ioutil"
+	"math"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
+)
+
+// Returned when a model response holds no data, such as the error page of a run that is not published
+var ErrEmptyModelData = errors.New("No model data was found in the response")
+
+// Returned when the dataset or time axis of model data does not match the model run it was requested from
+var ErrModelRunMismatch = errors.New("Model data is not from the requested model run")
+
+// The dataset url of a model run, such as .../mww3/20161018/multi_1.at_10m20161018_06z.ascii, and the
+// time range requested from it
+var (
+	modelRunURLPattern         = regexp.MustCompile(`/[a-z]*(\d{8})/[^/]*_(\d{2})z\.(?:ascii|dods)`)
+	modelTimeConstraintPattern = regexp.MustCompile(`[?,]time\[(\d+):[\d:]+\]`)
 )
 
 // A generic map useful for encapsulating model data from NOAA GRADS servers. This holds the data in a map so
@@ -17,6 +38,23 @@ type ModelData struct {
 	Location
 	Model NOAAModel
 	Data  ModelDataMap
+
+	// The values skipped while leniently parsing the raw data
+	ParseWarnings []*ParseError `json:",omitempty"`
+}
+
+// Get the UTC times of the time axis of the data, or nil if the data has no time axis
+func (m *ModelData) ValidTimes() []time.Time {
+	times := m.Data["time"]
+	if len(times) == 0 {
+		return nil
+	}
+
+	validTimes := make([]time.Time, len(times))
+	for i, value := range times {
+		validTimes[i] = GrADSTime(value)
+	}
+	return validTimes
 }
 
 // Export a ModelData object to a json formatted string
@@ -35,38 +73,306 @@ func (m *ModelData) ExportAsJSON(filename string) error {
 	return fileErr
 }
 
-func parseRawModelData(data []byte) ModelDataMap {
+// The longest line of a GrADS ascii response. Rows hold one value for every longitude requested, so
+// this fits global grids at the finest resolution the models are published at.
+const maxModelLineLength = 4 * 1024 * 1024
+
+// Parse the GrADS ascii response of a NOMADS dods server into a ModelDataMap. In lenient mode values that
+// can not be read are left missing and returned as warnings, in strict mode the first one fails the parse.
+func ParseModelData(r io.Reader, mode ParseMode) (ModelDataMap, []*ParseError, error) {
+	return parseModelData(r, "model data", mode)
+}
+
+// Stream the GrADS ascii response of a NOMADS dods server, passing each value to fn as it is read along
+// with the name of its variable and its index in each of the variable's dimensions. The coordinate
+// variables such as time, lat and lon are passed like any other, but only the first time they appear
+// when the response repeats them after each grid. An error returned by fn stops the parse and is returned.
+func ForEachModelValue(r io.Reader, mode ParseMode, fn func(variable string, index []int, value float64) error) ([]*ParseError, error) {
+	return readModelData(r, "model data", mode, fn)
+}
+
+func parseRawModelData(data []byte, source string, mode ParseMode) (ModelDataMap, []*ParseError, error) {
 	if data == nil {
-		return nil
+		return nil, nil, nil
 	}
 
-	// Get the data into a better status
-	allData := string(data)
-	splitData := strings.Split(allData, "\n")
+	return parseModelData(bytes.NewReader(data), source, mode)
+}
 
-	// Create the model data object to parse into
+func parseModelData(r io.Reader, source string, mode ParseMode) (ModelDataMap, []*ParseError, error) {
 	modelData := ModelDataMap{}
+	warnings, parseErr := readModelData(r, source, mode, func(variable string, index []int, value float64) error {
+		modelData[variable] = append(modelData[variable], value)
+		return nil
+	})
+	if parseErr != nil {
+		return nil, nil, parseErr
+	}
+
+	return modelData, warnings, nil
+}
+
+// Read a GrADS ascii response line by line. Each variable starts with a line holding its name and
+// dimensions, followed by rows prefixed with their index in every dimension but the last, like
+// [2][0], 1.5, 1.75, or by a single unprefixed row of values for one dimensional variables.
+func readModelData(r io.Reader, source string, mode ParseMode, fn func(variable string, index []int, value float64) error) ([]*ParseError, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxModelLineLength)
+
+	parser := newValueParser(source, mode)
+	seenVars := map[string]bool{}
 	currentVar := ""
+	skipVar := false
+	axisIndex := 0
+	rowLength := 0
+	lineNumber := 0
 
-	for _, value := range splitData {
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+
+		var prefix []int
+		var values []string
 		switch {
-		case len(value) < 1:
+		case len(line) < 1:
 			continue
-		case value[0] == '[':
-			datas := strings.Split(value, ",")
-			f, _ := strconv.ParseFloat(strings.TrimSpace(datas[1]), 64)
-			modelData[currentVar] = append(modelData[currentVar], f)
-		case value[0] >= '0' && value[0] <= '9':
-			timestamps := strings.Split(value, ",")
-			for _, timestamp := range timestamps {
-				timeValue, _ := strconv.ParseFloat(strings.TrimSpace(timestamp), 64)
-				modelData["time"] = append(modelData["time"], timeValue)
+		case line[0] == '[':
+			closing := strings.LastIndex(line, "]")
+			for _, token := range strings.Split(strings.Trim(line[:closing+1], "[]"), "][") {
+				position, positionErr := strconv.Atoi(token)
+				if positionErr != nil {
+					parser.fail(lineNumber, 1, currentVar, line[:closing+1], positionErr)
+				}
+				prefix = append(prefix, position)
+			}
+
+			// A short row is filled out with missing values so the rows after it keep their positions
+			values = strings.Split(line[closing+1:], ",")[1:]
+			if len(values) < 1 || len(values) < rowLength {
+				parser.fail(lineNumber, len(values)+2, currentVar, line, ErrTruncatedRow)
 			}
+		case isModelValueStart(line[0]):
+			values = strings.Split(line, ",")
 		default:
-			variables := strings.Split(value, ",")
-			currentVar = variables[0]
+			currentVar = strings.TrimSpace(strings.Split(line, ",")[0])
+			skipVar = seenVars[currentVar]
+			seenVars[currentVar] = true
+			axisIndex = 0
+			rowLength = modelRowLength(line)
+			continue
+		}
+
+		if failErr := parser.failed(); failErr != nil {
+			return nil, failErr
+		} else if skipVar {
+			continue
+		}
+
+		count := len(values)
+		if prefix != nil && count < rowLength {
+			count = rowLength
+		} else if prefix != nil && count < 1 {
+			count = 1
+		}
+
+		for i := 0; i < count; i++ {
+			column := i + 1
+			if prefix != nil {
+				column++
+			}
+
+			value := MissingValue()
+			if i < len(values) {
+				value = parser.float(strings.TrimSpace(values[i]), lineNumber, column, currentVar)
+				if failErr := parser.failed(); failErr != nil {
+					return nil, failErr
+				}
+			}
+
+			var index []int
+			if prefix != nil {
+				index = append(append(make([]int, 0, len(prefix)+1), prefix...), i)
+			} else {
+				index = []int{axisIndex}
+				axisIndex++
+			}
+
+			if callbackErr := fn(currentVar, index, value); callbackErr != nil {
+				return nil, callbackErr
+			}
+		}
+	}
+
+	if scanErr := scanner.Err(); scanErr != nil {
+		return nil, scanErr
+	}
+
+	return parser.warnings, nil
+}
+
+// Get the number of values in each row of a variable from the last dimension of its header line, such as
+// 2 for htsgwsfc, [61][1][2]. Returns 0 if the header has no dimensions.
+func modelRowLength(header string) int {
+	opening, closing := strings.LastIndex(header, "["), strings.LastIndex(header, "]")
+	if opening < 0 || closing < opening {
+		return 0
+	}
+
+	length, lengthErr := strconv.Atoi(header[opening+1 : closing])
+	if lengthErr != nil {
+		return 0
+	}
+	return length
+}
+
+// Check if a line starts with a value rather than a variable name. Coordinates such as the latitudes
+// of the southern hemisphere are negative.
+func isModelValueStart(c byte) bool {
+	return (c >= '0' && c <= '9') || c == '-' || c == '+' || c == '.'
+}
+
+// Parse raw GrADS ascii data into a ModelData container for the given location and model
+func modelDataFromRaw(loc Location, model NOAAModel, rawData []byte, source string) (*ModelData, error) {
+	modelDataContainer, warnings, parseErr := parseRawModelData(rawData, source, model.ParseMode)
+	if parseErr != nil {
+		return nil, parseErr
+	} else if len(modelDataContainer) == 0 {
+		return nil, ErrEmptyModelData
+	}
+
+	modelData := &ModelData{
+		Location:      loc,
+		Model:         model,
+		Data:          modelDataContainer,
+		ParseWarnings: warnings,
+	}
+	if runErr := modelData.resolveModelRun(source); runErr != nil {
+		return nil, runErr
+	}
+	return modelData, nil
+}
+
+// Collect GRIB2 fields, such as those of the forecast hours of a run downloaded from a NOMADS filter, into a
+// ModelData container holding the time series of every field at the grid cell of a location. The time,
+// lat and lon axes are filled in like those of the GrADS servers, and times a field is not given for are
+// left as MissingValue(). The fields must all be from the same run.
+func ModelDataFromGRIB2(loc Location, model NOAAModel, fields []*GRIB2Field) (*ModelData, error) {
+	if len(fields) == 0 {
+		return nil, ErrEmptyModelData
+	}
+
+	runTime := fields[0].RunTime
+	if !model.RunTime.IsZero() && !model.RunTime.Equal(runTime) {
+		return nil, ErrModelRunMismatch
+	}
+
+	timeIndices := map[time.Time]int{}
+	validTimes := []time.Time{}
+	for _, field := range fields {
+		if !field.RunTime.Equal(runTime) {
+			return nil, ErrModelRunMismatch
+		}
+		if _, seen := timeIndices[field.ValidTime]; !seen {
+			timeIndices[field.ValidTime] = 0
+			validTimes = append(validTimes, field.ValidTime)
+		}
+	}
+	sort.Slice(validTimes, func(i, j int) bool { return validTimes[i].Before(validTimes[j]) })
+
+	data := ModelDataMap{"time": make([]float64, len(validTimes))}
+	for i, validTime := range validTimes {
+		timeIndices[validTime] = i
+		data["time"][i] = GrADSDays(validTime)
+	}
+
+	for _, field := range fields {
+		latIndex, lonIndex := field.Grid.LocationIndices(loc)
+		if latIndex < 0 {
+			return nil, errors.New("The location is not on the grid of the GRIB2 data")
 		}
+		if _, exists := data["lat"]; !exists {
+			cell := field.Grid.Location(latIndex, lonIndex)
+			data["lat"] = []float64{cell.Latitude}
+			data["lon"] = []float64{cell.Longitude}
+		}
+
+		series, exists := data[field.Name]
+		if !exists {
+			series = make([]float64, len(validTimes))
+			for i := range series {
+				series[i] = MissingValue()
+			}
+			data[field.Name] = series
+		}
+		series[timeIndices[field.ValidTime]] = field.ValueAt(latIndex, lonIndex)
+	}
+
+	model.RunTime = runTime
+	model.ModelRun = FormatViewingTime(runTime)
+	return &ModelData{
+		Location: loc,
+		Model:    model,
+		Data:     data,
+	}, nil
+}
+
+// Set the run time of the model from the dataset url the data was fetched from and its time axis,
+// checking that both agree with the run that was requested. Data without a dataset url or a
+// requested run, such as data read from disk, is assumed to start at its run as it does when
+// fetched from the first time step.
+func (m *ModelData) resolveModelRun(source string) error {
+	return m.Model.resolveRun(m.Data["time"], source)
+}
+
+// Set the run time of the model from a dataset url and the GrADS time axis of the data fetched from it
+func (n *NOAAModel) resolveRun(timeAxis []float64, source string) error {
+	if match := modelRunURLPattern.FindStringSubmatch(source); match != nil {
+		datasetRun, runErr := time.Parse("2006010215", match[1]+match[2])
+		if runErr != nil {
+			return runErr
+		}
+		if !n.RunTime.IsZero() && !n.RunTime.Equal(datasetRun) {
+			return ErrModelRunMismatch
+		}
+		n.RunTime = datasetRun
 	}
 
-	return modelData
+	if len(timeAxis) > 0 {
+		startIndex := 0
+		if match := modelTimeConstraintPattern.FindStringSubmatch(source); match != nil {
+			startIndex, _ = strconv.Atoi(match[1])
+		}
+
+		firstTime := GrADSTime(timeAxis[0])
+		if n.RunTime.IsZero() {
+			n.RunTime = firstTime.Add(-time.Duration(startIndex) * n.timeStep())
+		}
+
+		// The time step is allowed a minute of slack for the rounding of the axis values
+		offset := firstTime.Sub(n.ValidTime(startIndex))
+		if offset < -time.Minute || offset > time.Minute {
+			return ErrModelRunMismatch
+		}
+	}
+
+	if !n.RunTime.IsZero() {
+		n.ModelRun = FormatViewingTime(n.RunTime)
+	}
+	return nil
+}
+
+// Convert a value of the GrADS time axis to a UTC time. GrADS counts days since 0001-01-01 in the
+// Julian calendar, which is two days behind the proleptic Gregorian calendar of the time package.
+func GrADSTime(days float64) time.Time {
+	const julianOffsetDays = 2
+	epoch := time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC)
+	seconds := math.Round((days - julianOffsetDays) * 24 * 60 * 60)
+	return time.Unix(epoch.Unix()+int64(seconds), 0).UTC()
+}
+
+// Convert a time to a value of the GrADS time axis, the inverse of GrADSTime
+func GrADSDays(t time.Time) float64 {
+	const julianOffsetDays = 2
+	epoch := time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC)
+	return float64(t.Unix()-epoch.Unix())/(24*60*60) + julianOffsetDays
 }
@@ -0,0 +1,146 @@
+package surfnerd
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const testModelGridData = `htsgwsfc, [2][2][2]
+[0][0], 1.25, 1.5
+[0][1], 1.75, 2.0
+[1][0], -0.5, 2.25
+[1][1], 2.5, 9.999E20
+time, [2]
+736000.0, 736000.125
+lat, [2]
+-40.5, -40.0
+lon, [2]
+288.0, 288.5
+
+perpwsfc, [2][2][2]
+[0][0], 8.0, 9.0
+[0][1], 10.0, 11.0
+[1][0], 12.0, 13.0
+[1][1], 14.0, 15.0
+time, [2]
+736000.0, 736000.125
+lat, [2]
+-40.5, -40.0
+lon, [2]
+288.0, 288.5
+`
+
+func TestParseModelDataAxes(t *testing.T) {
+	data, warnings, parseErr := ParseModelData(strings.NewReader(testModelGridData), StrictParsing)
+	if parseErr != nil {
+		t.Fatal(parseErr)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("Expected no warnings, got %v", warnings)
+	}
+
+	if len(data["htsgwsfc"]) != 8 || data["htsgwsfc"][4] != -0.5 {
+		t.Errorf("Unexpected wave heights: %v", data["htsgwsfc"])
+	}
+	if len(data["time"]) != 2 {
+		t.Errorf("Expected the repeated time axis to be read once, got %v", data["time"])
+	}
+	if len(data["lat"]) != 2 || data["lat"][0] != -40.5 {
+		t.Errorf("Expected negative latitudes to be read into lat, got %v", data["lat"])
+	}
+	if len(data["lon"]) != 2 || data["lon"][1] != 288.5 {
+		t.Errorf("Unexpected longitudes: %v", data["lon"])
+	}
+}
+
+func TestForEachModelValueIndices(t *testing.T) {
+	indices := map[string][][]int{}
+	_, parseErr := ForEachModelValue(strings.NewReader(testModelGridData), StrictParsing, func(variable string, index []int, value float64) error {
+		indices[variable] = append(indices[variable], index)
+		return nil
+	})
+	if parseErr != nil {
+		t.Fatal(parseErr)
+	}
+
+	last := indices["perpwsfc"][len(indices["perpwsfc"])-1]
+	if len(last) != 3 || last[0] != 1 || last[1] != 1 || last[2] != 1 {
+		t.Errorf("Expected the last value to be at [1][1][1], got %v", last)
+	}
+	if len(indices["lon"]) != 2 || indices["lon"][1][0] != 1 {
+		t.Errorf("Unexpected longitude indices: %v", indices["lon"])
+	}
+}
+
+func TestLenientModelDataEmptyRow(t *testing.T) {
+	rawData := "htsgwsfc, [3][1][2]\n[0][0], 1.25, 1.5\n[1][0]\n[2][0], 1.75, 2.0\n"
+	data, warnings, parseErr := ParseModelData(strings.NewReader(rawData), LenientParsing)
+	if parseErr != nil {
+		t.Fatal(parseErr)
+	}
+	if len(warnings) != 1 || warnings[0].Err != ErrTruncatedRow || warnings[0].Line != 3 {
+		t.Fatalf("Expected a truncated row warning, got %v", warnings)
+	}
+
+	// The empty row is filled with missing values so the last time step keeps its position
+	heights := data["htsgwsfc"]
+	if len(heights) != 6 || !IsMissing(heights[2]) || !IsMissing(heights[3]) || heights[4] != 1.75 || heights[5] != 2.0 {
+		t.Errorf("Unexpected wave heights: %v", heights)
+	}
+}
+
+const testModelRunData = `htsgwsfc, [2][1][1]
+[0][0], 1.25
+[1][0], 1.5
+time, [2]
+736256.25, 736256.375
+`
+
+func TestGrADSTime(t *testing.T) {
+	expected := time.Date(2016, 10, 18, 6, 0, 0, 0, time.UTC)
+	if converted := GrADSTime(736256.25); !converted.Equal(expected) {
+		t.Errorf("Expected %v, got %v", expected, converted)
+	}
+}
+
+func TestModelDataRunFromTimeAxis(t *testing.T) {
+	model := NewEastCoastWaveModel().NOAAModel
+	modelData := WaveModelDataFromRaw(Location{}, model, []byte(testModelRunData))
+	if modelData == nil {
+		t.Fatal("Expected the data to be parsed")
+	}
+
+	expectedRun := time.Date(2016, 10, 18, 6, 0, 0, 0, time.UTC)
+	if !modelData.Model.RunTime.Equal(expectedRun) {
+		t.Errorf("Expected the run to be taken from the time axis, got %v", modelData.Model.RunTime)
+	}
+
+	validTimes := modelData.ValidTimes()
+	if len(validTimes) != 2 || !validTimes[1].Equal(modelData.Model.ValidTime(1)) {
+		t.Errorf("Expected the time axis to line up with the run, got %v", validTimes)
+	}
+}
+
+func TestModelDataRunMismatch(t *testing.T) {
+	model := NewEastCoastWaveModel().NOAAModel
+	model.RunTime = time.Date(2016, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	// The dataset is the 06z run, not the 12z run that was requested
+	source := "http://nomads/dods/wave/mww3/20161018/multi_1.at_10m20161018_06z.ascii?time[0:1]"
+	if _, runErr := modelDataFromRaw(Location{}, model, []byte(testModelRunData), source); runErr != ErrModelRunMismatch {
+		t.Errorf("Expected a run mismatch for the dataset, got %v", runErr)
+	}
+
+	// The dataset is right, but the time axis starts two steps later than requested
+	source = "http://nomads/dods/wave/mww3/20161018/multi_1.at_10m20161018_12z.ascii?time[0:1]"
+	if _, runErr := modelDataFromRaw(Location{}, model, []byte(testModelRunData), source); runErr != ErrModelRunMismatch {
+		t.Errorf("Expected a run mismatch for the time axis, got %v", runErr)
+	}
+
+	model.RunTime = time.Date(2016, 10, 18, 0, 0, 0, 0, time.UTC)
+	source = "http://nomads/dods/wave/mww3/20161018/multi_1.at_10m20161018_00z.ascii?time[2:3]"
+	if _, runErr := modelDataFromRaw(Location{}, model, []byte(testModelRunData), source); runErr != nil {
+		t.Errorf("Expected the time axis to match the requested steps, got %v", runErr)
+	}
+}
@@ -0,0 +1,293 @@
+package surfnerd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// Returned when the corners of an area requested from a model are not inside its coverage, or the area
+// wraps past the longitude seam of the model grid
+var ErrAreaOutsideModel = errors.New("The area is not inside the coverage of the model")
+
+// A range of the time steps of a model run, from the first to the last index inclusive
+type ModelTimeRange struct {
+	StartIndex int
+	EndIndex   int
+}
+
+// Get the range of time steps of a model run covering the given number of steps from a start time.
+// Start times before the run start at its first time step.
+func (n NOAAModel) TimeRange(run time.Time, startTime time.Time, timeSteps int) ModelTimeRange {
+	startIndex := n.TimeIndex(run, startTime)
+	if startIndex < 0 {
+		startIndex = 0
+	}
+	if timeSteps < 1 {
+		timeSteps = 1
+	}
+	return ModelTimeRange{StartIndex: startIndex, EndIndex: startIndex + timeSteps - 1}
+}
+
+// Model output over an area of a model's coverage. Every variable is held as a grid indexed by latitude,
+// longitude and time, with the coordinates of each index given by the Latitudes, Longitudes and Times axes.
+type ModelGrid struct {
+	Model      NOAAModel
+	Latitudes  []float64
+	Longitudes []float64
+
+	// The UTC times the time steps of the grid are valid for
+	Times []time.Time
+
+	// The values of each variable, ordered by latitude, then longitude, then time
+	Data ModelDataMap
+}
+
+// Get the value of a variable at the given latitude, longitude and time indices of the grid. Unknown
+// variables and indices outside of the grid are missing.
+func (g *ModelGrid) Value(variable string, latIndex, lonIndex, timeIndex int) float64 {
+	values, ok := g.Data[variable]
+	if !ok || !g.containsIndices(latIndex, lonIndex) || timeIndex < 0 || timeIndex >= len(g.Times) {
+		return MissingValue()
+	}
+
+	offset := g.offset(latIndex, lonIndex) + timeIndex
+	if offset >= len(values) {
+		return MissingValue()
+	}
+	return values[offset]
+}
+
+// Get the time series of a variable at the given latitude and longitude indices of the grid, or nil
+// for unknown variables and indices outside of the grid
+func (g *ModelGrid) Series(variable string, latIndex, lonIndex int) []float64 {
+	values, ok := g.Data[variable]
+	if !ok || !g.containsIndices(latIndex, lonIndex) {
+		return nil
+	}
+
+	start := g.offset(latIndex, lonIndex)
+	if start+len(g.Times) > len(values) {
+		return nil
+	}
+	return values[start : start+len(g.Times)]
+}
+
+// Check if the given latitude and longitude indices are inside the grid
+func (g *ModelGrid) containsIndices(latIndex, lonIndex int) bool {
+	return latIndex >= 0 && lonIndex >= 0 && latIndex < len(g.Latitudes) && lonIndex < len(g.Longitudes)
+}
+
+// The offset of the time series of a grid cell in the values of a variable
+func (g *ModelGrid) offset(latIndex, lonIndex int) int {
+	return (latIndex*len(g.Longitudes) + lonIndex) * len(g.Times)
+}
+
+// Get the indices of the grid cell holding a location from the coordinates of the grid, truncating to
+// the south west corner of the cell like NOAAModel.LocationIndices. Returns (-1,-1) if the location is
+// not inside the grid.
+func (g *ModelGrid) LocationIndices(loc Location) (int, int) {
+	latIndex := axisCellIndex(g.Latitudes, loc.Latitude, g.Model.LocationResolution)
+	lonIndex := axisCellIndex(g.Longitudes, g.Model.modelLongitude(loc), g.Model.LocationResolution)
+	if latIndex < 0 || lonIndex < 0 {
+		return -1, -1
+	}
+	return latIndex, lonIndex
+}
+
+// Get the location of the grid point at the given latitude and longitude indices of the grid
+func (g *ModelGrid) Location(latIndex, lonIndex int) Location {
+	return NewLocationForLatLong(g.Latitudes[latIndex], g.Longitudes[lonIndex])
+}
+
+// Get the time series of every variable at the grid cell of a location as a ModelData container, in the
+// same form as the data fetched for a single location. The data is located at the grid point of the cell.
+func (g *ModelGrid) ModelData(loc Location) (*ModelData, error) {
+	latIndex, lonIndex := g.LocationIndices(loc)
+	if latIndex < 0 {
+		return nil, errors.New("The location is not inside the model grid")
+	}
+	return g.modelDataAt(loc, latIndex, lonIndex), nil
+}
+
+// Collect the time series of a grid cell used for a location. The name and elevation of the location are
+// kept with the coordinates of the cell.
+func (g *ModelGrid) modelDataAt(loc Location, latIndex, lonIndex int) *ModelData {
+	cell := g.Location(latIndex, lonIndex)
+	cell.Elevation = loc.Elevation
+	cell.LocationName = loc.LocationName
+
+	data := ModelDataMap{
+		"time": make([]float64, len(g.Times)),
+		"lat":  {g.Latitudes[latIndex]},
+		"lon":  {g.Longitudes[lonIndex]},
+	}
+	for i, validTime := range g.Times {
+		data["time"][i] = GrADSDays(validTime)
+	}
+	for variable := range g.Data {
+		data[variable] = append([]float64(nil), g.Series(variable, latIndex, lonIndex)...)
+	}
+
+	return &ModelData{
+		Location: cell,
+		Model:    g.Model,
+		Data:     data,
+	}
+}
+
+// Fetch the grids of model variables over the area between two corners from the binary DAP response of a
+// model run. The lat and lon axes of the run are fetched first so the area is found from the coordinates
+// of the server rather than the rounded resolution of the model. The time axis is requested along with
+// the variables so the run can be checked.
+func fetchModelGridFromDAP(ctx context.Context, fetcher Fetcher, model NOAAModel, run time.Time, datasetURL string, variables []string, bottomLeft, topRight Location, timeRange ModelTimeRange) (*ModelGrid, error) {
+	client := NewDAPClient(fetcher)
+	axes, axesErr := client.FetchArrays(ctx, datasetURL, DAPConstraint{Name: "lat"}, DAPConstraint{Name: "lon"})
+	if axesErr != nil {
+		return nil, axesErr
+	}
+
+	var latAxis, lonAxis []float64
+	for _, axis := range axes {
+		switch axis.Name {
+		case "lat":
+			latAxis = axis.Values
+		case "lon":
+			lonAxis = axis.Values
+		}
+	}
+	if len(latAxis) == 0 || len(lonAxis) == 0 {
+		return nil, fmt.Errorf("%w: no lat and lon axes in %s", ErrMalformedDAPResponse, datasetURL)
+	}
+
+	bottomIndex := axisCellIndex(latAxis, bottomLeft.Latitude, model.LocationResolution)
+	topIndex := axisCellIndex(latAxis, topRight.Latitude, model.LocationResolution)
+	leftIndex := axisCellIndex(lonAxis, model.modelLongitude(bottomLeft), model.LocationResolution)
+	rightIndex := axisCellIndex(lonAxis, model.modelLongitude(topRight), model.LocationResolution)
+	if bottomIndex < 0 || topIndex < 0 || leftIndex < 0 || rightIndex < 0 {
+		return nil, ErrAreaOutsideModel
+	}
+	if topIndex < bottomIndex {
+		bottomIndex, topIndex = topIndex, bottomIndex
+	}
+
+	// An area whose right edge is west of its left edge wraps past the seam of the grid, which can not be
+	// requested as a single hyperslab. Swapping the edges would fetch everything outside of the area.
+	if rightIndex < leftIndex {
+		return nil, fmt.Errorf("%w: the area crosses the %v longitude seam of the grid", ErrAreaOutsideModel, lonAxis[0])
+	}
+
+	timeSlice := NewDAPSlice(timeRange.StartIndex, timeRange.EndIndex)
+	constraints := []DAPConstraint{{Name: "time", Slices: []DAPSlice{timeSlice}}}
+	for _, variable := range variables {
+		constraints = append(constraints, DAPConstraint{
+			Name:   variable + "." + variable,
+			Slices: []DAPSlice{timeSlice, NewDAPSlice(bottomIndex, topIndex), NewDAPSlice(leftIndex, rightIndex)},
+		})
+	}
+
+	arrays, fetchErr := client.FetchArrays(ctx, datasetURL, constraints...)
+	if fetchErr != nil {
+		return nil, fetchErr
+	}
+
+	grid := &ModelGrid{
+		Model:      model,
+		Latitudes:  append([]float64(nil), latAxis[bottomIndex:topIndex+1]...),
+		Longitudes: append([]float64(nil), lonAxis[leftIndex:rightIndex+1]...),
+		Data:       ModelDataMap{},
+	}
+	grid.Model.RunTime = run.UTC()
+
+	if readErr := grid.readDAPArrays(arrays, datasetURL+".dods"+dapQuery(constraints)); readErr != nil {
+		return nil, readErr
+	}
+	return grid, nil
+}
+
+// Get the index of the point of an ascending axis at or below a value, so a value is placed in the cell
+// starting at that point. The last point's cell is as wide as the spacing of the axis, or the given
+// resolution for an axis of one point. Returns -1 if the value is not inside the axis.
+func axisCellIndex(axis []float64, value, resolution float64) int {
+	// Coordinates are written with limited precision, so values a hair below a point are still placed at it
+	const tolerance = 1e-6
+
+	if len(axis) == 0 {
+		return -1
+	}
+	spacing := resolution
+	if len(axis) > 1 {
+		spacing = axis[len(axis)-1] - axis[len(axis)-2]
+	}
+	if value < axis[0]-tolerance || value >= axis[len(axis)-1]+spacing {
+		return -1
+	}
+
+	index := sort.SearchFloat64s(axis, value+tolerance) - 1
+	if index < 0 {
+		index = 0
+	}
+	return index
+}
+
+// Get how far a value is from a point of an axis towards the next point, between 0 and 1
+func axisFraction(axis []float64, index int, value float64) float64 {
+	if index+1 >= len(axis) {
+		return 0
+	}
+	fraction := (value - axis[index]) / (axis[index+1] - axis[index])
+	return math.Max(0, math.Min(1, fraction))
+}
+
+// Fill the time axis and variables of a grid from DAP arrays, reordering the [time][lat][lon] arrays of
+// the variables to the latitude, longitude and time order of the grid. Fill values, such as those of land
+// cells, are stored as MissingValue().
+func (g *ModelGrid) readDAPArrays(arrays []*DAPArray, source string) error {
+	var timeAxis []float64
+	for _, array := range arrays {
+		if array.Name == "time" {
+			timeAxis = array.Values
+		}
+	}
+	if len(timeAxis) == 0 {
+		return ErrEmptyModelData
+	}
+	if runErr := g.Model.resolveRun(timeAxis, source); runErr != nil {
+		return runErr
+	}
+
+	g.Times = make([]time.Time, len(timeAxis))
+	for i, value := range timeAxis {
+		g.Times[i] = GrADSTime(value)
+	}
+
+	latCount, lonCount, timeCount := len(g.Latitudes), len(g.Longitudes), len(g.Times)
+	for _, array := range arrays {
+		if array.Name == "time" {
+			continue
+		}
+
+		shape := array.Shape()
+		if len(shape) != 3 || shape[0] != timeCount || shape[1] != latCount || shape[2] != lonCount {
+			return fmt.Errorf("%w: %s has shape %v", ErrMalformedDAPResponse, array.Name, shape)
+		}
+
+		values := make([]float64, len(array.Values))
+		for t := 0; t < timeCount; t++ {
+			for lat := 0; lat < latCount; lat++ {
+				for lon := 0; lon < lonCount; lon++ {
+					value := array.At(t, lat, lon)
+					if array.IsFill(value) || IsModelFillValue(value) {
+						value = MissingValue()
+					}
+					values[g.offset(lat, lon)+t] = value
+				}
+			}
+		}
+		g.Data[array.Name] = values
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package surfnerd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+// Write the lat and lon axes of a model in the binary DAP response of a dods server, with the points spaced
+// by the given resolution rather than the rounded resolution of the model
+func testModelAxesDODS(model NOAAModel, resolution float64) ([]float64, []float64, string) {
+	axis := func(start, end float64) []float64 {
+		values := []float64{}
+		for i := 0; start+float64(i)*resolution <= end+resolution/2; i++ {
+			values = append(values, start+float64(i)*resolution)
+		}
+		return values
+	}
+	latAxis := axis(model.BottomLeftLocation.Latitude, model.TopRightLocation.Latitude)
+	lonAxis := axis(model.BottomLeftLocation.Longitude, model.TopRightLocation.Longitude)
+
+	var dods bytes.Buffer
+	fmt.Fprintf(&dods, "Dataset {\n    Float64 lat[lat = %d];\n    Float64 lon[lon = %d];\n} test;\nData:\n", len(latAxis), len(lonAxis))
+	writeTestXDR(&dods, latAxis, len(latAxis))
+	writeTestXDR(&dods, lonAxis, len(lonAxis))
+	return latAxis, lonAxis, dods.String()
+}
+
+func TestModelGridFromDAP(t *testing.T) {
+	run := time.Date(2016, 10, 18, 6, 0, 0, 0, time.UTC)
+	model := NewEastCoastWaveModel()
+	bottomLeft := NewLocationForLatLong(40.1, -71.4)
+	topRight := NewLocationForLatLong(40.3, -71.1)
+
+	// The multi_1 grids are spaced by 1/6 of a degree, which the model rounds to 0.167
+	latAxis, lonAxis, axesDODS := testModelAxesDODS(model.NOAAModel, 1.0/6.0)
+	bottomIndex := axisCellIndex(latAxis, bottomLeft.Latitude, model.LocationResolution)
+	topIndex := axisCellIndex(latAxis, topRight.Latitude, model.LocationResolution)
+	leftIndex := axisCellIndex(lonAxis, model.modelLongitude(bottomLeft), model.LocationResolution)
+	rightIndex := axisCellIndex(lonAxis, model.modelLongitude(topRight), model.LocationResolution)
+
+	var dods bytes.Buffer
+	fmt.Fprintf(&dods, "Dataset {\n    Float64 time[time = 2];\n    Float32 htsgwsfc[time = 2][lat = %d][lon = %d];\n} test;\nData:\n", topIndex-bottomIndex+1, rightIndex-leftIndex+1)
+	writeTestXDR(&dods, []float64{GrADSDays(run), GrADSDays(run.Add(3 * time.Hour))}, 2)
+	heights := []float32{}
+	for t := 0; t < 2; t++ {
+		for lat := bottomIndex; lat <= topIndex; lat++ {
+			for lon := leftIndex; lon <= rightIndex; lon++ {
+				heights = append(heights, float32(t*100+(lat-bottomIndex)*10+lon-leftIndex))
+			}
+		}
+	}
+	writeTestXDR(&dods, heights, len(heights))
+
+	datasetURL := model.CreateDatasetURL(run)
+	query := fmt.Sprintf("?time[0:1:1],htsgwsfc.htsgwsfc[0:1:1][%d:1:%d][%d:1:%d]", bottomIndex, topIndex, leftIndex, rightIndex)
+	fetcher := mapFetcher{
+		datasetURL + ".das":          "Attributes {\n}\n",
+		datasetURL + ".dods?lat,lon": axesDODS,
+		datasetURL + ".dods" + query: dods.String(),
+	}
+
+	grid, gridErr := fetchModelGridFromDAP(context.Background(), fetcher, model.NOAAModel, run, datasetURL, []string{"htsgwsfc"}, bottomLeft, topRight, ModelTimeRange{StartIndex: 0, EndIndex: 1})
+	if gridErr != nil {
+		t.Fatal(gridErr)
+	}
+
+	if len(grid.Latitudes) != topIndex-bottomIndex+1 || len(grid.Longitudes) != rightIndex-leftIndex+1 || len(grid.Times) != 2 {
+		t.Fatalf("Unexpected grid of %d by %d by %d", len(grid.Latitudes), len(grid.Longitudes), len(grid.Times))
+	}
+	// The axes are the coordinates of the server, where the rounded resolution would place the
+	// southern row at 40.08 rather than 40
+	if grid.Latitudes[0] != latAxis[bottomIndex] || math.Abs(grid.Latitudes[0]-40.0) > 1e-9 || grid.Longitudes[0] != lonAxis[leftIndex] {
+		t.Errorf("Expected the axes of the server, got %v and %v", grid.Latitudes, grid.Longitudes)
+	}
+	if math.Abs(grid.Latitudes[1]-grid.Latitudes[0]-1.0/6.0) > 1e-9 {
+		t.Errorf("Expected the latitudes to step by the grid spacing, got %v", grid.Latitudes)
+	}
+	if !grid.Model.RunTime.Equal(run) || !grid.Times[1].Equal(run.Add(3*time.Hour)) {
+		t.Errorf("Unexpected run %v and times %v", grid.Model.RunTime, grid.Times)
+	}
+
+	if value := grid.Value("htsgwsfc", 1, 2, 1); value != 112 {
+		t.Errorf("Expected 112 at [1][2][1], got %v", value)
+	}
+	if series := grid.Series("htsgwsfc", 0, 1); len(series) != 2 || series[0] != 1 || series[1] != 101 {
+		t.Errorf("Unexpected series %v", series)
+	}
+	if !IsMissing(grid.Value("swell_1", 0, 0, 0)) || !IsMissing(grid.Value("htsgwsfc", 0, len(grid.Longitudes), 0)) || !IsMissing(grid.Value("htsgwsfc", 0, 0, 2)) {
+		t.Error("Expected unknown variables and indices outside of the grid to be missing")
+	}
+	if grid.Series("swell_1", 0, 0) != nil || grid.Series("htsgwsfc", -1, 0) != nil {
+		t.Error("Expected no series for unknown variables and indices outside of the grid")
+	}
+
+	modelData, dataErr := grid.ModelData(topRight)
+	if dataErr != nil {
+		t.Fatal(dataErr)
+	}
+	if heights := modelData.Data["htsgwsfc"]; len(heights) != 2 || heights[1] != float64(100+(topIndex-bottomIndex)*10+rightIndex-leftIndex) {
+		t.Errorf("Unexpected heights at the top right corner %v", heights)
+	}
+	if _, outsideErr := grid.ModelData(NewLocationForLatLong(41, -71.4)); outsideErr == nil {
+		t.Error("Expected a location outside the grid to fail")
+	}
+
+	timeAxis := &DAPArray{Name: "time", Values: []float64{GrADSDays(run)}}
+	flat := &DAPArray{Name: "htsgwsfc", Dimensions: []DAPDimension{{Name: "time", Size: 1}}, Values: []float64{1}}
+	if shapeErr := grid.readDAPArrays([]*DAPArray{timeAxis, flat}, datasetURL); !errors.Is(shapeErr, ErrMalformedDAPResponse) {
+		t.Errorf("Expected an array without the grid's shape to fail with ErrMalformedDAPResponse, got %v", shapeErr)
+	}
+}
+
+func TestModelGridAcrossSeam(t *testing.T) {
+	run := time.Date(2016, 10, 18, 6, 0, 0, 0, time.UTC)
+	model := NewGFSWindModel()
+	_, _, axesDODS := testModelAxesDODS(model.NOAAModel, model.LocationResolution)
+	datasetURL := model.CreateDatasetURL(run)
+	fetcher := mapFetcher{
+		datasetURL + ".das":          "Attributes {\n}\n",
+		datasetURL + ".dods?lat,lon": axesDODS,
+	}
+
+	// The global grid runs from 0 to 359.5, so this box wraps past its seam at 0
+	bottomLeft := NewLocationForLatLong(40, -5)
+	topRight := NewLocationForLatLong(45, 5)
+	_, gridErr := fetchModelGridFromDAP(context.Background(), fetcher, model.NOAAModel, run, datasetURL, []string{"windsfc"}, bottomLeft, topRight, ModelTimeRange{})
+	if !errors.Is(gridErr, ErrAreaOutsideModel) {
+		t.Errorf("Expected an area across the seam to fail with ErrAreaOutsideModel, got %v", gridErr)
+	}
+}
+
+func TestModelTimeRange(t *testing.T) {
+	model := NewEastCoastWaveModel()
+	run := time.Date(2016, 10, 18, 6, 0, 0, 0, time.UTC)
+
+	timeRange := model.TimeRange(run, run.Add(7*time.Hour), 4)
+	if timeRange.StartIndex != 3 || timeRange.EndIndex != 6 {
+		t.Errorf("Unexpected time range %+v", timeRange)
+	}
+	if early := model.TimeRange(run, run.Add(-time.Hour), 0); early.StartIndex != 0 || early.EndIndex != 0 {
+		t.Errorf("Expected a time before the run to start at the first step, got %+v", early)
+	}
+}
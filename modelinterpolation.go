@@ -0,0 +1,210 @@
+package surfnerd
+
+import (
+	"errors"
+	"math"
+)
+
+// How the values of a location are taken from the grid cells around it
+type ModelInterpolation int
+
+const (
+	// The values of a single cell are used, found by the CellSearch of the model
+	NearestCellInterpolation ModelInterpolation = iota
+
+	// The values of the 2x2 cells around the location are weighted by how close the location is to each
+	BilinearInterpolation
+
+	// The values of the 4x4 cells around the location are weighted by their inverse squared distance
+	InverseDistanceInterpolation
+)
+
+// The model variables holding compass directions, which are averaged as vectors so values either side of
+// north do not average to south
+var directionalModelVariables = map[string]bool{
+	"dirpwsfc": true,
+	"swdir_1":  true,
+	"swdir_2":  true,
+	"wdirsfc":  true,
+	"wvdirsfc": true,
+}
+
+// Get the number of rings of cells around the cell of a location an interpolation reads
+func (i ModelInterpolation) rings() int {
+	switch i {
+	case BilinearInterpolation:
+		return 1
+	case InverseDistanceInterpolation:
+		return 2
+	}
+	return 0
+}
+
+// A grid cell and the weight its values are given in an interpolation
+type weightedModelCell struct {
+	latIndex int
+	lonIndex int
+	weight   float64
+}
+
+// Get the time series of every variable interpolated to a location from the cells around it, as a
+// ModelData container located at the location. Cells without data are skipped and the weights of the
+// rest are rescaled, so a value is only missing when every cell around the location is missing it.
+// Returns ErrNoValidModelCell if none of the cells around the location has data.
+func (g *ModelGrid) InterpolatedModelData(loc Location, method ModelInterpolation) (*ModelData, error) {
+	latIndex, lonIndex := g.LocationIndices(loc)
+	if latIndex < 0 {
+		return nil, errors.New("The location is not inside the model grid")
+	}
+	if method == NearestCellInterpolation {
+		return g.modelDataAt(loc, latIndex, lonIndex), nil
+	}
+
+	var cells []weightedModelCell
+	if method == BilinearInterpolation {
+		cells = g.bilinearCells(loc, latIndex, lonIndex)
+	} else {
+		cells = g.inverseDistanceCells(loc, latIndex, lonIndex)
+	}
+
+	valid := false
+	for _, cell := range cells {
+		if g.IsValidCell(cell.latIndex, cell.lonIndex) {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return nil, ErrNoValidModelCell
+	}
+
+	data := ModelDataMap{
+		"time": make([]float64, len(g.Times)),
+		"lat":  {loc.Latitude},
+		"lon":  {g.Model.modelLongitude(loc)},
+	}
+	for i, validTime := range g.Times {
+		data["time"][i] = GrADSDays(validTime)
+	}
+	for variable := range g.Data {
+		values := make([]float64, len(g.Times))
+		for timeIndex := range values {
+			values[timeIndex] = g.interpolateValue(variable, timeIndex, cells)
+		}
+		data[variable] = values
+	}
+
+	return &ModelData{
+		Location: loc,
+		Model:    g.Model,
+		Data:     data,
+	}, nil
+}
+
+// Get the 2x2 cells around a location, weighted by the distance of the location from the opposite
+// corner along each axis
+func (g *ModelGrid) bilinearCells(loc Location, latIndex, lonIndex int) []weightedModelCell {
+	// A location on the last row or column of the grid is interpolated with the row or column before it
+	if latIndex == len(g.Latitudes)-1 && latIndex > 0 {
+		latIndex--
+	}
+	if lonIndex == len(g.Longitudes)-1 && lonIndex > 0 {
+		lonIndex--
+	}
+
+	latFraction := axisFraction(g.Latitudes, latIndex, loc.Latitude)
+	lonFraction := axisFraction(g.Longitudes, lonIndex, g.Model.modelLongitude(loc))
+
+	cells := []weightedModelCell{}
+	for latStep := 0; latStep < 2; latStep++ {
+		for lonStep := 0; lonStep < 2; lonStep++ {
+			if latIndex+latStep >= len(g.Latitudes) || lonIndex+lonStep >= len(g.Longitudes) {
+				continue
+			}
+
+			latWeight, lonWeight := 1-latFraction, 1-lonFraction
+			if latStep == 1 {
+				latWeight = latFraction
+			}
+			if lonStep == 1 {
+				lonWeight = lonFraction
+			}
+			cells = append(cells, weightedModelCell{latIndex + latStep, lonIndex + lonStep, latWeight * lonWeight})
+		}
+	}
+	return cells
+}
+
+// Get the 4x4 cells around a location, weighted by their inverse squared distance from it. A location
+// on a grid point takes the values of that point.
+func (g *ModelGrid) inverseDistanceCells(loc Location, latIndex, lonIndex int) []weightedModelCell {
+	cells := []weightedModelCell{}
+	for lat := latIndex - 1; lat <= latIndex+2; lat++ {
+		for lon := lonIndex - 1; lon <= lonIndex+2; lon++ {
+			if lat < 0 || lon < 0 || lat >= len(g.Latitudes) || lon >= len(g.Longitudes) {
+				continue
+			}
+
+			distance := loc.DistanceTo(g.Location(lat, lon))
+			if distance < 1e-6 {
+				return []weightedModelCell{{lat, lon, 1}}
+			}
+			cells = append(cells, weightedModelCell{lat, lon, 1 / (distance * distance)})
+		}
+	}
+	return cells
+}
+
+// Combine the values of a variable at a time step of the given cells by their weights, skipping missing
+// values. Directions are combined as unit vectors.
+func (g *ModelGrid) interpolateValue(variable string, timeIndex int, cells []weightedModelCell) float64 {
+	directional := directionalModelVariables[variable]
+
+	var sum, sinSum, cosSum, totalWeight float64
+	for _, cell := range cells {
+		value := g.Value(variable, cell.latIndex, cell.lonIndex, timeIndex)
+		if IsModelFillValue(value) || cell.weight <= 0 {
+			continue
+		}
+
+		if directional {
+			radians := value * math.Pi / 180.0
+			sinSum += cell.weight * math.Sin(radians)
+			cosSum += cell.weight * math.Cos(radians)
+		} else {
+			sum += cell.weight * value
+		}
+		totalWeight += cell.weight
+	}
+
+	if totalWeight == 0 {
+		return MissingValue()
+	}
+	if directional {
+		direction := math.Atan2(sinSum, cosSum) * 180.0 / math.Pi
+		return math.Mod(direction+360.0, 360.0)
+	}
+	return sum / totalWeight
+}
+
+// Get the time series of every variable for a location with the interpolation of the grid's model. The
+// cell search of the model is used when the model does not interpolate or no cell around the location
+// has data.
+func (g *ModelGrid) locationModelData(loc Location) (*ModelData, error) {
+	if g.Model.Interpolation != NearestCellInterpolation {
+		modelData, interpolateErr := g.InterpolatedModelData(loc, g.Model.Interpolation)
+		if interpolateErr != ErrNoValidModelCell {
+			return modelData, interpolateErr
+		}
+	}
+	return g.NearestModelData(loc, g.Model.cellSearch())
+}
+
+// Get the number of rings of cells around the cell of a location that are fetched to find its values
+func (n NOAAModel) locationRings() int {
+	rings := n.cellSearch().MaxRings
+	if interpolationRings := n.Interpolation.rings(); interpolationRings > rings {
+		rings = interpolationRings
+	}
+	return rings
+}
@@ -0,0 +1,87 @@
+package surfnerd
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBilinearInterpolation(t *testing.T) {
+	grid := testLandGrid(func(latIndex, lonIndex int) bool { return false })
+	resolution := grid.Model.LocationResolution
+	loc := grid.Location(3, 3)
+	loc.Latitude += resolution / 4
+	loc.Longitude += resolution / 2
+
+	// The heights rise by 10 a row and 1 a column, so the plane is reproduced exactly
+	modelData, dataErr := grid.InterpolatedModelData(loc, BilinearInterpolation)
+	if dataErr != nil {
+		t.Fatal(dataErr)
+	}
+	if height := modelData.Data["htsgwsfc"][0]; math.Abs(height-36) > 1e-6 {
+		t.Errorf("Expected a height of 36, got %v", height)
+	}
+	if modelData.Location.Latitude != loc.Latitude || modelData.Data["lat"][0] != loc.Latitude {
+		t.Errorf("Expected the data to be located at the location, got %v", modelData.Location)
+	}
+
+	// A masked corner is skipped and the others are rescaled
+	masked := testLandGrid(func(latIndex, lonIndex int) bool { return latIndex == 4 && lonIndex == 4 })
+	maskedData, maskedErr := masked.InterpolatedModelData(loc, BilinearInterpolation)
+	if maskedErr != nil {
+		t.Fatal(maskedErr)
+	}
+	if height := maskedData.Data["htsgwsfc"][0]; IsMissing(height) || height < 33 || height > 43 {
+		t.Errorf("Expected a height between the water corners, got %v", height)
+	}
+
+	inland := testLandGrid(func(latIndex, lonIndex int) bool { return true })
+	if _, inlandErr := inland.InterpolatedModelData(loc, BilinearInterpolation); inlandErr != ErrNoValidModelCell {
+		t.Errorf("Expected ErrNoValidModelCell, got %v", inlandErr)
+	}
+}
+
+func TestInverseDistanceInterpolation(t *testing.T) {
+	grid := testLandGrid(func(latIndex, lonIndex int) bool { return false })
+	modelData, dataErr := grid.InterpolatedModelData(grid.Location(2, 5), InverseDistanceInterpolation)
+	if dataErr != nil {
+		t.Fatal(dataErr)
+	}
+	if height := modelData.Data["htsgwsfc"][0]; height != 25 {
+		t.Errorf("Expected the height of the grid point, got %v", height)
+	}
+
+	loc := grid.Location(3, 3)
+	loc.Latitude += grid.Model.LocationResolution / 3
+	loc.Longitude += grid.Model.LocationResolution / 3
+	modelData, dataErr = grid.InterpolatedModelData(loc, InverseDistanceInterpolation)
+	if dataErr != nil {
+		t.Fatal(dataErr)
+	}
+	if height := modelData.Data["htsgwsfc"][0]; height <= 33 || height >= 44 {
+		t.Errorf("Expected a height inside the surrounding cells, got %v", height)
+	}
+}
+
+func TestDirectionalInterpolation(t *testing.T) {
+	grid := testLandGrid(func(latIndex, lonIndex int) bool { return false })
+	grid.Data["dirpwsfc"] = make([]float64, len(grid.Data["htsgwsfc"]))
+	for lat := range grid.Latitudes {
+		for lon := range grid.Longitudes {
+			direction := 350.0
+			if lon%2 == 1 {
+				direction = 10.0
+			}
+			grid.Data["dirpwsfc"][grid.offset(lat, lon)] = direction
+		}
+	}
+
+	loc := grid.Location(3, 3)
+	loc.Longitude += grid.Model.LocationResolution / 2
+	modelData, dataErr := grid.InterpolatedModelData(loc, BilinearInterpolation)
+	if dataErr != nil {
+		t.Fatal(dataErr)
+	}
+	if direction := modelData.Data["dirpwsfc"][0]; math.Abs(angleDifference(direction, 0)) > 1e-6 {
+		t.Errorf("Expected directions either side of north to average to north, got %v", direction)
+	}
+}
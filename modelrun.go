@@ -0,0 +1,87 @@
+package surfnerd
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// The time between the runs of the NOAA models
+const modelRunCycle = 6 * time.Hour
+
+// The number of earlier cycles a new ModelRunResolver tries, a full day of runs
+const defaultModelRunFallbackCycles = 4
+
+// Returned when none of the model runs a ModelRunResolver tried have been published
+var ErrNoModelRun = errors.New("No published model run was found")
+
+// Finds the newest run of a model published on the NOMADS dods server. Runs are published a few hours
+// after their cycle, so the resolver probes the .das description of the dataset for the current cycle
+// and falls back through earlier cycles until it finds one that is available.
+type ModelRunResolver struct {
+	// A run to use as is instead of probing the server, such as one already being processed
+	PinnedRun time.Time
+
+	// The number of cycles before the current one to try when it has not been published
+	MaxFallbackCycles int
+
+	// The Fetcher used to probe the server. A nil Fetcher uses the DefaultFetcher.
+	Fetcher Fetcher
+}
+
+// A Fetcher that pins the model run used with it, such as a FixtureFetcher replaying responses
+// recorded for one run. A zero run leaves the newest published run to be found.
+type ModelRunPinner interface {
+	Fetcher
+	PinnedModelRun() time.Time
+}
+
+// Create a new ModelRunResolver probing with the given Fetcher. A nil Fetcher uses the DefaultFetcher.
+// The run is pinned if the Fetcher is a ModelRunPinner with a run set.
+func NewModelRunResolver(fetcher Fetcher) *ModelRunResolver {
+	resolver := &ModelRunResolver{
+		MaxFallbackCycles: defaultModelRunFallbackCycles,
+		Fetcher:           fetcher,
+	}
+	if pinner, ok := fetcherOrDefault(fetcher).(ModelRunPinner); ok {
+		resolver.PinnedRun = pinner.PinnedModelRun()
+	}
+	return resolver
+}
+
+// Find the newest published run of a wave model
+func (r *ModelRunResolver) ResolveWaveModelRun(ctx context.Context, model *WaveModel) (time.Time, error) {
+	return r.resolve(ctx, model.CreateDatasetURL)
+}
+
+// Find the newest published run of a wind model
+func (r *ModelRunResolver) ResolveWindModelRun(ctx context.Context, model *WindModel) (time.Time, error) {
+	return r.resolve(ctx, model.CreateDatasetURL)
+}
+
+// Probe the dataset of every cycle from the current one back, returning the first that is published. Only
+// a missing dataset, reported as not found or as a DAP error in place of its description, falls back to
+// the cycle before. Any other failure, such as a timeout or a server error, is returned so a stale run is
+// not used in place of a newer one that could not be checked.
+func (r *ModelRunResolver) resolve(ctx context.Context, createDatasetURL func(time.Time) string) (time.Time, error) {
+	if !r.PinnedRun.IsZero() {
+		return r.PinnedRun.UTC(), nil
+	}
+
+	ctx = contextOrBackground(ctx)
+	run := time.Now().UTC().Truncate(modelRunCycle)
+	for cycle := 0; cycle <= r.MaxFallbackCycles; cycle++ {
+		das, probeErr := fetchRawDataFromURL(ctx, r.Fetcher, createDatasetURL(run)+".das")
+		if probeErr == nil && dapServerError(string(das)) == nil {
+			return run, nil
+		} else if ctx.Err() != nil {
+			return time.Time{}, ctx.Err()
+		} else if probeErr != nil && !errors.Is(probeErr, ErrNotFound) {
+			return time.Time{}, probeErr
+		}
+
+		run = run.Add(-modelRunCycle)
+	}
+
+	return time.Time{}, ErrNoModelRun
+}
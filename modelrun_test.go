@@ -0,0 +1,88 @@
+package surfnerd
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestModelRunResolverFallsBack(t *testing.T) {
+	model := NewEastCoastWaveModel()
+	publishedRun := time.Now().UTC().Truncate(6 * time.Hour).Add(-12 * time.Hour)
+	fetcher := mapFetcher{model.CreateDatasetURL(publishedRun) + ".das": "Attributes {\n}\n"}
+
+	run, runErr := NewModelRunResolver(fetcher).ResolveWaveModelRun(context.Background(), model)
+	if runErr != nil {
+		t.Fatal(runErr)
+	}
+	if !run.Equal(publishedRun) {
+		t.Errorf("Expected the run two cycles back at %v, got %v", publishedRun, run)
+	}
+
+	resolver := NewModelRunResolver(fetcher)
+	resolver.MaxFallbackCycles = 1
+	if _, runErr := resolver.ResolveWaveModelRun(context.Background(), model); runErr != ErrNoModelRun {
+		t.Errorf("Expected no run within one cycle, got %v", runErr)
+	}
+}
+
+// A Fetcher that fails every request with the same error
+type failingFetcher struct {
+	err error
+}
+
+func (f failingFetcher) Fetch(ctx context.Context, url string) ([]byte, error) {
+	return nil, f.err
+}
+
+func TestModelRunResolverDAPError(t *testing.T) {
+	model := NewEastCoastWaveModel()
+	currentRun := time.Now().UTC().Truncate(6 * time.Hour)
+	publishedRun := currentRun.Add(-6 * time.Hour)
+	fetcher := mapFetcher{
+		model.CreateDatasetURL(currentRun) + ".das":   "Error {\n    code = 0;\n    message = \"not an available dataset\";\n};\n",
+		model.CreateDatasetURL(publishedRun) + ".das": "Attributes {\n}\n",
+	}
+
+	run, runErr := NewModelRunResolver(fetcher).ResolveWaveModelRun(context.Background(), model)
+	if runErr != nil {
+		t.Fatal(runErr)
+	}
+	if !run.Equal(publishedRun) {
+		t.Errorf("Expected the DAP error to fall back to the run at %v, got %v", publishedRun, run)
+	}
+}
+
+func TestModelRunResolverFetchError(t *testing.T) {
+	// A server error says nothing about whether the run is published, so it must not fall back
+	serverErr := errors.New("Request failed with status 503 Service Unavailable")
+	_, runErr := NewModelRunResolver(failingFetcher{serverErr}).ResolveWaveModelRun(context.Background(), NewEastCoastWaveModel())
+	if runErr != serverErr {
+		t.Errorf("Expected the fetch error to be returned, got %v", runErr)
+	}
+}
+
+func TestModelRunResolverPinnedRun(t *testing.T) {
+	pinned := time.Date(2016, 10, 18, 6, 0, 0, 0, time.UTC)
+	resolver := &ModelRunResolver{PinnedRun: pinned, Fetcher: mapFetcher{}}
+
+	model := NewGFSWindModel()
+	run, runErr := resolver.ResolveWindModelRun(context.Background(), model)
+	if runErr != nil || !run.Equal(pinned) {
+		t.Fatalf("Expected the pinned run, got %v and %v", run, runErr)
+	}
+
+	url := model.CreateURL(NewLocationForLatLong(41.6, 288.5), run, 0, 2)
+	if !strings.Contains(url, "/dods/gfs_0p50/gfs20161018/gfs_0p50_06z.ascii?time[0:2]") {
+		t.Errorf("Unexpected url for the pinned run: %s", url)
+	}
+}
+
+func TestEmptyModelDataIsAnError(t *testing.T) {
+	_, parseErr := modelDataFromRaw(Location{}, NewEastCoastWaveModel().NOAAModel, []byte("<html>Not Found</html>\n"), "test")
+	if parseErr != ErrEmptyModelData {
+		t.Errorf("Expected an empty model data error, got %v", parseErr)
+	}
+}
@@ -0,0 +1,373 @@
+package surfnerd
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Returned when an NDBC text file does not start with a column header line
+var ErrMissingHeader = errors.New("No column header found in NDBC data")
+
+// A whitespace separated NDBC text table. Columns are found by the names in the header line rather
+// than by position, so files with extra, missing or reordered columns can all be read.
+type ndbcTable struct {
+	Fields  []string
+	Rows    []ndbcRow
+	columns map[string]int
+}
+
+// A single data row of an NDBC table, along with its one based line number in the source
+type ndbcRow struct {
+	Line   int
+	Tokens []string
+}
+
+// The column names that may hold each part of a row's date
+var (
+	ndbcYearColumns   = []string{"YYYY", "YY"}
+	ndbcMonthColumns  = []string{"MM"}
+	ndbcDayColumns    = []string{"DD"}
+	ndbcHourColumns   = []string{"hh"}
+	ndbcMinuteColumns = []string{"mm"}
+	ndbcSecondColumns = []string{"ss"}
+)
+
+// The longest line an NDBC table may have. Even the spectral files with a column for every frequency
+// band stay well below it.
+const maxNDBCLineLength = 1024 * 1024
+
+// Reads the rows of an NDBC table one at a time from a stream, so multi year archives can be read
+// without holding the whole file in memory.
+type ndbcTableReader struct {
+	*ndbcTable
+	scanner *bufio.Scanner
+	line    int
+}
+
+// Read an NDBC table from its lines. The first non empty line must be the column header, which may
+// start with a # as it does in the realtime files. A units line starting with #yr and any other
+// comment lines are skipped.
+func newNDBCTable(lines []string) (*ndbcTable, error) {
+	reader, readerErr := newNDBCTableReader(strings.NewReader(strings.Join(lines, "\n")))
+	if readerErr != nil {
+		return nil, readerErr
+	}
+
+	for {
+		row, ok := reader.next()
+		if !ok {
+			break
+		}
+		reader.Rows = append(reader.Rows, row)
+	}
+
+	if readErr := reader.err(); readErr != nil {
+		return nil, readErr
+	}
+	return reader.ndbcTable, nil
+}
+
+// Start reading an NDBC table from a stream, reading up to and including its column header. The
+// rows are then read with next, following the same rules as newNDBCTable.
+func newNDBCTableReader(r io.Reader) (*ndbcTableReader, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxNDBCLineLength)
+	reader := &ndbcTableReader{ndbcTable: &ndbcTable{columns: map[string]int{}}, scanner: scanner}
+
+	for scanner.Scan() {
+		reader.line++
+		tokens := strings.Fields(scanner.Text())
+		if len(tokens) == 0 {
+			continue
+		}
+
+		if !isNDBCHeader(tokens) {
+			return nil, ErrMissingHeader
+		}
+
+		reader.Fields = make([]string, len(tokens))
+		for i, token := range tokens {
+			reader.Fields[i] = strings.TrimLeft(token, "#")
+			if _, exists := reader.columns[reader.Fields[i]]; !exists {
+				reader.columns[reader.Fields[i]] = i
+			}
+		}
+		return reader, nil
+	}
+
+	if scanErr := scanner.Err(); scanErr != nil {
+		return nil, scanErr
+	}
+	return nil, ErrMissingHeader
+}
+
+// Read the next data row of the table. False is returned once the stream ends, after which err
+// reports any error reading it.
+func (t *ndbcTableReader) next() (ndbcRow, bool) {
+	for t.scanner.Scan() {
+		t.line++
+		tokens := strings.Fields(t.scanner.Text())
+		if len(tokens) == 0 || strings.HasPrefix(tokens[0], "#") {
+			continue
+		}
+		return ndbcRow{Line: t.line, Tokens: tokens}, true
+	}
+	return ndbcRow{}, false
+}
+
+// Get the error that stopped the table from being read, or nil if it was read to the end
+func (t *ndbcTableReader) err() error {
+	return t.scanner.Err()
+}
+
+// Stream the rows of an NDBC table, passing each complete row with a readable date to parseRow, which
+// may stop the read by returning an error. Input a negative integer or zero to read every row.
+func readNDBCRows(r io.Reader, source string, mode ParseMode, dataCountLimit int, parseRow func(table *ndbcTable, row ndbcRow, parser *valueParser) error) ([]*ParseError, error) {
+	reader, readerErr := newNDBCTableReader(r)
+	if readerErr != nil {
+		return nil, readerErr
+	}
+
+	parser := newValueParser(source, mode)
+	count := 0
+	for dataCountLimit <= 0 || count < dataCountLimit {
+		row, ok := reader.next()
+		if !ok {
+			break
+		}
+
+		if !reader.complete(row, parser) || reader.date(row, parser).IsZero() {
+			if failErr := parser.failed(); failErr != nil {
+				return nil, failErr
+			}
+			continue
+		}
+
+		rowErr := parseRow(reader.ndbcTable, row, parser)
+		if failErr := parser.failed(); failErr != nil {
+			return nil, failErr
+		} else if rowErr != nil {
+			return nil, rowErr
+		}
+		count++
+	}
+
+	if readErr := reader.err(); readErr != nil {
+		return nil, readErr
+	}
+
+	return parser.warnings, nil
+}
+
+// Get the lines of an NDBC text file given either as lines or, deprecated, as the whitespace separated
+// tokens of the whole file. Tokens are regrouped into lines using the header, which has one name for
+// each column and is followed by the units line starting with #yr. A row with a missing or extra value
+// can not be told apart in tokens, so every regrouped row must be complete and start with a readable
+// date, and the tokens fail with ErrTruncatedRow otherwise.
+func ndbcLines(rawData []string, source string) ([]string, error) {
+	for _, token := range rawData {
+		if strings.ContainsAny(strings.TrimSpace(token), " \t") {
+			return rawData, nil
+		}
+	}
+	if len(rawData) == 0 {
+		return rawData, nil
+	}
+
+	// The header ends where the next comment line starts
+	columnCount := 0
+	for i, token := range rawData {
+		if i > 0 && strings.HasPrefix(token, "#") {
+			columnCount = i
+			break
+		}
+	}
+	if columnCount == 0 || !strings.HasPrefix(rawData[0], "#") {
+		return nil, &ParseError{Source: source, Line: 1, Column: 1, Field: "header", Token: rawData[0], Err: ErrTruncatedRow}
+	}
+
+	header := &ndbcTable{Fields: make([]string, columnCount)}
+	for i, token := range rawData[:columnCount] {
+		header.Fields[i] = strings.TrimLeft(token, "#")
+	}
+	dateColumns := header.dateColumnCount()
+
+	lines := []string{}
+	for start := 0; start < len(rawData); start += columnCount {
+		end := start + columnCount
+		line := len(lines) + 1
+		if end > len(rawData) {
+			return nil, &ParseError{Source: source, Line: line, Column: len(rawData) - start + 1, Field: header.Fields[len(rawData)-start], Token: strings.Join(rawData[start:], " "), Err: ErrTruncatedRow}
+		}
+
+		row := rawData[start:end]
+		if !strings.HasPrefix(row[0], "#") {
+			for i := 0; i < dateColumns; i++ {
+				if !isNDBCDateToken(header.Fields[i], row[i]) {
+					return nil, &ParseError{Source: source, Line: line, Column: i + 1, Field: header.Fields[i], Token: row[i], Err: ErrTruncatedRow}
+				}
+			}
+		}
+		lines = append(lines, strings.Join(row, " "))
+	}
+	return lines, nil
+}
+
+// Check if a token could be the value of a date column, to find where tokens were regrouped into the
+// wrong rows
+func isNDBCDateToken(field, token string) bool {
+	value, parseErr := strconv.Atoi(token)
+	if parseErr != nil {
+		return false
+	}
+
+	switch field {
+	case "YYYY":
+		return len(token) == 4
+	case "YY":
+		return len(token) == 2 || len(token) == 4
+	case "MM":
+		return value >= 1 && value <= 12
+	case "DD":
+		return value >= 1 && value <= 31
+	case "hh":
+		return value >= 0 && value <= 23
+	}
+	return value >= 0 && value <= 59
+}
+
+// Check if a line of tokens is an NDBC column header, which always starts with the year
+func isNDBCHeader(tokens []string) bool {
+	first := strings.TrimLeft(tokens[0], "#")
+	return first == "YY" || first == "YYYY"
+}
+
+// Get the index of the first of the given columns present in the table, or -1 if none are
+func (t *ndbcTable) column(names ...string) int {
+	for _, name := range names {
+		if index, ok := t.columns[name]; ok {
+			return index
+		}
+	}
+	return -1
+}
+
+// Get the number of leading date columns in the table
+func (t *ndbcTable) dateColumnCount() int {
+	count := 0
+	for _, field := range t.Fields {
+		switch field {
+		case "YY", "YYYY", "MM", "DD", "hh", "mm", "ss":
+			count++
+		default:
+			return count
+		}
+	}
+	return count
+}
+
+// Check that a row has a value for every column in the header, recording an error if it does not
+func (t *ndbcTable) complete(row ndbcRow, parser *valueParser) bool {
+	if len(row.Tokens) < len(t.Fields) {
+		parser.fail(row.Line, len(row.Tokens)+1, t.Fields[len(row.Tokens)], strings.Join(row.Tokens, " "), ErrTruncatedRow)
+		return false
+	}
+	return true
+}
+
+// Read the date of a row. Two digit years are from the archives before 1999, files without a
+// minute column report on the hour, and the DART files add a second column. The zero time is
+// returned if any part of the date is unreadable or out of range, so the row can be dropped.
+func (t *ndbcTable) date(row ndbcRow, parser *valueParser) time.Time {
+	year, yearOK := t.integer(row, parser, 0, 9999, ndbcYearColumns...)
+	month, monthOK := t.integer(row, parser, 1, 12, ndbcMonthColumns...)
+	day, dayOK := t.integer(row, parser, 1, 31, ndbcDayColumns...)
+	hour, hourOK := t.integer(row, parser, 0, 23, ndbcHourColumns...)
+	minute, minuteOK := 0, true
+	if t.column(ndbcMinuteColumns...) >= 0 {
+		minute, minuteOK = t.integer(row, parser, 0, 59, ndbcMinuteColumns...)
+	}
+	second, secondOK := 0, true
+	if t.column(ndbcSecondColumns...) >= 0 {
+		second, secondOK = t.integer(row, parser, 0, 59, ndbcSecondColumns...)
+	}
+	if !yearOK || !monthOK || !dayOK || !hourOK || !minuteOK || !secondOK {
+		return time.Time{}
+	}
+
+	if year < 100 {
+		year += 1900
+	}
+	date := time.Date(year, time.Month(month), day, hour, minute, second, 0, time.UTC)
+
+	// time.Date moves days past the end of the month into the next one
+	if date.Day() != day {
+		index := t.column(ndbcDayColumns...)
+		parser.fail(row.Line, index+1, t.Fields[index], row.Tokens[index], ErrInvalidDate)
+		return time.Time{}
+	}
+	return date
+}
+
+// Read an hhmm time of day column as the latest time at or before the date of the row, such as the
+// time of the peak gust within the hour. The zero time is returned when the value is missing.
+func (t *ndbcTable) timeOfDay(row ndbcRow, parser *valueParser, date time.Time, names ...string) time.Time {
+	value := t.value(row, parser, names...)
+	if IsMissing(value) || date.IsZero() {
+		return time.Time{}
+	}
+
+	clock := int(value)
+	timeOfDay := time.Date(date.Year(), date.Month(), date.Day(), clock/100, clock%100, 0, 0, time.UTC)
+	if timeOfDay.After(date) {
+		timeOfDay = timeOfDay.AddDate(0, 0, -1)
+	}
+	return timeOfDay
+}
+
+// Read an integer date component of a row, which must be a whole number between the given bounds.
+// False is returned, and the value recorded as a ParseError, if it is missing, unreadable or out of
+// range.
+func (t *ndbcTable) integer(row ndbcRow, parser *valueParser, minimum, maximum int, names ...string) (int, bool) {
+	index := t.column(names...)
+	if index < 0 || index >= len(row.Tokens) {
+		return 0, false
+	}
+
+	token := row.Tokens[index]
+	value, parseErr := strconv.Atoi(token)
+	if token == "MM" {
+		parser.fail(row.Line, index+1, t.Fields[index], token, ErrMissingDate)
+		return 0, false
+	} else if parseErr != nil {
+		parser.fail(row.Line, index+1, t.Fields[index], token, parseErr)
+		return 0, false
+	} else if value < minimum || value > maximum {
+		parser.fail(row.Line, index+1, t.Fields[index], token, ErrInvalidDate)
+		return 0, false
+	}
+	return value, true
+}
+
+// Read a float value from the first of the given columns present in the table. Values from columns
+// the table does not have are missing.
+func (t *ndbcTable) value(row ndbcRow, parser *valueParser, names ...string) float64 {
+	index := t.column(names...)
+	if index < 0 || index >= len(row.Tokens) {
+		return MissingValue()
+	}
+	return parser.float(row.Tokens[index], row.Line, index+1, t.Fields[index])
+}
+
+// Read a raw string value from the first of the given columns present in the table
+func (t *ndbcTable) text(row ndbcRow, names ...string) string {
+	index := t.column(names...)
+	if index < 0 || index >= len(row.Tokens) {
+		return ""
+	}
+	return row.Tokens[index]
+}
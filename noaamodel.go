@@ -1,6 +1,7 @@
 package surfnerd
 
 import (
+	"math"
 	"time"
 )
 
@@ -18,12 +19,27 @@ type NOAAModel struct {
 	Units              UnitSystem
 	TimeLocation       string
 	ModelRun           string
+
+	// The UTC time of the model run the data is from
+	RunTime time.Time
+
+	// How values that can not be read from the model output are handled
+	ParseMode ParseMode `json:"-"`
+
+	// How the grid cell used for a location is found when the cell holding it has no data. A nil search
+	// uses the DefaultModelCellSearch.
+	CellSearch *ModelCellSearch `json:"-"`
+
+	// How the values of a location are taken from the cells around it. The zero value uses the values of
+	// the single cell found by the CellSearch.
+	Interpolation ModelInterpolation `json:"-"`
 }
 
 // Check if a given model contains a location as part of its coverage
 func (n NOAAModel) ContainsLocation(loc Location) bool {
 	if loc.Latitude > n.BottomLeftLocation.Latitude && loc.Latitude < n.TopRightLocation.Latitude {
-		if loc.Longitude > n.BottomLeftLocation.Longitude && loc.Longitude < n.TopRightLocation.Longitude {
+		lon := n.modelLongitude(loc)
+		if lon > n.BottomLeftLocation.Longitude && lon < n.TopRightLocation.Longitude {
 			return true
 		}
 	}
@@ -39,7 +55,7 @@ func (n NOAAModel) LocationIndices(loc Location) (int, int) {
 
 	// Find the offsets from the minimum lat and long
 	latOffset := loc.Latitude - n.BottomLeftLocation.Latitude
-	lonOffset := loc.Longitude - n.BottomLeftLocation.Longitude
+	lonOffset := n.modelLongitude(loc) - n.BottomLeftLocation.Longitude
 
 	// Get the indexes and return them
 	latIndex := int(latOffset / n.LocationResolution)
@@ -47,6 +63,12 @@ func (n NOAAModel) LocationIndices(loc Location) (int, int) {
 	return latIndex, lonIndex
 }
 
+// Get the longitude of a location in the convention of the model grid, so locations can be given
+// as either -180 to 180 or 0 to 360 degrees
+func (n NOAAModel) modelLongitude(loc Location) float64 {
+	return normalizeLongitudeFrom(loc.Longitude, n.BottomLeftLocation.Longitude)
+}
+
 // Get the index of a given altitude in a models coverage area
 // Returns -1 if the lcoation is not inside the models coverage area
 func (n NOAAModel) AltitudeIndex(altitude float64) int {
@@ -67,10 +89,22 @@ func (n NOAAModel) TimeResolutionHours() float64 {
 	return n.TimeResolution * 24.0
 }
 
-// Get the closest future data index of a given time
-func (n NOAAModel) TimeIndex(desiredTime time.Time) int {
-	latestModelTime, _ := LatestModelDateTime()
-	diff := desiredTime.UTC().Sub(latestModelTime)
+// Get the UTC time a time step of the model run is valid for, or the zero time if the run is not known
+func (n NOAAModel) ValidTime(timeIndex int) time.Time {
+	if n.RunTime.IsZero() {
+		return time.Time{}
+	}
+	return n.RunTime.Add(time.Duration(timeIndex) * n.timeStep())
+}
+
+// Get the time between the time steps of the model
+func (n NOAAModel) timeStep() time.Duration {
+	return time.Duration(math.Round(n.TimeResolutionHours() * float64(time.Hour)))
+}
+
+// Get the closest future data index of a given time in the given model run
+func (n NOAAModel) TimeIndex(run time.Time, desiredTime time.Time) int {
+	diff := desiredTime.UTC().Sub(run.UTC())
 	hoursDiff := int(diff.Hours())
 	if hoursDiff < 1 {
 		return -1
@@ -80,9 +114,10 @@ func (n NOAAModel) TimeIndex(desiredTime time.Time) int {
 	return (hoursDiff + (hoursResolution - (hoursDiff % hoursResolution))) / hoursResolution
 }
 
-// Get the time and hour of the latest NOAA WaveWatch model run
+// Guess the time and hour of the latest NOAA WaveWatch model run from the clock. The run may not be
+// published yet, so use a ModelRunResolver to find a run that is.
 func LatestModelDateTime() (time.Time, int64) {
-	currentTime := time.Now().UTC()
+	currentTime := time.Now().UTC().Truncate(time.Hour)
 	currentTime = currentTime.Add(time.Duration(-5 * int64(time.Hour)))
 	lastModelHour := int64(currentTime.Hour() - (currentTime.Hour() % 6))
 	currentTime = currentTime.Add(time.Duration(-(int64(currentTime.Hour()) - lastModelHour) * int64(time.Hour)))
@@ -98,3 +133,23 @@ func FetchTimeLocation(location string) *time.Location {
 func FormatViewingTime(timestamp time.Time) string {
 	return timestamp.Format("Monday January 02, 2006 15z")
 }
+
+// Format the date of a forecast time in the given time zone, such as Monday January 02, 2006.
+// A nil location formats the date in UTC.
+func FormatForecastDate(timestamp time.Time, loc *time.Location) string {
+	return timestamp.In(locationOrUTC(loc)).Format("Monday January 02, 2006")
+}
+
+// Format the hour of a forecast time in the given time zone, such as 03 PM. A nil location formats
+// the hour in UTC.
+func FormatForecastTime(timestamp time.Time, loc *time.Location) string {
+	return timestamp.In(locationOrUTC(loc)).Format("03 PM")
+}
+
+// Returns the given location, or UTC if it is nil
+func locationOrUTC(loc *time.Location) *time.Location {
+	if loc == nil {
+		return time.UTC
+	}
+	return loc
+}
@@ -1,7 +1,6 @@
 package surfnerd
 
 import (
-	"fmt"
 	"testing"
 	"time"
 )
@@ -11,7 +10,7 @@ func TestTimeIndex(t *testing.T) {
 
 	modelTime, _ := LatestModelDateTime()
 	futureTime := modelTime.Add(time.Duration(28 * int64(time.Hour)))
-	if eastCoastModel.TimeIndex(futureTime) != 10 {
+	if eastCoastModel.TimeIndex(modelTime, futureTime) != 10 {
 		t.Fail()
 	}
 }
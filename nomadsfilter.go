@@ -0,0 +1,138 @@
+package surfnerd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The GRIB2 parameters of the wave models, as the NOMADS filters name them
+var waveGRIBVariables = []string{"DIRPW", "HTSGW", "PERPW", "SWDIR", "SWELL", "SWPER", "UGRD", "VGRD", "WDIR", "WIND", "WVDIR", "WVHGT", "WVPER"}
+
+// The GRIB2 parameters and levels of the wind models, as the NOMADS filters name them
+var (
+	windGRIBVariables = []string{"UGRD", "VGRD", "GUST"}
+	windGRIBLevels    = []string{"lev_10_m_above_ground", "lev_surface"}
+)
+
+// Create the url of a NOMADS filter request for the GRIB2 file of a forecast hour of a run of the model,
+// subset to the box between two corners. The file holds every wave variable if none are given.
+func (w *WaveModel) CreateGRIBFilterURL(run time.Time, forecastHour int, bottomLeft, topRight Location, variables []string) string {
+	run = run.UTC()
+	if len(variables) == 0 {
+		variables = waveGRIBVariables
+	}
+
+	file := fmt.Sprintf("%s.t%02dz.f%03d.grib2", w.Name, run.Hour(), forecastHour)
+	dir := "/multi_1." + run.Format("20060102")
+	return createGRIBFilterURL("filter_wave_multi.pl", file, dir, []string{"all_lev"}, variables, w.NOAAModel, bottomLeft, topRight)
+}
+
+// Create the url of a NOMADS filter request for the GRIB2 file of a forecast hour of a run of the model,
+// subset to the box between two corners. The file holds the 10 meter winds and surface gusts if no
+// variables are given.
+func (w *WindModel) CreateGRIBFilterURL(run time.Time, forecastHour int, bottomLeft, topRight Location, variables []string) string {
+	run = run.UTC()
+	if len(variables) == 0 {
+		variables = windGRIBVariables
+	}
+
+	if w.ModelType == NAM {
+		file := fmt.Sprintf("nam.t%02dz.awphys%02d.tm00.grib2", run.Hour(), forecastHour)
+		return createGRIBFilterURL("filter_nam.pl", file, "/nam."+run.Format("20060102"), windGRIBLevels, variables, w.NOAAModel, bottomLeft, topRight)
+	}
+
+	// The half degree files hold the full set of GFS fields, the others only the most common ones
+	resolution := strings.TrimPrefix(w.Name, "gfs_")
+	product := "pgrb2"
+	if resolution == "0p50" {
+		product = "pgrb2full"
+	}
+	file := fmt.Sprintf("gfs.t%02dz.%s.%s.f%03d", run.Hour(), product, resolution, forecastHour)
+	dir := fmt.Sprintf("/gfs.%s/%02d/atmos", run.Format("20060102"), run.Hour())
+	return createGRIBFilterURL("filter_"+w.Name+".pl", file, dir, windGRIBLevels, variables, w.NOAAModel, bottomLeft, topRight)
+}
+
+// Format the query of a NOMADS filter script, selecting levels and variables of a file and the subregion
+// between two corners
+func createGRIBFilterURL(filter, file, dir string, levels, variables []string, model NOAAModel, bottomLeft, topRight Location) string {
+	formatDegrees := func(degrees float64) string {
+		return strconv.FormatFloat(degrees, 'f', -1, 64)
+	}
+
+	query := url.Values{}
+	query.Set("file", file)
+	query.Set("dir", dir)
+	for _, level := range levels {
+		query.Set(level, "on")
+	}
+	for _, variable := range variables {
+		query.Set("var_"+strings.ToUpper(variable), "on")
+	}
+	query.Set("subregion", "")
+	query.Set("leftlon", formatDegrees(model.modelLongitude(bottomLeft)))
+	query.Set("rightlon", formatDegrees(model.modelLongitude(topRight)))
+	query.Set("bottomlat", formatDegrees(bottomLeft.Latitude))
+	query.Set("toplat", formatDegrees(topRight.Latitude))
+	return NOMADSFilterBaseURL + "/" + filter + "?" + query.Encode()
+}
+
+// Fetch and decode the GRIB2 fields of a url, such as a NOMADS filter request, using the given context
+// and Fetcher. A nil Fetcher uses the DefaultFetcher.
+func FetchGRIB2FieldsContext(ctx context.Context, fetcher Fetcher, url string) ([]*GRIB2Field, error) {
+	body, fetchErr := fetchStreamFromURL(ctx, fetcher, url)
+	if fetchErr != nil {
+		return nil, fetchErr
+	}
+	defer body.Close()
+
+	return ReadGRIB2(body)
+}
+
+// Grabs the WaveWatch data of the given forecast hours of a model run for a given Location from the GRIB2
+// files of the NOMADS filter, using the given context and Fetcher. A nil Fetcher uses the DefaultFetcher.
+func FetchWaveModelDataFromGRIBContext(ctx context.Context, fetcher Fetcher, loc Location, run time.Time, forecastHours []int) (*ModelData, error) {
+	model := GetWaveModelForLocation(loc)
+	if model == nil {
+		return nil, errors.New("No wave model covers the given location")
+	}
+
+	return fetchModelDataFromGRIB(ctx, fetcher, loc, model.NOAAModel, forecastHours, func(hour int, bottomLeft, topRight Location) string {
+		return model.CreateGRIBFilterURL(run, hour, bottomLeft, topRight, nil)
+	})
+}
+
+// Grabs the wind data of the given forecast hours of a model run for a given Location from the GRIB2
+// files of the NOMADS filter, using the given context and Fetcher. A nil Fetcher uses the DefaultFetcher.
+func FetchWindModelDataFromGRIBContext(ctx context.Context, fetcher Fetcher, loc Location, model *WindModel, run time.Time, forecastHours []int) (*ModelData, error) {
+	if model == nil {
+		return nil, errors.New("No wind model given")
+	}
+
+	return fetchModelDataFromGRIB(ctx, fetcher, loc, model.NOAAModel, forecastHours, func(hour int, bottomLeft, topRight Location) string {
+		return model.CreateGRIBFilterURL(run, hour, bottomLeft, topRight, nil)
+	})
+}
+
+// Fetch the GRIB2 file of every forecast hour, subset to the cells around a location, and collect
+// their fields into a ModelData container
+func fetchModelDataFromGRIB(ctx context.Context, fetcher Fetcher, loc Location, model NOAAModel, forecastHours []int, createURL func(hour int, bottomLeft, topRight Location) string) (*ModelData, error) {
+	margin := model.LocationResolution
+	bottomLeft := NewLocationForLatLong(loc.Latitude-margin, loc.Longitude-margin)
+	topRight := NewLocationForLatLong(loc.Latitude+margin, loc.Longitude+margin)
+
+	fields := []*GRIB2Field{}
+	for _, hour := range forecastHours {
+		hourFields, fetchErr := FetchGRIB2FieldsContext(ctx, fetcher, createURL(hour, bottomLeft, topRight))
+		if fetchErr != nil {
+			return nil, fetchErr
+		}
+		fields = append(fields, hourFields...)
+	}
+
+	return ModelDataFromGRIB2(loc, model, fields)
+}
@@ -0,0 +1,128 @@
+package surfnerd
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// How the parsers handle values that can not be read
+type ParseMode int
+
+const (
+	// Unreadable values are read as missing and recorded as warnings so the rest of the data can still be used
+	LenientParsing ParseMode = iota
+
+	// The first unreadable value fails the parse with a *ParseError
+	StrictParsing
+)
+
+var (
+	// Returned inside a ParseError when a row ends before all of its expected values were read
+	ErrTruncatedRow = errors.New("Row is missing values")
+
+	// Returned inside a ParseError when a row's date is reported as missing
+	ErrMissingDate = errors.New("Row has a missing date")
+
+	// Returned inside a ParseError when a part of a row's date is out of range, such as a month of 13
+	ErrInvalidDate = errors.New("Row has an invalid date")
+)
+
+// Describes a single value that could not be parsed from a NOAA data source. Lines and columns
+// are one based, with columns counting whitespace separated tokens.
+type ParseError struct {
+	Source string
+	Line   int
+	Column int
+	Field  string
+	Token  string
+	Err    error
+}
+
+func (p *ParseError) Error() string {
+	return fmt.Sprintf("%s line %d column %d: could not parse %s from %q: %v", p.Source, p.Line, p.Column, p.Field, p.Token, p.Err)
+}
+
+// Get the underlying strconv or time error
+func (p *ParseError) Unwrap() error {
+	return p.Err
+}
+
+// Reads values out of a data source, either failing on or collecting the values that can not be parsed.
+// In strict mode the first failure is kept and every later read is skipped, so callers only need to
+// check failed once per row.
+type valueParser struct {
+	source   string
+	mode     ParseMode
+	warnings []*ParseError
+	failure  *ParseError
+}
+
+func newValueParser(source string, mode ParseMode) *valueParser {
+	return &valueParser{source: source, mode: mode}
+}
+
+// Get the error that failed a strict parse, or nil if the parse can continue
+func (v *valueParser) failed() error {
+	if v.failure == nil {
+		return nil
+	}
+	return v.failure
+}
+
+// Record a value that could not be parsed
+func (v *valueParser) fail(line, column int, field, token string, err error) {
+	if v.failure != nil {
+		return
+	}
+
+	parseErr := &ParseError{
+		Source: v.source,
+		Line:   line,
+		Column: column,
+		Field:  field,
+		Token:  token,
+		Err:    err,
+	}
+
+	if v.mode == StrictParsing {
+		v.failure = parseErr
+	} else {
+		v.warnings = append(v.warnings, parseErr)
+	}
+}
+
+// Parse a float value. NDBC marks missing values with MM or with a sentinel value for the column,
+// which are both read as a missing value without an error. Values that can not be parsed are also
+// read as missing, so they never show up as zero readings.
+func (v *valueParser) float(token string, line, column int, field string) float64 {
+	if v.failure != nil {
+		return MissingValue()
+	} else if token == "MM" {
+		return MissingValue()
+	}
+
+	value, parseErr := strconv.ParseFloat(token, 64)
+	if parseErr != nil {
+		v.fail(line, column, field, token, parseErr)
+		return MissingValue()
+	} else if isNDBCMissingSentinel(field, value) {
+		return MissingValue()
+	}
+	return value
+}
+
+// Parse a time value with the given layout
+func (v *valueParser) date(layout, token string, line, column int, field string) time.Time {
+	if v.failure != nil {
+		return time.Time{}
+	}
+
+	value, parseErr := time.Parse(layout, token)
+	if parseErr != nil {
+		v.fail(line, column, field, token, parseErr)
+		return time.Time{}
+	}
+	return value
+}
@@ -0,0 +1,139 @@
+package surfnerd
+
+import (
+	"strings"
+	"testing"
+)
+
+const testCorruptStandardData = `#YY  MM DD hh mm WDIR WSPD GST  WVHT   DPD   APD MWD   PRES  ATMP  WTMP  DEWP  VIS PTDY  TIDE
+#yr  mo dy hr mn degT m/s  m/s     m   sec   sec degT   hPa  degC  degC  degC  nmi  hPa    ft
+2016 10 18 14 50 210  6.0  8.0   1.2     9   6.1 180 1015.2  15.1  16.8  12.3   MM -0.9    MM
+2016 10 18 13 50 200  5.0  7.0   x.x     8   5.9 170 1015.8  15.0  16.8  12.1   MM -0.7    MM
+`
+
+func TestStrictStandardDataParse(t *testing.T) {
+	buoy := Buoy{StationID: "44017", ParseMode: StrictParsing}
+	parseErr := buoy.ParseRawStandardData(strings.Fields(testCorruptStandardData), -1)
+	if parseErr == nil {
+		t.Fatal("Expected the corrupt wave height to fail the parse")
+	}
+
+	typedErr, ok := parseErr.(*ParseError)
+	if !ok {
+		t.Fatalf("Expected a *ParseError, got %T", parseErr)
+	}
+	if typedErr.Line != 4 || typedErr.Column != 9 || typedErr.Field != "WVHT" || typedErr.Token != "x.x" {
+		t.Errorf("Unexpected parse error location: %v", typedErr)
+	}
+}
+
+func TestLenientStandardDataParse(t *testing.T) {
+	buoy := Buoy{StationID: "44017"}
+	parseErr := buoy.ParseRawStandardData(strings.Fields(testCorruptStandardData), -1)
+	if parseErr != nil {
+		t.Fatal(parseErr)
+	}
+
+	if len(buoy.BuoyData) != 2 {
+		t.Fatalf("Expected 2 data items, got %d", len(buoy.BuoyData))
+	}
+	if len(buoy.ParseWarnings) != 1 {
+		t.Fatalf("Expected 1 warning, got %d", len(buoy.ParseWarnings))
+	}
+	if buoy.ParseWarnings[0].Field != "WVHT" {
+		t.Fail()
+	}
+
+	// The unreadable wave height must not be reported as a calm sea
+	if !IsMissing(buoy.BuoyData[1].WaveSummary.WaveHeight) {
+		t.Errorf("Expected the unreadable wave height to be missing, got %v", buoy.BuoyData[1].WaveSummary.WaveHeight)
+	}
+}
+
+func TestLenientCorruptSpectraDateParse(t *testing.T) {
+	buoy := Buoy{StationID: "44097"}
+	alpha := []string{
+		"#YY  MM DD hh mm alpha1_1 (freq_1) alpha1_2 (freq_2)",
+		"2016 10 18 14 40 180.0 (0.033) 190.0 (0.038)",
+		"2016 1x 18 13 40 170.0 (0.033) 180.0 (0.038)",
+	}
+	energy := []string{
+		"#YY  MM DD hh mm Sep_Freq  < spec_1 (freq_1) spec_2 (freq_2)",
+		"2016 10 18 14 40 0.150 0.5 (0.033) 1.5 (0.038)",
+		"2016 1x 18 13 40 0.150 0.4 (0.033) 1.4 (0.038)",
+	}
+	if parseErr := buoy.ParseRawWaveSpectraData(alpha, energy, 2); parseErr != nil {
+		t.Fatal(parseErr)
+	}
+	if len(buoy.BuoyData) != 1 || buoy.BuoyData[0].Date.IsZero() {
+		t.Fatalf("Expected only the row with a readable date, got %+v", buoy.BuoyData)
+	}
+	if len(buoy.ParseWarnings) != 1 || buoy.ParseWarnings[0].Field != "date" {
+		t.Fatalf("Expected a date warning, got %v", buoy.ParseWarnings)
+	}
+
+	rOne := []string{"#YY  MM DD hh mm r1_1 (freq_1) r1_2 (freq_2)", "2016 10 18 14 40 0.8 (0.033) 0.7 (0.038)", "2016 1x 18 13 40 0.6 (0.033) 0.5 (0.038)"}
+	table, parseErr := parseRealtimeSpectralTable(rOne, "r1", "r1", LenientParsing)
+	if parseErr != nil {
+		t.Fatal(parseErr)
+	}
+	if len(table.Dates) != 1 || table.Dates[0].IsZero() || len(table.ParseWarnings) != 1 {
+		t.Errorf("Expected only the row with a readable date, got %v with warnings %v", table.Dates, table.ParseWarnings)
+	}
+}
+
+const testCorruptDateData = `#YY  MM DD hh mm WDIR WSPD GST  WVHT   DPD   APD MWD   PRES  ATMP  WTMP  DEWP  VIS PTDY  TIDE
+#yr  mo dy hr mn degT m/s  m/s     m   sec   sec degT   hPa  degC  degC  degC  nmi  hPa    ft
+20x6 10 18 14 50 210  6.0  8.0   1.2     9   6.1 180 1015.2  15.1  16.8  12.3   MM -0.9    MM
+2016 1x 18 13 50 200  5.0  7.0   1.1     8   5.9 170 1015.8  15.0  16.8  12.1   MM -0.7    MM
+2016 00 18 12 50 200  5.0  7.0   1.1     8   5.9 170 1015.8  15.0  16.8  12.1   MM -0.7    MM
+2016 02 30 12 50 200  5.0  7.0   1.1     8   5.9 170 1015.8  15.0  16.8  12.1   MM -0.7    MM
+2016 10 18 11 50 190  4.0  6.0   1.0     8   5.8 160 1016.1  14.9  16.8  12.0   MM -0.5    MM
+`
+
+func TestLenientCorruptDateParse(t *testing.T) {
+	buoy := Buoy{StationID: "44017"}
+	if parseErr := buoy.ParseRawStandardData(strings.Split(testCorruptDateData, "\n"), -1); parseErr != nil {
+		t.Fatal(parseErr)
+	}
+
+	// Only the row with a readable date is kept, rather than guessing at a date for the others
+	if len(buoy.BuoyData) != 1 || buoy.BuoyData[0].WindDirection != 190 {
+		t.Fatalf("Expected only the row with a readable date, got %+v", buoy.BuoyData)
+	}
+	if len(buoy.ParseWarnings) != 4 {
+		t.Fatalf("Expected 4 warnings, got %d", len(buoy.ParseWarnings))
+	}
+	if buoy.ParseWarnings[0].Field != "YY" || buoy.ParseWarnings[1].Field != "MM" {
+		t.Errorf("Unexpected warnings %v and %v", buoy.ParseWarnings[0], buoy.ParseWarnings[1])
+	}
+	if buoy.ParseWarnings[2].Err != ErrInvalidDate || buoy.ParseWarnings[3].Err != ErrInvalidDate {
+		t.Errorf("Expected out of range dates to be invalid, got %v and %v", buoy.ParseWarnings[2], buoy.ParseWarnings[3])
+	}
+
+	strict := Buoy{StationID: "44017", ParseMode: StrictParsing}
+	parseErr := strict.ParseRawStandardData(strings.Split(testCorruptDateData, "\n"), -1)
+	if typedErr, ok := parseErr.(*ParseError); !ok || typedErr.Line != 3 || typedErr.Field != "YY" {
+		t.Errorf("Expected the corrupt year to fail the parse, got %v", parseErr)
+	}
+}
+
+func TestStrictModelDataParse(t *testing.T) {
+	rawData := []byte("htsgwsfc, [2][1][1]\n[0][0], 1.25\n[1][0], bad\n")
+
+	_, _, parseErr := parseRawModelData(rawData, "test", StrictParsing)
+	if parseErr == nil {
+		t.Fatal("Expected the corrupt value to fail the parse")
+	}
+	if typedErr, ok := parseErr.(*ParseError); !ok || typedErr.Line != 3 || typedErr.Field != "htsgwsfc" {
+		t.Errorf("Unexpected parse error: %v", parseErr)
+	}
+
+	data, warnings, parseErr := parseRawModelData(rawData, "test", LenientParsing)
+	if parseErr != nil {
+		t.Fatal(parseErr)
+	}
+	if len(data["htsgwsfc"]) != 2 || len(warnings) != 1 {
+		t.Fail()
+	}
+}
@@ -0,0 +1,269 @@
+package surfnerd
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+const (
+	// Partitions holding less than this fraction of the total energy are merged into their neighbours
+	minimumPartitionEnergyFraction = 0.01
+
+	// Waves with a phase speed below this multiple of the wind speed along their direction are
+	// still being forced by the wind, as in the WAVEWATCH III partitioning
+	windSeaWaveAgeFactor = 1.7
+
+	// The number of direction bins used when partitioning a buoy spectra
+	defaultPartitionDirectionCount = 72
+)
+
+// Split the spectrum into its separate wave systems with a watershed algorithm. Every cell climbs to
+// its highest neighbour until it reaches a peak, and the cells draining to the same peak form one
+// partition, so two swells with the same period but different directions are kept apart. Partitions
+// with a tiny share of the energy are merged into their most energetic neighbour. The swells are
+// returned in metric units ordered from the most to the least energetic.
+func (d DirectionalSpectrum) Partition() []Swell {
+	frequencyCount := len(d.Frequencies)
+	directionCount := len(d.Directions)
+	if frequencyCount == 0 || directionCount == 0 || len(d.Energies) != frequencyCount {
+		return nil
+	}
+
+	energy := func(i, j int) float64 {
+		if len(d.Energies[i]) != directionCount || IsMissing(d.Energies[i][j]) {
+			return 0
+		}
+		return d.Energies[i][j]
+	}
+
+	// Find the neighbour each cell climbs to, or -1 for the peaks and empty cells
+	cellCount := frequencyCount * directionCount
+	uphill := make([]int, cellCount)
+	for i := 0; i < frequencyCount; i++ {
+		for j := 0; j < directionCount; j++ {
+			cell := i*directionCount + j
+			uphill[cell] = -1
+
+			highest := energy(i, j)
+			if highest <= 0 {
+				continue
+			}
+
+			d.forEachNeighbour(i, j, func(ni, nj int) {
+				if neighbourEnergy := energy(ni, nj); neighbourEnergy > highest {
+					highest = neighbourEnergy
+					uphill[cell] = ni*directionCount + nj
+				}
+			})
+		}
+	}
+
+	// Label every cell with the peak it drains to
+	labels := make([]int, cellCount)
+	for cell := range labels {
+		labels[cell] = -1
+	}
+	var findPeak func(cell int) int
+	findPeak = func(cell int) int {
+		if labels[cell] >= 0 {
+			return labels[cell]
+		} else if uphill[cell] < 0 {
+			labels[cell] = cell
+			return cell
+		}
+		labels[cell] = findPeak(uphill[cell])
+		return labels[cell]
+	}
+	for cell := range labels {
+		if energy(cell/directionCount, cell%directionCount) > 0 {
+			findPeak(cell)
+		}
+	}
+
+	d.mergeSmallPartitions(labels, energy)
+
+	// Gather the cells of each partition, keeping the partitions in order of their peaks so ties
+	// are always sorted the same way
+	partitions := map[int][]int{}
+	peaks := []int{}
+	for cell, label := range labels {
+		if label < 0 {
+			continue
+		} else if _, exists := partitions[label]; !exists {
+			peaks = append(peaks, label)
+		}
+		partitions[label] = append(partitions[label], cell)
+	}
+	sort.Ints(peaks)
+
+	swells := make([]Swell, 0, len(partitions))
+	for _, peak := range peaks {
+		swells = append(swells, d.partitionSwell(partitions[peak], energy))
+	}
+
+	sort.Stable(sort.Reverse(ByMaxEnergy(swells)))
+
+	return swells
+}
+
+// Call the given function for each of the eight neighbours of a cell. Directions wrap around while
+// frequencies do not.
+func (d DirectionalSpectrum) forEachNeighbour(i, j int, apply func(ni, nj int)) {
+	directionCount := len(d.Directions)
+	for di := -1; di <= 1; di++ {
+		ni := i + di
+		if ni < 0 || ni >= len(d.Frequencies) {
+			continue
+		}
+		for dj := -1; dj <= 1; dj++ {
+			if di == 0 && dj == 0 {
+				continue
+			}
+			apply(ni, (j+dj+directionCount)%directionCount)
+		}
+	}
+}
+
+// Merge the partitions holding too little energy into the neighbouring partition they share their
+// most energetic boundary cell with, until every partition is large enough
+func (d DirectionalSpectrum) mergeSmallPartitions(labels []int, energy func(i, j int) float64) {
+	directionCount := len(d.Directions)
+	bandwidths := frequencyBandwidths(d.Frequencies)
+	resolution := d.DirectionResolution()
+
+	for {
+		totals := map[int]float64{}
+		totalEnergy := 0.0
+		for cell, label := range labels {
+			if label < 0 {
+				continue
+			}
+			i := cell / directionCount
+			cellEnergy := energy(i, cell%directionCount) * bandwidths[i] * resolution
+			totals[label] += cellEnergy
+			totalEnergy += cellEnergy
+		}
+
+		// Find the smallest partition, stopping once it is big enough or it is the only one left
+		smallest, smallestEnergy := -1, math.Inf(1)
+		for label, total := range totals {
+			if total < smallestEnergy {
+				smallest, smallestEnergy = label, total
+			}
+		}
+		if len(totals) < 2 || smallestEnergy >= minimumPartitionEnergyFraction*totalEnergy {
+			return
+		}
+
+		target, targetEnergy := -1, -1.0
+		for cell, label := range labels {
+			if label != smallest {
+				continue
+			}
+			d.forEachNeighbour(cell/directionCount, cell%directionCount, func(ni, nj int) {
+				neighbour := ni*directionCount + nj
+				if labels[neighbour] >= 0 && labels[neighbour] != smallest && energy(ni, nj) > targetEnergy {
+					target, targetEnergy = labels[neighbour], energy(ni, nj)
+				}
+			})
+		}
+
+		// An isolated partition has no one to merge with, so it is dropped instead
+		for cell, label := range labels {
+			if label == smallest {
+				labels[cell] = target
+			}
+		}
+	}
+}
+
+// Compute the integrated statistics of a partition from its cells
+func (d DirectionalSpectrum) partitionSwell(cells []int, energy func(i, j int) float64) Swell {
+	directionCount := len(d.Directions)
+	bandwidths := frequencyBandwidths(d.Frequencies)
+	resolution := d.DirectionResolution()
+
+	frequencyEnergies := make([]float64, len(d.Frequencies))
+	zeroMoment, firstMoment := 0.0, 0.0
+	eastward, northward := 0.0, 0.0
+	for _, cell := range cells {
+		i, j := cell/directionCount, cell%directionCount
+		cellEnergy := energy(i, j) * bandwidths[i] * resolution
+
+		frequencyEnergies[i] += energy(i, j) * resolution
+		zeroMoment += cellEnergy
+		firstMoment += cellEnergy * d.Frequencies[i]
+
+		theta := d.Directions[j] * math.Pi / 180.0
+		eastward += cellEnergy * math.Sin(theta)
+		northward += cellEnergy * math.Cos(theta)
+	}
+
+	peakIndex := 0
+	for i, frequencyEnergy := range frequencyEnergies {
+		if frequencyEnergy > frequencyEnergies[peakIndex] {
+			peakIndex = i
+		}
+	}
+
+	// The directional spread follows Kuik et al. (1988) from the first Fourier coefficient
+	r1 := math.Hypot(eastward, northward) / zeroMoment
+
+	swell := Swell{Units: Metric}
+	swell.WaveHeight = 4.0 * math.Sqrt(zeroMoment)
+	swell.Period = 1.0 / d.Frequencies[peakIndex]
+	swell.MeanPeriod = zeroMoment / firstMoment
+	swell.Direction = math.Mod(math.Atan2(eastward, northward)*180.0/math.Pi+360.0, 360.0)
+	swell.CompassDirection = DegreeToDirection(swell.Direction)
+	swell.DirectionalSpread = circularSpread(r1)
+	swell.MaxEnergy = frequencyEnergies[peakIndex]
+	swell.FrequencyIndex = peakIndex
+	return swell
+}
+
+// Mark the swells that are still being forced by the wind as wind sea, using the wave age criterion.
+// A partition is wind sea when its peak phase speed is slower than 1.7 times the wind speed along
+// its direction. Wind speed is in m/s and both directions are where the wind and waves come from.
+// Nothing is classified when the wind is missing.
+func ClassifyWindSea(swells []Swell, windSpeed, windDirection float64) {
+	const gravity = 9.81
+
+	if IsMissing(windSpeed) || IsMissing(windDirection) {
+		return
+	}
+
+	for i := range swells {
+		if IsMissing(swells[i].Period) || IsMissing(swells[i].Direction) {
+			continue
+		}
+
+		phaseSpeed := gravity * swells[i].Period / (2.0 * math.Pi)
+		alignedWind := windSpeed * math.Cos(angleDifference(windDirection, swells[i].Direction)*math.Pi/180.0)
+		swells[i].WindSea = windSeaWaveAgeFactor*alignedWind > phaseSpeed
+	}
+}
+
+// Partition the directional spectrum reconstructed with the Maximum Entropy Method into its swell
+// and wind sea components. The wind speed in m/s and direction are used to classify the wind sea,
+// and may be missing.
+func (b BuoySpectraItem) PartitionSwellComponents(windSpeed, windDirection float64) ([]Swell, error) {
+	spectrum, spectrumErr := b.DirectionalSpectrum(defaultPartitionDirectionCount, MaximumEntropyMethod)
+	if spectrumErr != nil {
+		return nil, spectrumErr
+	}
+
+	swells := spectrum.Partition()
+	if len(swells) == 0 {
+		return nil, errors.New("The spectra has no energy to partition")
+	}
+
+	ClassifyWindSea(swells, windSpeed, windDirection)
+	return swells, nil
+}
+
+// Partition the wave spectra of the item into its swell and wind sea components, using the wind
+// measured with it. The item must be in metric units.
+func (b BuoyDataItem) PartitionSwellComponents() ([]Swell, error) {
+	return b.WaveSpectra.PartitionSwellComponents(b.WindSpeed, b.WindDirection)
+}
@@ -0,0 +1,81 @@
+package surfnerd
+
+import (
+	"math"
+	"testing"
+)
+
+// Build a spectrum of two swells with the same period crossing from different directions
+func crossingSwellSpectrum() DirectionalSpectrum {
+	spectrum := DirectionalSpectrum{}
+	for f := 0.05; f < 0.3; f += 0.01 {
+		spectrum.Frequencies = append(spectrum.Frequencies, f)
+	}
+	for direction := 0.0; direction < 360.0; direction += 5.0 {
+		spectrum.Directions = append(spectrum.Directions, direction)
+	}
+
+	spectrum.Energies = make([][]float64, len(spectrum.Frequencies))
+	for i, f := range spectrum.Frequencies {
+		frequencyEnergy := math.Exp(-math.Pow((f-0.08)/0.01, 2))
+		eastern := cosineSpreading(spectrum.Directions, 90.0, 0.9)
+		southern := cosineSpreading(spectrum.Directions, 200.0, 0.9)
+
+		spectrum.Energies[i] = make([]float64, len(spectrum.Directions))
+		for j := range spectrum.Directions {
+			spectrum.Energies[i][j] = frequencyEnergy * (2.0*eastern[j] + southern[j])
+		}
+	}
+	return spectrum
+}
+
+func TestPartitionCrossingSwells(t *testing.T) {
+	spectrum := crossingSwellSpectrum()
+	swells := spectrum.Partition()
+	if len(swells) != 2 {
+		t.Fatalf("Expected 2 partitions, got %d", len(swells))
+	}
+
+	if math.Abs(swells[0].Direction-90.0) > 5.0 || math.Abs(swells[1].Direction-200.0) > 5.0 {
+		t.Errorf("Expected swells from 90 and 200, got %v and %v", swells[0].Direction, swells[1].Direction)
+	}
+	if math.Abs(swells[0].Period-12.5) > 0.01 || math.Abs(swells[1].Period-12.5) > 0.01 {
+		t.Errorf("Expected both swells to peak at 12.5 seconds, got %v and %v", swells[0].Period, swells[1].Period)
+	}
+	if swells[0].WaveHeight <= swells[1].WaveHeight {
+		t.Error("Expected the eastern swell to be larger")
+	}
+	if swells[0].DirectionalSpread <= 0 || swells[0].DirectionalSpread > 40 {
+		t.Errorf("Unexpected directional spread %v", swells[0].DirectionalSpread)
+	}
+
+	// The partitions should add back up to the whole spectrum
+	total := 0.0
+	bandwidths := frequencyBandwidths(spectrum.Frequencies)
+	for i, energy := range spectrum.FrequencyEnergies() {
+		total += energy * bandwidths[i]
+	}
+	partitioned := math.Pow(swells[0].WaveHeight/4.0, 2) + math.Pow(swells[1].WaveHeight/4.0, 2)
+	if math.Abs(partitioned-total) > 1e-6*total {
+		t.Errorf("Expected the partitions to hold all %v of the energy, got %v", total, partitioned)
+	}
+}
+
+func TestClassifyWindSea(t *testing.T) {
+	swells := []Swell{
+		{Period: 4.0, Direction: 225.0},
+		{Period: 12.0, Direction: 225.0},
+		{Period: 4.0, Direction: 45.0},
+	}
+
+	ClassifyWindSea(swells, 10.0, 220.0)
+	if !swells[0].WindSea || swells[1].WindSea || swells[2].WindSea {
+		t.Errorf("Unexpected wind sea classification %v", swells)
+	}
+
+	swells[0].WindSea = false
+	ClassifyWindSea(swells, MissingValue(), 220.0)
+	if swells[0].WindSea {
+		t.Error("Nothing should be classified without a wind")
+	}
+}
@@ -0,0 +1,726 @@
+package surfnerd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// The postfixes of the realtime files for the data beyond the standard meteorological and wave reports
+const (
+	oceanDataPostfix          = ".ocean"
+	continuousWindDataPostfix = ".cwind"
+	supplementalDataPostfix   = ".supl"
+	solarRadiationDataPostfix = ".srad"
+	currentDataPostfix        = ".adcp"
+	dartDataPostfix           = ".dart"
+	housekeepingDataPostfix   = ".hkp"
+)
+
+// An oceanographic observation from the .ocean realtime file. Temperatures are in degrees C,
+// conductivity in mS/cm, salinity in psu, chlorophyll in ug/l, turbidity in FTU and the redox
+// potential in mV.
+type BuoyOceanItem struct {
+	Date time.Time
+
+	// The depth of the sensors in meters
+	Depth float64
+
+	OceanTemperature    float64
+	Conductivity        float64
+	Salinity            float64
+	OxygenSaturation    float64
+	OxygenConcentration float64
+	Chlorophyll         float64
+	Turbidity           float64
+	PH                  float64
+	RedoxPotential      float64
+}
+
+// A 10 minute wind observation from the .cwind realtime file. The gust is only reported once an
+// hour, for the highest 5 second gust of the hour and the time it happened. Speeds are in m/s.
+type BuoyContinuousWindItem struct {
+	Date time.Time
+
+	WindDirection float64
+	WindSpeed     float64
+	GustDirection float64
+	GustSpeed     float64
+	GustTime      time.Time
+}
+
+// The extremes of the hour from the .supl realtime file, with the times they happened. Pressure is
+// in hPa and wind speed in m/s.
+type BuoySupplementalItem struct {
+	Date time.Time
+
+	MinimumPressure      float64
+	MinimumPressureTime  time.Time
+	MaximumWindSpeed     float64
+	MaximumWindDirection float64
+	MaximumWindTime      time.Time
+}
+
+// A solar radiation observation from the .srad realtime file, in W/m2
+type BuoySolarRadiationItem struct {
+	Date time.Time
+
+	// The shortwave radiation measured by the LI-COR and Eppley PSP pyranometers
+	LICORShortwaveRadiation float64
+	ShortwaveRadiation      float64
+
+	// The longwave radiation measured by the Eppley PIR pyrgeometer
+	LongwaveRadiation float64
+}
+
+// A current profile from the .adcp realtime file, one bin for each depth the ADCP measured
+type BuoyCurrentProfileItem struct {
+	Date time.Time
+	Bins []CurrentBin
+}
+
+// The current at a single depth of a profile. The depth is in meters, the direction in degrees the
+// current is flowing toward and the speed in cm/s.
+type CurrentBin struct {
+	Depth     float64
+	Direction float64
+	Speed     float64
+}
+
+// A water column height from the .dart realtime file, measured by a DART tsunameter on the sea floor
+type BuoyDartItem struct {
+	Date time.Time
+
+	// How the height was measured, 1 for the 15 minute readings of normal reporting and 2 and 3 for
+	// the 1 minute and 15 second readings sent once a tsunami is detected
+	MeasurementType int
+
+	// The height of the water column above the tsunameter in meters
+	WaterColumnHeight float64
+}
+
+// A housekeeping report from the .hkp realtime file, such as battery voltages. The columns vary by
+// station, so the values are kept by their column name.
+type BuoyHousekeepingItem struct {
+	Date   time.Time
+	Values map[string]float64
+}
+
+// Convert a BuoyOceanItem to json, writing missing measurements as null
+func (b BuoyOceanItem) MarshalJSON() ([]byte, error) {
+	type plainItem BuoyOceanItem
+	return json.Marshal(struct {
+		plainItem
+		Depth               nullableFloat
+		OceanTemperature    nullableFloat
+		Conductivity        nullableFloat
+		Salinity            nullableFloat
+		OxygenSaturation    nullableFloat
+		OxygenConcentration nullableFloat
+		Chlorophyll         nullableFloat
+		Turbidity           nullableFloat
+		PH                  nullableFloat
+		RedoxPotential      nullableFloat
+	}{
+		plainItem(b),
+		nullableFloat(b.Depth),
+		nullableFloat(b.OceanTemperature),
+		nullableFloat(b.Conductivity),
+		nullableFloat(b.Salinity),
+		nullableFloat(b.OxygenSaturation),
+		nullableFloat(b.OxygenConcentration),
+		nullableFloat(b.Chlorophyll),
+		nullableFloat(b.Turbidity),
+		nullableFloat(b.PH),
+		nullableFloat(b.RedoxPotential),
+	})
+}
+
+// Read a BuoyOceanItem from json, reading null measurements as missing
+func (b *BuoyOceanItem) UnmarshalJSON(data []byte) error {
+	type plainItem BuoyOceanItem
+	shadow := struct {
+		*plainItem
+		Depth               nullableFloat
+		OceanTemperature    nullableFloat
+		Conductivity        nullableFloat
+		Salinity            nullableFloat
+		OxygenSaturation    nullableFloat
+		OxygenConcentration nullableFloat
+		Chlorophyll         nullableFloat
+		Turbidity           nullableFloat
+		PH                  nullableFloat
+		RedoxPotential      nullableFloat
+	}{
+		plainItem:           (*plainItem)(b),
+		Depth:               nullableFloat(MissingValue()),
+		OceanTemperature:    nullableFloat(MissingValue()),
+		Conductivity:        nullableFloat(MissingValue()),
+		Salinity:            nullableFloat(MissingValue()),
+		OxygenSaturation:    nullableFloat(MissingValue()),
+		OxygenConcentration: nullableFloat(MissingValue()),
+		Chlorophyll:         nullableFloat(MissingValue()),
+		Turbidity:           nullableFloat(MissingValue()),
+		PH:                  nullableFloat(MissingValue()),
+		RedoxPotential:      nullableFloat(MissingValue()),
+	}
+
+	if err := json.Unmarshal(data, &shadow); err != nil {
+		return err
+	}
+
+	b.Depth = float64(shadow.Depth)
+	b.OceanTemperature = float64(shadow.OceanTemperature)
+	b.Conductivity = float64(shadow.Conductivity)
+	b.Salinity = float64(shadow.Salinity)
+	b.OxygenSaturation = float64(shadow.OxygenSaturation)
+	b.OxygenConcentration = float64(shadow.OxygenConcentration)
+	b.Chlorophyll = float64(shadow.Chlorophyll)
+	b.Turbidity = float64(shadow.Turbidity)
+	b.PH = float64(shadow.PH)
+	b.RedoxPotential = float64(shadow.RedoxPotential)
+	return nil
+}
+
+// Convert a BuoyContinuousWindItem to json, writing missing measurements as null
+func (b BuoyContinuousWindItem) MarshalJSON() ([]byte, error) {
+	type plainItem BuoyContinuousWindItem
+	return json.Marshal(struct {
+		plainItem
+		WindDirection nullableFloat
+		WindSpeed     nullableFloat
+		GustDirection nullableFloat
+		GustSpeed     nullableFloat
+	}{
+		plainItem(b),
+		nullableFloat(b.WindDirection),
+		nullableFloat(b.WindSpeed),
+		nullableFloat(b.GustDirection),
+		nullableFloat(b.GustSpeed),
+	})
+}
+
+// Read a BuoyContinuousWindItem from json, reading null measurements as missing
+func (b *BuoyContinuousWindItem) UnmarshalJSON(data []byte) error {
+	type plainItem BuoyContinuousWindItem
+	shadow := struct {
+		*plainItem
+		WindDirection nullableFloat
+		WindSpeed     nullableFloat
+		GustDirection nullableFloat
+		GustSpeed     nullableFloat
+	}{
+		plainItem:     (*plainItem)(b),
+		WindDirection: nullableFloat(MissingValue()),
+		WindSpeed:     nullableFloat(MissingValue()),
+		GustDirection: nullableFloat(MissingValue()),
+		GustSpeed:     nullableFloat(MissingValue()),
+	}
+
+	if err := json.Unmarshal(data, &shadow); err != nil {
+		return err
+	}
+
+	b.WindDirection = float64(shadow.WindDirection)
+	b.WindSpeed = float64(shadow.WindSpeed)
+	b.GustDirection = float64(shadow.GustDirection)
+	b.GustSpeed = float64(shadow.GustSpeed)
+	return nil
+}
+
+// Convert a BuoySupplementalItem to json, writing missing measurements as null
+func (b BuoySupplementalItem) MarshalJSON() ([]byte, error) {
+	type plainItem BuoySupplementalItem
+	return json.Marshal(struct {
+		plainItem
+		MinimumPressure      nullableFloat
+		MaximumWindSpeed     nullableFloat
+		MaximumWindDirection nullableFloat
+	}{
+		plainItem(b),
+		nullableFloat(b.MinimumPressure),
+		nullableFloat(b.MaximumWindSpeed),
+		nullableFloat(b.MaximumWindDirection),
+	})
+}
+
+// Read a BuoySupplementalItem from json, reading null measurements as missing
+func (b *BuoySupplementalItem) UnmarshalJSON(data []byte) error {
+	type plainItem BuoySupplementalItem
+	shadow := struct {
+		*plainItem
+		MinimumPressure      nullableFloat
+		MaximumWindSpeed     nullableFloat
+		MaximumWindDirection nullableFloat
+	}{
+		plainItem:            (*plainItem)(b),
+		MinimumPressure:      nullableFloat(MissingValue()),
+		MaximumWindSpeed:     nullableFloat(MissingValue()),
+		MaximumWindDirection: nullableFloat(MissingValue()),
+	}
+
+	if err := json.Unmarshal(data, &shadow); err != nil {
+		return err
+	}
+
+	b.MinimumPressure = float64(shadow.MinimumPressure)
+	b.MaximumWindSpeed = float64(shadow.MaximumWindSpeed)
+	b.MaximumWindDirection = float64(shadow.MaximumWindDirection)
+	return nil
+}
+
+// Convert a BuoySolarRadiationItem to json, writing missing measurements as null
+func (b BuoySolarRadiationItem) MarshalJSON() ([]byte, error) {
+	type plainItem BuoySolarRadiationItem
+	return json.Marshal(struct {
+		plainItem
+		LICORShortwaveRadiation nullableFloat
+		ShortwaveRadiation      nullableFloat
+		LongwaveRadiation       nullableFloat
+	}{
+		plainItem(b),
+		nullableFloat(b.LICORShortwaveRadiation),
+		nullableFloat(b.ShortwaveRadiation),
+		nullableFloat(b.LongwaveRadiation),
+	})
+}
+
+// Read a BuoySolarRadiationItem from json, reading null measurements as missing
+func (b *BuoySolarRadiationItem) UnmarshalJSON(data []byte) error {
+	type plainItem BuoySolarRadiationItem
+	shadow := struct {
+		*plainItem
+		LICORShortwaveRadiation nullableFloat
+		ShortwaveRadiation      nullableFloat
+		LongwaveRadiation       nullableFloat
+	}{
+		plainItem:               (*plainItem)(b),
+		LICORShortwaveRadiation: nullableFloat(MissingValue()),
+		ShortwaveRadiation:      nullableFloat(MissingValue()),
+		LongwaveRadiation:       nullableFloat(MissingValue()),
+	}
+
+	if err := json.Unmarshal(data, &shadow); err != nil {
+		return err
+	}
+
+	b.LICORShortwaveRadiation = float64(shadow.LICORShortwaveRadiation)
+	b.ShortwaveRadiation = float64(shadow.ShortwaveRadiation)
+	b.LongwaveRadiation = float64(shadow.LongwaveRadiation)
+	return nil
+}
+
+// Convert a CurrentBin to json, writing missing measurements as null
+func (c CurrentBin) MarshalJSON() ([]byte, error) {
+	type plainBin CurrentBin
+	return json.Marshal(struct {
+		plainBin
+		Depth     nullableFloat
+		Direction nullableFloat
+		Speed     nullableFloat
+	}{
+		plainBin(c),
+		nullableFloat(c.Depth),
+		nullableFloat(c.Direction),
+		nullableFloat(c.Speed),
+	})
+}
+
+// Read a CurrentBin from json, reading null measurements as missing
+func (c *CurrentBin) UnmarshalJSON(data []byte) error {
+	type plainBin CurrentBin
+	shadow := struct {
+		*plainBin
+		Depth     nullableFloat
+		Direction nullableFloat
+		Speed     nullableFloat
+	}{
+		plainBin:  (*plainBin)(c),
+		Depth:     nullableFloat(MissingValue()),
+		Direction: nullableFloat(MissingValue()),
+		Speed:     nullableFloat(MissingValue()),
+	}
+
+	if err := json.Unmarshal(data, &shadow); err != nil {
+		return err
+	}
+
+	c.Depth = float64(shadow.Depth)
+	c.Direction = float64(shadow.Direction)
+	c.Speed = float64(shadow.Speed)
+	return nil
+}
+
+// Convert a BuoyDartItem to json, writing a missing height as null
+func (b BuoyDartItem) MarshalJSON() ([]byte, error) {
+	type plainItem BuoyDartItem
+	return json.Marshal(struct {
+		plainItem
+		WaterColumnHeight nullableFloat
+	}{
+		plainItem(b),
+		nullableFloat(b.WaterColumnHeight),
+	})
+}
+
+// Read a BuoyDartItem from json, reading null heights as missing
+func (b *BuoyDartItem) UnmarshalJSON(data []byte) error {
+	type plainItem BuoyDartItem
+	shadow := struct {
+		*plainItem
+		WaterColumnHeight nullableFloat
+	}{
+		plainItem:         (*plainItem)(b),
+		WaterColumnHeight: nullableFloat(MissingValue()),
+	}
+
+	if err := json.Unmarshal(data, &shadow); err != nil {
+		return err
+	}
+
+	b.WaterColumnHeight = float64(shadow.WaterColumnHeight)
+	return nil
+}
+
+// Convert a BuoyHousekeepingItem to json, writing missing values as null
+func (b BuoyHousekeepingItem) MarshalJSON() ([]byte, error) {
+	values := make(map[string]nullableFloat, len(b.Values))
+	for name, value := range b.Values {
+		values[name] = nullableFloat(value)
+	}
+
+	return json.Marshal(struct {
+		Date   time.Time
+		Values map[string]nullableFloat
+	}{b.Date, values})
+}
+
+// Read a BuoyHousekeepingItem from json, reading null values as missing
+func (b *BuoyHousekeepingItem) UnmarshalJSON(data []byte) error {
+	shadow := struct {
+		Date   time.Time
+		Values map[string]nullableFloat
+	}{}
+
+	if err := json.Unmarshal(data, &shadow); err != nil {
+		return err
+	}
+
+	b.Date = shadow.Date
+	b.Values = make(map[string]float64, len(shadow.Values))
+	for name, value := range shadow.Values {
+		b.Values[name] = float64(value)
+	}
+	return nil
+}
+
+// Get the url of the oceanographic data
+func (b Buoy) CreateOceanDataURL() string {
+	return fmt.Sprintf(baseDataURL, NDBCBaseURL, b.StationID, oceanDataPostfix)
+}
+
+// Get the url of the continuous wind data
+func (b Buoy) CreateContinuousWindDataURL() string {
+	return fmt.Sprintf(baseDataURL, NDBCBaseURL, b.StationID, continuousWindDataPostfix)
+}
+
+// Get the url of the supplemental measurements data
+func (b Buoy) CreateSupplementalDataURL() string {
+	return fmt.Sprintf(baseDataURL, NDBCBaseURL, b.StationID, supplementalDataPostfix)
+}
+
+// Get the url of the solar radiation data
+func (b Buoy) CreateSolarRadiationDataURL() string {
+	return fmt.Sprintf(baseDataURL, NDBCBaseURL, b.StationID, solarRadiationDataPostfix)
+}
+
+// Get the url of the ADCP current profile data
+func (b Buoy) CreateCurrentDataURL() string {
+	return fmt.Sprintf(baseDataURL, NDBCBaseURL, b.StationID, currentDataPostfix)
+}
+
+// Get the url of the DART water column height data
+func (b Buoy) CreateDartDataURL() string {
+	return fmt.Sprintf(baseDataURL, NDBCBaseURL, b.StationID, dartDataPostfix)
+}
+
+// Get the url of the housekeeping data
+func (b Buoy) CreateHousekeepingDataURL() string {
+	return fmt.Sprintf(baseDataURL, NDBCBaseURL, b.StationID, housekeepingDataPostfix)
+}
+
+// Read the rows of a realtime NDBC table, newest first as NDBC writes them, passing each complete
+// row to parseRow. Input a negative integer or zero to read every row.
+func parseRealtimeTable(rawData []string, source string, mode ParseMode, dataCountLimit int, parseRow func(table *ndbcTable, row ndbcRow, parser *valueParser)) ([]*ParseError, error) {
+	return readNDBCRows(strings.NewReader(strings.Join(rawData, "\n")), source, mode, dataCountLimit, func(table *ndbcTable, row ndbcRow, parser *valueParser) error {
+		parseRow(table, row, parser)
+		return nil
+	})
+}
+
+// Parses the lines of the .ocean realtime file into OceanData
+func (b *Buoy) ParseRawOceanData(rawData []string, dataCountLimit int) error {
+	oceanData := []BuoyOceanItem{}
+	warnings, parseErr := parseRealtimeTable(rawData, b.CreateOceanDataURL(), b.ParseMode, dataCountLimit, func(table *ndbcTable, row ndbcRow, parser *valueParser) {
+		oceanData = append(oceanData, BuoyOceanItem{
+			Date:                table.date(row, parser),
+			Depth:               table.value(row, parser, "DEPTH"),
+			OceanTemperature:    table.value(row, parser, "OTMP"),
+			Conductivity:        table.value(row, parser, "COND"),
+			Salinity:            table.value(row, parser, "SAL"),
+			OxygenSaturation:    table.value(row, parser, "O2%", "O2PCT"),
+			OxygenConcentration: table.value(row, parser, "O2PPM"),
+			Chlorophyll:         table.value(row, parser, "CLCON"),
+			Turbidity:           table.value(row, parser, "TURB"),
+			PH:                  table.value(row, parser, "PH"),
+			RedoxPotential:      table.value(row, parser, "EH"),
+		})
+	})
+	if parseErr != nil {
+		return parseErr
+	}
+
+	b.OceanData = oceanData
+	b.ParseWarnings = warnings
+	return nil
+}
+
+// Parses the lines of the .cwind realtime file into ContinuousWindData
+func (b *Buoy) ParseRawContinuousWindData(rawData []string, dataCountLimit int) error {
+	windData := []BuoyContinuousWindItem{}
+	warnings, parseErr := parseRealtimeTable(rawData, b.CreateContinuousWindDataURL(), b.ParseMode, dataCountLimit, func(table *ndbcTable, row ndbcRow, parser *valueParser) {
+		item := BuoyContinuousWindItem{Date: table.date(row, parser)}
+		item.WindDirection = table.value(row, parser, "WDIR")
+		item.WindSpeed = table.value(row, parser, "WSPD")
+		item.GustDirection = table.value(row, parser, "GDR")
+		item.GustSpeed = table.value(row, parser, "GST")
+		item.GustTime = table.timeOfDay(row, parser, item.Date, "GTIME")
+		windData = append(windData, item)
+	})
+	if parseErr != nil {
+		return parseErr
+	}
+
+	b.ContinuousWindData = windData
+	b.ParseWarnings = warnings
+	return nil
+}
+
+// Parses the lines of the .supl realtime file into SupplementalData
+func (b *Buoy) ParseRawSupplementalData(rawData []string, dataCountLimit int) error {
+	supplementalData := []BuoySupplementalItem{}
+	warnings, parseErr := parseRealtimeTable(rawData, b.CreateSupplementalDataURL(), b.ParseMode, dataCountLimit, func(table *ndbcTable, row ndbcRow, parser *valueParser) {
+		item := BuoySupplementalItem{Date: table.date(row, parser)}
+		item.MinimumPressure = table.value(row, parser, "PRES")
+		item.MinimumPressureTime = table.timeOfDay(row, parser, item.Date, "PTIME")
+		item.MaximumWindSpeed = table.value(row, parser, "WSPD")
+		item.MaximumWindDirection = table.value(row, parser, "WDIR")
+		item.MaximumWindTime = table.timeOfDay(row, parser, item.Date, "WTIME")
+		supplementalData = append(supplementalData, item)
+	})
+	if parseErr != nil {
+		return parseErr
+	}
+
+	b.SupplementalData = supplementalData
+	b.ParseWarnings = warnings
+	return nil
+}
+
+// Parses the lines of the .srad realtime file into SolarRadiationData
+func (b *Buoy) ParseRawSolarRadiationData(rawData []string, dataCountLimit int) error {
+	radiationData := []BuoySolarRadiationItem{}
+	warnings, parseErr := parseRealtimeTable(rawData, b.CreateSolarRadiationDataURL(), b.ParseMode, dataCountLimit, func(table *ndbcTable, row ndbcRow, parser *valueParser) {
+		radiationData = append(radiationData, BuoySolarRadiationItem{
+			Date:                    table.date(row, parser),
+			LICORShortwaveRadiation: table.value(row, parser, "SRAD1"),
+			ShortwaveRadiation:      table.value(row, parser, "SWRAD"),
+			LongwaveRadiation:       table.value(row, parser, "LWRAD"),
+		})
+	})
+	if parseErr != nil {
+		return parseErr
+	}
+
+	b.SolarRadiationData = radiationData
+	b.ParseWarnings = warnings
+	return nil
+}
+
+// Parses the lines of the .adcp realtime file into CurrentData. Each depth bin has its own DEPnn,
+// DIRnn and SPDnn columns, and bins without a depth are left out of the profile.
+func (b *Buoy) ParseRawCurrentData(rawData []string, dataCountLimit int) error {
+	currentData := []BuoyCurrentProfileItem{}
+	warnings, parseErr := parseRealtimeTable(rawData, b.CreateCurrentDataURL(), b.ParseMode, dataCountLimit, func(table *ndbcTable, row ndbcRow, parser *valueParser) {
+		item := BuoyCurrentProfileItem{Date: table.date(row, parser), Bins: []CurrentBin{}}
+		for _, field := range table.Fields {
+			if !strings.HasPrefix(field, "DEP") {
+				continue
+			}
+
+			bin := strings.TrimPrefix(field, "DEP")
+			depth := table.value(row, parser, field)
+			if IsMissing(depth) {
+				continue
+			}
+
+			item.Bins = append(item.Bins, CurrentBin{
+				Depth:     depth,
+				Direction: table.value(row, parser, "DIR"+bin),
+				Speed:     table.value(row, parser, "SPD"+bin),
+			})
+		}
+		currentData = append(currentData, item)
+	})
+	if parseErr != nil {
+		return parseErr
+	}
+
+	b.CurrentData = currentData
+	b.ParseWarnings = warnings
+	return nil
+}
+
+// Parses the lines of the .dart realtime file into DartData
+func (b *Buoy) ParseRawDartData(rawData []string, dataCountLimit int) error {
+	dartData := []BuoyDartItem{}
+	warnings, parseErr := parseRealtimeTable(rawData, b.CreateDartDataURL(), b.ParseMode, dataCountLimit, func(table *ndbcTable, row ndbcRow, parser *valueParser) {
+		item := BuoyDartItem{Date: table.date(row, parser)}
+		if measurementType := table.value(row, parser, "T"); !IsMissing(measurementType) {
+			item.MeasurementType = int(measurementType)
+		}
+		item.WaterColumnHeight = table.value(row, parser, "HEIGHT")
+		dartData = append(dartData, item)
+	})
+	if parseErr != nil {
+		return parseErr
+	}
+
+	b.DartData = dartData
+	b.ParseWarnings = warnings
+	return nil
+}
+
+// Parses the lines of the .hkp realtime file into HousekeepingData, keeping every column after the
+// date by its name
+func (b *Buoy) ParseRawHousekeepingData(rawData []string, dataCountLimit int) error {
+	housekeepingData := []BuoyHousekeepingItem{}
+	warnings, parseErr := parseRealtimeTable(rawData, b.CreateHousekeepingDataURL(), b.ParseMode, dataCountLimit, func(table *ndbcTable, row ndbcRow, parser *valueParser) {
+		item := BuoyHousekeepingItem{Date: table.date(row, parser), Values: map[string]float64{}}
+		for _, field := range table.Fields[table.dateColumnCount():] {
+			item.Values[field] = table.value(row, parser, field)
+		}
+		housekeepingData = append(housekeepingData, item)
+	})
+	if parseErr != nil {
+		return parseErr
+	}
+
+	b.HousekeepingData = housekeepingData
+	b.ParseWarnings = warnings
+	return nil
+}
+
+// Fetches the oceanographic data such as ocean temperature, salinity and dissolved oxygen. Input a
+// negative integer or zero to download all available data points.
+func (b *Buoy) FetchOceanData(dataCountLimit int) error {
+	return b.FetchOceanDataContext(context.Background(), nil, dataCountLimit)
+}
+
+// Fetches the oceanographic data using the given context and Fetcher. A nil Fetcher uses the DefaultFetcher.
+func (b *Buoy) FetchOceanDataContext(ctx context.Context, fetcher Fetcher, dataCountLimit int) error {
+	rawData, fetchErr := fetchLineDelimitedString(ctx, fetcher, b.CreateOceanDataURL())
+	if fetchErr != nil {
+		return fetchErr
+	}
+	return b.ParseRawOceanData(rawData, dataCountLimit)
+}
+
+// Fetches the 10 minute wind data. Input a negative integer or zero to download all available data points.
+func (b *Buoy) FetchContinuousWindData(dataCountLimit int) error {
+	return b.FetchContinuousWindDataContext(context.Background(), nil, dataCountLimit)
+}
+
+// Fetches the 10 minute wind data using the given context and Fetcher. A nil Fetcher uses the DefaultFetcher.
+func (b *Buoy) FetchContinuousWindDataContext(ctx context.Context, fetcher Fetcher, dataCountLimit int) error {
+	rawData, fetchErr := fetchLineDelimitedString(ctx, fetcher, b.CreateContinuousWindDataURL())
+	if fetchErr != nil {
+		return fetchErr
+	}
+	return b.ParseRawContinuousWindData(rawData, dataCountLimit)
+}
+
+// Fetches the hourly minimum pressure and maximum wind data. Input a negative integer or zero to
+// download all available data points.
+func (b *Buoy) FetchSupplementalData(dataCountLimit int) error {
+	return b.FetchSupplementalDataContext(context.Background(), nil, dataCountLimit)
+}
+
+// Fetches the supplemental data using the given context and Fetcher. A nil Fetcher uses the DefaultFetcher.
+func (b *Buoy) FetchSupplementalDataContext(ctx context.Context, fetcher Fetcher, dataCountLimit int) error {
+	rawData, fetchErr := fetchLineDelimitedString(ctx, fetcher, b.CreateSupplementalDataURL())
+	if fetchErr != nil {
+		return fetchErr
+	}
+	return b.ParseRawSupplementalData(rawData, dataCountLimit)
+}
+
+// Fetches the solar radiation data. Input a negative integer or zero to download all available data points.
+func (b *Buoy) FetchSolarRadiationData(dataCountLimit int) error {
+	return b.FetchSolarRadiationDataContext(context.Background(), nil, dataCountLimit)
+}
+
+// Fetches the solar radiation data using the given context and Fetcher. A nil Fetcher uses the DefaultFetcher.
+func (b *Buoy) FetchSolarRadiationDataContext(ctx context.Context, fetcher Fetcher, dataCountLimit int) error {
+	rawData, fetchErr := fetchLineDelimitedString(ctx, fetcher, b.CreateSolarRadiationDataURL())
+	if fetchErr != nil {
+		return fetchErr
+	}
+	return b.ParseRawSolarRadiationData(rawData, dataCountLimit)
+}
+
+// Fetches the ADCP current profiles of buoys that DoesBuoyHaveWaterCurrentData. Input a negative
+// integer or zero to download all available data points.
+func (b *Buoy) FetchCurrentData(dataCountLimit int) error {
+	return b.FetchCurrentDataContext(context.Background(), nil, dataCountLimit)
+}
+
+// Fetches the current profiles using the given context and Fetcher. A nil Fetcher uses the DefaultFetcher.
+func (b *Buoy) FetchCurrentDataContext(ctx context.Context, fetcher Fetcher, dataCountLimit int) error {
+	rawData, fetchErr := fetchLineDelimitedString(ctx, fetcher, b.CreateCurrentDataURL())
+	if fetchErr != nil {
+		return fetchErr
+	}
+	return b.ParseRawCurrentData(rawData, dataCountLimit)
+}
+
+// Fetches the water column heights of stations that DoesBuoyHaveDartData. Input a negative integer
+// or zero to download all available data points.
+func (b *Buoy) FetchDartData(dataCountLimit int) error {
+	return b.FetchDartDataContext(context.Background(), nil, dataCountLimit)
+}
+
+// Fetches the water column heights using the given context and Fetcher. A nil Fetcher uses the DefaultFetcher.
+func (b *Buoy) FetchDartDataContext(ctx context.Context, fetcher Fetcher, dataCountLimit int) error {
+	rawData, fetchErr := fetchLineDelimitedString(ctx, fetcher, b.CreateDartDataURL())
+	if fetchErr != nil {
+		return fetchErr
+	}
+	return b.ParseRawDartData(rawData, dataCountLimit)
+}
+
+// Fetches the housekeeping data. Input a negative integer or zero to download all available data points.
+func (b *Buoy) FetchHousekeepingData(dataCountLimit int) error {
+	return b.FetchHousekeepingDataContext(context.Background(), nil, dataCountLimit)
+}
+
+// Fetches the housekeeping data using the given context and Fetcher. A nil Fetcher uses the DefaultFetcher.
+func (b *Buoy) FetchHousekeepingDataContext(ctx context.Context, fetcher Fetcher, dataCountLimit int) error {
+	rawData, fetchErr := fetchLineDelimitedString(ctx, fetcher, b.CreateHousekeepingDataURL())
+	if fetchErr != nil {
+		return fetchErr
+	}
+	return b.ParseRawHousekeepingData(rawData, dataCountLimit)
+}
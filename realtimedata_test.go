@@ -0,0 +1,186 @@
+package surfnerd
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testOceanData = `#YY  MM DD hh mm   DEPTH  OTMP   COND   SAL   O2% O2PPM  CLCON  TURB    PH    EH
+#yr  mo dy hr mn       m  degC  mS/cm   psu     %   ppm   ug/l   FTU     -    mv
+2016 10 18 14 00     1.0 16.83  44.12 33.21    MM    MM     MM    MM    MM    MM
+2016 10 18 13 00     1.0 16.80  44.10 33.20    MM    MM     MM    MM    MM    MM
+`
+
+const testContinuousWindData = `#YY  MM DD hh mm WDIR WSPD GDR GST GTIME
+#yr  mo dy hr mn degT m/s degT m/s hhmm
+2016 10 18 14 50 210  6.0 999 99.0 9999
+2016 10 18 14 40 200  6.2 999 99.0 9999
+2016 10 18 00 00 190  5.1 200  8.3 2346
+`
+
+const testSupplementalData = `#YY  MM DD hh mm PRES  PTIME WSPD  WDIR WTIME
+#yr  mo dy hr mn hPa   hhmm  m/s   degT hhmm
+2016 10 18 14 50 1014.8 1420  8.0  210 1435
+`
+
+const testSolarRadiationData = `#YY  MM DD hh mm  SRAD1  SWRAD  LWRAD
+#yr  mo dy hr mn   w/m2   w/m2   w/m2
+2016 10 18 14 50  412.0     MM  361.2
+`
+
+const testCurrentData = `#YY  MM DD hh mm DEP01 DIR01 SPD01 DEP02 DIR02 SPD02 DEP03 DIR03 SPD03
+#yr  mo dy hr mn     m  degT  cm/s     m  degT  cm/s     m  degT  cm/s
+2016 10 18 14 40     2   150    25    10   160    18    MM    MM    MM
+`
+
+const testDartData = `#YY  MM DD hh mm ss T   HEIGHT
+#yr  mo dy hr mn  s -      m
+2016 10 18 14 45 00 1 5862.012
+2016 10 18 14 30 00 1 9999.000
+`
+
+const testHousekeepingData = `#YY  MM DD hh mm BATTV BATTC
+#yr  mo dy hr mn     V     A
+2016 10 18 14 00  13.2    MM
+`
+
+func TestParseRawOceanData(t *testing.T) {
+	buoy := Buoy{StationID: "44017"}
+	if parseErr := buoy.ParseRawOceanData(strings.Split(testOceanData, "\n"), 1); parseErr != nil {
+		t.Fatal(parseErr)
+	}
+
+	if len(buoy.OceanData) != 1 {
+		t.Fatalf("Expected 1 ocean item, got %d", len(buoy.OceanData))
+	}
+	item := buoy.OceanData[0]
+	if item.OceanTemperature != 16.83 || item.Salinity != 33.21 || item.Depth != 1.0 || !IsMissing(item.PH) {
+		t.Errorf("Unexpected ocean item %+v", item)
+	}
+	if !item.Date.Equal(time.Date(2016, 10, 18, 14, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected date %v", item.Date)
+	}
+}
+
+func TestParseRawContinuousWindData(t *testing.T) {
+	buoy := Buoy{StationID: "44017"}
+	if parseErr := buoy.ParseRawContinuousWindData(strings.Split(testContinuousWindData, "\n"), -1); parseErr != nil {
+		t.Fatal(parseErr)
+	}
+
+	if len(buoy.ContinuousWindData) != 3 {
+		t.Fatalf("Expected 3 wind items, got %d", len(buoy.ContinuousWindData))
+	}
+	if first := buoy.ContinuousWindData[0]; first.WindSpeed != 6.0 || !IsMissing(first.GustSpeed) || !first.GustTime.IsZero() {
+		t.Errorf("Expected the gust to be missing between the hourly reports, got %+v", first)
+	}
+
+	// The gust of the hour ending at midnight happened the day before
+	hourly := buoy.ContinuousWindData[2]
+	if hourly.GustSpeed != 8.3 || hourly.GustDirection != 200 {
+		t.Errorf("Unexpected hourly gust %+v", hourly)
+	}
+	if !hourly.GustTime.Equal(time.Date(2016, 10, 17, 23, 46, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected gust time %v", hourly.GustTime)
+	}
+}
+
+func TestParseRawSupplementalAndSolarData(t *testing.T) {
+	buoy := Buoy{StationID: "44017"}
+	if parseErr := buoy.ParseRawSupplementalData(strings.Split(testSupplementalData, "\n"), -1); parseErr != nil {
+		t.Fatal(parseErr)
+	}
+	supplemental := buoy.SupplementalData[0]
+	if supplemental.MinimumPressure != 1014.8 || supplemental.MaximumWindSpeed != 8.0 || supplemental.MaximumWindDirection != 210 {
+		t.Errorf("Unexpected supplemental item %+v", supplemental)
+	}
+	if !supplemental.MinimumPressureTime.Equal(time.Date(2016, 10, 18, 14, 20, 0, 0, time.UTC)) || !supplemental.MaximumWindTime.Equal(time.Date(2016, 10, 18, 14, 35, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected extreme times %+v", supplemental)
+	}
+
+	if parseErr := buoy.ParseRawSolarRadiationData(strings.Split(testSolarRadiationData, "\n"), -1); parseErr != nil {
+		t.Fatal(parseErr)
+	}
+	if radiation := buoy.SolarRadiationData[0]; radiation.LICORShortwaveRadiation != 412.0 || !IsMissing(radiation.ShortwaveRadiation) || radiation.LongwaveRadiation != 361.2 {
+		t.Errorf("Unexpected radiation item %+v", radiation)
+	}
+}
+
+func TestParseRawCurrentData(t *testing.T) {
+	buoy := Buoy{StationID: "44017"}
+	if parseErr := buoy.ParseRawCurrentData(strings.Split(testCurrentData, "\n"), -1); parseErr != nil {
+		t.Fatal(parseErr)
+	}
+
+	bins := buoy.CurrentData[0].Bins
+	if len(bins) != 2 {
+		t.Fatalf("Expected the bin without a depth to be left out, got %d bins", len(bins))
+	}
+	if bins[1].Depth != 10 || bins[1].Direction != 160 || bins[1].Speed != 18 {
+		t.Errorf("Unexpected bin %+v", bins[1])
+	}
+}
+
+func TestParseRawDartData(t *testing.T) {
+	buoy := Buoy{StationID: "21413"}
+	if parseErr := buoy.ParseRawDartData(strings.Split(testDartData, "\n"), -1); parseErr != nil {
+		t.Fatal(parseErr)
+	}
+
+	if len(buoy.DartData) != 2 {
+		t.Fatalf("Expected 2 dart items, got %d", len(buoy.DartData))
+	}
+	if first := buoy.DartData[0]; first.WaterColumnHeight != 5862.012 || first.MeasurementType != 1 {
+		t.Errorf("Unexpected dart item %+v", first)
+	}
+	if !IsMissing(buoy.DartData[1].WaterColumnHeight) {
+		t.Error("Expected the 9999 height to be missing")
+	}
+}
+
+func TestParseRawHousekeepingData(t *testing.T) {
+	buoy := Buoy{StationID: "44017"}
+	if parseErr := buoy.ParseRawHousekeepingData(strings.Split(testHousekeepingData, "\n"), -1); parseErr != nil {
+		t.Fatal(parseErr)
+	}
+
+	values := buoy.HousekeepingData[0].Values
+	if len(values) != 2 || values["BATTV"] != 13.2 || !IsMissing(values["BATTC"]) {
+		t.Errorf("Unexpected housekeeping values %v", values)
+	}
+
+	// Missing values are written as null and read back as missing
+	rawJSON, jsonErr := json.Marshal(buoy.HousekeepingData[0])
+	if jsonErr != nil {
+		t.Fatal(jsonErr)
+	}
+	decoded := BuoyHousekeepingItem{}
+	if jsonErr := json.Unmarshal(rawJSON, &decoded); jsonErr != nil {
+		t.Fatal(jsonErr)
+	}
+	if decoded.Values["BATTV"] != 13.2 || !IsMissing(decoded.Values["BATTC"]) {
+		t.Errorf("Housekeeping values did not survive json, got %v", decoded.Values)
+	}
+}
+
+func TestRealtimeDataJSON(t *testing.T) {
+	buoy := Buoy{StationID: "44017"}
+	if parseErr := buoy.ParseRawContinuousWindData(strings.Split(testContinuousWindData, "\n"), -1); parseErr != nil {
+		t.Fatal(parseErr)
+	}
+
+	rawJSON, jsonErr := buoy.ToJSON()
+	if jsonErr != nil {
+		t.Fatal(jsonErr)
+	}
+
+	decoded := Buoy{}
+	if jsonErr := json.Unmarshal(rawJSON, &decoded); jsonErr != nil {
+		t.Fatal(jsonErr)
+	}
+	if len(decoded.ContinuousWindData) != 3 || !IsMissing(decoded.ContinuousWindData[0].GustSpeed) || decoded.ContinuousWindData[2].GustSpeed != 8.3 {
+		t.Errorf("Continuous winds did not survive json, got %+v", decoded.ContinuousWindData)
+	}
+}
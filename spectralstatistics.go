@@ -0,0 +1,304 @@
+package surfnerd
+
+import (
+	"math"
+)
+
+// Get the width of each frequency band
+func (b BuoySpectraItem) Bandwidths() []float64 {
+	return frequencyBandwidths(b.Frequencies)
+}
+
+// Get the nth spectral moment, the sum of E(f) * f^n * df over the frequency bands as defined at
+// http://www.ndbc.noaa.gov/algor.shtml. Bands with missing energies are left out, and negative moments
+// are allowed, such as the -1 moment used for the energy period.
+func (b BuoySpectraItem) SpectralMoments(n float64) float64 {
+	return b.spectralMomentInRange(n, math.Inf(-1), math.Inf(1))
+}
+
+// Get the nth spectral moment of the bands with frequencies from lower up to but not including upper
+func (b BuoySpectraItem) spectralMomentInRange(n, lower, upper float64) float64 {
+	if len(b.Frequencies) == 0 || len(b.Energies) != len(b.Frequencies) {
+		return MissingValue()
+	}
+
+	bandwidths := b.Bandwidths()
+	moment := 0.0
+	counted := false
+	for i, frequency := range b.Frequencies {
+		if IsMissing(b.Energies[i]) || frequency < lower || frequency >= upper {
+			continue
+		}
+		moment += b.Energies[i] * math.Pow(frequency, n) * bandwidths[i]
+		counted = true
+	}
+
+	if !counted {
+		return MissingValue()
+	}
+	return moment
+}
+
+// Get the significant wave height Hm0 = 4 * sqrt(m0)
+func (b BuoySpectraItem) SignificantWaveHeight() float64 {
+	return 4.0 * math.Sqrt(b.SpectralMoments(0))
+}
+
+// Get the mean period Tm01 = m0 / m1
+func (b BuoySpectraItem) MeanPeriod() float64 {
+	return positiveRatio(b.SpectralMoments(0), b.SpectralMoments(1))
+}
+
+// Get the zero crossing period Tm02 = sqrt(m0 / m2)
+func (b BuoySpectraItem) ZeroCrossingPeriod() float64 {
+	return math.Sqrt(positiveRatio(b.SpectralMoments(0), b.SpectralMoments(2)))
+}
+
+// Get the energy period Tm-10 = m-1 / m0, used for wave power
+func (b BuoySpectraItem) EnergyPeriod() float64 {
+	return positiveRatio(b.SpectralMoments(-1), b.SpectralMoments(0))
+}
+
+// Get the peak period. The peak frequency is refined by fitting a parabola through the most energetic
+// band and its two neighbours, so the period is not limited to the center of a band.
+func (b BuoySpectraItem) PeakPeriod() float64 {
+	peakFrequency := b.peakFrequencyInRange(math.Inf(-1), math.Inf(1))
+	if IsMissing(peakFrequency) || peakFrequency <= 0 {
+		return MissingValue()
+	}
+	return 1.0 / peakFrequency
+}
+
+// Get the spectral width ε = sqrt(1 - m2^2 / (m0 * m4)) of Cartwright and Longuet-Higgins (1956).
+// It is 0 for a single frequency and approaches 1 for a broad spectrum.
+func (b BuoySpectraItem) SpectralWidth() float64 {
+	m0, m2, m4 := b.SpectralMoments(0), b.SpectralMoments(2), b.SpectralMoments(4)
+	return math.Sqrt(math.Max(0, 1.0-positiveRatio(m2*m2, m0*m4)))
+}
+
+// Get the spectral narrowness ν = sqrt(m0 * m2 / m1^2 - 1) of Longuet-Higgins (1975). It is 0 for a
+// single frequency and grows as the spectrum broadens.
+func (b BuoySpectraItem) SpectralNarrowness() float64 {
+	m0, m1, m2 := b.SpectralMoments(0), b.SpectralMoments(1), b.SpectralMoments(2)
+	return math.Sqrt(math.Max(0, positiveRatio(m0*m2, m1*m1)-1.0))
+}
+
+// Get Goda's peakedness Qp = 2 / m0^2 * sum(f * E(f)^2 * df). It is 1 for a white spectrum, about 2
+// for a Pierson-Moskowitz spectrum and higher for the peaked spectra of long period swell.
+func (b BuoySpectraItem) Peakedness() float64 {
+	m0 := b.SpectralMoments(0)
+	if IsMissing(m0) || m0 <= 0 {
+		return MissingValue()
+	}
+
+	bandwidths := b.Bandwidths()
+	sum := 0.0
+	for i, frequency := range b.Frequencies {
+		if IsMissing(b.Energies[i]) {
+			continue
+		}
+		sum += frequency * b.Energies[i] * b.Energies[i] * bandwidths[i]
+	}
+	return 2.0 * sum / (m0 * m0)
+}
+
+// Get the directional spread of each frequency band in degrees, sqrt(2 * (1 - r1)) from Kuik et al.
+// (1988). Bands are missing when the spectra has no r1 coefficients.
+func (b BuoySpectraItem) DirectionalSpreads() []float64 {
+	spreads := make([]float64, len(b.Frequencies))
+	for i := range spreads {
+		spreads[i] = MissingValue()
+		if len(b.R1) == len(b.Frequencies) && !IsMissing(b.R1[i]) {
+			spreads[i] = circularSpread(b.R1[i])
+		}
+	}
+	return spreads
+}
+
+// Get the mean wave direction over the whole spectra in degrees, the direction waves come from. The
+// mean direction of each band is weighted by its energy and, when available, its r1 coefficient.
+func (b BuoySpectraItem) MeanDirection() float64 {
+	direction, _ := b.directionalMomentsInRange(math.Inf(-1), math.Inf(1))
+	return direction
+}
+
+// Get the directional spread over the whole spectra in degrees. It is missing when the spectra has no
+// r1 coefficients, since the mean directions alone can not describe the spread within each band.
+func (b BuoySpectraItem) DirectionalSpread() float64 {
+	_, spread := b.directionalMomentsInRange(math.Inf(-1), math.Inf(1))
+	return spread
+}
+
+// Split the spectra at the seperation frequency into its swell and wind wave components, as NDBC does
+// for the detailed wave summary. Each has its height, peak period, mean period, and the direction of
+// its peak band. The wind wave is marked as wind sea.
+func (b BuoySpectraItem) SplitAtSeperationFrequency() (swell, windWave Swell) {
+	if IsMissing(b.SeperationFrequency) {
+		return Swell{WaveHeight: MissingValue()}, Swell{WaveHeight: MissingValue()}
+	}
+
+	swell = b.swellInRange(math.Inf(-1), b.SeperationFrequency)
+	windWave = b.swellInRange(b.SeperationFrequency, math.Inf(1))
+	windWave.WindSea = true
+	return
+}
+
+// Compute the integrated parameters of the bands with frequencies from lower up to but not including upper
+func (b BuoySpectraItem) swellInRange(lower, upper float64) Swell {
+	swell := Swell{Units: Metric}
+
+	m0 := b.spectralMomentInRange(0, lower, upper)
+	swell.WaveHeight = 4.0 * math.Sqrt(m0)
+	swell.MeanPeriod = positiveRatio(m0, b.spectralMomentInRange(1, lower, upper))
+	swell.Period = MissingValue()
+	swell.Direction = MissingValue()
+
+	peakIndex := b.peakIndexInRange(lower, upper)
+	if peakIndex >= 0 {
+		swell.Period = 1.0 / b.Frequencies[peakIndex]
+		swell.MaxEnergy = b.Energies[peakIndex]
+		swell.FrequencyIndex = peakIndex
+		if len(b.Angles) == len(b.Frequencies) {
+			swell.Direction = b.Angles[peakIndex]
+		}
+	}
+
+	_, swell.DirectionalSpread = b.directionalMomentsInRange(lower, upper)
+	swell.CompassDirection = DegreeToDirection(swell.Direction)
+	return swell
+}
+
+// Get the index of the most energetic band in the frequency range, or -1 if it has no energy
+func (b BuoySpectraItem) peakIndexInRange(lower, upper float64) int {
+	if len(b.Energies) != len(b.Frequencies) {
+		return -1
+	}
+
+	peakIndex := -1
+	for i, frequency := range b.Frequencies {
+		if IsMissing(b.Energies[i]) || frequency < lower || frequency >= upper {
+			continue
+		} else if peakIndex < 0 || b.Energies[i] > b.Energies[peakIndex] {
+			peakIndex = i
+		}
+	}
+
+	if peakIndex >= 0 && b.Energies[peakIndex] <= 0 {
+		return -1
+	}
+	return peakIndex
+}
+
+// Get the peak frequency of the frequency range, refined with a parabola through the peak band and
+// its neighbours when they are all known
+func (b BuoySpectraItem) peakFrequencyInRange(lower, upper float64) float64 {
+	peakIndex := b.peakIndexInRange(lower, upper)
+	if peakIndex < 0 {
+		return MissingValue()
+	} else if peakIndex == 0 || peakIndex == len(b.Frequencies)-1 {
+		return b.Frequencies[peakIndex]
+	} else if IsMissing(b.Energies[peakIndex-1]) || IsMissing(b.Energies[peakIndex+1]) {
+		return b.Frequencies[peakIndex]
+	}
+
+	return parabolicPeak(
+		b.Frequencies[peakIndex-1], b.Energies[peakIndex-1],
+		b.Frequencies[peakIndex], b.Energies[peakIndex],
+		b.Frequencies[peakIndex+1], b.Energies[peakIndex+1],
+	)
+}
+
+// Compute the energy weighted mean direction and the directional spread of the frequency range. The
+// bands are only weighted by r1 when every band in the range has it, and the spread is missing otherwise.
+func (b BuoySpectraItem) directionalMomentsInRange(lower, upper float64) (direction, spread float64) {
+	if len(b.Angles) != len(b.Frequencies) || len(b.Energies) != len(b.Frequencies) {
+		return MissingValue(), MissingValue()
+	}
+
+	inRange := func(i int) bool {
+		frequency := b.Frequencies[i]
+		return !IsMissing(b.Energies[i]) && !IsMissing(b.Angles[i]) && frequency >= lower && frequency < upper
+	}
+
+	hasR1 := len(b.R1) == len(b.Frequencies)
+	for i := range b.Frequencies {
+		if hasR1 && inRange(i) && IsMissing(b.R1[i]) {
+			hasR1 = false
+		}
+	}
+
+	bandwidths := b.Bandwidths()
+	eastward, northward, total := 0.0, 0.0, 0.0
+	for i := range b.Frequencies {
+		if !inRange(i) {
+			continue
+		}
+
+		weight := b.Energies[i] * bandwidths[i]
+		r1 := 1.0
+		if hasR1 {
+			r1 = b.R1[i]
+		}
+
+		theta := b.Angles[i] * math.Pi / 180.0
+		eastward += weight * r1 * math.Sin(theta)
+		northward += weight * r1 * math.Cos(theta)
+		total += weight
+	}
+
+	if total <= 0 {
+		return MissingValue(), MissingValue()
+	}
+
+	direction = math.Mod(math.Atan2(eastward, northward)*180.0/math.Pi+360.0, 360.0)
+	spread = MissingValue()
+	if hasR1 {
+		spread = circularSpread(math.Hypot(eastward, northward) / total)
+	}
+	return
+}
+
+// Convert a first Fourier coefficient r1 to a directional spread in degrees
+func circularSpread(r1 float64) float64 {
+	return math.Sqrt(2.0*(1.0-math.Min(r1, 1.0))) * 180.0 / math.Pi
+}
+
+// Find the x of the vertex of the parabola through three points. Falls back to the middle point when
+// the points do not make a downward parabola.
+func parabolicPeak(x0, y0, x1, y1, x2, y2 float64) float64 {
+	denominator := (x0 - x1) * (x0 - x2) * (x1 - x2)
+	a := (x2*(y1-y0) + x1*(y0-y2) + x0*(y2-y1)) / denominator
+	if denominator == 0 || a >= 0 {
+		return x1
+	}
+
+	b := (x2*x2*(y0-y1) + x1*x1*(y2-y0) + x0*x0*(y1-y2)) / denominator
+	vertex := -b / (2.0 * a)
+	if vertex < x0 || vertex > x2 {
+		return x1
+	}
+	return vertex
+}
+
+// Divide two positive values, returning missing if either is missing or not positive
+func positiveRatio(numerator, denominator float64) float64 {
+	if IsMissing(numerator) || IsMissing(denominator) || numerator <= 0 || denominator <= 0 {
+		return MissingValue()
+	}
+	return numerator / denominator
+}
+
+// Get the width of each frequency band, using the spacing to the previous band and the spacing to
+// the next band for the first one
+func frequencyBandwidths(frequencies []float64) []float64 {
+	bandwidths := make([]float64, len(frequencies))
+	for index := range frequencies {
+		bandwidths[index] = 0.01
+		if index > 0 {
+			bandwidths[index] = math.Abs(frequencies[index] - frequencies[index-1])
+		} else if len(frequencies) > 1 {
+			bandwidths[index] = math.Abs(frequencies[index+1] - frequencies[index])
+		}
+	}
+	return bandwidths
+}
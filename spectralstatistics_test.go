@@ -0,0 +1,183 @@
+package surfnerd
+
+import (
+	"math"
+	"testing"
+)
+
+// Build a spectra with evenly spaced bands from 0.01 to 0.5 Hz and the given band energies
+func evenSpectra(energies map[int]float64) BuoySpectraItem {
+	spectra := BuoySpectraItem{SeperationFrequency: MissingValue()}
+	for i := 1; i <= 50; i++ {
+		spectra.Frequencies = append(spectra.Frequencies, float64(i)*0.01)
+		spectra.Energies = append(spectra.Energies, energies[i])
+		spectra.Angles = append(spectra.Angles, 180.0)
+	}
+	return spectra
+}
+
+func closeTo(value, expected, tolerance float64) bool {
+	return math.Abs(value-expected) <= tolerance
+}
+
+func TestSpectralMoments(t *testing.T) {
+	spectra := evenSpectra(map[int]float64{10: 4.0})
+
+	if m0 := spectra.SpectralMoments(0); !closeTo(m0, 0.04, 1e-12) {
+		t.Errorf("Expected m0 of 0.04, got %v", m0)
+	}
+	if hs := spectra.SignificantWaveHeight(); !closeTo(hs, 0.8, 1e-12) {
+		t.Errorf("Expected Hm0 of 0.8, got %v", hs)
+	}
+
+	// A single band has every period equal to the band period and no width
+	for name, period := range map[string]float64{
+		"Tm01":  spectra.MeanPeriod(),
+		"Tm02":  spectra.ZeroCrossingPeriod(),
+		"Tm-10": spectra.EnergyPeriod(),
+		"Tp":    spectra.PeakPeriod(),
+	} {
+		if !closeTo(period, 10.0, 1e-9) {
+			t.Errorf("Expected %s of 10, got %v", name, period)
+		}
+	}
+	if width := spectra.SpectralWidth(); !closeTo(width, 0, 1e-6) {
+		t.Errorf("Expected no spectral width, got %v", width)
+	}
+	if narrowness := spectra.SpectralNarrowness(); !closeTo(narrowness, 0, 1e-6) {
+		t.Errorf("Expected no spectral narrowness, got %v", narrowness)
+	}
+
+	empty := evenSpectra(nil)
+	empty.Energies[0] = MissingValue()
+	if !IsMissing(empty.MeanPeriod()) || !IsMissing(empty.PeakPeriod()) {
+		t.Error("Expected the periods of an empty spectra to be missing")
+	}
+}
+
+func TestParabolicPeakPeriod(t *testing.T) {
+	spectra := evenSpectra(map[int]float64{9: 1.0, 10: 3.0, 11: 2.0})
+
+	// The parabola through the three bands peaks a sixth of a band above 0.1 Hz
+	if tp := spectra.PeakPeriod(); !closeTo(tp, 1.0/(0.1+0.01/6.0), 1e-9) {
+		t.Errorf("Unexpected peak period %v", tp)
+	}
+}
+
+func TestPeakedness(t *testing.T) {
+	white := map[int]float64{}
+	for i := 1; i <= 50; i++ {
+		white[i] = 1.0
+	}
+
+	if qp := evenSpectra(white).Peakedness(); !closeTo(qp, 1.0, 0.03) {
+		t.Errorf("Expected a white spectrum to have a peakedness of 1, got %v", qp)
+	}
+	if qp := evenSpectra(map[int]float64{10: 1.0, 11: 1.0}).Peakedness(); qp < 5 {
+		t.Errorf("Expected a narrow spectrum to be peaked, got %v", qp)
+	}
+}
+
+func TestSpectralDirections(t *testing.T) {
+	spectra := evenSpectra(map[int]float64{10: 1.0, 20: 1.0})
+	spectra.Angles[9] = 80.0
+	spectra.Angles[19] = 100.0
+
+	if direction := spectra.MeanDirection(); !closeTo(direction, 90.0, 1e-9) {
+		t.Errorf("Expected a mean direction of 90, got %v", direction)
+	}
+	if !IsMissing(spectra.DirectionalSpread()) {
+		t.Error("Expected the spread to be missing without r1")
+	}
+
+	spectra.R1 = make([]float64, len(spectra.Frequencies))
+	for i := range spectra.R1 {
+		spectra.R1[i] = 1.0
+	}
+	if spread := spectra.DirectionalSpread(); !closeTo(spread, math.Sqrt(2.0*(1.0-math.Cos(10.0*math.Pi/180.0)))*180.0/math.Pi, 1e-9) {
+		t.Errorf("Unexpected directional spread %v", spread)
+	}
+	if spreads := spectra.DirectionalSpreads(); spreads[9] != 0 {
+		t.Errorf("Expected a band with an r1 of 1 to have no spread, got %v", spreads[9])
+	}
+}
+
+func TestSplitAtSeperationFrequency(t *testing.T) {
+	spectra := evenSpectra(map[int]float64{8: 4.0, 20: 1.0})
+	spectra.Angles[19] = 225.0
+	spectra.SeperationFrequency = 0.1
+
+	swell, windWave := spectra.SplitAtSeperationFrequency()
+	if !closeTo(swell.WaveHeight, 0.8, 1e-12) || !closeTo(swell.Period, 12.5, 1e-12) || swell.WindSea {
+		t.Errorf("Unexpected swell %+v", swell)
+	}
+	if !closeTo(windWave.WaveHeight, 0.4, 1e-12) || !closeTo(windWave.Period, 5.0, 1e-12) || windWave.Direction != 225.0 || !windWave.WindSea {
+		t.Errorf("Unexpected wind wave %+v", windWave)
+	}
+
+	total := spectra.SignificantWaveHeight()
+	if !closeTo(total*total, swell.WaveHeight*swell.WaveHeight+windWave.WaveHeight*windWave.WaveHeight, 1e-12) {
+		t.Error("Expected the split to hold all of the energy")
+	}
+}
+
+// A swell spectrum on the 0.005 Hz bands of the NDBC directional buoys. The expected values were worked
+// by hand from the definitions at http://www.ndbc.noaa.gov/algor.shtml.
+func algorSpectra() BuoySpectraItem {
+	return BuoySpectraItem{
+		SeperationFrequency: MissingValue(),
+		Frequencies:         []float64{0.0800, 0.0850, 0.0900, 0.0950, 0.1000, 0.1050, 0.1100},
+		Energies:            []float64{0.5, 1.5, 3.0, 4.0, 2.5, 1.0, 0.5},
+		Angles:              []float64{200, 195, 190, 185, 180, 170, 160},
+		R1:                  []float64{0.60, 0.70, 0.80, 0.85, 0.80, 0.70, 0.60},
+	}
+}
+
+func TestAlgorSpectralParameters(t *testing.T) {
+	spectra := algorSpectra()
+
+	for name, check := range map[string][2]float64{
+		"m0":    {spectra.SpectralMoments(0), 0.065},
+		"m1":    {spectra.SpectralMoments(1), 0.0061375},
+		"m2":    {spectra.SpectralMoments(2), 0.0005825625},
+		"Hm0":   {spectra.SignificantWaveHeight(), 1.0198039027},
+		"Tm01":  {spectra.MeanPeriod(), 10.5906313646},
+		"Tm02":  {spectra.ZeroCrossingPeriod(), 10.5629546604},
+		"Tm-10": {spectra.EnergyPeriod(), 10.6465322603},
+	} {
+		if !closeTo(check[0], check[1], 1e-9) {
+			t.Errorf("Expected %s of %v, got %v", name, check[1], check[0])
+		}
+	}
+
+	// The parabola through 3.0, 4.0 and 2.5 peaks a tenth of a band below 0.095 Hz
+	if tp := spectra.PeakPeriod(); !closeTo(tp, 1.0/0.0945, 1e-9) {
+		t.Errorf("Expected Tp of %v, got %v", 1.0/0.0945, tp)
+	}
+	if direction := spectra.MeanDirection(); !closeTo(direction, 184.9252388656, 1e-9) {
+		t.Errorf("Expected a mean direction of 184.93, got %v", direction)
+	}
+	if spread := spectra.DirectionalSpread(); !closeTo(spread, 38.5654945386, 1e-9) {
+		t.Errorf("Expected a directional spread of 38.57, got %v", spread)
+	}
+	if spreads := spectra.DirectionalSpreads(); !closeTo(spreads[3], 31.3821908892, 1e-9) {
+		t.Errorf("Expected the peak band to spread 31.38, got %v", spreads[3])
+	}
+}
+
+func TestMeanDirectionWithMissingR1(t *testing.T) {
+	withoutR1 := algorSpectra()
+	withoutR1.R1 = nil
+
+	// A single band without r1 leaves every band unweighted by r1, wherever it falls in the spectra
+	for _, missing := range []int{0, 3, 6} {
+		spectra := algorSpectra()
+		spectra.R1[missing] = MissingValue()
+		if direction := spectra.MeanDirection(); !closeTo(direction, withoutR1.MeanDirection(), 1e-9) {
+			t.Errorf("Expected a missing r1 at %d to give the unweighted direction %v, got %v", missing, withoutR1.MeanDirection(), direction)
+		}
+		if !IsMissing(spectra.DirectionalSpread()) {
+			t.Errorf("Expected the spread to be missing with a missing r1 at %d", missing)
+		}
+	}
+}
@@ -0,0 +1,350 @@
+package surfnerd
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How long the DefaultStationCatalog uses the active station list before checking it for changes
+const defaultStationCatalogTTL = time.Hour
+
+// The catalog used by GetBuoyByID. It keeps the station list in memory and revalidates it hourly.
+var DefaultStationCatalog = NewStationCatalog("", defaultStationCatalogTTL)
+
+// A cached copy of the NDBC active station list. The list is downloaded the first time it is needed and
+// reused until it is older than the TTL, then revalidated with If-None-Match and If-Modified-Since when
+// the Fetcher is a ConditionalFetcher, or downloaded again otherwise. When CachePath is set the list is
+// also kept on disk, so it survives between runs of a program. Lookups keep serving a cached list when
+// revalidating it fails, with the failure reported by LastRefreshError.
+//
+// A StationCatalog is safe for concurrent use.
+type StationCatalog struct {
+	// The file the list is cached in. Empty keeps the list in memory only.
+	CachePath string
+
+	// How long a list is used before it is revalidated. Zero or less revalidates on every lookup.
+	TTL time.Duration
+
+	lock       sync.Mutex
+	cache      *stationCatalogCache
+	stations   *BuoyStations
+	byID       map[string]*Buoy
+	refreshErr error
+}
+
+// The contents of the cache file
+type stationCatalogCache struct {
+	URL        string          `json:"url"`
+	FetchedAt  time.Time       `json:"fetchedAt"`
+	Validators CacheValidators `json:"validators"`
+	Data       []byte          `json:"data"`
+}
+
+// The differences between two snapshots of the station list
+type StationDiff struct {
+	Added   []*Buoy
+	Removed []*Buoy
+	Changed []StationChange
+}
+
+// A station that is in both snapshots but whose attributes differ
+type StationChange struct {
+	StationID string
+	Old       *Buoy
+	New       *Buoy
+
+	// The names of the changed attributes as they appear in activestations.xml, such as met or lat
+	Fields []string
+}
+
+// Create a new catalog cached at the given path, which may be empty to keep it in memory only
+func NewStationCatalog(cachePath string, ttl time.Duration) *StationCatalog {
+	return &StationCatalog{CachePath: cachePath, TTL: ttl}
+}
+
+// Checks if the two snapshots have no differences
+func (s StationDiff) IsEmpty() bool {
+	return len(s.Added) == 0 && len(s.Removed) == 0 && len(s.Changed) == 0
+}
+
+// Get the station list, refreshing it if it is missing or older than the TTL. The returned stations
+// are shared with the catalog and must not be modified.
+func (c *StationCatalog) Stations() (*BuoyStations, error) {
+	return c.StationsContext(context.Background(), nil)
+}
+
+// Get the station list using the given context and Fetcher. A nil Fetcher uses the DefaultFetcher.
+// If a refresh fails the older list is returned when one is cached, and the error otherwise.
+func (c *StationCatalog) StationsContext(ctx context.Context, fetcher Fetcher) (*BuoyStations, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if refreshErr := c.refreshForLookup(ctx, fetcher); refreshErr != nil {
+		return nil, refreshErr
+	}
+	return c.stations, nil
+}
+
+// Get the error of the last refresh of the list, or nil if it succeeded. Lookups serve the cached list
+// when a refresh fails, so this is how a caller finds out the list could not be revalidated.
+func (c *StationCatalog) LastRefreshError() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.refreshErr
+}
+
+// Find a station by its id, refreshing the list first if it is stale. The returned buoy is a copy that
+// the caller is free to modify. The buoy and error are both nil when the station is not in the list.
+func (c *StationCatalog) FindBuoyByID(stationID string) (*Buoy, error) {
+	return c.FindBuoyByIDContext(context.Background(), nil, stationID)
+}
+
+// Find a station by its id using the given context and Fetcher. A nil Fetcher uses the DefaultFetcher.
+func (c *StationCatalog) FindBuoyByIDContext(ctx context.Context, fetcher Fetcher, stationID string) (*Buoy, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if refreshErr := c.refreshForLookup(ctx, fetcher); refreshErr != nil {
+		return nil, refreshErr
+	}
+
+	buoy, ok := c.byID[strings.ToLower(stationID)]
+	if !ok {
+		return nil, nil
+	}
+	return buoy.copyStation(), nil
+}
+
+// Revalidate the station list now, whatever its age, and report how it changed
+func (c *StationCatalog) Refresh() (StationDiff, error) {
+	return c.RefreshContext(context.Background(), nil)
+}
+
+// Revalidate the station list now using the given context and Fetcher. A nil Fetcher uses the DefaultFetcher.
+func (c *StationCatalog) RefreshContext(ctx context.Context, fetcher Fetcher) (StationDiff, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	// Load the cache file first so the diff is against the last known list
+	if loadErr := c.loadIfNeeded(); loadErr != nil {
+		return StationDiff{}, loadErr
+	}
+
+	previous := c.stations
+	c.refreshErr = c.refreshIfStale(ctx, fetcher, true)
+	if c.refreshErr != nil {
+		return StationDiff{}, c.refreshErr
+	}
+	return DiffStations(previous, c.stations), nil
+}
+
+// Refresh a stale list before a lookup. A failed refresh is recorded for LastRefreshError, and only
+// returned when there is no list cached for the current url to serve in its place.
+func (c *StationCatalog) refreshForLookup(ctx context.Context, fetcher Fetcher) error {
+	c.refreshErr = c.refreshIfStale(ctx, fetcher, false)
+	if c.refreshErr != nil && c.stations != nil && c.cache != nil && c.cache.URL == ActiveBuoysURL {
+		return nil
+	}
+	return c.refreshErr
+}
+
+// Refresh the list if there is none, it came from a different url, or it is older than the TTL
+func (c *StationCatalog) refreshIfStale(ctx context.Context, fetcher Fetcher, force bool) error {
+	if loadErr := c.loadIfNeeded(); loadErr != nil {
+		return loadErr
+	}
+
+	if !force && c.cache != nil && c.cache.URL == ActiveBuoysURL && time.Since(c.cache.FetchedAt) < c.TTL {
+		return nil
+	}
+
+	validators := CacheValidators{}
+	if c.cache != nil && c.cache.URL == ActiveBuoysURL {
+		validators = c.cache.Validators
+	}
+
+	data, newValidators, fetchErr := fetchIfModified(ctx, fetcher, ActiveBuoysURL, validators)
+	if errors.Is(fetchErr, ErrNotModified) && c.cache != nil {
+		c.cache.FetchedAt = time.Now()
+		return c.save()
+	} else if fetchErr != nil {
+		return fetchErr
+	}
+
+	if setErr := c.setCache(&stationCatalogCache{URL: ActiveBuoysURL, FetchedAt: time.Now(), Validators: newValidators, Data: data}); setErr != nil {
+		return setErr
+	}
+	return c.save()
+}
+
+// Read the cache file if nothing has been loaded yet. A missing cache file is not an error.
+func (c *StationCatalog) loadIfNeeded() error {
+	if c.cache != nil || c.CachePath == "" {
+		return nil
+	}
+
+	rawCache, readErr := ioutil.ReadFile(c.CachePath)
+	if os.IsNotExist(readErr) {
+		return nil
+	} else if readErr != nil {
+		return readErr
+	}
+
+	cache := &stationCatalogCache{}
+	if jsonErr := json.Unmarshal(rawCache, cache); jsonErr != nil {
+		return jsonErr
+	}
+	return c.setCache(cache)
+}
+
+// Parse the cached station list and index it by id
+func (c *StationCatalog) setCache(cache *stationCatalogCache) error {
+	stations := &BuoyStations{}
+	if xmlErr := xml.Unmarshal(cache.Data, stations); xmlErr != nil {
+		return xmlErr
+	}
+	stations.BuildIndex()
+
+	byID := make(map[string]*Buoy, len(stations.Stations))
+	for _, buoy := range stations.Stations {
+		byID[strings.ToLower(buoy.StationID)] = buoy
+	}
+
+	c.cache = cache
+	c.stations = stations
+	c.byID = byID
+	return nil
+}
+
+// Write the cache file, replacing it atomically so a crash never leaves half a file behind
+func (c *StationCatalog) save() error {
+	if c.CachePath == "" || c.cache == nil {
+		return nil
+	}
+
+	rawCache, jsonErr := json.Marshal(c.cache)
+	if jsonErr != nil {
+		return jsonErr
+	}
+
+	tempFile, tempErr := ioutil.TempFile(filepath.Dir(c.CachePath), filepath.Base(c.CachePath)+".tmp")
+	if tempErr != nil {
+		return tempErr
+	}
+	defer os.Remove(tempFile.Name())
+
+	if _, writeErr := tempFile.Write(rawCache); writeErr != nil {
+		tempFile.Close()
+		return writeErr
+	}
+	if closeErr := tempFile.Close(); closeErr != nil {
+		return closeErr
+	}
+	return os.Rename(tempFile.Name(), c.CachePath)
+}
+
+// Fetch a url, revalidating with the validators when the fetcher supports it. Empty validators fetch
+// the url unconditionally but still collect the validators of the response.
+func fetchIfModified(ctx context.Context, fetcher Fetcher, url string, validators CacheValidators) ([]byte, CacheValidators, error) {
+	fetcher = fetcherOrDefault(fetcher)
+	ctx = contextOrBackground(ctx)
+
+	if conditional, ok := fetcher.(ConditionalFetcher); ok {
+		return conditional.FetchIfModified(ctx, url, validators)
+	}
+
+	data, fetchErr := fetcher.Fetch(ctx, url)
+	return data, CacheValidators{}, fetchErr
+}
+
+// Compare two snapshots of the station list. Stations are matched by id, ignoring case, and each list
+// is ordered by station id. Either snapshot may be nil.
+func DiffStations(old, new *BuoyStations) StationDiff {
+	oldByID := stationsByID(old)
+	newByID := stationsByID(new)
+	diff := StationDiff{}
+
+	for id, newBuoy := range newByID {
+		oldBuoy, ok := oldByID[id]
+		if !ok {
+			diff.Added = append(diff.Added, newBuoy)
+		} else if fields := changedStationFields(oldBuoy, newBuoy); len(fields) > 0 {
+			diff.Changed = append(diff.Changed, StationChange{StationID: newBuoy.StationID, Old: oldBuoy, New: newBuoy, Fields: fields})
+		}
+	}
+	for id, oldBuoy := range oldByID {
+		if _, ok := newByID[id]; !ok {
+			diff.Removed = append(diff.Removed, oldBuoy)
+		}
+	}
+
+	sortStationsByID(diff.Added)
+	sortStationsByID(diff.Removed)
+	sort.Slice(diff.Changed, func(i, j int) bool {
+		return strings.ToLower(diff.Changed[i].StationID) < strings.ToLower(diff.Changed[j].StationID)
+	})
+	return diff
+}
+
+func stationsByID(stations *BuoyStations) map[string]*Buoy {
+	byID := map[string]*Buoy{}
+	if stations == nil {
+		return byID
+	}
+	for _, buoy := range stations.Stations {
+		byID[strings.ToLower(buoy.StationID)] = buoy
+	}
+	return byID
+}
+
+func sortStationsByID(stations []*Buoy) {
+	sort.Slice(stations, func(i, j int) bool {
+		return strings.ToLower(stations[i].StationID) < strings.ToLower(stations[j].StationID)
+	})
+}
+
+// Get the names of the station attributes that differ between two snapshots of a station
+func changedStationFields(old, new *Buoy) []string {
+	fields := []string{}
+	compare := func(name, oldValue, newValue string) {
+		if oldValue != newValue {
+			fields = append(fields, name)
+		}
+	}
+
+	oldLocation, newLocation := Location{}, Location{}
+	if old.Location != nil {
+		oldLocation = *old.Location
+	}
+	if new.Location != nil {
+		newLocation = *new.Location
+	}
+	if oldLocation.Latitude != newLocation.Latitude {
+		fields = append(fields, "lat")
+	}
+	if oldLocation.Longitude != newLocation.Longitude {
+		fields = append(fields, "lon")
+	}
+	if oldLocation.Elevation != newLocation.Elevation {
+		fields = append(fields, "elev")
+	}
+	compare("name", oldLocation.LocationName, newLocation.LocationName)
+	compare("owner", old.Owner, new.Owner)
+	compare("pgm", old.PGM, new.PGM)
+	compare("type", old.Type, new.Type)
+	compare("met", old.Active, new.Active)
+	compare("currents", old.Currents, new.Currents)
+	compare("waterquality", old.WaterQuality, new.WaterQuality)
+	compare("dart", old.Dart, new.Dart)
+	return fields
+}
@@ -0,0 +1,201 @@
+package surfnerd
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testActiveStations = `<?xml version="1.0" encoding="UTF-8"?>
+<stations created="2016-10-18T14:50:00UTC" count="3">
+  <station id="44017" lat="40.694" lon="-72.048" elev="0" name="MONTAUK POINT" owner="NDBC" pgm="NDBC Meteorological/Ocean" type="buoy" met="y" currents="n" waterquality="n" dart="n"/>
+  <station id="44097" lat="40.967" lon="-71.126" elev="0" name="Block Island, RI" owner="Scripps" pgm="IOOS Partners" type="buoy" met="n" currents="n" waterquality="n" dart="n"/>
+  <station id="NWPR1" lat="41.505" lon="-71.326" elev="0" name="Newport, RI" owner="NOS" pgm="NOS/CO-OPS" type="fixed" met="y" currents="n" waterquality="y" dart="n"/>
+</stations>
+`
+
+const testChangedActiveStations = `<?xml version="1.0" encoding="UTF-8"?>
+<stations created="2016-10-18T15:50:00UTC" count="3">
+  <station id="44017" lat="40.694" lon="-72.048" elev="0" name="MONTAUK POINT" owner="NDBC" pgm="NDBC Meteorological/Ocean" type="buoy" met="y" currents="n" waterquality="n" dart="n"/>
+  <station id="44097" lat="40.967" lon="-71.126" elev="0" name="Block Island, RI" owner="Scripps" pgm="IOOS Partners" type="buoy" met="y" currents="n" waterquality="n" dart="n"/>
+  <station id="41001" lat="34.675" lon="-72.698" elev="0" name="EAST HATTERAS" owner="NDBC" pgm="NDBC Meteorological/Ocean" type="buoy" met="y" currents="n" waterquality="n" dart="n"/>
+</stations>
+`
+
+// A ConditionalFetcher serving one document, counting the full and not modified responses. Every
+// request fails with err when it is set.
+type countingFetcher struct {
+	data         string
+	etag         string
+	err          error
+	fetches      int
+	notModifieds int
+}
+
+func (c *countingFetcher) Fetch(ctx context.Context, url string) ([]byte, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	c.fetches++
+	return []byte(c.data), nil
+}
+
+func (c *countingFetcher) FetchIfModified(ctx context.Context, url string, validators CacheValidators) ([]byte, CacheValidators, error) {
+	if c.err != nil {
+		return nil, CacheValidators{}, c.err
+	} else if validators.ETag != "" && validators.ETag == c.etag {
+		c.notModifieds++
+		return nil, validators, ErrNotModified
+	}
+	c.fetches++
+	return []byte(c.data), CacheValidators{ETag: c.etag}, nil
+}
+
+func TestStationCatalogCachesLookups(t *testing.T) {
+	fetcher := &countingFetcher{data: testActiveStations, etag: "\"1\""}
+	catalog := NewStationCatalog("", time.Hour)
+
+	for _, id := range []string{"44017", "44097", "nwpr1", "44017"} {
+		buoy, findErr := catalog.FindBuoyByIDContext(context.Background(), fetcher, id)
+		if findErr != nil {
+			t.Fatal(findErr)
+		} else if buoy == nil {
+			t.Fatalf("Could not find station %s", id)
+		}
+	}
+	if fetcher.fetches != 1 {
+		t.Errorf("Expected the station list to be fetched once, fetched %d times", fetcher.fetches)
+	}
+
+	missing, findErr := catalog.FindBuoyByIDContext(context.Background(), fetcher, "00000")
+	if findErr != nil || missing != nil {
+		t.Error("Expected a missing station to return nil without an error")
+	}
+
+	// Lookups return copies, so changing one does not change the catalog
+	buoy, _ := catalog.FindBuoyByIDContext(context.Background(), fetcher, "44017")
+	buoy.Latitude = 0
+	buoy.Active = "n"
+	again, _ := catalog.FindBuoyByIDContext(context.Background(), fetcher, "44017")
+	if again.Latitude != 40.694 || again.Active != "y" {
+		t.Error("Modifying a looked up buoy changed the catalog")
+	}
+}
+
+func TestStationCatalogRevalidatesAfterTTL(t *testing.T) {
+	fetcher := &countingFetcher{data: testActiveStations, etag: "\"1\""}
+	catalog := NewStationCatalog("", 0)
+
+	for i := 0; i < 3; i++ {
+		if _, stationsErr := catalog.StationsContext(context.Background(), fetcher); stationsErr != nil {
+			t.Fatal(stationsErr)
+		}
+	}
+	if fetcher.fetches != 1 || fetcher.notModifieds != 2 {
+		t.Errorf("Expected 1 fetch and 2 revalidations, got %d and %d", fetcher.fetches, fetcher.notModifieds)
+	}
+
+	fetcher.data, fetcher.etag = testChangedActiveStations, "\"2\""
+	diff, refreshErr := catalog.RefreshContext(context.Background(), fetcher)
+	if refreshErr != nil {
+		t.Fatal(refreshErr)
+	}
+	if len(diff.Added) != 1 || diff.Added[0].StationID != "41001" {
+		t.Errorf("Expected 41001 to be added, got %v", diff.Added)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0].StationID != "NWPR1" {
+		t.Errorf("Expected NWPR1 to be removed, got %v", diff.Removed)
+	}
+	if len(diff.Changed) != 1 || diff.Changed[0].StationID != "44097" || len(diff.Changed[0].Fields) != 1 || diff.Changed[0].Fields[0] != "met" {
+		t.Errorf("Expected the met flag of 44097 to change, got %v", diff.Changed)
+	}
+
+	diff, refreshErr = catalog.RefreshContext(context.Background(), fetcher)
+	if refreshErr != nil {
+		t.Fatal(refreshErr)
+	} else if !diff.IsEmpty() {
+		t.Error("Expected no changes when the list was not modified")
+	}
+}
+
+func TestStationCatalogServesStaleListOnError(t *testing.T) {
+	fetcher := &countingFetcher{data: testActiveStations, etag: "\"1\""}
+	catalog := NewStationCatalog("", 0)
+	if _, findErr := catalog.FindBuoyByIDContext(context.Background(), fetcher, "44017"); findErr != nil {
+		t.Fatal(findErr)
+	}
+
+	// The TTL has passed, but NDBC can not be reached to revalidate the list
+	fetcher.err = errors.New("Request failed with status 503 Service Unavailable")
+	buoy, findErr := catalog.FindBuoyByIDContext(context.Background(), fetcher, "44097")
+	if findErr != nil || buoy == nil {
+		t.Fatalf("Expected the cached list to be served, got %v and %v", buoy, findErr)
+	}
+	if refreshErr := catalog.LastRefreshError(); refreshErr != fetcher.err {
+		t.Errorf("Expected the refresh error to be reported, got %v", refreshErr)
+	}
+
+	fetcher.err = nil
+	if _, findErr := catalog.FindBuoyByIDContext(context.Background(), fetcher, "44097"); findErr != nil || catalog.LastRefreshError() != nil {
+		t.Errorf("Expected the refresh error to clear, got %v and %v", findErr, catalog.LastRefreshError())
+	}
+
+	// Without a cached list there is nothing to serve
+	empty := NewStationCatalog("", time.Hour)
+	fetcher.err = errors.New("Request failed with status 503 Service Unavailable")
+	if _, findErr := empty.FindBuoyByIDContext(context.Background(), fetcher, "44017"); findErr != fetcher.err {
+		t.Errorf("Expected the refresh error without a cached list, got %v", findErr)
+	}
+}
+
+func TestStationCatalogCacheFile(t *testing.T) {
+	cacheDir, dirErr := ioutil.TempDir("", "surfnerd-catalog")
+	if dirErr != nil {
+		t.Fatal(dirErr)
+	}
+	defer os.RemoveAll(cacheDir)
+	cachePath := filepath.Join(cacheDir, "stations.json")
+
+	fetcher := &countingFetcher{data: testActiveStations, etag: "\"1\""}
+	if _, stationsErr := NewStationCatalog(cachePath, time.Hour).StationsContext(context.Background(), fetcher); stationsErr != nil {
+		t.Fatal(stationsErr)
+	}
+
+	// A new catalog reads the file instead of fetching while it is fresh
+	reloaded := NewStationCatalog(cachePath, time.Hour)
+	buoy, findErr := reloaded.FindBuoyByIDContext(context.Background(), fetcher, "nwpr1")
+	if findErr != nil {
+		t.Fatal(findErr)
+	} else if buoy == nil {
+		t.Fatal("Could not find the station in the cache file")
+	}
+	if fetcher.fetches != 1 {
+		t.Errorf("Expected the cache file to be used, fetched %d times", fetcher.fetches)
+	}
+
+	// Once stale it is revalidated with the cached validators
+	stale := NewStationCatalog(cachePath, 0)
+	if _, stationsErr := stale.StationsContext(context.Background(), fetcher); stationsErr != nil {
+		t.Fatal(stationsErr)
+	}
+	if fetcher.fetches != 1 || fetcher.notModifieds != 1 {
+		t.Errorf("Expected the cache file to be revalidated, got %d fetches and %d revalidations", fetcher.fetches, fetcher.notModifieds)
+	}
+}
+
+func TestDiffStationsWithNil(t *testing.T) {
+	fetcher := &countingFetcher{data: testActiveStations}
+	stations, stationsErr := NewStationCatalog("", time.Hour).StationsContext(context.Background(), fetcher)
+	if stationsErr != nil {
+		t.Fatal(stationsErr)
+	}
+
+	diff := DiffStations(nil, stations)
+	if len(diff.Added) != 3 || len(diff.Removed) != 0 || len(diff.Changed) != 0 {
+		t.Errorf("Expected every station to be added, got %+v", diff)
+	}
+}
@@ -0,0 +1,337 @@
+package surfnerd
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// Decides whether a station is included in the results of a search. A nil filter includes every
+// station, and filters can be combined with And, Or and Not.
+type StationFilter func(buoy *Buoy) bool
+
+// Filters for the station flags reported in the active stations list
+var (
+	ActiveStationFilter       StationFilter = func(buoy *Buoy) bool { return buoy.IsBuoyActive() }
+	WaterCurrentStationFilter StationFilter = func(buoy *Buoy) bool { return buoy.DoesBuoyHaveWaterCurrentData() }
+	WaterQualityStationFilter StationFilter = func(buoy *Buoy) bool { return buoy.DoesBuoyHaveWaterQualityData() }
+	DartStationFilter         StationFilter = func(buoy *Buoy) bool { return buoy.DoesBuoyHaveDartData() }
+)
+
+// Create a filter for the stations of any of the given types, such as buoy, fixed or dart
+func StationTypeFilter(types ...string) StationFilter {
+	return func(buoy *Buoy) bool {
+		return matchesAnyFold(buoy.Type, types)
+	}
+}
+
+// Create a filter for the stations with any of the given owners
+func StationOwnerFilter(owners ...string) StationFilter {
+	return func(buoy *Buoy) bool {
+		return matchesAnyFold(buoy.Owner, owners)
+	}
+}
+
+// Create a filter for the stations in any of the given programs, such as NDBC Meteorological/Ocean
+func StationPGMFilter(pgms ...string) StationFilter {
+	return func(buoy *Buoy) bool {
+		return matchesAnyFold(buoy.PGM, pgms)
+	}
+}
+
+// Check if a station passes the filter. A nil filter passes every station.
+func (f StationFilter) Matches(buoy *Buoy) bool {
+	return f == nil || f(buoy)
+}
+
+// Create a filter for the stations that pass both filters
+func (f StationFilter) And(other StationFilter) StationFilter {
+	return func(buoy *Buoy) bool {
+		return f.Matches(buoy) && other.Matches(buoy)
+	}
+}
+
+// Create a filter for the stations that pass either filter
+func (f StationFilter) Or(other StationFilter) StationFilter {
+	return func(buoy *Buoy) bool {
+		return f.Matches(buoy) || other.Matches(buoy)
+	}
+}
+
+// Create a filter for the stations that do not pass the filter
+func (f StationFilter) Not() StationFilter {
+	return func(buoy *Buoy) bool {
+		return !f.Matches(buoy)
+	}
+}
+
+func matchesAnyFold(value string, options []string) bool {
+	for _, option := range options {
+		if strings.EqualFold(value, option) {
+			return true
+		}
+	}
+	return false
+}
+
+// A k-d tree of the station locations as points on the unit sphere. Straight line distances between
+// the points increase with the great circle distance, so nearest neighbours can be found without
+// worrying about the poles or the longitude seam.
+type stationIndex struct {
+	nodes []stationNode
+
+	// The slice of stations the index was built from
+	stations []*Buoy
+}
+
+// A station in the tree. The nodes are stored in a slice with each subtree occupying a contiguous
+// range and its root in the middle.
+type stationNode struct {
+	buoy  *Buoy
+	point [3]float64
+}
+
+// A station found by a search along with its straight line distance on the unit sphere
+type stationMatch struct {
+	buoy  *Buoy
+	chord float64
+}
+
+// Build an index of the stations that have a location
+func newStationIndex(stations []*Buoy) *stationIndex {
+	index := &stationIndex{stations: stations}
+	for _, buoy := range stations {
+		if buoy == nil || buoy.Location == nil {
+			continue
+		}
+		index.nodes = append(index.nodes, stationNode{buoy: buoy, point: unitVector(*buoy.Location)})
+	}
+
+	index.build(0, len(index.nodes), 0)
+	return index
+}
+
+// Check if the index was built from the given slice of stations, by its length and the address of its
+// first element. Copies of a BuoyStations share their index, so a copy holding a different slice of the
+// same length must not be given the stations of another.
+func (s *stationIndex) indexes(stations []*Buoy) bool {
+	if len(s.stations) != len(stations) {
+		return false
+	}
+	return len(stations) == 0 || &s.stations[0] == &stations[0]
+}
+
+// Arrange the nodes in the range so the median on the axis is in the middle, then do the same for
+// each half on the next axis
+func (s *stationIndex) build(start, end, axis int) {
+	if end-start < 2 {
+		return
+	}
+
+	nodes := s.nodes[start:end]
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].point[axis] < nodes[j].point[axis]
+	})
+
+	middle := start + (end-start)/2
+	s.build(start, middle, (axis+1)%3)
+	s.build(middle+1, end, (axis+1)%3)
+}
+
+// Find the k closest stations passing the filter, closest first
+func (s *stationIndex) nearest(loc Location, k int, filter StationFilter) []stationMatch {
+	if k < 1 {
+		return nil
+	}
+
+	target := unitVector(loc)
+	matches := make([]stationMatch, 0, k)
+
+	var search func(start, end, axis int)
+	search = func(start, end, axis int) {
+		if start >= end {
+			return
+		}
+
+		middle := start + (end-start)/2
+		node := s.nodes[middle]
+		if filter.Matches(node.buoy) {
+			matches = insertMatch(matches, stationMatch{buoy: node.buoy, chord: chordDistance(node.point, target)}, k)
+		}
+
+		// Search the side holding the target first, then the other side if it could hold anything closer
+		difference := target[axis] - node.point[axis]
+		nearStart, nearEnd, farStart, farEnd := start, middle, middle+1, end
+		if difference > 0 {
+			nearStart, nearEnd, farStart, farEnd = middle+1, end, start, middle
+		}
+
+		search(nearStart, nearEnd, (axis+1)%3)
+		if len(matches) < k || math.Abs(difference) < matches[len(matches)-1].chord {
+			search(farStart, farEnd, (axis+1)%3)
+		}
+	}
+	search(0, len(s.nodes), 0)
+
+	return matches
+}
+
+// Find every station passing the filter within the straight line distance on the unit sphere, closest first
+func (s *stationIndex) withinChord(loc Location, chord float64, filter StationFilter) []stationMatch {
+	target := unitVector(loc)
+	matches := []stationMatch{}
+
+	var search func(start, end, axis int)
+	search = func(start, end, axis int) {
+		if start >= end {
+			return
+		}
+
+		middle := start + (end-start)/2
+		node := s.nodes[middle]
+		if distance := chordDistance(node.point, target); distance <= chord && filter.Matches(node.buoy) {
+			matches = append(matches, stationMatch{buoy: node.buoy, chord: distance})
+		}
+
+		difference := target[axis] - node.point[axis]
+		if difference <= chord {
+			search(start, middle, (axis+1)%3)
+		}
+		if difference >= -chord {
+			search(middle+1, end, (axis+1)%3)
+		}
+	}
+	search(0, len(s.nodes), 0)
+
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].chord < matches[j].chord
+	})
+	return matches
+}
+
+// Find every station passing the filter inside the box, checking only the subtrees whose points can
+// fall inside the box's bounds on the unit sphere
+func (s *stationIndex) inBox(box geoBox, filter StationFilter) []*Buoy {
+	lower, upper := box.bounds()
+	stations := []*Buoy{}
+
+	var search func(start, end, axis int)
+	search = func(start, end, axis int) {
+		if start >= end {
+			return
+		}
+
+		middle := start + (end-start)/2
+		node := s.nodes[middle]
+		if box.contains(*node.buoy.Location) && filter.Matches(node.buoy) {
+			stations = append(stations, node.buoy)
+		}
+
+		if node.point[axis] >= lower[axis] {
+			search(start, middle, (axis+1)%3)
+		}
+		if node.point[axis] <= upper[axis] {
+			search(middle+1, end, (axis+1)%3)
+		}
+	}
+	search(0, len(s.nodes), 0)
+
+	return stations
+}
+
+// Add a match to the list sorted by distance, keeping at most k matches
+func insertMatch(matches []stationMatch, match stationMatch, k int) []stationMatch {
+	position := sort.Search(len(matches), func(i int) bool {
+		return matches[i].chord > match.chord
+	})
+	if position >= k {
+		return matches
+	}
+
+	if len(matches) < k {
+		matches = append(matches, stationMatch{})
+	}
+	copy(matches[position+1:], matches[position:])
+	matches[position] = match
+	return matches
+}
+
+// A latitude and longitude box. The longitude range runs east from the west edge to the east edge,
+// so a box can cross the longitude seam.
+type geoBox struct {
+	south, north float64
+	west, east   float64
+}
+
+func newGeoBox(bottomLeft, topRight Location) geoBox {
+	west := NormalizeLongitude360(bottomLeft.Longitude)
+	east := normalizeLongitudeFrom(topRight.Longitude, west)
+	if topRight.Longitude-bottomLeft.Longitude >= 360 {
+		east = west + 360
+	}
+	return geoBox{south: bottomLeft.Latitude, north: topRight.Latitude, west: west, east: east}
+}
+
+func (g geoBox) contains(loc Location) bool {
+	if loc.Latitude < g.south || loc.Latitude > g.north {
+		return false
+	}
+	return normalizeLongitudeFrom(loc.Longitude, g.west) <= g.east
+}
+
+// Get the bounds of the box on the unit sphere
+func (g geoBox) bounds() (lower, upper [3]float64) {
+	south, north := g.south*math.Pi/180, g.north*math.Pi/180
+	west, east := g.west*math.Pi/180, g.east*math.Pi/180
+
+	// The cosine of the latitude is largest at the equator and smallest at the edge farthest from it
+	cosLatMin := math.Min(math.Cos(south), math.Cos(north))
+	cosLatMax := math.Max(math.Cos(south), math.Cos(north))
+	if south <= 0 && north >= 0 {
+		cosLatMax = 1
+	}
+
+	// The sine and cosine of the longitude range are bounded by the edges and any quarter turns inside it
+	cosLonMin, cosLonMax := math.Min(math.Cos(west), math.Cos(east)), math.Max(math.Cos(west), math.Cos(east))
+	sinLonMin, sinLonMax := math.Min(math.Sin(west), math.Sin(east)), math.Max(math.Sin(west), math.Sin(east))
+	for quarter := math.Ceil(west / (math.Pi / 2)); quarter*math.Pi/2 <= east; quarter++ {
+		switch int(math.Mod(quarter, 4)) {
+		case 0:
+			cosLonMax = 1
+		case 1:
+			sinLonMax = 1
+		case 2:
+			cosLonMin = -1
+		case 3:
+			sinLonMin = -1
+		}
+	}
+
+	lower[0], upper[0] = scaledRange(cosLatMin, cosLatMax, cosLonMin, cosLonMax)
+	lower[1], upper[1] = scaledRange(cosLatMin, cosLatMax, sinLonMin, sinLonMax)
+	lower[2], upper[2] = math.Sin(south), math.Sin(north)
+	return
+}
+
+// Get the range of the product of a non negative range and another range
+func scaledRange(scaleMin, scaleMax, valueMin, valueMax float64) (float64, float64) {
+	products := []float64{scaleMin * valueMin, scaleMin * valueMax, scaleMax * valueMin, scaleMax * valueMax}
+	sort.Float64s(products)
+	return products[0], products[3]
+}
+
+// Get the point on the unit sphere of a location
+func unitVector(loc Location) [3]float64 {
+	lat, lon := loc.radians()
+	return [3]float64{math.Cos(lat) * math.Cos(lon), math.Cos(lat) * math.Sin(lon), math.Sin(lat)}
+}
+
+func chordDistance(a, b [3]float64) float64 {
+	return math.Sqrt(math.Pow(a[0]-b[0], 2) + math.Pow(a[1]-b[1], 2) + math.Pow(a[2]-b[2], 2))
+}
+
+// Convert a great circle distance in kilometers to a straight line distance on the unit sphere
+func chordForDistance(distance float64) float64 {
+	angle := math.Min(distance/earthRadiusKilometers, math.Pi)
+	return 2 * math.Sin(angle/2)
+}
@@ -0,0 +1,170 @@
+package surfnerd
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// Create stations scattered over the globe, alternating between the station types and flags
+func randomStations(count int) []*Buoy {
+	random := rand.New(rand.NewSource(42))
+	types := []string{"buoy", "fixed", "dart"}
+	flags := []string{"y", "n"}
+
+	stations := make([]*Buoy, count)
+	for i := range stations {
+		stations[i] = &Buoy{
+			StationID: fmt.Sprintf("%05d", i),
+			Type:      types[i%len(types)],
+			Active:    flags[i%2],
+			Currents:  flags[(i/2)%2],
+			Location:  &Location{Latitude: random.Float64()*180 - 90, Longitude: random.Float64()*360 - 180},
+		}
+	}
+	return stations
+}
+
+func stationIDs(buoys []*Buoy) []string {
+	ids := make([]string, len(buoys))
+	for i, buoy := range buoys {
+		ids[i] = buoy.StationID
+	}
+	return ids
+}
+
+func TestFindNearestMatchesLinearSearch(t *testing.T) {
+	stations := BuoyStations{Stations: randomStations(2000)}
+	filter := ActiveStationFilter.And(StationTypeFilter("buoy", "fixed"))
+
+	for _, loc := range []Location{
+		NewLocationForLatLong(41.0, -71.0),
+		NewLocationForLatLong(-60.0, 179.9),
+		NewLocationForLatLong(89.5, 10.0),
+		NewLocationForLatLong(0.0, 359.0),
+	} {
+		expected := []*Buoy{}
+		for _, buoy := range stations.Stations {
+			if filter(buoy) {
+				expected = append(expected, buoy)
+			}
+		}
+		sort.SliceStable(expected, func(i, j int) bool {
+			return loc.DistanceTo(*expected[i].Location) < loc.DistanceTo(*expected[j].Location)
+		})
+
+		nearest := stations.FindNearest(loc, 5, filter)
+		if fmt.Sprint(stationIDs(nearest)) != fmt.Sprint(stationIDs(expected[:5])) {
+			t.Errorf("Expected %v near %v, got %v", stationIDs(expected[:5]), loc, stationIDs(nearest))
+		}
+
+		radius := loc.DistanceTo(*expected[20].Location) - 1e-6
+		within := stations.FindWithinRadius(loc, radius, filter)
+		if fmt.Sprint(stationIDs(within)) != fmt.Sprint(stationIDs(expected[:20])) {
+			t.Errorf("Expected %v within %v km of %v, got %v", stationIDs(expected[:20]), radius, loc, stationIDs(within))
+		}
+	}
+}
+
+func TestFindInBoundingBox(t *testing.T) {
+	stations := BuoyStations{Stations: randomStations(2000)}
+
+	for _, box := range [][2]Location{
+		{NewLocationForLatLong(30, -80), NewLocationForLatLong(45, -60)},
+		{NewLocationForLatLong(30, 280), NewLocationForLatLong(45, 300)},
+		{NewLocationForLatLong(-20, 170), NewLocationForLatLong(20, -170)},
+		{NewLocationForLatLong(60, -180), NewLocationForLatLong(90, 180)},
+	} {
+		geo := newGeoBox(box[0], box[1])
+		expected := []string{}
+		for _, buoy := range stations.Stations {
+			if geo.contains(*buoy.Location) && buoy.DoesBuoyHaveWaterCurrentData() {
+				expected = append(expected, buoy.StationID)
+			}
+		}
+
+		found := stationIDs(stations.FindInBoundingBox(box[0], box[1], WaterCurrentStationFilter))
+		sort.Strings(found)
+		if len(expected) == 0 || fmt.Sprint(found) != fmt.Sprint(expected) {
+			t.Errorf("Expected %v in box %v, got %v", expected, box, found)
+		}
+	}
+}
+
+func TestFindClosestActiveWaveBuoys(t *testing.T) {
+	loc := NewLocationForLatLong(41.0, -71.0)
+
+	// The closest buoys are listed farthest first, which the old insertion dropped
+	stations := BuoyStations{}
+	for i := 5; i > 0; i-- {
+		stations.Stations = append(stations.Stations, &Buoy{
+			StationID: fmt.Sprint(i),
+			Type:      "buoy",
+			Active:    "y",
+			Location:  &Location{Latitude: 41.0 + float64(i)*0.1, Longitude: -71.0},
+		})
+	}
+
+	closest := stations.FindClosestActiveWaveBuoys(loc)
+	if fmt.Sprint(stationIDs(closest)) != "[1 2 3]" {
+		t.Errorf("Expected buoys 1, 2 and 3, got %v", stationIDs(closest))
+	}
+
+	// The index is rebuilt when stations are added
+	stations.Stations = append(stations.Stations, &Buoy{StationID: "0", Type: "buoy", Active: "y", Location: &Location{Latitude: 41.0, Longitude: 289.0}})
+	if closest := stations.FindClosestActiveWaveBuoy(loc); closest == nil || closest.StationID != "0" {
+		t.Errorf("Expected the new buoy to be closest, got %v", closest)
+	}
+}
+
+func TestCopiedBuoyStations(t *testing.T) {
+	stations := BuoyStations{Stations: randomStations(100)}
+	stations.BuildIndex()
+
+	// Copies share the index, so searching either value gives the same stations
+	copied := stations
+	loc := NewLocationForLatLong(41.0, -71.0)
+	if fmt.Sprint(stationIDs(copied.FindNearest(loc, 5, nil))) != fmt.Sprint(stationIDs(stations.FindNearest(loc, 5, nil))) {
+		t.Error("Expected the copied stations to find the same nearest stations")
+	}
+}
+
+func TestCopiedBuoyStationsWithDifferentStations(t *testing.T) {
+	loc := NewLocationForLatLong(41.0, -71.0)
+	near := &Buoy{StationID: "near", Location: &Location{Latitude: 41.1, Longitude: -71.0}}
+	far := &Buoy{StationID: "far", Location: &Location{Latitude: 10.0, Longitude: 20.0}}
+
+	stations := BuoyStations{Stations: []*Buoy{near}}
+	stations.BuildIndex()
+
+	// The copy holds a different slice of the same length, so it must not find the original's buoys
+	copied := stations
+	copied.Stations = []*Buoy{far}
+	if found := copied.FindNearest(loc, 1, nil); len(found) != 1 || found[0] != far {
+		t.Errorf("Expected the copy to find its own station, got %v", stationIDs(found))
+	}
+	if found := stations.FindNearest(loc, 1, nil); len(found) != 1 || found[0] != near {
+		t.Errorf("Expected the original to find its own station, got %v", stationIDs(found))
+	}
+
+	// Building the index of one copy does not hand its stations to the other
+	copied.BuildIndex()
+	if found := stations.FindNearest(loc, 1, nil); len(found) != 1 || found[0] != near {
+		t.Errorf("Expected the original to keep its own station, got %v", stationIDs(found))
+	}
+}
+
+func TestStationFilters(t *testing.T) {
+	buoy := &Buoy{Type: "buoy", Owner: "NDBC", PGM: "NDBC Meteorological/Ocean", Active: "y", Dart: "n"}
+
+	if !StationOwnerFilter("ndbc").And(StationPGMFilter("NDBC Meteorological/Ocean")).Matches(buoy) {
+		t.Error("Expected the owner and program filters to match")
+	}
+	if DartStationFilter.Or(StationTypeFilter("fixed")).Matches(buoy) {
+		t.Error("Expected the dart or fixed filter not to match")
+	}
+	if !DartStationFilter.Not().Matches(buoy) || !StationFilter(nil).Matches(buoy) {
+		t.Error("Expected the not dart and nil filters to match")
+	}
+}
@@ -0,0 +1,365 @@
+package surfnerd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	baseStationTableURL = "%s/data/stations/station_table.txt"
+	baseStationPageURL  = "%s/station_page.php?station=%s"
+
+	// The column names of station_table.txt
+	stationTableIDField      = "STATION_ID"
+	stationTableTypeField    = "TTYPE"
+	stationTableHullField    = "HULL"
+	stationTablePayloadField = "PAYLOAD"
+)
+
+// Returned when a buoy's metadata is needed but has not been fetched
+var ErrMissingStationMetadata = errors.New("No station metadata for the buoy")
+
+// Patterns for reading the station page
+var (
+	// Matches the first number in a station page value, such as the 4.1 in "4.1 m above site elevation"
+	stationPageNumberPattern = regexp.MustCompile(`-?\d+(\.\d+)?`)
+
+	stationPageLineBreakPattern = regexp.MustCompile(`(?i)<br\s*/?>|</p>|</div>|\n`)
+	stationPageTagPattern       = regexp.MustCompile(`<[^>]*>`)
+)
+
+// Describes the platform and sensor placement of a station, from the NDBC station table and station
+// page. Heights and depths are in meters, and are missing when the station does not report them.
+type StationMetadata struct {
+	// The platform description such as 3-meter foundation buoy, the NDBC hull code such as 3D, and
+	// the payload such as SCOOP payload
+	StationType string `json:",omitempty"`
+	HullType    string `json:",omitempty"`
+	Payload     string `json:",omitempty"`
+
+	// The elevation of the site above mean sea level
+	SiteElevation float64
+
+	// The heights of the sensors above the site elevation, except the barometer which is above mean sea level
+	AirTemperatureHeight float64
+	AnemometerHeight     float64
+	BarometerElevation   float64
+
+	// The depth of the water temperature sensor below the water line
+	SeaTemperatureDepth float64
+
+	// The depth of the water at the station and the radius a moored buoy can drift around its anchor
+	WaterDepth        float64
+	WatchCircleRadius float64
+}
+
+// Create a StationMetadata with every measurement missing
+func newStationMetadata() StationMetadata {
+	return StationMetadata{
+		SiteElevation:        MissingValue(),
+		AirTemperatureHeight: MissingValue(),
+		AnemometerHeight:     MissingValue(),
+		BarometerElevation:   MissingValue(),
+		SeaTemperatureDepth:  MissingValue(),
+		WaterDepth:           MissingValue(),
+		WatchCircleRadius:    MissingValue(),
+	}
+}
+
+// Convert a StationMetadata to json, writing missing measurements as null
+func (s StationMetadata) MarshalJSON() ([]byte, error) {
+	type plainMetadata StationMetadata
+	return json.Marshal(struct {
+		plainMetadata
+		SiteElevation        nullableFloat
+		AirTemperatureHeight nullableFloat
+		AnemometerHeight     nullableFloat
+		BarometerElevation   nullableFloat
+		SeaTemperatureDepth  nullableFloat
+		WaterDepth           nullableFloat
+		WatchCircleRadius    nullableFloat
+	}{
+		plainMetadata(s),
+		nullableFloat(s.SiteElevation),
+		nullableFloat(s.AirTemperatureHeight),
+		nullableFloat(s.AnemometerHeight),
+		nullableFloat(s.BarometerElevation),
+		nullableFloat(s.SeaTemperatureDepth),
+		nullableFloat(s.WaterDepth),
+		nullableFloat(s.WatchCircleRadius),
+	})
+}
+
+// Read a StationMetadata from json, reading null measurements as missing
+func (s *StationMetadata) UnmarshalJSON(data []byte) error {
+	type plainMetadata StationMetadata
+	shadow := struct {
+		*plainMetadata
+		SiteElevation        nullableFloat
+		AirTemperatureHeight nullableFloat
+		AnemometerHeight     nullableFloat
+		BarometerElevation   nullableFloat
+		SeaTemperatureDepth  nullableFloat
+		WaterDepth           nullableFloat
+		WatchCircleRadius    nullableFloat
+	}{
+		plainMetadata:        (*plainMetadata)(s),
+		SiteElevation:        nullableFloat(MissingValue()),
+		AirTemperatureHeight: nullableFloat(MissingValue()),
+		AnemometerHeight:     nullableFloat(MissingValue()),
+		BarometerElevation:   nullableFloat(MissingValue()),
+		SeaTemperatureDepth:  nullableFloat(MissingValue()),
+		WaterDepth:           nullableFloat(MissingValue()),
+		WatchCircleRadius:    nullableFloat(MissingValue()),
+	}
+
+	if err := json.Unmarshal(data, &shadow); err != nil {
+		return err
+	}
+
+	s.SiteElevation = float64(shadow.SiteElevation)
+	s.AirTemperatureHeight = float64(shadow.AirTemperatureHeight)
+	s.AnemometerHeight = float64(shadow.AnemometerHeight)
+	s.BarometerElevation = float64(shadow.BarometerElevation)
+	s.SeaTemperatureDepth = float64(shadow.SeaTemperatureDepth)
+	s.WaterDepth = float64(shadow.WaterDepth)
+	s.WatchCircleRadius = float64(shadow.WatchCircleRadius)
+	return nil
+}
+
+// Fill in the values that are missing or empty with the values of another StationMetadata
+func (s *StationMetadata) merge(other StationMetadata) {
+	mergeString := func(value *string, otherValue string) {
+		if *value == "" {
+			*value = otherValue
+		}
+	}
+	mergeFloat := func(value *float64, otherValue float64) {
+		if IsMissing(*value) {
+			*value = otherValue
+		}
+	}
+
+	mergeString(&s.StationType, other.StationType)
+	mergeString(&s.HullType, other.HullType)
+	mergeString(&s.Payload, other.Payload)
+	mergeFloat(&s.SiteElevation, other.SiteElevation)
+	mergeFloat(&s.AirTemperatureHeight, other.AirTemperatureHeight)
+	mergeFloat(&s.AnemometerHeight, other.AnemometerHeight)
+	mergeFloat(&s.BarometerElevation, other.BarometerElevation)
+	mergeFloat(&s.SeaTemperatureDepth, other.SeaTemperatureDepth)
+	mergeFloat(&s.WaterDepth, other.WaterDepth)
+	mergeFloat(&s.WatchCircleRadius, other.WatchCircleRadius)
+}
+
+// Get the url of the NDBC station table, which lists the platform of every station
+func CreateStationTableURL() string {
+	return fmt.Sprintf(baseStationTableURL, NDBCBaseURL)
+}
+
+// Get the url of the station page, which lists the sensor heights and the water depth
+func (b *Buoy) CreateStationPageURL() string {
+	return fmt.Sprintf(baseStationPageURL, NDBCBaseURL, b.StationID)
+}
+
+// Fetch the NDBC station table and parse it into the metadata of each station, keyed by the lower
+// case station id. The table only has the platform descriptions, so every measurement is missing.
+func FetchStationTable() (map[string]StationMetadata, error) {
+	return FetchStationTableContext(context.Background(), nil)
+}
+
+// Fetch the NDBC station table using the given context and Fetcher. A nil Fetcher uses the DefaultFetcher.
+func FetchStationTableContext(ctx context.Context, fetcher Fetcher) (map[string]StationMetadata, error) {
+	rawData, fetchErr := fetchLineDelimitedString(ctx, fetcher, CreateStationTableURL())
+	if fetchErr != nil {
+		return nil, fetchErr
+	}
+	return ParseStationTable(rawData)
+}
+
+// Parse the pipe delimited lines of the NDBC station table into the metadata of each station, keyed
+// by the lower case station id. The columns are found from the header line starting with
+// # STATION_ID, so the order of the columns does not matter.
+func ParseStationTable(lines []string) (map[string]StationMetadata, error) {
+	var columns map[string]int
+	stations := map[string]StationMetadata{}
+
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		} else if strings.HasPrefix(line, "#") {
+			if columns == nil && strings.Contains(line, stationTableIDField) {
+				columns = map[string]int{}
+				for i, field := range strings.Split(strings.TrimPrefix(line, "#"), "|") {
+					columns[strings.TrimSpace(field)] = i
+				}
+			}
+			continue
+		} else if columns == nil {
+			return nil, ErrMissingHeader
+		}
+
+		values := strings.Split(line, "|")
+		value := func(field string) string {
+			index, ok := columns[field]
+			if !ok || index >= len(values) {
+				return ""
+			}
+			return strings.TrimSpace(values[index])
+		}
+
+		id := value(stationTableIDField)
+		if id == "" {
+			continue
+		}
+
+		metadata := newStationMetadata()
+		metadata.StationType = value(stationTableTypeField)
+		metadata.HullType = value(stationTableHullField)
+		metadata.Payload = value(stationTablePayloadField)
+		stations[strings.ToLower(id)] = metadata
+	}
+
+	if columns == nil {
+		return nil, ErrMissingHeader
+	}
+	return stations, nil
+}
+
+// Parse the metadata section of an NDBC station page. Each sensor is listed on its own line as a
+// label and a value, such as "<b>Anemometer height:</b> 4.1 m above site elevation<br>". Values given
+// in feet or yards are converted to meters, and a site elevation of sea level is zero.
+func ParseStationPage(page string) StationMetadata {
+	metadata := newStationMetadata()
+
+	for _, line := range stationPageLineBreakPattern.Split(page, -1) {
+		text := strings.TrimSpace(html.UnescapeString(stationPageTagPattern.ReplaceAllString(line, "")))
+		if text == "" {
+			continue
+		}
+
+		separator := strings.Index(text, ":")
+		if separator < 0 {
+			// The unlabeled lines describe the platform, such as "3-meter foundation buoy" and "SCOOP payload"
+			if strings.HasSuffix(strings.ToLower(text), " payload") {
+				metadata.Payload = text
+			}
+			continue
+		}
+
+		label := strings.ToLower(strings.TrimSpace(text[:separator]))
+		value := strings.TrimSpace(text[separator+1:])
+		switch label {
+		case "site elevation":
+			metadata.SiteElevation = parseStationPageLength(value)
+		case "air temp height":
+			metadata.AirTemperatureHeight = parseStationPageLength(value)
+		case "anemometer height":
+			metadata.AnemometerHeight = parseStationPageLength(value)
+		case "barometer elevation":
+			metadata.BarometerElevation = parseStationPageLength(value)
+		case "sea temp depth":
+			metadata.SeaTemperatureDepth = parseStationPageLength(value)
+		case "water depth":
+			metadata.WaterDepth = parseStationPageLength(value)
+		case "watch circle radius":
+			metadata.WatchCircleRadius = parseStationPageLength(value)
+		}
+	}
+
+	return metadata
+}
+
+// Read a length from a station page value in meters
+func parseStationPageLength(value string) float64 {
+	if strings.HasPrefix(strings.ToLower(value), "sea level") {
+		return 0
+	}
+
+	number := stationPageNumberPattern.FindString(value)
+	length, parseErr := strconv.ParseFloat(number, 64)
+	if parseErr != nil {
+		return MissingValue()
+	}
+
+	unit := strings.Fields(strings.TrimSpace(strings.TrimPrefix(value[strings.Index(value, number):], number)))
+	if len(unit) > 0 {
+		switch strings.ToLower(unit[0]) {
+		case "ft", "feet":
+			return FeetToMeters(length)
+		case "yd", "yds", "yards":
+			return length * 0.9144
+		}
+	}
+	return length
+}
+
+// Fetch the platform and sensor metadata of the buoy from its station page and the NDBC station table
+func (b *Buoy) FetchStationMetadata() error {
+	return b.FetchStationMetadataContext(context.Background(), nil)
+}
+
+// Fetch the metadata of the buoy using the given context and Fetcher. A nil Fetcher uses the
+// DefaultFetcher. To fill in many buoys, fetch the station table once with FetchStationTable and
+// pass it to FetchStationMetadataWithTableContext instead.
+func (b *Buoy) FetchStationMetadataContext(ctx context.Context, fetcher Fetcher) error {
+	table, tableErr := FetchStationTableContext(ctx, fetcher)
+	if tableErr != nil {
+		return tableErr
+	}
+	return b.FetchStationMetadataWithTableContext(ctx, fetcher, table)
+}
+
+// Fetch the station page of the buoy and combine it with its entry in an already parsed station table
+func (b *Buoy) FetchStationMetadataWithTableContext(ctx context.Context, fetcher Fetcher, table map[string]StationMetadata) error {
+	rawPage, fetchErr := fetchRawDataFromURL(ctx, fetcher, b.CreateStationPageURL())
+	if fetchErr != nil {
+		return fetchErr
+	}
+
+	metadata := ParseStationPage(string(rawPage))
+	if tableMetadata, ok := table[strings.ToLower(b.StationID)]; ok {
+		metadata.merge(tableMetadata)
+	}
+
+	b.Metadata = &metadata
+	return nil
+}
+
+// Reduce the observed wind speeds and gusts of the buoy data to 10 meter neutral winds, using the
+// height of the anemometer above the sea from the station metadata. The anemometer height is given above
+// the site elevation, which is added for C-MAN and other fixed stations standing above the sea. Winds
+// are changed in place and marked with TenMeterWinds, so items that were already adjusted are skipped.
+func (b *Buoy) AdjustWindToTenMeters() error {
+	if b.Metadata == nil || IsMissing(b.Metadata.AnemometerHeight) {
+		return ErrMissingStationMetadata
+	}
+
+	height := b.Metadata.AnemometerHeight
+	if !IsMissing(b.Metadata.SiteElevation) {
+		height += b.Metadata.SiteElevation
+	}
+
+	for i := range b.BuoyData {
+		item := &b.BuoyData[i]
+		if item.TenMeterWinds {
+			continue
+		}
+
+		item.TenMeterWinds = true
+		if item.Units == English {
+			item.WindSpeed = MetersPerSecondToMilesPerHour(NeutralWindSpeedAtTenMeters(MilesPerHourToMetersPerSecond(item.WindSpeed), height))
+			item.WindGust = MetersPerSecondToMilesPerHour(NeutralWindSpeedAtTenMeters(MilesPerHourToMetersPerSecond(item.WindGust), height))
+		} else {
+			item.WindSpeed = NeutralWindSpeedAtTenMeters(item.WindSpeed, height)
+			item.WindGust = NeutralWindSpeedAtTenMeters(item.WindGust, height)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package surfnerd
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const testStationTable = `# STATION_ID | OWNER | TTYPE | HULL | NAME | PAYLOAD | LOCATION | TIMEZONE | FORECAST | NOTE
+#          |       |       |      |      |         |          |          |          |
+44017|N|3-meter foundation buoy|3D|MONTAUK POINT - 23 NM SSW of Montauk Point, NY|SCOOP payload|40.693 N 72.049 W (40&#176;41'36" N 72&#176;2'56" W)|E||
+NWPR1|NOS|Water Level Observation Network||8452660 - Newport, RI||41.505 N 71.326 W (41&#176;30'18" N 71&#176;19'34" W)|E||
+`
+
+const testStationPage = `<div id="stn_metadata">
+<p>
+<b>Owned and maintained by National Data Buoy Center</b><br>
+<b>3-meter foundation buoy</b><br>
+<b>SCOOP payload</b><br>
+<b>40.693 N 72.049 W (40&#176;41'36" N 72&#176;2'56" W)</b><br>
+<br>
+<b>Site elevation:</b> sea level<br>
+<b>Air temp height:</b> 3.7 m above site elevation<br>
+<b>Anemometer height:</b> 4.1 m above site elevation<br>
+<b>Barometer elevation:</b> 2.7 m above mean sea level<br>
+<b>Sea temp depth:</b> 1.5 m below water line<br>
+<b>Water depth:</b> 48 m<br>
+<b>Watch circle radius:</b> 75 yards<br>
+</p>
+</div>`
+
+func TestParseStationTable(t *testing.T) {
+	table, parseErr := ParseStationTable(strings.Split(testStationTable, "\n"))
+	if parseErr != nil {
+		t.Fatal(parseErr)
+	}
+
+	buoy, ok := table["44017"]
+	if !ok {
+		t.Fatal("Station 44017 is missing from the table")
+	}
+	if buoy.StationType != "3-meter foundation buoy" || buoy.HullType != "3D" || buoy.Payload != "SCOOP payload" {
+		t.Errorf("Unexpected platform %+v", buoy)
+	}
+	if !IsMissing(buoy.WaterDepth) {
+		t.Error("Expected the table to leave the water depth missing")
+	}
+
+	if fixed := table["nwpr1"]; fixed.HullType != "" || fixed.StationType != "Water Level Observation Network" {
+		t.Errorf("Unexpected platform %+v", fixed)
+	}
+
+	if _, parseErr := ParseStationTable([]string{"44017|N|buoy"}); parseErr != ErrMissingHeader {
+		t.Error("Expected a table without a header to fail")
+	}
+}
+
+func TestParseStationPage(t *testing.T) {
+	metadata := ParseStationPage(testStationPage)
+	if metadata.SiteElevation != 0 || metadata.AirTemperatureHeight != 3.7 || metadata.AnemometerHeight != 4.1 {
+		t.Errorf("Unexpected heights %+v", metadata)
+	}
+	if metadata.BarometerElevation != 2.7 || metadata.SeaTemperatureDepth != 1.5 || metadata.WaterDepth != 48 {
+		t.Errorf("Unexpected heights %+v", metadata)
+	}
+	if !closeTo(metadata.WatchCircleRadius, 68.58, 0.01) {
+		t.Errorf("Expected the watch circle in meters, got %v", metadata.WatchCircleRadius)
+	}
+	if metadata.Payload != "SCOOP payload" {
+		t.Errorf("Expected the payload from the page, got %q", metadata.Payload)
+	}
+
+	// Stations without a sensor leave it missing
+	if empty := ParseStationPage("<b>Site elevation:</b> 3 ft above mean sea level<br>"); !IsMissing(empty.WaterDepth) || !closeTo(empty.SiteElevation, FeetToMeters(3), 0.0001) {
+		t.Errorf("Unexpected metadata %+v", empty)
+	}
+}
+
+func TestStationMetadataJSON(t *testing.T) {
+	metadata := ParseStationPage("<b>Anemometer height:</b> 4.1 m above site elevation<br>")
+	rawJSON, jsonErr := json.Marshal(metadata)
+	if jsonErr != nil {
+		t.Fatal(jsonErr)
+	}
+
+	decoded := StationMetadata{}
+	if jsonErr := json.Unmarshal(rawJSON, &decoded); jsonErr != nil {
+		t.Fatal(jsonErr)
+	}
+	if decoded.AnemometerHeight != 4.1 || !IsMissing(decoded.WaterDepth) {
+		t.Errorf("Metadata did not survive json, got %+v from %s", decoded, rawJSON)
+	}
+}
+
+func TestAdjustWindRequiresMetadata(t *testing.T) {
+	buoy := Buoy{StationID: "44017", BuoyData: []BuoyDataItem{{WindSpeed: 8.0, WindGust: 10.0, Units: Metric}}}
+	if adjustErr := buoy.AdjustWindToTenMeters(); adjustErr != ErrMissingStationMetadata {
+		t.Error("Expected adjusting without metadata to fail")
+	}
+
+	metadata := ParseStationPage(testStationPage)
+	buoy.Metadata = &metadata
+	if adjustErr := buoy.AdjustWindToTenMeters(); adjustErr != nil {
+		t.Fatal(adjustErr)
+	}
+	if buoy.BuoyData[0].WindSpeed != NeutralWindSpeedAtTenMeters(8.0, 4.1) || !buoy.BuoyData[0].TenMeterWinds {
+		t.Errorf("Unexpected adjusted wind %v", buoy.BuoyData[0].WindSpeed)
+	}
+
+	// Adjusting again leaves the winds alone
+	if adjustErr := buoy.AdjustWindToTenMeters(); adjustErr != nil {
+		t.Fatal(adjustErr)
+	}
+	if buoy.BuoyData[0].WindSpeed != NeutralWindSpeedAtTenMeters(8.0, 4.1) {
+		t.Errorf("Expected a second adjustment to be skipped, got %v", buoy.BuoyData[0].WindSpeed)
+	}
+}
+
+func TestAdjustWindAboveSiteElevation(t *testing.T) {
+	// A C-MAN station on a pier, with its anemometer given above the pier rather than the sea
+	metadata := newStationMetadata()
+	metadata.SiteElevation = 6.0
+	metadata.AnemometerHeight = 13.0
+	buoy := Buoy{StationID: "BUZM3", Metadata: &metadata, BuoyData: []BuoyDataItem{{WindSpeed: 8.0, WindGust: 10.0, Units: Metric}}}
+
+	if adjustErr := buoy.AdjustWindToTenMeters(); adjustErr != nil {
+		t.Fatal(adjustErr)
+	}
+	if buoy.BuoyData[0].WindSpeed != NeutralWindSpeedAtTenMeters(8.0, 19.0) || buoy.BuoyData[0].WindGust != NeutralWindSpeedAtTenMeters(10.0, 19.0) {
+		t.Errorf("Expected the winds to be reduced from 19 meters, got %v and %v", buoy.BuoyData[0].WindSpeed, buoy.BuoyData[0].WindGust)
+	}
+}
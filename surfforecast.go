@@ -3,6 +3,7 @@ package surfnerd
 import (
 	"encoding/json"
 	"io/ioutil"
+	"time"
 )
 
 // A human readable abstracted representation of a surfing forecast for a given location.
@@ -81,24 +82,24 @@ func NewSurfForecast(loc Location, beachAngle, beachSlope float64, waveForecast
 		return nil
 	}
 
-	noWindData := false
-	if windForecast.ForecastData == nil {
-		noWindData = true
-	} else if len(windForecast.ForecastData) < 1 {
-		noWindData = true
+	// The wind model may run on a different schedule, so its data is matched to the waves by time
+	windData := map[time.Time]WindForecastItem{}
+	for _, windItem := range windForecast.ForecastData {
+		windData[windItem.ValidTime.UTC()] = windItem
 	}
 
 	// Get the wind and wave data from the two model runs
 	for i, _ := range waveForecast.ForecastData {
 		surfForecastItem := SurfForecastItem{}
-		surfForecastItem.Date = waveForecast.ForecastData[i].Date
-		surfForecastItem.Time = waveForecast.ForecastData[i].Time
-
-		if !noWindData {
-			surfForecastItem.WindSpeed = windForecast.ForecastData[i].WindSpeed
-			surfForecastItem.WindGustSpeed = windForecast.ForecastData[i].WindGustSpeed
-			surfForecastItem.WindDirection = windForecast.ForecastData[i].WindDirection
-			surfForecastItem.WindCompassDirection = DegreeToDirection(windForecast.ForecastData[i].WindDirection)
+		surfForecastItem.ValidTime = waveForecast.ForecastData[i].ValidTime
+		surfForecastItem.ModelRunTime = waveForecast.ForecastData[i].ModelRunTime
+		surfForecastItem.ForecastHour = waveForecast.ForecastData[i].ForecastHour
+
+		if windItem, ok := windData[surfForecastItem.ValidTime.UTC()]; ok {
+			surfForecastItem.WindSpeed = windItem.WindSpeed
+			surfForecastItem.WindGustSpeed = windItem.WindGustSpeed
+			surfForecastItem.WindDirection = windItem.WindDirection
+			surfForecastItem.WindCompassDirection = DegreeToDirection(windItem.WindDirection)
 		} else {
 			surfForecastItem.WindSpeed = waveForecast.ForecastData[i].SurfaceWindSpeed
 			surfForecastItem.WindGustSpeed = -1
@@ -2,6 +2,7 @@ package surfnerd
 
 import (
 	"testing"
+	"time"
 )
 
 func TestSurfForecastFetch(t *testing.T) {
@@ -32,3 +33,41 @@ func TestSurfForecastFetch(t *testing.T) {
 	surfForecast.ChangeUnits(English)
 	surfForecast.ExportAsJSON("test_forecast.json")
 }
+
+func TestSurfForecastJoinsWindByTime(t *testing.T) {
+	runTime := time.Date(2016, 10, 18, 6, 0, 0, 0, time.UTC)
+	waveModel := NewEastCoastWaveModel().NOAAModel
+	waveModel.RunTime = runTime
+	waveForecast := WaveForecastFromModelData(&ModelData{
+		Model: waveModel,
+		Data: ModelDataMap{
+			"htsgwsfc": {1.0, 1.2}, "dirpwsfc": {180, 190}, "perpwsfc": {9, 10},
+			"swell_1": {0.8, 0.9}, "swdir_1": {180, 190}, "swper_1": {9, 10},
+			"swell_2": {0.2, 0.3}, "swdir_2": {120, 130}, "swper_2": {6, 7},
+			"wvhgtsfc": {0.1, 0.1}, "wvdirsfc": {200, 200}, "wvpersfc": {4, 4},
+			"windsfc": {3, 4}, "wdirsfc": {200, 210},
+		},
+	})
+	if waveForecast.ForecastData[1].ForecastHour != 3 || !waveForecast.ForecastData[1].ValidTime.Equal(runTime.Add(3*time.Hour)) {
+		t.Fatalf("Unexpected forecast time: %+v", waveForecast.ForecastData[1])
+	}
+
+	// The wind model ran 3 hours later, so only its first step lines up with the second wave step
+	windModel := NewGFSWindModel().NOAAModel
+	windModel.RunTime = runTime.Add(3 * time.Hour)
+	windForecast := WindForecastFromModelData(&ModelData{
+		Model: windModel,
+		Data:  ModelDataMap{"ugrd10m": {0, 0}, "vgrd10m": {10, 12}, "gustsfc": {12, 14}},
+	})
+
+	surfForecast := NewSurfForecast(Location{}, 145.0, 0.02, waveForecast, windForecast)
+	if surfForecast.ForecastData[0].WindGustSpeed != -1 {
+		t.Error("Expected the first step to fall back to the wave model wind")
+	}
+	if surfForecast.ForecastData[1].WindGustSpeed != 12 {
+		t.Errorf("Expected the second step to use the first wind step, got gusts of %f", surfForecast.ForecastData[1].WindGustSpeed)
+	}
+	if surfForecast.ForecastData[1].FormatTime(nil) != "09 AM" {
+		t.Errorf("Unexpected formatted time %s", surfForecast.ForecastData[1].FormatTime(nil))
+	}
+}
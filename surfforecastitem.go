@@ -1,9 +1,20 @@
 package surfnerd
 
+import (
+	"time"
+)
+
 // A single timestep in a surf forecast.
 type SurfForecastItem struct {
-	Date                    string
-	Time                    string
+	// The UTC time the forecast is valid for
+	ValidTime time.Time
+
+	// The UTC time of the model run the forecast is from
+	ModelRunTime time.Time
+
+	// The number of hours from the model run to the valid time
+	ForecastHour int
+
 	MinimumBreakingHeight   float64
 	MaximumBreakingHeight   float64
 	WindSpeed               float64
@@ -41,3 +52,13 @@ func (s *SurfForecastItem) ChangeUnits(newUnits UnitSystem) {
 
 	s.Units = newUnits
 }
+
+// Format the date the forecast is valid for in the given time zone, such as Monday January 02, 2006
+func (s SurfForecastItem) FormatDate(loc *time.Location) string {
+	return FormatForecastDate(s.ValidTime, loc)
+}
+
+// Format the hour the forecast is valid for in the given time zone, such as 03 PM
+func (s SurfForecastItem) FormatTime(loc *time.Location) string {
+	return FormatForecastTime(s.ValidTime, loc)
+}
@@ -0,0 +1,388 @@
+package surfnerdtest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mpiannucci/surfnerd"
+)
+
+// A model run requested from the fake NOMADS server
+type dataset struct {
+	Model surfnerd.NOAAModel
+	Run   time.Time
+
+	// Reports the grid points that are land, which are served as fill values
+	Land func(loc surfnerd.Location) bool
+}
+
+// A single variable constraint from a dods query, such as htsgwsfc.htsgwsfc[0:60][246][171]
+type constraint struct {
+	Name   string
+	Grid   bool
+	Ranges [][2]int
+}
+
+// Find the model and run time for a dods dataset path
+func parseDatasetPath(datasetPath string) (dataset, error) {
+	base := strings.TrimSuffix(path.Base(datasetPath), path.Ext(datasetPath))
+	separatorIndex := strings.LastIndex(base, "_")
+	if separatorIndex < 0 || !strings.HasSuffix(base, "z") {
+		return dataset{}, errors.New("Unknown dataset " + datasetPath)
+	}
+	hour, hourErr := strconv.Atoi(strings.TrimSuffix(base[separatorIndex+1:], "z"))
+	if hourErr != nil {
+		return dataset{}, hourErr
+	}
+
+	// The run date is the only eight digit run of numbers in the directory
+	dir := path.Base(path.Dir(datasetPath))
+	if len(dir) < 8 {
+		return dataset{}, errors.New("No run date in dataset " + datasetPath)
+	}
+	runDate, dateErr := time.Parse("20060102", dir[len(dir)-8:])
+	if dateErr != nil {
+		return dataset{}, dateErr
+	}
+
+	var models []surfnerd.NOAAModel
+	for _, model := range surfnerd.GetAllAvailableWaveModels() {
+		models = append(models, model.NOAAModel)
+	}
+	for _, model := range surfnerd.GetAllAvailableWindModels() {
+		models = append(models, model.NOAAModel)
+	}
+	for _, model := range models {
+		if strings.HasPrefix(base, model.Name) {
+			return dataset{Model: model, Run: runDate.Add(time.Duration(hour) * time.Hour)}, nil
+		}
+	}
+
+	return dataset{}, errors.New("Unknown model for dataset " + datasetPath)
+}
+
+// The names of the time, latitude and longitude axes, in the order of the dimensions of the variables
+var axisNames = []string{"time", "lat", "lon"}
+
+// Get the dimension of an axis by name, or -1 if the name is not an axis
+func axisNumber(name string) int {
+	for axis, axisName := range axisNames {
+		if name == axisName {
+			return axis
+		}
+	}
+	return -1
+}
+
+// Parse the comma separated constraint expression of a dods query. The lat and lon axes may be requested
+// without a range, which selects the whole axis of the dataset.
+func parseConstraints(rawQuery string, d dataset) ([]constraint, error) {
+	query, unescapeErr := url.QueryUnescape(rawQuery)
+	if unescapeErr != nil {
+		return nil, unescapeErr
+	}
+
+	var constraints []constraint
+	for _, rawConstraint := range strings.Split(query, ",") {
+		bracketIndex := strings.Index(rawConstraint, "[")
+		if axis := axisNumber(rawConstraint); bracketIndex < 0 && axis > 0 {
+			constraints = append(constraints, constraint{Name: rawConstraint, Ranges: [][2]int{{0, d.axisCount(axis) - 1}}})
+			continue
+		} else if bracketIndex < 0 {
+			return nil, errors.New("Unconstrained variable " + rawConstraint)
+		}
+
+		c := constraint{Name: rawConstraint[:bracketIndex], Grid: true}
+		if dotIndex := strings.LastIndex(c.Name, "."); dotIndex >= 0 {
+			c.Name = c.Name[dotIndex+1:]
+			c.Grid = false
+		}
+
+		for _, rawRange := range strings.Split(strings.Trim(rawConstraint[bracketIndex:], "[]"), "][") {
+			bounds := strings.Split(rawRange, ":")
+			start, startErr := strconv.Atoi(bounds[0])
+			if startErr != nil {
+				return nil, startErr
+			}
+			end := start
+			if len(bounds) > 1 {
+				var endErr error
+				end, endErr = strconv.Atoi(bounds[len(bounds)-1])
+				if endErr != nil {
+					return nil, endErr
+				}
+			}
+			if end < start {
+				return nil, errors.New("Invalid range in constraint " + rawConstraint)
+			}
+			c.Ranges = append(c.Ranges, [2]int{start, end})
+		}
+
+		if axisNumber(c.Name) >= 0 {
+			c.Grid = false
+		}
+		constraints = append(constraints, c)
+	}
+
+	return constraints, nil
+}
+
+// Convert a time to the GrADS time axis value, days since 0001-01-01 in the mixed
+// Julian and Gregorian calendar
+func gradsTime(t time.Time) float64 {
+	const julianOffsetDays = 2
+	epoch := time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC)
+	return float64(t.Unix()-epoch.Unix())/86400.0 + julianOffsetDays
+}
+
+// Get the spacing of the grid points of a dataset. The models round their resolution, such as 0.167 for
+// the 1/6 degree multi_1 grids, so the points are spaced by the whole fraction of a degree it rounds.
+func (d dataset) resolution() float64 {
+	return 1 / math.Round(1/d.Model.LocationResolution)
+}
+
+// Get the time, latitude and longitude axis values of a dataset
+func (d dataset) axisValue(axis, index int) float64 {
+	switch axis {
+	case 0:
+		return gradsTime(d.Run) + float64(index)*d.Model.TimeResolution
+	case 1:
+		return d.Model.BottomLeftLocation.Latitude + float64(index)*d.resolution()
+	default:
+		return d.Model.BottomLeftLocation.Longitude + float64(index)*d.resolution()
+	}
+}
+
+// Get the number of points on the latitude or longitude axis of a dataset
+func (d dataset) axisCount(axis int) int {
+	extent := d.Model.TopRightLocation.Longitude - d.Model.BottomLeftLocation.Longitude
+	if axis == 1 {
+		extent = d.Model.TopRightLocation.Latitude - d.Model.BottomLeftLocation.Latitude
+	}
+	return int(math.Round(extent/d.resolution())) + 1
+}
+
+// Synthesize a plausible value for a model variable at a time step and grid cell
+func (d dataset) value(name string, timeIndex, latIndex, lonIndex int) float64 {
+	if d.Land != nil && d.Land(surfnerd.NewLocationForLatLong(d.axisValue(1, latIndex), d.axisValue(2, lonIndex))) {
+		return gradsFillValue
+	}
+
+	phase := float64(timeIndex)/8.0 + float64(latIndex+lonIndex)/50.0
+	swing := math.Sin(phase)
+
+	windSpeed := 6.0 + 2.0*swing
+	windDirection := 210.0 + 20.0*swing
+	windRadians := windDirection * math.Pi / 180.0
+
+	switch name {
+	case "htsgwsfc":
+		return 1.5 + 0.5*swing
+	case "perpwsfc":
+		return 9.0 + swing
+	case "dirpwsfc":
+		return 160.0 + 10.0*swing
+	case "swell_1":
+		return 1.2 + 0.4*swing
+	case "swper_1":
+		return 10.0 + swing
+	case "swdir_1":
+		return 150.0 + 10.0*swing
+	case "swell_2":
+		return 0.5 + 0.2*swing
+	case "swper_2":
+		return 7.0 + 0.5*swing
+	case "swdir_2":
+		return 100.0 + 5.0*swing
+	case "wvhgtsfc":
+		return 0.6 + 0.2*swing
+	case "wvpersfc":
+		return 5.0 + 0.5*swing
+	case "wvdirsfc":
+		return windDirection
+	case "windsfc":
+		return windSpeed
+	case "wdirsfc":
+		return windDirection
+	case "ugrdsfc", "ugrd10m":
+		return -windSpeed * math.Sin(windRadians)
+	case "vgrdsfc", "vgrd10m":
+		return -windSpeed * math.Cos(windRadians)
+	case "gustsfc":
+		return windSpeed * 1.4
+	default:
+		return 0.0
+	}
+}
+
+// The units of the variables served by the fake models
+var gradsVariableUnits = map[string]string{
+	"htsgwsfc": "m", "perpwsfc": "s", "dirpwsfc": "deg",
+	"swell_1": "m", "swper_1": "s", "swdir_1": "deg",
+	"swell_2": "m", "swper_2": "s", "swdir_2": "deg",
+	"wvhgtsfc": "m", "wvpersfc": "s", "wvdirsfc": "deg",
+	"windsfc": "m/s", "wdirsfc": "deg", "ugrdsfc": "m/s", "vgrdsfc": "m/s",
+	"ugrd10m": "m/s", "vgrd10m": "m/s", "gustsfc": "m/s",
+}
+
+// The value GrADS fills cells without data with, such as the land cells of the wave models
+const gradsFillValue = 9.999e20
+
+// Render the dataset attribute structure describing a model run
+func gradsDAS(d dataset) string {
+	names := make([]string, 0, len(gradsVariableUnits))
+	for name := range gradsVariableUnits {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString("Attributes {\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, "    %s {\n        Float32 _FillValue 9.999E20;\n        Float32 missing_value 9.999E20;\n        String units \"%s\";\n    }\n", name, gradsVariableUnits[name])
+	}
+	b.WriteString("    time {\n        String grads_dim \"t\";\n        String units \"days since 1-1-1 00:00:0.0\";\n    }\n")
+	b.WriteString("    lat {\n        String grads_dim \"y\";\n        String units \"degrees_north\";\n    }\n")
+	b.WriteString("    lon {\n        String grads_dim \"x\";\n        String units \"degrees_east\";\n    }\n")
+	fmt.Fprintf(&b, "    NC_GLOBAL {\n        String title \"%s starting from %s\";\n    }\n", d.Model.Description, d.Run.Format("15Z02Jan2006"))
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// Get the time, latitude and longitude ranges of a constraint, selecting the first index of any
+// dimension it leaves out
+func (c constraint) paddedRanges() [][2]int {
+	ranges := append([][2]int{}, c.Ranges...)
+	for len(ranges) < 3 {
+		ranges = append(ranges, [2]int{0, 0})
+	}
+	return ranges
+}
+
+// Render the DAP dataset descriptor for a set of constraints
+func gradsDDS(d dataset, constraints []constraint) string {
+	var b strings.Builder
+	b.WriteString("Dataset {\n")
+	for _, c := range constraints {
+		if axisNumber(c.Name) >= 0 {
+			fmt.Fprintf(&b, "    Float64 %s[%s = %d];\n", c.Name, c.Name, c.Ranges[0][1]-c.Ranges[0][0]+1)
+			continue
+		}
+
+		ranges := c.paddedRanges()
+		dimensions := ""
+		for axis, r := range ranges[:3] {
+			dimensions += fmt.Sprintf("[%s = %d]", axisNames[axis], r[1]-r[0]+1)
+		}
+
+		if !c.Grid {
+			fmt.Fprintf(&b, "    Float32 %s%s;\n", c.Name, dimensions)
+			continue
+		}
+
+		fmt.Fprintf(&b, "    Grid {\n     ARRAY:\n        Float32 %s%s;\n     MAPS:\n", c.Name, dimensions)
+		for axis, r := range ranges[:3] {
+			fmt.Fprintf(&b, "        Float64 %s[%s = %d];\n", axisNames[axis], axisNames[axis], r[1]-r[0]+1)
+		}
+		fmt.Fprintf(&b, "    } %s;\n", c.Name)
+	}
+	fmt.Fprintf(&b, "} %s%s_%02dz;\n", d.Model.Name, d.Run.Format("20060102"), d.Run.Hour())
+	return b.String()
+}
+
+// Render the XDR encoded values of a DAP data response for a set of constraints
+func gradsXDR(d dataset, constraints []constraint) []byte {
+	var b bytes.Buffer
+	for _, c := range constraints {
+		if axis := axisNumber(c.Name); axis >= 0 {
+			writeXDRAxis(&b, d, axis, c.Ranges[0])
+			continue
+		}
+
+		ranges := c.paddedRanges()
+		values := []float32{}
+		for t := ranges[0][0]; t <= ranges[0][1]; t++ {
+			for lat := ranges[1][0]; lat <= ranges[1][1]; lat++ {
+				for lon := ranges[2][0]; lon <= ranges[2][1]; lon++ {
+					values = append(values, float32(d.value(c.Name, t, lat, lon)))
+				}
+			}
+		}
+		binary.Write(&b, binary.BigEndian, uint32(len(values)))
+		binary.Write(&b, binary.BigEndian, uint32(len(values)))
+		binary.Write(&b, binary.BigEndian, values)
+
+		if c.Grid {
+			for axis, r := range ranges[:3] {
+				writeXDRAxis(&b, d, axis, r)
+			}
+		}
+	}
+	return b.Bytes()
+}
+
+func writeXDRAxis(b *bytes.Buffer, d dataset, axis int, r [2]int) {
+	values := make([]float64, 0, r[1]-r[0]+1)
+	for i := r[0]; i <= r[1]; i++ {
+		values = append(values, d.axisValue(axis, i))
+	}
+	binary.Write(b, binary.BigEndian, uint32(len(values)))
+	binary.Write(b, binary.BigEndian, uint32(len(values)))
+	binary.Write(b, binary.BigEndian, values)
+}
+
+// Render the GrADS ascii response for a set of constraints
+func gradsASCII(d dataset, constraints []constraint) string {
+	var b strings.Builder
+	for _, c := range constraints {
+		if axis := axisNumber(c.Name); axis >= 0 {
+			writeAxis(&b, d, c.Name, axis, c.Ranges[0])
+			continue
+		}
+
+		writeArray(&b, d, c)
+		if c.Grid {
+			for axis, r := range c.Ranges {
+				if axis < len(axisNames) {
+					writeAxis(&b, d, axisNames[axis], axis, r)
+				}
+			}
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func writeAxis(b *strings.Builder, d dataset, name string, axis int, r [2]int) {
+	fmt.Fprintf(b, "%s, [%d]\n", name, r[1]-r[0]+1)
+	values := make([]string, 0, r[1]-r[0]+1)
+	for i := r[0]; i <= r[1]; i++ {
+		values = append(values, strconv.FormatFloat(d.axisValue(axis, i), 'f', -1, 64))
+	}
+	b.WriteString(strings.Join(values, ", "))
+	b.WriteString("\n")
+}
+
+func writeArray(b *strings.Builder, d dataset, c constraint) {
+	ranges := c.paddedRanges()
+
+	fmt.Fprintf(b, "%s, [%d][%d][%d]\n", c.Name, ranges[0][1]-ranges[0][0]+1, ranges[1][1]-ranges[1][0]+1, ranges[2][1]-ranges[2][0]+1)
+	for t := ranges[0][0]; t <= ranges[0][1]; t++ {
+		for lat := ranges[1][0]; lat <= ranges[1][1]; lat++ {
+			fmt.Fprintf(b, "[%d][%d]", t-ranges[0][0], lat-ranges[1][0])
+			for lon := ranges[2][0]; lon <= ranges[2][1]; lon++ {
+				fmt.Fprintf(b, ", %s", strconv.FormatFloat(d.value(c.Name, t, lat, lon), 'f', 4, 64))
+			}
+			b.WriteString("\n")
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package surfnerdtest
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// The kinds of realtime spectra files served by the fake NDBC endpoints
+type spectraKind int
+
+const (
+	energySpectraKind spectraKind = iota
+	alphaOneSpectraKind
+	alphaTwoSpectraKind
+	rOneSpectraKind
+	rTwoSpectraKind
+)
+
+// The frequency bands reported in the realtime spectra files
+var spectraFrequencies = func() []float64 {
+	frequencies := []float64{}
+	for f := 0.0325; f < 0.1; f += 0.005 {
+		frequencies = append(frequencies, f)
+	}
+	for f := 0.1; f < 0.35; f += 0.01 {
+		frequencies = append(frequencies, f)
+	}
+	for f := 0.35; f <= 0.485; f += 0.02 {
+		frequencies = append(frequencies, f)
+	}
+	return frequencies
+}()
+
+// A deterministic oscillation so each observation differs from its neighbours
+func swing(station Station, index int) float64 {
+	seed := 0.0
+	for _, r := range station.ID {
+		seed += float64(r)
+	}
+	return math.Sin(seed + float64(index)/6.0)
+}
+
+func activeStationsXML(stations []Station, now time.Time) string {
+	flag := func(value bool) string {
+		if value {
+			return "y"
+		}
+		return "n"
+	}
+
+	var b strings.Builder
+	b.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	fmt.Fprintf(&b, "<stations created=\"%s\" count=\"%d\">\n", now.Format("2006-01-02T15:04:05UTC"), len(stations))
+	for _, s := range stations {
+		fmt.Fprintf(&b, "  <station id=\"%s\" lat=\"%.3f\" lon=\"%.3f\" elev=\"0\" name=\"%s\" owner=\"%s\" pgm=\"%s\" type=\"%s\" met=\"%s\" currents=\"%s\" waterquality=\"%s\" dart=\"%s\"/>\n",
+			s.ID, s.Latitude, s.Longitude, s.Name, s.Owner, s.PGM, s.Type, flag(s.Met), flag(s.Currents), flag(s.WaterQuality), flag(s.Dart))
+	}
+	b.WriteString("</stations>\n")
+	return b.String()
+}
+
+// The platform of a station as listed in the station table, with a hull code and payload for buoys
+func stationPlatform(station Station) (description, hull, payload string) {
+	if station.Type == "buoy" {
+		return "3-meter discus buoy", "3D", "SCOOP payload"
+	}
+	return "C-MAN station", "", ""
+}
+
+func stationTable(stations []Station) string {
+	var b strings.Builder
+	b.WriteString("# STATION_ID | OWNER | TTYPE | HULL | NAME | PAYLOAD | LOCATION | TIMEZONE | FORECAST | NOTE\n")
+	b.WriteString("#\t|\t|\t|\t|\t|\t|\t|\t|\t|\n")
+	for _, s := range stations {
+		description, hull, payload := stationPlatform(s)
+		fmt.Fprintf(&b, "%s|%s|%s|%s|%s|%s|%.3f N %.3f W|E||\n", s.ID, s.Owner, description, hull, s.Name, payload, s.Latitude, -s.Longitude)
+	}
+	return b.String()
+}
+
+func stationPage(station Station) string {
+	description, _, payload := stationPlatform(station)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "<html><body><h1>Station %s - %s</h1>\n<div id=\"stn_metadata\"><p>\n", strings.ToUpper(station.ID), station.Name)
+	fmt.Fprintf(&b, "<b>Owned and maintained by %s</b><br>\n<b>%s</b><br>\n", station.Owner, description)
+	if payload != "" {
+		fmt.Fprintf(&b, "<b>%s</b><br>\n", payload)
+	}
+	b.WriteString("<br>\n<b>Site elevation:</b> sea level<br>\n")
+	b.WriteString("<b>Air temp height:</b> 3.7 m above site elevation<br>\n")
+	b.WriteString("<b>Anemometer height:</b> 4.1 m above site elevation<br>\n")
+	b.WriteString("<b>Barometer elevation:</b> 2.7 m above mean sea level<br>\n")
+	b.WriteString("<b>Sea temp depth:</b> 1.5 m below water line<br>\n")
+	if station.Type == "buoy" {
+		b.WriteString("<b>Water depth:</b> 48 m<br>\n<b>Watch circle radius:</b> 75 yards<br>\n")
+	}
+	b.WriteString("</p></div></body></html>\n")
+	return b.String()
+}
+
+func standardData(station Station, now time.Time, count int) string {
+	var b strings.Builder
+	b.WriteString("#YY  MM DD hh mm WDIR WSPD GST  WVHT   DPD   APD MWD   PRES  ATMP  WTMP  DEWP  VIS PTDY  TIDE\n")
+	b.WriteString("#yr  mo dy hr mn degT m/s  m/s     m   sec   sec degT   hPa  degC  degC  degC  nmi  hPa    ft\n")
+	for i := 0; i < count; i++ {
+		date := now.Add(-time.Duration(i) * time.Hour)
+		v := swing(station, i)
+		fmt.Fprintf(&b, "%s %3.0f %4.1f %4.1f %5.2f %5.0f %5.1f %3.0f %6.1f %5.1f %5.1f %5.1f %4s %4.1f %5s\n",
+			date.Format("2006 01 02 15 04"),
+			210+20*v, 6+2*v, 8+2*v, 1.5+0.5*v, 9+v, 6+0.5*v, 160+10*v,
+			1015+3*v, 15+v, 17+0.5*v, 12+v, "MM", 0.5*v, "MM")
+	}
+	return b.String()
+}
+
+func detailedWaveData(station Station, now time.Time, count int) string {
+	compass := []string{"S", "SSE", "SE", "SSW", "SW"}
+
+	var b strings.Builder
+	b.WriteString("#YY  MM DD hh mm WVHT  SwH  SwP  WWH  WWP SwD WWD  STEEPNESS  APD MWD\n")
+	b.WriteString("#yr  mo dy hr mn    m    m  sec    m  sec  -  degT     -      sec degT\n")
+	for i := 0; i < count; i++ {
+		date := now.Add(-time.Duration(i) * time.Hour)
+		v := swing(station, i)
+		fmt.Fprintf(&b, "%s %4.1f %4.1f %4.1f %4.1f %4.1f %3s %3s %10s %4.1f %3.0f\n",
+			date.Format("2006 01 02 15 04"),
+			1.5+0.5*v, 1.2+0.4*v, 10+v, 0.6+0.2*v, 5+0.5*v,
+			compass[i%len(compass)], "SW", "AVERAGE", 6+0.5*v, 160+10*v)
+	}
+	return b.String()
+}
+
+func spectraData(station Station, now time.Time, count int, kind spectraKind) string {
+	var b strings.Builder
+	switch kind {
+	case energySpectraKind:
+		b.WriteString("#YY  MM DD hh mm Sep_Freq  < spec_1 (freq_1) spec_2 (freq_2) spec_3 (freq_3) ... >\n")
+	case alphaTwoSpectraKind:
+		b.WriteString("#YY  MM DD hh mm alpha2_1 (freq_1) alpha2_2 (freq_2) alpha2_3 (freq_3) ... >\n")
+	case rOneSpectraKind:
+		b.WriteString("#YY  MM DD hh mm r1_1 (freq_1) r1_2 (freq_2) r1_3 (freq_3) ... >\n")
+	case rTwoSpectraKind:
+		b.WriteString("#YY  MM DD hh mm r2_1 (freq_1) r2_2 (freq_2) r2_3 (freq_3) ... >\n")
+	default:
+		b.WriteString("#YY  MM DD hh mm alpha1_1 (freq_1) alpha1_2 (freq_2) alpha1_3 (freq_3) ... >\n")
+	}
+
+	for i := 0; i < count; i++ {
+		date := now.Add(-time.Duration(i) * time.Hour)
+		v := swing(station, i)
+		b.WriteString(date.Format("2006 01 02 15 04"))
+
+		if kind == energySpectraKind {
+			b.WriteString(" 0.150")
+		}
+
+		// A long period swell peak and a short period wind sea peak
+		swellPeak, windPeak := 0.09+0.005*v, 0.2
+		for _, f := range spectraFrequencies {
+			var value float64
+			switch kind {
+			case energySpectraKind:
+				value = 3.0*math.Exp(-math.Pow((f-swellPeak)/0.012, 2)) + 0.8*math.Exp(-math.Pow((f-windPeak)/0.03, 2))
+			default:
+				if f < 0.15 {
+					value = 160 + 10*v
+				} else {
+					value = 220
+				}
+			}
+			fmt.Fprintf(&b, " %.3f (%.4f)", value, f)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func latestObservation(station Station, now time.Time) string {
+	v := swing(station, 0)
+	latitudeHemisphere, longitudeHemisphere := "N", "E"
+	if station.Latitude < 0 {
+		latitudeHemisphere = "S"
+	}
+	if station.Longitude < 0 {
+		longitudeHemisphere = "W"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Station %s\n", station.ID)
+	fmt.Fprintf(&b, "%.3f %s %.3f %s\n", math.Abs(station.Latitude), latitudeHemisphere, math.Abs(station.Longitude), longitudeHemisphere)
+	b.WriteString("\n")
+	fmt.Fprintf(&b, "%s\n", now.Format("3:04 pm")+" GMT")
+	fmt.Fprintf(&b, "%s GMT %s\n", now.Format("1504"), now.Format("01/02/06"))
+	b.WriteString("\n")
+	fmt.Fprintf(&b, "Wind: SW (%.0f°), %.1f kt\n", 220+10*v, 11+2*v)
+	fmt.Fprintf(&b, "Gust: %.1f kt\n", 14+2*v)
+	fmt.Fprintf(&b, "Seas: %.1f ft\n", 4+v)
+	fmt.Fprintf(&b, "Peak Period: %.0f sec\n", 9+v)
+	fmt.Fprintf(&b, "Pres: %.2f falling\n", 30+0.1*v)
+	fmt.Fprintf(&b, "Air Temp: %.1f °F\n", 60+2*v)
+	fmt.Fprintf(&b, "Water Temp: %.1f °F\n", 63+v)
+	fmt.Fprintf(&b, "Dew Point: %.1f °F\n", 55+v)
+	b.WriteString("\n")
+	b.WriteString("Wave Summary\n")
+	fmt.Fprintf(&b, "%s GMT %s\n", now.Format("1504"), now.Format("01/02/06"))
+	fmt.Fprintf(&b, "Swell: %.1f ft\n", 3+v)
+	fmt.Fprintf(&b, "Period: %.1f sec\n", 9+v)
+	b.WriteString("Direction: SSE\n")
+	fmt.Fprintf(&b, "Wind Wave: %.1f ft\n", 2+0.5*v)
+	fmt.Fprintf(&b, "Period: %.1f sec\n", 4+0.5*v)
+	b.WriteString("Direction: SW\n")
+	return b.String()
+}
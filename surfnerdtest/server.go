@@ -0,0 +1,255 @@
+// Package surfnerdtest provides a fake NOAA server for testing code built on surfnerd without
+// reaching the real NDBC and NOMADS servers. The data it serves is synthetic but follows the
+// formats of the real endpoints closely enough for every surfnerd parser to read it.
+package surfnerdtest
+
+import (
+	"context"
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/mpiannucci/surfnerd"
+)
+
+// A buoy station served by the fake NDBC endpoints
+type Station struct {
+	ID           string
+	Name         string
+	Owner        string
+	PGM          string
+	Type         string
+	Latitude     float64
+	Longitude    float64
+	Met          bool
+	Currents     bool
+	WaterQuality bool
+	Dart         bool
+}
+
+// The stations served by a new Server
+var DefaultStations = []Station{
+	{ID: "44017", Name: "MONTAUK POINT - 23 NM SSW of Montauk Point, NY", Owner: "NDBC", PGM: "NDBC Meteorological/Ocean", Type: "buoy", Latitude: 40.694, Longitude: -72.048, Met: true},
+	{ID: "44097", Name: "Block Island, RI (154)", Owner: "Scripps Institution of Oceanography", PGM: "IOOS Partners", Type: "buoy", Latitude: 40.967, Longitude: -71.126, Met: false},
+	{ID: "44025", Name: "LONG ISLAND - 30 NM South of Islip, NY", Owner: "NDBC", PGM: "NDBC Meteorological/Ocean", Type: "buoy", Latitude: 40.251, Longitude: -73.164, Met: true},
+	{ID: "41001", Name: "EAST HATTERAS - 150 NM East of Cape Hatteras", Owner: "NDBC", PGM: "NDBC Meteorological/Ocean", Type: "buoy", Latitude: 34.675, Longitude: -72.698, Met: true},
+	{ID: "46026", Name: "SAN FRANCISCO - 18NM West of San Francisco, CA", Owner: "NDBC", PGM: "NDBC Meteorological/Ocean", Type: "buoy", Latitude: 37.754, Longitude: -122.839, Met: true},
+	{ID: "nwpr1", Name: "Newport, RI", Owner: "NOS", PGM: "NOS/CO-OPS", Type: "fixed", Latitude: 41.505, Longitude: -71.326, Met: true, WaterQuality: true},
+}
+
+// A fake NOAA server emulating the NDBC realtime2, latest_obs, station metadata and activestations.xml endpoints
+// as well as the NOMADS dods .ascii endpoints used by the wave and wind models.
+//
+// The surfnerd base urls are left alone. Requests reach the server through the Fetcher it hands out,
+// which is passed to the Context variants of the surfnerd fetch functions, so any number of Servers
+// can run at once, including from parallel tests.
+type Server struct {
+	*httptest.Server
+
+	// The stations listed in activestations.xml and served by the realtime endpoints
+	Stations []Station
+
+	// The time of the newest buoy observation. Older observations are served hourly before it.
+	Now time.Time
+
+	// The number of observations served in each realtime buoy file
+	RecordCount int
+
+	// How long after its cycle a model run is published. Requests for runs newer than Now minus the
+	// delay are not found, as they are on the real server early in a cycle.
+	ModelRunDelay time.Duration
+
+	// Reports the grid points of the wave models that are land, which are served as fill values like the
+	// land cells of the real wave models. A nil mask serves every grid point as water.
+	LandMask func(loc surfnerd.Location) bool
+}
+
+// Start a new fake NOAA server
+func NewServer() *Server {
+	s := &Server{
+		Stations:      DefaultStations,
+		Now:           time.Now().UTC().Truncate(time.Hour).Add(-10 * time.Minute),
+		RecordCount:   48,
+		ModelRunDelay: 4 * time.Hour,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/activestations.xml", s.handleActiveStations)
+	mux.HandleFunc("/data/realtime2/", s.handleRealtime)
+	mux.HandleFunc("/data/latest_obs/", s.handleLatestObservation)
+	mux.HandleFunc("/data/stations/station_table.txt", s.handleStationTable)
+	mux.HandleFunc("/station_page.php", s.handleStationPage)
+	mux.HandleFunc("/dods/", s.handleDods)
+	s.Server = httptest.NewServer(mux)
+
+	return s
+}
+
+// Get a Fetcher that sends the requests for the NDBC and NOMADS servers to the fake server instead.
+// Other urls are fetched as given.
+func (s *Server) Fetcher() surfnerd.Fetcher {
+	return &serverFetcher{url: s.URL, http: surfnerd.NewHTTPFetcher(s.Client())}
+}
+
+// A Fetcher rewriting the urls of the NOAA servers to the url of a fake server
+type serverFetcher struct {
+	url  string
+	http *surfnerd.HTTPFetcher
+}
+
+// Get the url of the fake server for a url of the NOAA servers
+func (f *serverFetcher) rewrite(url string) string {
+	if url == surfnerd.ActiveBuoysURL {
+		return f.url + "/activestations.xml"
+	}
+	for _, base := range []string{surfnerd.NDBCBaseURL, surfnerd.NOMADSBaseURL} {
+		if strings.HasPrefix(url, base) {
+			return f.url + strings.TrimPrefix(url, base)
+		}
+	}
+	return url
+}
+
+func (f *serverFetcher) Fetch(ctx context.Context, url string) ([]byte, error) {
+	return f.http.Fetch(ctx, f.rewrite(url))
+}
+
+func (f *serverFetcher) FetchStream(ctx context.Context, url string) (io.ReadCloser, error) {
+	return f.http.FetchStream(ctx, f.rewrite(url))
+}
+
+func (f *serverFetcher) FetchIfModified(ctx context.Context, url string, validators surfnerd.CacheValidators) ([]byte, surfnerd.CacheValidators, error) {
+	return f.http.FetchIfModified(ctx, f.rewrite(url), validators)
+}
+
+// Find a served station by its id
+func (s *Server) station(id string) (Station, bool) {
+	for _, station := range s.Stations {
+		if strings.ToLower(station.ID) == strings.ToLower(id) {
+			return station, true
+		}
+	}
+	return Station{}, false
+}
+
+func (s *Server) handleActiveStations(w http.ResponseWriter, r *http.Request) {
+	// Serve validators so station catalogs can revalidate their cached copy
+	body := activeStationsXML(s.Stations, s.Now)
+	w.Header().Set("Content-Type", "text/xml")
+	w.Header().Set("ETag", fmt.Sprintf("\"%x\"", sha1.Sum([]byte(body))))
+	http.ServeContent(w, r, "activestations.xml", s.Now, strings.NewReader(body))
+}
+
+func (s *Server) handleRealtime(w http.ResponseWriter, r *http.Request) {
+	fileName := strings.TrimPrefix(r.URL.Path, "/data/realtime2/")
+	dotIndex := strings.Index(fileName, ".")
+	if dotIndex < 0 {
+		http.NotFound(w, r)
+		return
+	}
+
+	station, ok := s.station(fileName[:dotIndex])
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	var body string
+	switch fileName[dotIndex:] {
+	case ".txt":
+		body = standardData(station, s.Now, s.RecordCount)
+	case ".spec":
+		body = detailedWaveData(station, s.Now, s.RecordCount)
+	case ".swdir":
+		body = spectraData(station, s.Now, s.RecordCount, alphaOneSpectraKind)
+	case ".swdir2":
+		body = spectraData(station, s.Now, s.RecordCount, alphaTwoSpectraKind)
+	case ".swr1":
+		body = spectraData(station, s.Now, s.RecordCount, rOneSpectraKind)
+	case ".swr2":
+		body = spectraData(station, s.Now, s.RecordCount, rTwoSpectraKind)
+	case ".data_spec":
+		body = spectraData(station, s.Now, s.RecordCount, energySpectraKind)
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte(body))
+}
+
+func (s *Server) handleLatestObservation(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/data/latest_obs/"), ".txt")
+	station, ok := s.station(id)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte(latestObservation(station, s.Now)))
+}
+
+func (s *Server) handleStationTable(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte(stationTable(s.Stations)))
+}
+
+func (s *Server) handleStationPage(w http.ResponseWriter, r *http.Request) {
+	station, ok := s.station(r.URL.Query().Get("station"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	w.Write([]byte(stationPage(station)))
+}
+
+func (s *Server) handleDods(w http.ResponseWriter, r *http.Request) {
+	dataset, datasetErr := parseDatasetPath(r.URL.Path)
+	if datasetErr != nil {
+		http.Error(w, datasetErr.Error(), http.StatusNotFound)
+		return
+	} else if dataset.Run.After(s.Now.Add(-s.ModelRunDelay)) {
+		http.Error(w, "The model run has not been published", http.StatusNotFound)
+		return
+	}
+
+	if strings.HasPrefix(dataset.Model.Name, "multi_1") {
+		dataset.Land = s.LandMask
+	}
+
+	if strings.HasSuffix(r.URL.Path, ".das") {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(gradsDAS(dataset)))
+		return
+	}
+
+	constraints, constraintErr := parseConstraints(r.URL.RawQuery, dataset)
+	if constraintErr != nil {
+		http.Error(w, constraintErr.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch path.Ext(r.URL.Path) {
+	case ".ascii":
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(gradsASCII(dataset, constraints)))
+	case ".dds":
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(gradsDDS(dataset, constraints)))
+	case ".dods":
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write([]byte(gradsDDS(dataset, constraints) + "Data:\n"))
+		w.Write(gradsXDR(dataset, constraints))
+	default:
+		http.NotFound(w, r)
+	}
+}
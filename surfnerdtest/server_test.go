@@ -0,0 +1,322 @@
+package surfnerdtest
+
+import (
+	"context"
+	"io/ioutil"
+	"math"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/mpiannucci/surfnerd"
+)
+
+// Find a station of the server with a catalog of its own, so the cache of the DefaultStationCatalog is
+// left alone
+func findBuoy(t *testing.T, fetcher surfnerd.Fetcher, stationID string) *surfnerd.Buoy {
+	buoy, findErr := surfnerd.NewStationCatalog("", 0).FindBuoyByIDContext(context.Background(), fetcher, stationID)
+	if findErr != nil {
+		t.Fatal(findErr)
+	} else if buoy == nil {
+		t.Fatal("Could not find the buoy for the given ID")
+	}
+	return buoy
+}
+
+func TestBuoyFlows(t *testing.T) {
+	t.Parallel()
+	server := NewServer()
+	defer server.Close()
+	ctx, fetcher := context.Background(), server.Fetcher()
+
+	buoy := findBuoy(t, fetcher, "44017")
+
+	if fetchErr := buoy.FetchLatestBuoyReadingContext(ctx, fetcher); fetchErr != nil {
+		t.Fatal(fetchErr)
+	}
+	if buoy.BuoyData[0].Date.IsZero() {
+		t.Error("Latest reading has no date")
+	}
+
+	if fetchErr := buoy.FetchStandardDataContext(ctx, fetcher, 24); fetchErr != nil {
+		t.Fatal(fetchErr)
+	}
+	if len(buoy.BuoyData) != 24 {
+		t.Errorf("Expected 24 standard data items, got %d", len(buoy.BuoyData))
+	}
+	if !buoy.BuoyData[0].Date.Equal(server.Now) {
+		t.Errorf("Expected the newest observation at %v, got %v", server.Now, buoy.BuoyData[0].Date)
+	}
+
+	if fetchErr := buoy.FetchDetailedWaveDataContext(ctx, fetcher, 24); fetchErr != nil {
+		t.Fatal(fetchErr)
+	}
+	if _, dur := buoy.FindConditionsForDateAndTime(time.Now()); dur < 0 {
+		t.Error("Failed to find buoy data for the given date")
+	}
+
+	if fetchErr := buoy.FetchRawWaveSpectraDataContext(ctx, fetcher, 1); fetchErr != nil {
+		t.Fatal(fetchErr)
+	}
+	if buoy.BuoyData[0].WaveSummary.WaveHeight <= 0 {
+		t.Error("Spectra produced no wave height")
+	}
+
+	if fetchErr := buoy.FetchDirectionalWaveSpectraDataContext(ctx, fetcher, 2); fetchErr != nil {
+		t.Fatal(fetchErr)
+	}
+	if !buoy.BuoyData[1].WaveSpectra.HasDirectionalCoefficients() {
+		t.Fatal("Spectra has no directional coefficients")
+	}
+	if _, spectrumErr := buoy.BuoyData[1].WaveSpectra.DirectionalSpectrum(72, surfnerd.MaximumEntropyMethod); spectrumErr != nil {
+		t.Error(spectrumErr)
+	}
+}
+
+func TestClosestBuoy(t *testing.T) {
+	t.Parallel()
+	server := NewServer()
+	defer server.Close()
+	ctx, fetcher := context.Background(), server.Fetcher()
+
+	stations := surfnerd.BuoyStations{}
+	if fetchErr := stations.GetAllActiveBuoyStationsContext(ctx, fetcher); fetchErr != nil {
+		t.Fatal(fetchErr)
+	}
+	if stations.StationCount != len(server.Stations) {
+		t.Fatalf("Expected %d stations, got %d", len(server.Stations), stations.StationCount)
+	}
+
+	closest := stations.FindClosestActiveBuoy(surfnerd.NewLocationForLatLong(40.695, -72.048))
+	if closest == nil || closest.StationID != "44017" {
+		t.Fatal("Failed to find the correct closest active buoy")
+	}
+}
+
+func TestSurfForecastFlow(t *testing.T) {
+	t.Parallel()
+	server := NewServer()
+	defer server.Close()
+	ctx, fetcher := context.Background(), server.Fetcher()
+
+	waveLocation := surfnerd.NewLocationForLatLong(41.323, 360-71.396)
+	waveForecast, waveErr := surfnerd.FetchWaveForecastContext(ctx, fetcher, waveLocation)
+	if waveErr != nil {
+		t.Fatal(waveErr)
+	} else if len(waveForecast.ForecastData) == 0 {
+		t.Fatal("No wave forecast was fetched")
+	}
+	if waveForecast.ForecastData[0].SignificantWaveHeight <= 0 {
+		t.Error("Wave forecast has no wave height")
+	}
+
+	windLocation := surfnerd.NewLocationForLatLong(41.6, 360-71.459)
+	windForecast, windErr := surfnerd.FetchWindForecastForModelContext(ctx, fetcher, windLocation, surfnerd.NewGFSWindModel())
+	if windErr != nil {
+		t.Fatal(windErr)
+	} else if len(windForecast.ForecastData) == 0 {
+		t.Fatal("No wind forecast was fetched")
+	}
+	if windForecast.ForecastData[0].WindSpeed <= 0 {
+		t.Error("Wind forecast has no wind speed")
+	}
+
+	surfForecast := surfnerd.NewSurfForecast(waveLocation, 145.0, 0.02, waveForecast, windForecast)
+	if surfForecast == nil || len(surfForecast.ForecastData) != len(waveForecast.ForecastData) {
+		t.Fatal("Failed to build the surf forecast")
+	}
+}
+
+func TestStationCatalogRevalidation(t *testing.T) {
+	t.Parallel()
+	server := NewServer()
+	defer server.Close()
+	ctx, fetcher := context.Background(), server.Fetcher()
+
+	catalog := surfnerd.NewStationCatalog("", 0)
+	first, firstErr := catalog.StationsContext(ctx, fetcher)
+	if firstErr != nil {
+		t.Fatal(firstErr)
+	}
+
+	diff, refreshErr := catalog.RefreshContext(ctx, fetcher)
+	if refreshErr != nil {
+		t.Fatal(refreshErr)
+	} else if !diff.IsEmpty() {
+		t.Error("Expected the unchanged station list to be revalidated without changes")
+	}
+
+	second, secondErr := catalog.StationsContext(ctx, fetcher)
+	if secondErr != nil {
+		t.Fatal(secondErr)
+	} else if second != first {
+		t.Error("Expected a not modified response to keep the cached stations")
+	}
+}
+
+func TestStationMetadataFlow(t *testing.T) {
+	t.Parallel()
+	server := NewServer()
+	defer server.Close()
+	ctx, fetcher := context.Background(), server.Fetcher()
+
+	buoy := findBuoy(t, fetcher, "44017")
+	if fetchErr := buoy.FetchStationMetadataContext(ctx, fetcher); fetchErr != nil {
+		t.Fatal(fetchErr)
+	}
+	if buoy.Metadata.HullType != "3D" || buoy.Metadata.WaterDepth != 48 || buoy.Metadata.AnemometerHeight != 4.1 {
+		t.Errorf("Unexpected station metadata %+v", buoy.Metadata)
+	}
+
+	if fetchErr := buoy.FetchStandardDataContext(ctx, fetcher, 1); fetchErr != nil {
+		t.Fatal(fetchErr)
+	}
+	observed := buoy.BuoyData[0].WindSpeed
+	if adjustErr := buoy.AdjustWindToTenMeters(); adjustErr != nil {
+		t.Fatal(adjustErr)
+	}
+	if buoy.BuoyData[0].WindSpeed <= observed {
+		t.Errorf("Expected the 10 meter wind to be stronger than the %v observed at 4.1 meters, got %v", observed, buoy.BuoyData[0].WindSpeed)
+	}
+}
+
+func TestWaveModelGrid(t *testing.T) {
+	t.Parallel()
+	server := NewServer()
+	defer server.Close()
+	ctx, fetcher := context.Background(), server.Fetcher()
+
+	bottomLeft := surfnerd.NewLocationForLatLong(40.9, 360-71.6)
+	topRight := surfnerd.NewLocationForLatLong(41.4, 360-71.1)
+	timeRange := surfnerd.ModelTimeRange{StartIndex: 0, EndIndex: 8}
+	grid, gridErr := surfnerd.FetchWaveModelGridContext(ctx, fetcher, bottomLeft, topRight, timeRange)
+	if gridErr != nil {
+		t.Fatal(gridErr)
+	}
+	if len(grid.Latitudes) < 2 || len(grid.Longitudes) < 2 || len(grid.Times) != 9 {
+		t.Fatalf("Unexpected grid of %d by %d by %d", len(grid.Latitudes), len(grid.Longitudes), len(grid.Times))
+	}
+
+	// The axes are the points of the 1/6 degree grid, not multiples of the rounded 0.167 resolution
+	for _, coordinate := range append(append([]float64{}, grid.Latitudes...), grid.Longitudes...) {
+		if math.Abs(coordinate*6-math.Round(coordinate*6)) > 1e-6 {
+			t.Fatalf("Expected %v to be on the 1/6 degree grid", coordinate)
+		}
+	}
+
+	// A single location is the same as a one cell grid
+	loc := surfnerd.NewLocationForLatLong(41.323, 360-71.396)
+	modelData, dataErr := surfnerd.FetchWaveModelDataContext(ctx, fetcher, loc)
+	if dataErr != nil {
+		t.Fatal(dataErr)
+	}
+	latIndex, lonIndex := grid.LocationIndices(loc)
+	series := grid.Series("htsgwsfc", latIndex, lonIndex)
+	for i, height := range series {
+		if height != modelData.Data["htsgwsfc"][i] {
+			t.Fatalf("Expected the grid and single location heights to match at %d, got %v and %v", i, height, modelData.Data["htsgwsfc"][i])
+		}
+	}
+}
+
+func TestWaveModelLandCell(t *testing.T) {
+	t.Parallel()
+	server := NewServer()
+	defer server.Close()
+	ctx, fetcher := context.Background(), server.Fetcher()
+
+	// Everything north of the spot's cell is land, like a spot tucked up a bay
+	server.LandMask = func(loc surfnerd.Location) bool {
+		return loc.Latitude > 41.2
+	}
+
+	loc := surfnerd.NewLocationForLatLong(41.323, 360-71.396)
+	modelData, dataErr := surfnerd.FetchWaveModelDataContext(ctx, fetcher, loc)
+	if dataErr != nil {
+		t.Fatal(dataErr)
+	}
+	if modelData.Location.Latitude > 41.2 {
+		t.Errorf("Expected a water cell south of the spot, got %v", modelData.Location.Latitude)
+	}
+	for i, height := range modelData.Data["htsgwsfc"] {
+		if surfnerd.IsMissing(height) {
+			t.Fatalf("Expected a wave height at %d", i)
+		}
+	}
+}
+
+func TestWindModelInterpolation(t *testing.T) {
+	t.Parallel()
+	server := NewServer()
+	defer server.Close()
+	ctx, fetcher := context.Background(), server.Fetcher()
+
+	loc := surfnerd.NewLocationForLatLong(41.6, 360-71.459)
+	model := surfnerd.NewGFSWindModel()
+	model.Interpolation = surfnerd.BilinearInterpolation
+	modelData, dataErr := surfnerd.FetchWindModelDataForModelContext(ctx, fetcher, loc, model)
+	if dataErr != nil {
+		t.Fatal(dataErr)
+	}
+	if modelData.Location.Latitude != loc.Latitude || modelData.Location.Longitude != loc.Longitude {
+		t.Errorf("Expected the data to be interpolated to the location, got %v", modelData.Location)
+	}
+	for i, speed := range modelData.Data["ugrd10m"] {
+		if surfnerd.IsMissing(speed) {
+			t.Fatalf("Expected a wind speed at %d", i)
+		}
+	}
+}
+
+func TestSurfForecastReplay(t *testing.T) {
+	t.Parallel()
+	fixtureDir, dirErr := ioutil.TempDir("", "surfnerd-forecast-fixtures")
+	if dirErr != nil {
+		t.Fatal(dirErr)
+	}
+	defer os.RemoveAll(fixtureDir)
+
+	// Record a forecast from a run that is long out of date by the time it is replayed
+	run := time.Date(2016, 10, 18, 6, 0, 0, 0, time.UTC)
+	waveLocation := surfnerd.NewLocationForLatLong(41.323, 360-71.396)
+	waveLocation.Elevation = 30
+	windLocation := surfnerd.NewLocationForLatLong(41.6, 360-71.459)
+	fetchForecast := func(fetcher surfnerd.Fetcher) *surfnerd.SurfForecast {
+		ctx := context.Background()
+		waveForecast, waveErr := surfnerd.FetchWaveForecastContext(ctx, fetcher, waveLocation)
+		if waveErr != nil {
+			t.Fatal(waveErr)
+		}
+		windForecast, windErr := surfnerd.FetchWindForecastForModelContext(ctx, fetcher, windLocation, surfnerd.NewGFSWindModel())
+		if windErr != nil {
+			t.Fatal(windErr)
+		}
+		return surfnerd.NewSurfForecast(waveLocation, 145.0, 0.02, waveForecast, windForecast)
+	}
+
+	server := NewServer()
+	server.Now = run.Add(7 * time.Hour)
+	recorder := surfnerd.NewRecordingFetcher(fixtureDir, server.Fetcher())
+	recorder.ModelRun = run
+	recorded := fetchForecast(recorder)
+	server.Close()
+
+	replayer := surfnerd.NewReplayFetcher(fixtureDir)
+	replayer.ModelRun = run
+	replayed := fetchForecast(replayer)
+
+	if len(replayed.ForecastData) == 0 || len(replayed.ForecastData) != len(recorded.ForecastData) {
+		t.Fatalf("Expected %d replayed forecast items, got %d", len(recorded.ForecastData), len(replayed.ForecastData))
+	}
+	for i, item := range replayed.ForecastData {
+		if !item.ValidTime.Equal(recorded.ForecastData[i].ValidTime) || item.MaximumBreakingHeight != recorded.ForecastData[i].MaximumBreakingHeight || item.WindSpeed != recorded.ForecastData[i].WindSpeed {
+			t.Fatalf("Replayed forecast differs from the recorded one at %d", i)
+		}
+	}
+	if surfnerd.IsMissing(replayed.ForecastData[0].MaximumBreakingHeight) {
+		t.Error("Replayed forecast has no breaking wave height")
+	}
+	if !replayed.ForecastData[0].ModelRunTime.Equal(run) {
+		t.Errorf("Expected the forecast to be from the pinned run, got %v", replayed.ForecastData[0].ModelRunTime)
+	}
+}
@@ -1,6 +1,7 @@
 package surfnerd
 
 import (
+	"encoding/json"
 	"math"
 )
 
@@ -10,6 +11,12 @@ type Swell struct {
 	Direction        float64
 	CompassDirection string
 
+	// Partition statistics, set when the swell is found by partitioning a directional spectrum.
+	// The directional spread is in degrees.
+	MeanPeriod        float64 `json:",omitempty"`
+	DirectionalSpread float64 `json:",omitempty"`
+	WindSea           bool    `json:",omitempty"`
+
 	// Metadata
 	MaxEnergy      float64 `json:",omitempty"`
 	FrequencyIndex int     `json:",omitempty"`
@@ -35,18 +42,64 @@ func (s *Swell) ChangeUnits(newUnits UnitSystem) {
 	s.Units = newUnits
 }
 
-// Tests if the swell has valid numbers or if it is just maxed out to show null
+// Tests if the swell has valid numbers or if it is missing or just maxed out to show null
 func (s *Swell) IsValid() bool {
-	if s.WaveHeight > 1000 {
+	if IsMissing(s.WaveHeight) {
+		return false
+	} else if s.WaveHeight > 1000 {
 		return false
 	}
 	return true
 }
 
+// Convert a Swell to json, writing missing values as null
+func (s Swell) MarshalJSON() ([]byte, error) {
+	type plainSwell Swell
+	return json.Marshal(struct {
+		plainSwell
+		WaveHeight        nullableFloat
+		Period            nullableFloat
+		Direction         nullableFloat
+		MeanPeriod        nullableFloat `json:",omitempty"`
+		DirectionalSpread nullableFloat `json:",omitempty"`
+	}{
+		plainSwell(s),
+		nullableFloat(s.WaveHeight),
+		nullableFloat(s.Period),
+		nullableFloat(s.Direction),
+		nullableFloat(s.MeanPeriod),
+		nullableFloat(s.DirectionalSpread),
+	})
+}
+
+// Read a Swell from json, reading null values as missing
+func (s *Swell) UnmarshalJSON(data []byte) error {
+	type plainSwell Swell
+	shadow := struct {
+		*plainSwell
+		WaveHeight        nullableFloat
+		Period            nullableFloat
+		Direction         nullableFloat
+		MeanPeriod        nullableFloat
+		DirectionalSpread nullableFloat
+	}{plainSwell: (*plainSwell)(s)}
+
+	if err := json.Unmarshal(data, &shadow); err != nil {
+		return err
+	}
+
+	s.WaveHeight = float64(shadow.WaveHeight)
+	s.Period = float64(shadow.Period)
+	s.Direction = float64(shadow.Direction)
+	s.MeanPeriod = float64(shadow.MeanPeriod)
+	s.DirectionalSpread = float64(shadow.DirectionalSpread)
+	return nil
+}
+
 // Interpolates the approximate breaking wave heights using the contained swell data. Data must
 // be in metric units prior to calling this function. The depth argument must be in meters.
 func (s *Swell) BreakingWaveHeights(beachAngle, depth, beachSlope float64) (minimumBreakHeight, maximumBreakHeight float64) {
-	if !s.IsValid() {
+	if !s.IsValid() || IsMissing(s.Period) || IsMissing(s.Direction) {
 		return
 	}
 
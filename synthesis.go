@@ -0,0 +1,265 @@
+package surfnerd
+
+import (
+	"math"
+)
+
+// The parametric spectrum shapes that can be synthesized from a Swell
+type SpectrumShape int
+
+const (
+	// The JONSWAP spectrum of Hasselmann et al. (1973), a fetch limited sea with a peak enhanced by gamma
+	JONSWAPSpectrum SpectrumShape = iota
+
+	// The fully developed sea of Pierson and Moskowitz (1964), the JONSWAP spectrum with a gamma of 1
+	PiersonMoskowitzSpectrum
+
+	// The TMA spectrum of Bouws et al. (1985), the JONSWAP spectrum reduced for a finite water depth
+	TMASpectrum
+)
+
+// The JONSWAP peak enhancement factor used when none is given
+const defaultJONSWAPGamma = 3.3
+
+// Describes how to synthesize a spectrum from Swell values
+type SpectrumOptions struct {
+	Shape SpectrumShape
+
+	// The JONSWAP and TMA peak enhancement factor. Zero uses the standard 3.3.
+	Gamma float64
+
+	// The water depth in meters for the TMA spectrum
+	Depth float64
+
+	// The cos-2s spreading parameter for swells that do not have a DirectionalSpread. Zero uses the
+	// same default as the cosine directional spreading method.
+	Spreading float64
+}
+
+// Get the 47 frequency bands reported by the NDBC directional wave buoys, in Hz
+func DefaultSpectralFrequencies() []float64 {
+	frequencies := []float64{0.02}
+	for i := 0; i < 13; i++ {
+		frequencies = append(frequencies, 0.0325+0.005*float64(i))
+	}
+	for i := 0; i < 26; i++ {
+		frequencies = append(frequencies, 0.1+0.01*float64(i))
+	}
+	for i := 0; i < 7; i++ {
+		frequencies = append(frequencies, 0.365+0.02*float64(i))
+	}
+	return frequencies
+}
+
+// Compute the energies of a JONSWAP spectrum with the given significant wave height in meters, peak
+// period in seconds and peak enhancement factor. The spectrum is scaled so its energy over the given
+// frequencies matches the wave height exactly.
+func JONSWAPEnergies(frequencies []float64, waveHeight, peakPeriod, gamma float64) []float64 {
+	const gravity = 9.81
+
+	energies := make([]float64, len(frequencies))
+	if peakPeriod <= 0 || waveHeight <= 0 {
+		return energies
+	}
+
+	peakFrequency := 1.0 / peakPeriod
+	for i, f := range frequencies {
+		if f <= 0 {
+			continue
+		}
+
+		sigma := 0.07
+		if f > peakFrequency {
+			sigma = 0.09
+		}
+
+		shape := math.Pow(gravity, 2) * math.Pow(2.0*math.Pi, -4) * math.Pow(f, -5) * math.Exp(-1.25*math.Pow(peakFrequency/f, 4))
+		enhancement := math.Pow(gamma, math.Exp(-math.Pow(f-peakFrequency, 2)/(2.0*math.Pow(sigma*peakFrequency, 2))))
+		energies[i] = shape * enhancement
+	}
+
+	return scaleToWaveHeight(frequencies, energies, waveHeight)
+}
+
+// Compute the energies of a Pierson-Moskowitz spectrum with the given significant wave height in
+// meters and peak period in seconds
+func PiersonMoskowitzEnergies(frequencies []float64, waveHeight, peakPeriod float64) []float64 {
+	return JONSWAPEnergies(frequencies, waveHeight, peakPeriod, 1.0)
+}
+
+// Compute the energies of a TMA spectrum in the given water depth in meters. The JONSWAP shape is
+// reduced by the Kitaigorodskii depth factor and then scaled back to the significant wave height.
+func TMAEnergies(frequencies []float64, waveHeight, peakPeriod, gamma, depth float64) []float64 {
+	const gravity = 9.81
+
+	energies := JONSWAPEnergies(frequencies, waveHeight, peakPeriod, gamma)
+	if depth <= 0 {
+		return energies
+	}
+
+	for i, f := range frequencies {
+		omega := 2.0 * math.Pi * f * math.Sqrt(depth/gravity)
+		switch {
+		case omega <= 1.0:
+			energies[i] *= 0.5 * omega * omega
+		case omega < 2.0:
+			energies[i] *= 1.0 - 0.5*math.Pow(2.0-omega, 2)
+		}
+	}
+
+	return scaleToWaveHeight(frequencies, energies, waveHeight)
+}
+
+// Synthesize the wave spectra of a set of swells, adding the energies of each. The directional
+// coefficients of the combined cos-2s spreading are filled in, so the result can be compared with a
+// buoy spectra band by band or turned back into a 2D spectrum. Swells that are missing a height, period
+// or direction are skipped, and English swells are converted to metric.
+func SynthesizeSpectra(swells []Swell, frequencies []float64, options SpectrumOptions) BuoySpectraItem {
+	spectra := BuoySpectraItem{
+		Frequencies:         frequencies,
+		Energies:            make([]float64, len(frequencies)),
+		Angles:              make([]float64, len(frequencies)),
+		SecondaryAngles:     make([]float64, len(frequencies)),
+		R1:                  make([]float64, len(frequencies)),
+		R2:                  make([]float64, len(frequencies)),
+		SeperationFrequency: MissingValue(),
+	}
+
+	// The energy weighted Fourier coefficients of every swell at each frequency
+	a1 := make([]float64, len(frequencies))
+	b1 := make([]float64, len(frequencies))
+	a2 := make([]float64, len(frequencies))
+	b2 := make([]float64, len(frequencies))
+
+	for _, swell := range synthesisSwells(swells) {
+		energies := options.energies(frequencies, swell)
+		s := options.spreadingFor(swell)
+		r1 := s / (s + 1.0)
+		r2 := s * (s - 1.0) / ((s + 1.0) * (s + 2.0))
+		theta := swell.Direction * math.Pi / 180.0
+
+		for i, energy := range energies {
+			spectra.Energies[i] += energy
+			a1[i] += energy * r1 * math.Cos(theta)
+			b1[i] += energy * r1 * math.Sin(theta)
+			a2[i] += energy * r2 * math.Cos(2.0*theta)
+			b2[i] += energy * r2 * math.Sin(2.0*theta)
+		}
+	}
+
+	for i, energy := range spectra.Energies {
+		if energy <= 0 {
+			spectra.Angles[i] = MissingValue()
+			spectra.SecondaryAngles[i] = MissingValue()
+			spectra.R1[i] = MissingValue()
+			spectra.R2[i] = MissingValue()
+			continue
+		}
+
+		spectra.Angles[i] = math.Mod(math.Atan2(b1[i], a1[i])*180.0/math.Pi+360.0, 360.0)
+		spectra.SecondaryAngles[i] = math.Mod(math.Atan2(b2[i], a2[i])*90.0/math.Pi+360.0, 180.0)
+		spectra.R1[i] = math.Hypot(a1[i], b1[i]) / energy
+		spectra.R2[i] = math.Hypot(a2[i], b2[i]) / energy
+	}
+
+	return spectra
+}
+
+// Synthesize the 2D frequency-direction spectrum of a set of swells with the given number of direction
+// bins, spreading the energy of each swell over direction with its own cos-2s distribution
+func SynthesizeDirectionalSpectrum(swells []Swell, frequencies []float64, directionCount int, options SpectrumOptions) *DirectionalSpectrum {
+	if directionCount < 1 {
+		return nil
+	}
+
+	spectrum := &DirectionalSpectrum{
+		Frequencies: frequencies,
+		Directions:  make([]float64, directionCount),
+		Energies:    make([][]float64, len(frequencies)),
+	}
+	for i := range spectrum.Directions {
+		spectrum.Directions[i] = float64(i) * 360.0 / float64(directionCount)
+	}
+	for i := range spectrum.Energies {
+		spectrum.Energies[i] = make([]float64, directionCount)
+	}
+
+	for _, swell := range synthesisSwells(swells) {
+		energies := options.energies(frequencies, swell)
+		s := options.spreadingFor(swell)
+		spreading := cosineSpreading(spectrum.Directions, swell.Direction, s/(s+1.0))
+
+		for i, energy := range energies {
+			for j, weight := range spreading {
+				spectrum.Energies[i][j] += energy * weight
+			}
+		}
+	}
+
+	return spectrum
+}
+
+// Get the swells that can be synthesized, in metric units. Swells without units are taken as metric.
+func synthesisSwells(swells []Swell) []Swell {
+	valid := make([]Swell, 0, len(swells))
+	for _, swell := range swells {
+		if !swell.IsValid() || IsMissing(swell.Period) || IsMissing(swell.Direction) || swell.WaveHeight <= 0 || swell.Period <= 0 {
+			continue
+		} else if swell.Units == English {
+			swell.ChangeUnits(Metric)
+		}
+		valid = append(valid, swell)
+	}
+	return valid
+}
+
+// Compute the energies of a swell with the shape of the options
+func (o SpectrumOptions) energies(frequencies []float64, swell Swell) []float64 {
+	gamma := o.Gamma
+	if gamma <= 0 {
+		gamma = defaultJONSWAPGamma
+	}
+
+	switch o.Shape {
+	case PiersonMoskowitzSpectrum:
+		return PiersonMoskowitzEnergies(frequencies, swell.WaveHeight, swell.Period)
+	case TMASpectrum:
+		return TMAEnergies(frequencies, swell.WaveHeight, swell.Period, gamma, o.Depth)
+	default:
+		return JONSWAPEnergies(frequencies, swell.WaveHeight, swell.Period, gamma)
+	}
+}
+
+// Get the cos-2s spreading parameter of a swell. A directional spread from partitioning is converted
+// with the same r1 relation used for buoy spectra.
+func (o SpectrumOptions) spreadingFor(swell Swell) float64 {
+	if swell.DirectionalSpread > 0 && !IsMissing(swell.DirectionalSpread) {
+		spread := swell.DirectionalSpread * math.Pi / 180.0
+		r1 := 1.0 - spread*spread/2.0
+		if r1 > 0 {
+			return math.Max(minimumCosineSpreading, math.Min(r1/(1.0-r1), maximumCosineSpreading))
+		}
+		return minimumCosineSpreading
+	} else if o.Spreading > 0 {
+		return o.Spreading
+	}
+	return defaultCosineSpreading
+}
+
+// Scale the energies so their zero moment gives the significant wave height
+func scaleToWaveHeight(frequencies, energies []float64, waveHeight float64) []float64 {
+	bandwidths := frequencyBandwidths(frequencies)
+	zeroMoment := 0.0
+	for i, energy := range energies {
+		zeroMoment += SolveZeroSpectralMoment(energy, bandwidths[i])
+	}
+	if zeroMoment <= 0 {
+		return energies
+	}
+
+	scale := math.Pow(waveHeight/4.0, 2) / zeroMoment
+	for i := range energies {
+		energies[i] *= scale
+	}
+	return energies
+}
@@ -0,0 +1,73 @@
+package surfnerd
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDefaultSpectralFrequencies(t *testing.T) {
+	frequencies := DefaultSpectralFrequencies()
+	if len(frequencies) != 47 {
+		t.Fatalf("Expected 47 frequency bands, got %d", len(frequencies))
+	}
+	if !closeTo(frequencies[13], 0.0925, 1e-9) || !closeTo(frequencies[14], 0.1, 1e-9) || !closeTo(frequencies[46], 0.485, 1e-9) {
+		t.Errorf("Unexpected frequency bands %v", frequencies)
+	}
+}
+
+func TestParametricSpectra(t *testing.T) {
+	frequencies := DefaultSpectralFrequencies()
+
+	for name, energies := range map[string][]float64{
+		"JONSWAP":          JONSWAPEnergies(frequencies, 2.0, 10.0, 3.3),
+		"PiersonMoskowitz": PiersonMoskowitzEnergies(frequencies, 2.0, 10.0),
+		"TMA":              TMAEnergies(frequencies, 2.0, 10.0, 3.3, 10.0),
+	} {
+		spectra := BuoySpectraItem{Frequencies: frequencies, Energies: energies}
+		if hs := spectra.SignificantWaveHeight(); !closeTo(hs, 2.0, 1e-9) {
+			t.Errorf("Expected the %s spectrum to have a wave height of 2, got %v", name, hs)
+		}
+		if tp := spectra.PeakPeriod(); !closeTo(tp, 10.0, 0.5) {
+			t.Errorf("Expected the %s spectrum to peak near 10 seconds, got %v", name, tp)
+		}
+	}
+
+	// The peak enhancement makes the JONSWAP spectrum more peaked than the fully developed sea
+	jonswap := BuoySpectraItem{Frequencies: frequencies, Energies: JONSWAPEnergies(frequencies, 2.0, 10.0, 3.3)}
+	pm := BuoySpectraItem{Frequencies: frequencies, Energies: PiersonMoskowitzEnergies(frequencies, 2.0, 10.0)}
+	if jonswap.Peakedness() <= pm.Peakedness() {
+		t.Errorf("Expected JONSWAP to be more peaked than Pierson-Moskowitz, got %v and %v", jonswap.Peakedness(), pm.Peakedness())
+	}
+}
+
+func TestSynthesizeSpectra(t *testing.T) {
+	swells := []Swell{
+		NewSwellWithDirection(2.0, 14.0, 90.0),
+		NewSwellWithDirection(1.0, 5.0, 220.0),
+		NewSwellWithDirection(MissingValue(), 8.0, 180.0),
+	}
+	options := SpectrumOptions{Shape: JONSWAPSpectrum}
+	frequencies := DefaultSpectralFrequencies()
+
+	spectra := SynthesizeSpectra(swells, frequencies, options)
+	if hs := spectra.SignificantWaveHeight(); !closeTo(hs, math.Sqrt(5.0), 0.01) {
+		t.Errorf("Expected the combined wave height to be sqrt(5), got %v", hs)
+	}
+
+	// Far from each peak, the band direction should follow the swell that dominates it
+	if !closeTo(spectra.Angles[5], 90.0, 1.0) || !closeTo(spectra.Angles[40], 220.0, 5.0) {
+		t.Errorf("Unexpected band directions %v and %v", spectra.Angles[5], spectra.Angles[40])
+	}
+	if !closeTo(spectra.R1[5], defaultCosineSpreading/(defaultCosineSpreading+1.0), 0.01) {
+		t.Errorf("Unexpected r1 %v", spectra.R1[5])
+	}
+
+	directional := SynthesizeDirectionalSpectrum(swells, frequencies, 72, options)
+	partitions := directional.Partition()
+	if len(partitions) != 2 {
+		t.Fatalf("Expected the two swells back from partitioning, got %d", len(partitions))
+	}
+	if !closeTo(partitions[0].Direction, 90.0, 5.0) || !closeTo(partitions[1].Direction, 220.0, 5.0) {
+		t.Errorf("Unexpected partition directions %v and %v", partitions[0].Direction, partitions[1].Direction)
+	}
+}
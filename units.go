@@ -31,7 +31,7 @@ func DegreeToDirection(degree float64) string {
 	degree = math.Abs(degree)
 
 	// Make sure its in the range
-	if degree > 361 {
+	if IsMissing(degree) || degree > 361 {
 		return "NULL"
 	}
 
@@ -110,6 +110,16 @@ func MilesPerHourToKnots(mphValue float64) float64 {
 	return mphValue / 1.15
 }
 
+// Converts from Kilometers to Nautical Miles
+func KilometersToNauticalMiles(kmValue float64) float64 {
+	return kmValue / 1.852
+}
+
+// Converts from Nautical Miles to Kilometers
+func NauticalMilesToKilometers(nmValue float64) float64 {
+	return nmValue * 1.852
+}
+
 // Converts from Celsius to Fahrenheit
 func CelsiusToFahrenheit(celsiusValue float64) float64 {
 	return (celsiusValue * (9.0 / 5.0)) + 32.0
@@ -2,50 +2,65 @@
 package surfnerd
 
 import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
 	"io/ioutil"
-	"net/http"
 	"strings"
 )
 
-func fetchSpaceDelimitedString(url string) ([]string, error) {
-	// Get the response from the website and find if it can retreive the data
-	response, httpError := http.Get(url)
-	defer response.Body.Close()
+// The base urls of the NOAA servers the package talks to. They may be overridden to point
+// the package at a mirror. Test servers, such as the one in the surfnerdtest package, are
+// reached through a Fetcher instead so tests can run in parallel.
+var (
+	NDBCBaseURL         = "http://www.ndbc.noaa.gov"
+	NOMADSBaseURL       = "http://nomads.ncep.noaa.gov:9090"
+	NOMADSFilterBaseURL = "http://nomads.ncep.noaa.gov/cgi-bin"
+)
 
-	if httpError != nil {
-		return []string{}, httpError
+func fetchLineDelimitedString(ctx context.Context, fetcher Fetcher, url string) ([]string, error) {
+	// Get the response from the website and find if it can retreive the data
+	rawData, fetchError := fetchRawDataFromURL(ctx, fetcher, url)
+	if fetchError != nil {
+		return []string{}, fetchError
 	}
 
-	rawData, readError := ioutil.ReadAll(response.Body)
-	rawString := string(rawData)
-
-	return strings.Fields(rawString), readError
+	return strings.Split(string(rawData), "\n"), nil
 }
 
-func fetchLineDelimitedString(url string) ([]string, error) {
-	// Get the response from the website and find if it can retreive the data
-	response, httpError := http.Get(url)
-	defer response.Body.Close()
-
-	if httpError != nil {
-		return []string{}, httpError
+// Open a gzipped or plain stream for reading. Servers and proxies sometimes decompress the archives on
+// the way, so streams without the gzip magic number are read as is.
+func gunzipReaderIfCompressed(r io.Reader) (io.Reader, error) {
+	buffered := bufio.NewReader(r)
+	magic, peekErr := buffered.Peek(2)
+	if peekErr != nil && peekErr != io.EOF {
+		return nil, peekErr
+	}
+	if len(magic) < 2 || magic[0] != 0x1f || magic[1] != 0x8b {
+		return buffered, nil
 	}
 
-	rawData, readError := ioutil.ReadAll(response.Body)
-	rawString := string(rawData)
-
-	return strings.Split(rawString, "\n"), readError
+	return gzip.NewReader(buffered)
 }
 
-func fetchRawDataFromURL(url string) ([]byte, error) {
-	// Fetch the data
-	resp, httpErr := http.Get(url)
-	if httpErr != nil {
-		return nil, httpErr
+// Open the body of a url as a stream. Fetchers that can not stream have their whole response wrapped
+// in a reader instead.
+func fetchStreamFromURL(ctx context.Context, fetcher Fetcher, url string) (io.ReadCloser, error) {
+	fetcher = fetcherOrDefault(fetcher)
+	if streamingFetcher, ok := fetcher.(StreamingFetcher); ok {
+		return streamingFetcher.FetchStream(contextOrBackground(ctx), url)
 	}
-	defer resp.Body.Close()
 
-	// Read all of the raw data
-	contents, readErr := ioutil.ReadAll(resp.Body)
-	return contents, readErr
+	rawData, fetchErr := fetcher.Fetch(contextOrBackground(ctx), url)
+	if fetchErr != nil {
+		return nil, fetchErr
+	}
+	return ioutil.NopCloser(bytes.NewReader(rawData)), nil
+}
+
+func fetchRawDataFromURL(ctx context.Context, fetcher Fetcher, url string) ([]byte, error) {
+	// Fetch the data, falling back to the default fetcher and context
+	return fetcherOrDefault(fetcher).Fetch(contextOrBackground(ctx), url)
 }
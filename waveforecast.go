@@ -3,7 +3,6 @@ package surfnerd
 import (
 	"encoding/json"
 	"io/ioutil"
-	"time"
 )
 
 // Container holding a complete WaveWatch forecast with the location, model description, run time, and
@@ -101,14 +100,20 @@ func WaveForecastFromModelData(modelData *ModelData) *WaveForecast {
 	itemCount := len(modelData.Data["dirpwsfc"])
 	forecastItems := make([]WaveForecastItem, itemCount)
 
-	modelTime, _ := LatestModelDateTime()
+	validTimes := modelData.ValidTimes()
 
 	for i := 0; i < itemCount; i++ {
 		thisForecastItem := WaveForecastItem{}
 
-		forecastTime := modelTime.Add(time.Duration(3 * int64(i) * int64(time.Hour)))
-		thisForecastItem.Date = forecastTime.In(modelData.Model.TimezoneLocation()).Format("Monday January 02, 2006")
-		thisForecastItem.Time = forecastTime.In(modelData.Model.TimezoneLocation()).Format("03 PM")
+		thisForecastItem.ModelRunTime = modelData.Model.RunTime
+		if i < len(validTimes) {
+			thisForecastItem.ValidTime = validTimes[i]
+		} else {
+			thisForecastItem.ValidTime = modelData.Model.ValidTime(i)
+		}
+		if !thisForecastItem.ModelRunTime.IsZero() {
+			thisForecastItem.ForecastHour = int(thisForecastItem.ValidTime.Sub(thisForecastItem.ModelRunTime).Hours())
+		}
 		thisForecastItem.SignificantWaveHeight = modelData.Data["htsgwsfc"][i]
 		thisForecastItem.DominantWaveDirection = modelData.Data["dirpwsfc"][i]
 		thisForecastItem.MeanWavePeriod = modelData.Data["perpwsfc"][i]
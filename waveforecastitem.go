@@ -1,9 +1,20 @@
 package surfnerd
 
+import (
+	"time"
+)
+
 // Data container for WaveWatch data at a specific timestep and location.
 type WaveForecastItem struct {
-	Date                     string
-	Time                     string
+	// The UTC time the forecast is valid for
+	ValidTime time.Time
+
+	// The UTC time of the model run the forecast is from
+	ModelRunTime time.Time
+
+	// The number of hours from the model run to the valid time
+	ForecastHour int
+
 	SignificantWaveHeight    float64
 	DominantWaveDirection    float64
 	MeanWavePeriod           float64
@@ -44,3 +55,29 @@ func (w *WaveForecastItem) ChangeUnits(newUnits UnitSystem) {
 
 	w.Units = newUnits
 }
+
+// Get the primary, secondary and wind swell components of the forecast, in the units of the item.
+// The wind swell is marked as wind sea. Synthesize their spectra with SynthesizeSpectra to compare the
+// forecast with buoy spectra.
+func (w WaveForecastItem) SwellComponents() []Swell {
+	primary := NewSwellWithDirection(w.PrimarySwellWaveHeight, w.PrimarySwellPeriod, w.PrimarySwellDirection)
+	secondary := NewSwellWithDirection(w.SecondarySwellWaveHeight, w.SecondarySwellPeriod, w.SecondarySwellDirection)
+	windSwell := NewSwellWithDirection(w.WindSwellWaveHeight, w.WindSwellPeriod, w.WindSwellDirection)
+	windSwell.WindSea = true
+
+	swells := []Swell{primary, secondary, windSwell}
+	for i := range swells {
+		swells[i].Units = w.Units
+	}
+	return swells
+}
+
+// Format the date the forecast is valid for in the given time zone, such as Monday January 02, 2006
+func (w WaveForecastItem) FormatDate(loc *time.Location) string {
+	return FormatForecastDate(w.ValidTime, loc)
+}
+
+// Format the hour the forecast is valid for in the given time zone, such as 03 PM
+func (w WaveForecastItem) FormatTime(loc *time.Location) string {
+	return FormatForecastTime(w.ValidTime, loc)
+}
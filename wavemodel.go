@@ -1,52 +1,53 @@
 package surfnerd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"time"
 )
 
 const (
-	baseMultigridUrl = "http://nomads.ncep.noaa.gov:9090/dods/wave/mww3/%[1]s/%[2]s%[1]s_%[3]s.ascii?time[%[6]d:%[7]d],dirpwsfc.dirpwsfc[%[6]d:%[7]d][%[4]d][%[5]d],htsgwsfc.htsgwsfc[%[6]d:%[7]d][%[4]d][%[5]d],perpwsfc.perpwsfc[%[6]d:%[7]d][%[4]d][%[5]d],swdir_1.swdir_1[%[6]d:%[7]d][%[4]d][%[5]d],swdir_2.swdir_2[%[6]d:%[7]d][%[4]d][%[5]d],swell_1.swell_1[%[6]d:%[7]d][%[4]d][%[5]d],swell_2.swell_2[%[6]d:%[7]d][%[4]d][%[5]d],swper_1.swper_1[%[6]d:%[7]d][%[4]d][%[5]d],swper_2.swper_2[%[6]d:%[7]d][%[4]d][%[5]d],ugrdsfc.ugrdsfc[%[6]d:%[7]d][%[4]d][%[5]d],vgrdsfc.vgrdsfc[%[6]d:%[7]d][%[4]d][%[5]d],wdirsfc.wdirsfc[%[6]d:%[7]d][%[4]d][%[5]d],windsfc.windsfc[%[6]d:%[7]d][%[4]d][%[5]d],wvdirsfc.wvdirsfc[%[6]d:%[7]d][%[4]d][%[5]d],wvhgtsfc.wvhgtsfc[%[6]d:%[7]d][%[4]d][%[5]d],wvpersfc.wvpersfc[%[6]d:%[7]d][%[4]d][%[5]d]"
+	baseMultigridDatasetURL = "%[1]s/dods/wave/mww3/%[2]s/%[3]s%[2]s_%[4]s"
+	multigridQuery          = ".ascii?time[%[3]d:%[4]d],dirpwsfc.dirpwsfc[%[3]d:%[4]d][%[1]d][%[2]d],htsgwsfc.htsgwsfc[%[3]d:%[4]d][%[1]d][%[2]d],perpwsfc.perpwsfc[%[3]d:%[4]d][%[1]d][%[2]d],swdir_1.swdir_1[%[3]d:%[4]d][%[1]d][%[2]d],swdir_2.swdir_2[%[3]d:%[4]d][%[1]d][%[2]d],swell_1.swell_1[%[3]d:%[4]d][%[1]d][%[2]d],swell_2.swell_2[%[3]d:%[4]d][%[1]d][%[2]d],swper_1.swper_1[%[3]d:%[4]d][%[1]d][%[2]d],swper_2.swper_2[%[3]d:%[4]d][%[1]d][%[2]d],ugrdsfc.ugrdsfc[%[3]d:%[4]d][%[1]d][%[2]d],vgrdsfc.vgrdsfc[%[3]d:%[4]d][%[1]d][%[2]d],wdirsfc.wdirsfc[%[3]d:%[4]d][%[1]d][%[2]d],windsfc.windsfc[%[3]d:%[4]d][%[1]d][%[2]d],wvdirsfc.wvdirsfc[%[3]d:%[4]d][%[1]d][%[2]d],wvhgtsfc.wvhgtsfc[%[3]d:%[4]d][%[1]d][%[2]d],wvpersfc.wvpersfc[%[3]d:%[4]d][%[1]d][%[2]d]"
 )
 
+// The variables fetched from the wave models
+var waveModelVariables = []string{"dirpwsfc", "htsgwsfc", "perpwsfc", "swdir_1", "swdir_2", "swell_1", "swell_2", "swper_1", "swper_2", "ugrdsfc", "vgrdsfc", "wdirsfc", "windsfc", "wvdirsfc", "wvhgtsfc", "wvpersfc"}
+
 // A container representing a NOAA WaveWatch III MultiGrid Wave Model. This type has everything needed to construct a url
 // to get the data needed for a correct location.
 type WaveModel struct {
 	NOAAModel
 }
 
-// Create a URL for downloading data from the NOAA GRADS servers
+// Create the url of the dods dataset of a run of the model. Appending .das or .info gives the
+// description of the dataset, which is only published once the run is available.
+func (w *WaveModel) CreateDatasetURL(run time.Time) string {
+	run = run.UTC()
+	return fmt.Sprintf(baseMultigridDatasetURL, NOMADSBaseURL, run.Format("20060102"), w.Name, fmt.Sprintf("%02dz", run.Hour()))
+}
+
+// Create a URL for downloading data of the given model run from the NOAA GRADS servers
 // The time indices can be calculated assuming every index expands to the TimeResolution in terms of
 // Days. So if model.TimeResolution return 0.167, that means each index is equal to 0.167 days.
-func (w *WaveModel) CreateURL(loc Location, startTimeIndex, endTimeIndex int) string {
-	// Get the times
-	timestamp, _ := LatestModelDateTime()
-	w.ModelRun = FormatViewingTime(timestamp)
-	dateString := timestamp.Format("20060102")
-	lastModelTime := timestamp.Hour()
-	hourString := fmt.Sprintf("%02dz", lastModelTime)
+func (w *WaveModel) CreateURL(loc Location, run time.Time, startTimeIndex, endTimeIndex int) string {
+	w.RunTime = run.UTC()
+	w.ModelRun = FormatViewingTime(w.RunTime)
 
 	// Get the location
 	latIndex, lngIndex := w.LocationIndices(loc)
 
 	// Format the url and return
-	url := fmt.Sprintf(baseMultigridUrl, dateString, w.Name, hourString, latIndex, lngIndex, startTimeIndex, endTimeIndex)
-	return url
+	return w.CreateDatasetURL(run) + fmt.Sprintf(multigridQuery, latIndex, lngIndex, startTimeIndex, endTimeIndex)
 }
 
-// Create a URL for downloading data from the NOAA GRADS servers
+// Create a URL for downloading data of the given model run from the NOAA GRADS servers
 // The time interval may be specified by a valid future time object that
 // represents the interval to fetch
-func (w *WaveModel) CreateTimedURL(loc Location, startTime time.Time, timeSteps int) string {
-	startIndex := w.TimeIndex(startTime)
-	if startIndex < 0 {
-		startIndex = 0
-	}
-	if timeSteps < 1 {
-		timeSteps = 1
-	}
-	endIndex := startIndex + timeSteps - 1
-	return w.CreateURL(loc, startIndex, endIndex)
+func (w *WaveModel) CreateTimedURL(loc Location, run time.Time, startTime time.Time, timeSteps int) string {
+	timeRange := w.TimeRange(run, startTime, timeSteps)
+	return w.CreateURL(loc, run, timeRange.StartIndex, timeRange.EndIndex)
 }
 
 // Get the US East Coast Model
@@ -127,47 +128,127 @@ func GetWaveModelForLocation(loc Location) *WaveModel {
 // Grabs the latest wave data from NOAA GRADS servers for a given location
 // Data is returned as a Forecast object
 func FetchWaveForecast(loc Location) *WaveForecast {
-	modelData := FetchWaveModelData(loc)
-	forecast := WaveForecastFromModelData(modelData)
+	forecast, _ := FetchWaveForecastContext(context.Background(), nil, loc)
 	return forecast
 }
 
+// Grabs the latest wave data for a given location using the given context and Fetcher.
+// A nil Fetcher uses the DefaultFetcher.
+func FetchWaveForecastContext(ctx context.Context, fetcher Fetcher, loc Location) (*WaveForecast, error) {
+	modelData, fetchErr := FetchWaveModelDataContext(ctx, fetcher, loc)
+	if fetchErr != nil {
+		return nil, fetchErr
+	}
+	return WaveForecastFromModelData(modelData), nil
+}
+
 // Grabs the latest WaveWatch data from NOAA GRADS servers for a given Location
 // Data is returned as a WaveModelData object which contains a map of raw values.
 func FetchWaveModelData(loc Location) *ModelData {
+	modelData, _ := FetchWaveModelDataContext(context.Background(), nil, loc)
+	return modelData
+}
+
+// Grabs the latest WaveWatch data for a given Location using the given context and Fetcher. The
+// newest published model run is found with a ModelRunResolver. A nil Fetcher uses the DefaultFetcher.
+func FetchWaveModelDataContext(ctx context.Context, fetcher Fetcher, loc Location) (*ModelData, error) {
 	model := GetWaveModelForLocation(loc)
 	if model == nil {
-		return nil
+		return nil, errors.New("No wave model covers the given location")
 	}
 
-	// Create the url
-	url := model.CreateURL(loc, 0, 60)
+	run, runErr := NewModelRunResolver(fetcher).ResolveWaveModelRun(ctx, model)
+	if runErr != nil {
+		return nil, runErr
+	}
+
+	return fetchWaveModelData(ctx, fetcher, loc, model, run)
+}
 
-	// Fetch the raw data
-	rawData, err := fetchRawDataFromURL(url)
-	if err != nil {
-		return nil
+// Grabs the WaveWatch data of a specific model run for a given Location using the given context and
+// Fetcher. A nil Fetcher uses the DefaultFetcher.
+func FetchWaveModelDataForRunContext(ctx context.Context, fetcher Fetcher, loc Location, run time.Time) (*ModelData, error) {
+	model := GetWaveModelForLocation(loc)
+	if model == nil {
+		return nil, errors.New("No wave model covers the given location")
 	}
 
-	// Call to parse the raw data into containers
-	modelDataContainer := parseRawModelData(rawData)
-	modelData := &ModelData{
-		Location: loc,
-		Model:    model.NOAAModel,
-		Data:     modelDataContainer,
+	return fetchWaveModelData(ctx, fetcher, loc, model, run)
+}
+
+// Grabs the latest WaveWatch data for a given Location and Model using the given context and Fetcher.
+// The Interpolation and CellSearch of the model pick how the values of the location are taken from the
+// cells around it. A nil Fetcher uses the DefaultFetcher.
+func FetchWaveModelDataForModelContext(ctx context.Context, fetcher Fetcher, loc Location, model *WaveModel) (*ModelData, error) {
+	if model == nil {
+		return nil, errors.New("No wave model given")
 	}
-	return modelData
+
+	run, runErr := NewModelRunResolver(fetcher).ResolveWaveModelRun(ctx, model)
+	if runErr != nil {
+		return nil, runErr
+	}
+
+	return fetchWaveModelData(ctx, fetcher, loc, model, run)
+}
+
+// Fetch the rings of cells around a location as a grid, taking the data of the location from them with
+// the interpolation or cell search of the model
+func fetchWaveModelData(ctx context.Context, fetcher Fetcher, loc Location, model *WaveModel, run time.Time) (*ModelData, error) {
+	bottomLeft, topRight := model.ringArea(loc, model.locationRings())
+	grid, gridErr := fetchWaveModelGrid(ctx, fetcher, model, run, bottomLeft, topRight, ModelTimeRange{StartIndex: 0, EndIndex: 60})
+	if gridErr != nil {
+		return nil, gridErr
+	}
+	return grid.locationModelData(loc)
+}
+
+// Grabs the latest WaveWatch data from NOAA GRADS servers over the area between two corners, for the
+// given time steps of the run. Data is returned as a ModelGrid holding every variable by latitude,
+// longitude and time.
+func FetchWaveModelGrid(bottomLeft, topRight Location, timeRange ModelTimeRange) *ModelGrid {
+	grid, _ := FetchWaveModelGridContext(context.Background(), nil, bottomLeft, topRight, timeRange)
+	return grid
+}
+
+// Grabs the latest WaveWatch data over the area between two corners using the given context and
+// Fetcher. The newest published model run is found with a ModelRunResolver. A nil Fetcher uses the
+// DefaultFetcher.
+func FetchWaveModelGridContext(ctx context.Context, fetcher Fetcher, bottomLeft, topRight Location, timeRange ModelTimeRange) (*ModelGrid, error) {
+	model := GetWaveModelForLocation(bottomLeft)
+	if model == nil || !model.ContainsLocation(topRight) {
+		return nil, errors.New("No wave model covers the given area")
+	}
+
+	run, runErr := NewModelRunResolver(fetcher).ResolveWaveModelRun(ctx, model)
+	if runErr != nil {
+		return nil, runErr
+	}
+
+	return fetchWaveModelGrid(ctx, fetcher, model, run, bottomLeft, topRight, timeRange)
+}
+
+// Grabs the WaveWatch data of a specific model run over the area between two corners using the given
+// context and Fetcher. A nil Fetcher uses the DefaultFetcher.
+func FetchWaveModelGridForRunContext(ctx context.Context, fetcher Fetcher, bottomLeft, topRight Location, run time.Time, timeRange ModelTimeRange) (*ModelGrid, error) {
+	model := GetWaveModelForLocation(bottomLeft)
+	if model == nil || !model.ContainsLocation(topRight) {
+		return nil, errors.New("No wave model covers the given area")
+	}
+
+	return fetchWaveModelGrid(ctx, fetcher, model, run, bottomLeft, topRight, timeRange)
+}
+
+func fetchWaveModelGrid(ctx context.Context, fetcher Fetcher, model *WaveModel, run time.Time, bottomLeft, topRight Location, timeRange ModelTimeRange) (*ModelGrid, error) {
+	return fetchModelGridFromDAP(ctx, fetcher, model.NOAAModel, run, model.CreateDatasetURL(run), waveModelVariables, bottomLeft, topRight, timeRange)
 }
 
 // Takes in raw data and parses it into a ModelData object. Useful for
-// implementing your own network fetching.
+// implementing your own network fetching. The run time of the model is taken from
+// its time axis unless it is already set. Returns nil if the model is parsed strictly
+// and the data contains unreadable values, or if the data is not from the run of the model.
 func WaveModelDataFromRaw(loc Location, model NOAAModel, rawData []byte) *ModelData {
 	// Call to parse the raw data into containers
-	modelDataContainer := parseRawModelData(rawData)
-	modelData := &ModelData{
-		Location: loc,
-		Model:    model,
-		Data:     modelDataContainer,
-	}
+	modelData, _ := modelDataFromRaw(loc, model, rawData, model.Name)
 	return modelData
 }
@@ -138,3 +138,29 @@ func SolveSteepness(significantWaveWieght, dominantPeriod float64) string {
 		return "Swell"
 	}
 }
+
+// Reduces a wind speed measured at the given height in meters to the 10 meter neutral wind speed,
+// assuming a logarithmic wind profile over the sea. The roughness length comes from the Charnock
+// relation with a smooth flow term, solved together with the friction velocity. Speeds are in m/s.
+func NeutralWindSpeedAtTenMeters(windSpeed, height float64) float64 {
+	const gravity = 9.81
+	const vonKarman = 0.4
+	const charnock = 0.011
+	const kinematicViscosity = 1.5e-5
+	const referenceHeight = 10.0
+	const maxIteration = 20
+
+	if IsMissing(windSpeed) || IsMissing(height) || windSpeed <= 0 || height <= 0 || height == referenceHeight {
+		return windSpeed
+	}
+
+	// Start from a drag coefficient of about 1.2e-3 and refine the friction velocity
+	frictionVelocity := 0.035 * windSpeed
+	roughness := 0.0
+	for iteration := 0; iteration < maxIteration; iteration++ {
+		roughness = charnock*math.Pow(frictionVelocity, 2)/gravity + 0.11*kinematicViscosity/frictionVelocity
+		frictionVelocity = vonKarman * windSpeed / math.Log(height/roughness)
+	}
+
+	return windSpeed * math.Log(referenceHeight/roughness) / math.Log(height/roughness)
+}
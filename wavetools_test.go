@@ -60,3 +60,21 @@ func TestUVScalarConversion(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestNeutralWindSpeedAtTenMeters(t *testing.T) {
+	// A 4 meter anemometer reads roughly 10 percent low in moderate winds
+	adjusted := NeutralWindSpeedAtTenMeters(8.0, 4.1)
+	if adjusted < 8.5 || adjusted > 9.0 {
+		t.Errorf("Expected about 8.8 m/s at 10 meters, got %v", adjusted)
+	}
+
+	if NeutralWindSpeedAtTenMeters(8.0, 10.0) != 8.0 {
+		t.Error("Expected a 10 meter wind to be unchanged")
+	}
+	if lowered := NeutralWindSpeedAtTenMeters(8.0, 20.0); lowered >= 8.0 {
+		t.Errorf("Expected a wind measured above 10 meters to be reduced, got %v", lowered)
+	}
+	if !IsMissing(NeutralWindSpeedAtTenMeters(MissingValue(), 4.1)) {
+		t.Error("Expected a missing wind to stay missing")
+	}
+}
@@ -3,7 +3,6 @@ package surfnerd
 import (
 	"encoding/json"
 	"io/ioutil"
-	"time"
 )
 
 type WindForecast struct {
@@ -76,14 +75,20 @@ func WindForecastFromModelData(modelData *ModelData) *WindForecast {
 	itemCount := len(modelData.Data["ugrd10m"])
 	forecastItems := make([]WindForecastItem, itemCount)
 
-	modelTime, _ := LatestModelDateTime()
+	validTimes := modelData.ValidTimes()
 
 	for i := 0; i < itemCount; i++ {
 		thisForecastItem := WindForecastItem{}
 
-		forecastTime := modelTime.Add(time.Duration(3 * int64(i) * int64(time.Hour)))
-		thisForecastItem.Date = forecastTime.In(modelData.Model.TimezoneLocation()).Format("Monday January 02, 2006")
-		thisForecastItem.Time = forecastTime.In(modelData.Model.TimezoneLocation()).Format("03 PM")
+		thisForecastItem.ModelRunTime = modelData.Model.RunTime
+		if i < len(validTimes) {
+			thisForecastItem.ValidTime = validTimes[i]
+		} else {
+			thisForecastItem.ValidTime = modelData.Model.ValidTime(i)
+		}
+		if !thisForecastItem.ModelRunTime.IsZero() {
+			thisForecastItem.ForecastHour = int(thisForecastItem.ValidTime.Sub(thisForecastItem.ModelRunTime).Hours())
+		}
 
 		speed, direction := ScalarFromUV(modelData.Data["ugrd10m"][i], modelData.Data["vgrd10m"][i])
 		thisForecastItem.WindSpeed = speed
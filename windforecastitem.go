@@ -1,9 +1,20 @@
 package surfnerd
 
+import (
+	"time"
+)
+
 // A single timestep in a wind forecast
 type WindForecastItem struct {
-	Date          string
-	Time          string
+	// The UTC time the forecast is valid for
+	ValidTime time.Time
+
+	// The UTC time of the model run the forecast is from
+	ModelRunTime time.Time
+
+	// The number of hours from the model run to the valid time
+	ForecastHour int
+
 	WindSpeed     float64
 	WindGustSpeed float64
 	WindDirection float64
@@ -27,3 +38,13 @@ func (w *WindForecastItem) ChangeUnits(newUnits UnitSystem) {
 
 	w.Units = newUnits
 }
+
+// Format the date the forecast is valid for in the given time zone, such as Monday January 02, 2006
+func (w WindForecastItem) FormatDate(loc *time.Location) string {
+	return FormatForecastDate(w.ValidTime, loc)
+}
+
+// Format the hour the forecast is valid for in the given time zone, such as 03 PM
+func (w WindForecastItem) FormatTime(loc *time.Location) string {
+	return FormatForecastTime(w.ValidTime, loc)
+}
@@ -1,6 +1,8 @@
 package surfnerd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"time"
 )
@@ -13,10 +15,14 @@ const (
 )
 
 const (
-	gfsURL = "http://nomads.ncep.noaa.gov:9090/dods/%[1]s/gfs%[2]s/%[1]s_%[3]s.ascii?time[%[7]d:%[8]d],ugrd10m[%[7]d:%[8]d][%[5]d][%[6]d],vgrd10m[%[7]d:%[8]d][%[5]d][%[6]d],gustsfc[%[7]d:%[8]d][%[5]d][%[6]d]"
-	namURL = "http://nomads.ncep.noaa.gov:9090/dods/nam/nam%[2]s/%[1]s_%[3]s.ascii?time[%[7]d:%[8]d],ugrd10m[%[7]d:%[8]d][%[5]d][%[6]d],vgrd10m[%[7]d:%[8]d][%[5]d][%[6]d],gustsfc[%[7]d:%[8]d][%[5]d][%[6]d]"
+	gfsDatasetURL = "%[1]s/dods/%[2]s/gfs%[3]s/%[2]s_%[4]s"
+	namDatasetURL = "%[1]s/dods/nam/nam%[3]s/%[2]s_%[4]s"
+	windQuery     = ".ascii?time[%[3]d:%[4]d],ugrd10m[%[3]d:%[4]d][%[1]d][%[2]d],vgrd10m[%[3]d:%[4]d][%[1]d][%[2]d],gustsfc[%[3]d:%[4]d][%[1]d][%[2]d]"
 )
 
+// The variables fetched from the wind models
+var windModelVariables = []string{"ugrd10m", "vgrd10m", "gustsfc"}
+
 // Represents a NOAA Wind Model
 type WindModel struct {
 	NOAAModel
@@ -24,45 +30,38 @@ type WindModel struct {
 	ModelType            WindModelType
 }
 
-// Create the URL for fetching the data from the wind model
-func (w *WindModel) CreateURL(loc Location, startTimeIndex, endTimeIndex int) string {
-	// Get the times
-	timestamp, _ := LatestModelDateTime()
-	w.ModelRun = FormatViewingTime(timestamp)
-	dateString := timestamp.Format("20060102")
-	lastModelTime := timestamp.Hour()
-	hourString := fmt.Sprintf("%02dz", lastModelTime)
-
-	// Get the location
-	latIndex, lngIndex := w.LocationIndices(loc)
-
-	// Get the altitude
-	altIndex := w.MinimumAltitudeIndex
+// Create the url of the dods dataset of a run of the model. Appending .das or .info gives the
+// description of the dataset, which is only published once the run is available.
+func (w *WindModel) CreateDatasetURL(run time.Time) string {
+	run = run.UTC()
 
-	// Format the url and return
 	var baseURL string
 	if w.ModelType == GFS {
-		baseURL = gfsURL
+		baseURL = gfsDatasetURL
 	} else if w.ModelType == NAM {
-		baseURL = namURL
+		baseURL = namDatasetURL
 	}
-	url := fmt.Sprintf(baseURL, w.Name, dateString, hourString, altIndex, latIndex, lngIndex, startTimeIndex, endTimeIndex)
-	return url
+	return fmt.Sprintf(baseURL, NOMADSBaseURL, w.Name, run.Format("20060102"), fmt.Sprintf("%02dz", run.Hour()))
+}
+
+// Create the URL for fetching the data of the given run from the wind model
+func (w *WindModel) CreateURL(loc Location, run time.Time, startTimeIndex, endTimeIndex int) string {
+	w.RunTime = run.UTC()
+	w.ModelRun = FormatViewingTime(w.RunTime)
+
+	// Get the location
+	latIndex, lngIndex := w.LocationIndices(loc)
+
+	// Format the url and return
+	return w.CreateDatasetURL(run) + fmt.Sprintf(windQuery, latIndex, lngIndex, startTimeIndex, endTimeIndex)
 }
 
-// Create a URL for downloading data from the NOAA GRADS servers
+// Create a URL for downloading data of the given model run from the NOAA GRADS servers
 // The time interval may be specified by a valid future time object that
 // represents the interval to fetch
-func (w *WindModel) CreateTimedURL(loc Location, startTime time.Time, timeSteps int) string {
-	startIndex := w.TimeIndex(startTime)
-	if startIndex < 0 {
-		startIndex = 0
-	}
-	if timeSteps < 1 {
-		timeSteps = 1
-	}
-	endIndex := startIndex + timeSteps - 1
-	return w.CreateURL(loc, startIndex, endIndex)
+func (w *WindModel) CreateTimedURL(loc Location, run time.Time, startTime time.Time, timeSteps int) string {
+	timeRange := w.TimeRange(run, startTime, timeSteps)
+	return w.CreateURL(loc, run, timeRange.StartIndex, timeRange.EndIndex)
 }
 
 // Create a new GFS Model
@@ -151,93 +150,145 @@ func GetWindModelForLocationAndType(loc Location, modelType WindModelType) *Wind
 // Grabs the latest wind data from NOAA GRADS servers for a given location
 // Data is returned as a Forecast object
 func FetchWindForecast(loc Location) *WindForecast {
-	modelData := FetchWindModelData(loc)
-	forecast := WindForecastFromModelData(modelData)
+	forecast, _ := FetchWindForecastContext(context.Background(), nil, loc)
 	return forecast
 }
 
+// Grabs the latest wind data for a given location using the given context and Fetcher.
+// A nil Fetcher uses the DefaultFetcher.
+func FetchWindForecastContext(ctx context.Context, fetcher Fetcher, loc Location) (*WindForecast, error) {
+	modelData, fetchErr := FetchWindModelDataContext(ctx, fetcher, loc)
+	if fetchErr != nil {
+		return nil, fetchErr
+	}
+	return WindForecastFromModelData(modelData), nil
+}
+
 // Grabs the latest wind data from NOAA GRADS servers for a given location and model
 // Data is returned as a Forecast object
 func FetchWindForecastForModel(loc Location, model *WindModel) *WindForecast {
-	modelData := FetchWindModelDataForModel(loc, model)
-	forecast := WindForecastFromModelData(modelData)
+	forecast, _ := FetchWindForecastForModelContext(context.Background(), nil, loc, model)
 	return forecast
 }
 
+// Grabs the latest wind data for a given location and model using the given context and Fetcher.
+// A nil Fetcher uses the DefaultFetcher.
+func FetchWindForecastForModelContext(ctx context.Context, fetcher Fetcher, loc Location, model *WindModel) (*WindForecast, error) {
+	modelData, fetchErr := FetchWindModelDataForModelContext(ctx, fetcher, loc, model)
+	if fetchErr != nil {
+		return nil, fetchErr
+	}
+	return WindForecastFromModelData(modelData), nil
+}
+
 // Grabs the latest Wave Model data from NOAA GRADS servers for a given Location
 // Data is returned as a WaveModelData object which contains a map of raw values.
 func FetchWindModelData(loc Location) *ModelData {
+	modelData, _ := FetchWindModelDataContext(context.Background(), nil, loc)
+	return modelData
+}
+
+// Grabs the latest wind model data for a given Location using the given context and Fetcher.
+// A nil Fetcher uses the DefaultFetcher.
+func FetchWindModelDataContext(ctx context.Context, fetcher Fetcher, loc Location) (*ModelData, error) {
 	model := GetWindModelForLocation(loc)
 	if model == nil {
-		return nil
+		return nil, errors.New("No wind model covers the given location")
 	}
 
-	// Create the url
-	var timeStepCount int = 0
-	if model.ModelType == GFS {
-		timeStepCount = 60
-	} else if model.ModelType == NAM {
-		timeStepCount = 20
-	}
-	url := model.CreateURL(loc, 0, timeStepCount)
+	return FetchWindModelDataForModelContext(ctx, fetcher, loc, model)
+}
 
-	// Fetch the raw data
-	rawData, err := fetchRawDataFromURL(url)
-	if err != nil {
-		return nil
+// Grabs the latest Wave Model data from NOAA GRADS servers for a given Location and Model
+// Data is returned as a WaveModelData object which contains a map of raw values.
+func FetchWindModelDataForModel(loc Location, model *WindModel) *ModelData {
+	modelData, _ := FetchWindModelDataForModelContext(context.Background(), nil, loc, model)
+	return modelData
+}
+
+// Grabs the latest wind model data for a given Location and Model using the given context and
+// Fetcher. The newest published model run is found with a ModelRunResolver. A nil Fetcher uses
+// the DefaultFetcher.
+func FetchWindModelDataForModelContext(ctx context.Context, fetcher Fetcher, loc Location, model *WindModel) (*ModelData, error) {
+	if model == nil {
+		return nil, errors.New("No wind model given")
 	}
 
-	// Call to parse the raw data into containers
-	modelDataContainer := parseRawModelData(rawData)
-	modelData := &ModelData{
-		Location: loc,
-		Model:    model.NOAAModel,
-		Data:     modelDataContainer,
+	run, runErr := NewModelRunResolver(fetcher).ResolveWindModelRun(ctx, model)
+	if runErr != nil {
+		return nil, runErr
 	}
-	return modelData
+
+	return FetchWindModelDataForRunContext(ctx, fetcher, loc, model, run)
 }
 
-// Grabs the latest Wave Model data from NOAA GRADS servers for a given Location and Model
-// Data is returned as a WaveModelData object which contains a map of raw values.
-func FetchWindModelDataForModel(loc Location, model *WindModel) *ModelData {
+// Grabs the wind model data of a specific model run for a given Location and Model using the given
+// context and Fetcher. A nil Fetcher uses the DefaultFetcher.
+func FetchWindModelDataForRunContext(ctx context.Context, fetcher Fetcher, loc Location, model *WindModel, run time.Time) (*ModelData, error) {
 	if model == nil {
-		return nil
+		return nil, errors.New("No wind model given")
 	}
 
-	// Create the url
+	// Find the number of time steps to fetch
 	var timeStepCount int = 0
 	if model.ModelType == GFS {
 		timeStepCount = 60
 	} else if model.ModelType == NAM {
 		timeStepCount = 20
 	}
-	url := model.CreateURL(loc, 0, timeStepCount)
 
-	// Fetch the raw data
-	rawData, err := fetchRawDataFromURL(url)
-	if err != nil {
-		return nil
+	bottomLeft, topRight := model.ringArea(loc, model.locationRings())
+	grid, gridErr := fetchWindModelGrid(ctx, fetcher, model, run, bottomLeft, topRight, ModelTimeRange{StartIndex: 0, EndIndex: timeStepCount})
+	if gridErr != nil {
+		return nil, gridErr
 	}
+	return grid.locationModelData(loc)
+}
 
-	// Call to parse the raw data into containers
-	modelDataContainer := parseRawModelData(rawData)
-	modelData := &ModelData{
-		Location: loc,
-		Model:    model.NOAAModel,
-		Data:     modelDataContainer,
+// Grabs the latest wind model data from NOAA GRADS servers over the area between two corners, for the
+// given time steps of the run. Data is returned as a ModelGrid holding every variable by latitude,
+// longitude and time.
+func FetchWindModelGrid(bottomLeft, topRight Location, model *WindModel, timeRange ModelTimeRange) *ModelGrid {
+	grid, _ := FetchWindModelGridContext(context.Background(), nil, bottomLeft, topRight, model, timeRange)
+	return grid
+}
+
+// Grabs the latest wind model data over the area between two corners using the given context and
+// Fetcher. The newest published model run is found with a ModelRunResolver. A nil Fetcher uses the
+// DefaultFetcher.
+func FetchWindModelGridContext(ctx context.Context, fetcher Fetcher, bottomLeft, topRight Location, model *WindModel, timeRange ModelTimeRange) (*ModelGrid, error) {
+	if model == nil {
+		return nil, errors.New("No wind model given")
 	}
-	return modelData
+
+	run, runErr := NewModelRunResolver(fetcher).ResolveWindModelRun(ctx, model)
+	if runErr != nil {
+		return nil, runErr
+	}
+
+	return fetchWindModelGrid(ctx, fetcher, model, run, bottomLeft, topRight, timeRange)
+}
+
+// Grabs the wind model data of a specific model run over the area between two corners using the given
+// context and Fetcher. A nil Fetcher uses the DefaultFetcher.
+func FetchWindModelGridForRunContext(ctx context.Context, fetcher Fetcher, bottomLeft, topRight Location, model *WindModel, run time.Time, timeRange ModelTimeRange) (*ModelGrid, error) {
+	if model == nil {
+		return nil, errors.New("No wind model given")
+	}
+
+	return fetchWindModelGrid(ctx, fetcher, model, run, bottomLeft, topRight, timeRange)
+}
+
+func fetchWindModelGrid(ctx context.Context, fetcher Fetcher, model *WindModel, run time.Time, bottomLeft, topRight Location, timeRange ModelTimeRange) (*ModelGrid, error) {
+	return fetchModelGridFromDAP(ctx, fetcher, model.NOAAModel, run, model.CreateDatasetURL(run), windModelVariables, bottomLeft, topRight, timeRange)
 }
 
 // Takes in raw data and parses it into a ModelData object. Useful for
-// implementing your own network fetching.
+// implementing your own network fetching. The run time of the model is taken from
+// its time axis unless it is already set. Returns nil if the model is parsed strictly
+// and the data contains unreadable values, or if the data is not from the run of the model.
 func WindModelDataFromRaw(loc Location, model NOAAModel, rawData []byte) *ModelData {
 	// Call to parse the raw data into containers
-	modelDataContainer := parseRawModelData(rawData)
-	modelData := &ModelData{
-		Location: loc,
-		Model:    model,
-		Data:     modelDataContainer,
-	}
+	modelData, _ := modelDataFromRaw(loc, model, rawData, model.Name)
 	return modelData
 }